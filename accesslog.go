@@ -0,0 +1,268 @@
+package s3
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// AccessRecord is a request recorded in an S3 server access log. Fields
+// logged as "-" are left empty, and fields added to the format after a log
+// was written are empty too.
+type AccessRecord struct {
+	BucketOwner      string
+	Bucket           string
+	Time             time.Time
+	RemoteIP         string
+	Requester        string
+	RequestID        string
+	Operation        string
+	Key              string
+	RequestURI       string
+	HTTPStatus       int
+	ErrorCode        string
+	BytesSent        int64
+	ObjectSize       int64
+	TotalTime        time.Duration
+	TurnAroundTime   time.Duration
+	Referer          string
+	UserAgent        string
+	VersionID        string
+	HostID           string
+	SignatureVersion string
+	CipherSuite      string
+	AuthType         string
+	HostHeader       string
+	TLSVersion       string
+	AccessPointARN   string
+	ACLRequired      string
+}
+
+// accessLogTime is the layout of access log timestamps.
+const accessLogTime = "02/Jan/2006:15:04:05 -0700"
+
+// ParseAccessLog iterates over the records of an S3 server access log.
+// Iteration stops after the first malformed line.
+func ParseAccessLog(r io.Reader) iter.Seq2[AccessRecord, error] {
+	return func(yield func(AccessRecord, error) bool) {
+		s := bufio.NewScanner(r)
+		s.Buffer(make([]byte, 64<<10), 1<<20)
+		var n int
+		for s.Scan() {
+			n++
+			if strings.TrimSpace(s.Text()) == "" {
+				continue
+			}
+			rec, err := parseAccessRecord(s.Text())
+			if err != nil {
+				yield(AccessRecord{}, fmt.Errorf("s3: access log line %d: %w", n, err))
+				return
+			}
+			if !yield(rec, nil) {
+				return
+			}
+		}
+		if err := s.Err(); err != nil {
+			yield(AccessRecord{}, err)
+		}
+	}
+}
+
+// parseAccessRecord parses a line of an access log.
+func parseAccessRecord(line string) (AccessRecord, error) {
+	var f []string
+	for line = strings.TrimLeft(line, " "); line != ""; line = strings.TrimLeft(line, " ") {
+		end := " "
+		switch line[0] {
+		case '[':
+			end = "]"
+		case '"':
+			end = `"`
+		}
+		if end != " " {
+			i := strings.Index(line[1:], end)
+			if i < 0 {
+				return AccessRecord{}, errors.New("unterminated " + line[:1])
+			}
+			f = append(f, line[1:i+1])
+			line = line[i+2:]
+			continue
+		}
+		v, rest, _ := strings.Cut(line, " ")
+		f = append(f, v)
+		line = rest
+	}
+	if len(f) < 18 {
+		return AccessRecord{}, fmt.Errorf("%d fields, want at least 18", len(f))
+	}
+	for i, v := range f {
+		if v == "-" {
+			f[i] = ""
+		}
+	}
+	f = append(f, make([]string, max(26-len(f), 0))...)
+
+	t, err := time.Parse(accessLogTime, f[2])
+	if err != nil {
+		return AccessRecord{}, err
+	}
+	k, err := url.QueryUnescape(f[7])
+	if err != nil {
+		return AccessRecord{}, err
+	}
+	rec := AccessRecord{
+		BucketOwner:      f[0],
+		Bucket:           f[1],
+		Time:             t,
+		RemoteIP:         f[3],
+		Requester:        f[4],
+		RequestID:        f[5],
+		Operation:        f[6],
+		Key:              k,
+		RequestURI:       f[8],
+		ErrorCode:        f[10],
+		Referer:          f[15],
+		UserAgent:        f[16],
+		VersionID:        f[17],
+		HostID:           f[18],
+		SignatureVersion: f[19],
+		CipherSuite:      f[20],
+		AuthType:         f[21],
+		HostHeader:       f[22],
+		TLSVersion:       f[23],
+		AccessPointARN:   f[24],
+		ACLRequired:      f[25],
+	}
+	var errs []error
+	num := func(v string) int64 {
+		if v == "" {
+			return 0
+		}
+		n, err := strconv.ParseInt(v, 10, 64)
+		errs = append(errs, err)
+		return n
+	}
+	rec.HTTPStatus = int(num(f[9]))
+	rec.BytesSent = num(f[11])
+	rec.ObjectSize = num(f[12])
+	rec.TotalTime = time.Duration(num(f[13])) * time.Millisecond
+	rec.TurnAroundTime = time.Duration(num(f[14])) * time.Millisecond
+	if err = errors.Join(errs...); err != nil {
+		return AccessRecord{}, err
+	}
+	return rec, nil
+}
+
+// ReadAccessLogs iterates over the records of the S3 server access logs
+// delivered under the prefix of the client's bucket, which is the target
+// bucket of the logging configuration. Log objects are read one at a time in
+// listing order; iteration stops after the first error.
+func (c *client) ReadAccessLogs(p string) iter.Seq2[AccessRecord, error] {
+	return func(yield func(AccessRecord, error) bool) {
+
+		var n, files int
+		err := c.walk(p, func(obj types.Object) error {
+			files++
+			r, err := c.getBucket(*c.Bucket, *obj.Key)
+			if err != nil {
+				return err
+			}
+			defer r.Close()
+			for rec, err := range ParseAccessLog(r) {
+				if err != nil {
+					return fmt.Errorf("%s: %w", *obj.Key, err)
+				}
+				n++
+				if !yield(rec, nil) {
+					return errAccessLogsStopped
+				}
+			}
+			return nil
+		})
+		if err == errAccessLogsStopped {
+			err = nil
+		}
+
+		c.log("ReadAccessLogs", err).
+			Str("prefix", p).
+			Int("files", files).
+			Int("records", n).
+			Msg("ReadAccessLogs")
+
+		if err != nil {
+			yield(AccessRecord{}, err)
+		}
+	}
+}
+
+// errAccessLogsStopped stops the ReadAccessLogs listing once iteration
+// has stopped.
+var errAccessLogsStopped = errors.New("s3: access logs stopped")
+
+// AccessSummary totals the requests in a group of access log records.
+type AccessSummary struct {
+	Requests  int64
+	Errors    int64
+	BytesSent int64
+}
+
+// SummarizeAccess totals the records by the group by returns for each,
+// skipping records it returns "" for. It stops at the first error.
+func SummarizeAccess(records iter.Seq2[AccessRecord, error], by func(AccessRecord) string) (map[string]AccessSummary, error) {
+	groups := map[string]AccessSummary{}
+	for rec, err := range records {
+		if err != nil {
+			return groups, err
+		}
+		g := by(rec)
+		if g == "" {
+			continue
+		}
+		s := groups[g]
+		s.Requests++
+		if rec.HTTPStatus >= 400 {
+			s.Errors++
+		}
+		s.BytesSent += rec.BytesSent
+		groups[g] = s
+	}
+	return groups, nil
+}
+
+// AccessByKey groups access log records by object key.
+func AccessByKey(r AccessRecord) string {
+	return r.Key
+}
+
+// AccessByRequester groups access log records by the requester's IAM
+// identity, or "anonymous" for unauthenticated requests.
+func AccessByRequester(r AccessRecord) string {
+	if r.Requester == "" {
+		return "anonymous"
+	}
+	return r.Requester
+}
+
+// AccessByPrefix groups access log records by the first depth "/" separated
+// segments of their key, e.g. "users/" for "users/1.json" with a depth of 1.
+func AccessByPrefix(depth int) func(AccessRecord) string {
+	return func(r AccessRecord) string {
+		i, k := 0, r.Key
+		for range depth {
+			j := strings.Index(k[i:], "/")
+			if j < 0 {
+				return k
+			}
+			i += j + 1
+		}
+		return k[:i]
+	}
+}
@@ -0,0 +1,82 @@
+package s3
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testAccessLog = `79a59df900b949e5 bytelyon-db [06/Feb/2019:00:00:38 +0000] 192.0.2.3 arn:aws:iam::123456789012:user/ann 3E57427F3EXAMPLE REST.GET.OBJECT users/1.json "GET /bytelyon-db/users/1.json HTTP/1.1" 200 - 113 113 7 6 "-" "aws-sdk-go-v2/1.41.1" - s9lzHYrFp76ZVxRc= SigV4 ECDHE-RSA-AES128-GCM-SHA256 AuthHeader bytelyon-db.s3.us-east-1.amazonaws.com TLSv1.2 - -
+79a59df900b949e5 bytelyon-db [06/Feb/2019:00:00:39 +0000] 192.0.2.3 - 891CE47D2EXAMPLE REST.GET.OBJECT users/my%20photo.png "GET /bytelyon-db/users/my%20photo.png HTTP/1.1" 404 NoSuchKey 243 - 12 - "https://example.com/" "Mozilla/5.0 (X11; Linux)" - Ke1bUcazaN1jWuUl= SigV4 - - bytelyon-db.s3.amazonaws.com - - -
+
+79a59df900b949e5 bytelyon-db [06/Feb/2019:00:01:00 +0000] 192.0.2.9 arn:aws:iam::123456789012:user/ann A1206F460EXAMPLE REST.PUT.OBJECT logs/a.txt "PUT /bytelyon-db/logs/a.txt HTTP/1.1" 200 - - 1024 70 10 "-" "curl/8.0" - BNaBsXZQQDbssi6x= SigV4
+`
+
+func TestParseAccessLog(t *testing.T) {
+
+	var records []AccessRecord
+	for r, err := range ParseAccessLog(strings.NewReader(testAccessLog)) {
+		assert.NoError(t, err)
+		records = append(records, r)
+	}
+	if assert.Len(t, records, 3) {
+		r := records[0]
+		assert.Equal(t, "bytelyon-db", r.Bucket)
+		assert.Equal(t, time.Date(2019, 2, 6, 0, 0, 38, 0, time.UTC), r.Time.UTC())
+		assert.Equal(t, "arn:aws:iam::123456789012:user/ann", r.Requester)
+		assert.Equal(t, "REST.GET.OBJECT", r.Operation)
+		assert.Equal(t, "users/1.json", r.Key)
+		assert.Equal(t, "GET /bytelyon-db/users/1.json HTTP/1.1", r.RequestURI)
+		assert.Equal(t, 200, r.HTTPStatus)
+		assert.Equal(t, int64(113), r.BytesSent)
+		assert.Equal(t, 7*time.Millisecond, r.TotalTime)
+		assert.Equal(t, "aws-sdk-go-v2/1.41.1", r.UserAgent)
+		assert.Equal(t, "TLSv1.2", r.TLSVersion)
+
+		r = records[1]
+		assert.Equal(t, "users/my photo.png", r.Key)
+		assert.Equal(t, "NoSuchKey", r.ErrorCode)
+		assert.Empty(t, r.Requester)
+		assert.Equal(t, "Mozilla/5.0 (X11; Linux)", r.UserAgent)
+		assert.Equal(t, "https://example.com/", r.Referer)
+
+		// older logs end after the signature version
+		assert.Equal(t, int64(1024), records[2].ObjectSize)
+		assert.Empty(t, records[2].HostHeader)
+	}
+
+	for _, err := range ParseAccessLog(strings.NewReader("owner bucket [06/Feb/2019:00:00:38 +0000 rest")) {
+		assert.ErrorContains(t, err, "line 1")
+	}
+}
+
+func TestClient_ReadAccessLogs(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	lines := strings.SplitAfter(testAccessLog, "\n")
+	b.put("logs/2019-02-06-00-00-00-A", []byte(lines[0]+lines[1]), nil)
+	b.put("logs/2019-02-06-00-01-00-B", []byte(lines[3]), nil)
+
+	byRequester, err := SummarizeAccess(c.ReadAccessLogs("logs/"), AccessByRequester)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]AccessSummary{
+		"arn:aws:iam::123456789012:user/ann": {Requests: 2, BytesSent: 113},
+		"anonymous":                          {Requests: 1, Errors: 1, BytesSent: 243},
+	}, byRequester)
+
+	byPrefix, err := SummarizeAccess(c.ReadAccessLogs("logs/"), AccessByPrefix(1))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), byPrefix["users/"].Requests)
+	assert.Equal(t, int64(1), byPrefix["logs/"].Requests)
+
+	byKey, err := SummarizeAccess(c.ReadAccessLogs("logs/"), AccessByKey)
+	assert.NoError(t, err)
+	assert.Len(t, byKey, 3)
+
+	// iteration can stop early
+	for range c.ReadAccessLogs("logs/") {
+		break
+	}
+}
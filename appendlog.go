@@ -0,0 +1,279 @@
+package s3
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"iter"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/oklog/ulid/v2"
+)
+
+const (
+	appendChunks    = "/chunks/"
+	appendSegments  = "/segments/"
+	appendCompacted = "/compacted/"
+)
+
+// appendSettle is how old chunks must be before Compact merges them, so
+// appends still in flight, or from writers with skewed clocks, aren't
+// ordered before a segment that has already been written.
+var appendSettle = time.Minute
+
+// AppendLog emulates an append-only log, which S3 lacks, under a prefix.
+// Each Append writes its records as a small chunk object named with a ULID,
+// <prefix>/chunks/<ulid>, so chunks list in the order they were written, and
+// Compact merges settled chunks into larger segments, <prefix>/segments/<ulid>,
+// named when they are written, and records the chunks each holds under
+// <prefix>/compacted/<ulid> until they are deleted. Appends from one
+// AppendLog are ordered; appends from different writers are ordered by their
+// clocks, except that chunks written after a segment that sorts after them
+// are read after it.
+type AppendLog struct {
+	c       *client
+	prefix  string
+	mu      sync.Mutex
+	entropy io.Reader
+}
+
+// AppendLog returns the append-only log stored under the prefix.
+func (c *client) AppendLog(p string) *AppendLog {
+	return &AppendLog{c: c, prefix: strings.TrimSuffix(p, "/"), entropy: ulid.Monotonic(rand.Reader, 0)}
+}
+
+// id returns a ULID that sorts after every other returned by the log.
+func (l *AppendLog) id() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return ulid.MustNew(ulid.Now(), l.entropy).String()
+}
+
+// Append writes the records to the log as one chunk, so they are read in
+// order and together, after the records of earlier appends.
+func (l *AppendLog) Append(records ...[]byte) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	var body []byte
+	for _, r := range records {
+		body = binary.AppendUvarint(body, uint64(len(r)))
+		body = append(body, r...)
+	}
+	k := l.prefix + appendChunks + l.id()
+	_, err := l.c.putObject(&s3.PutObjectInput{
+		Bucket:      l.c.Bucket,
+		Key:         &k,
+		ContentType: aws.String("application/octet-stream"),
+	}, body)
+
+	l.c.log("Append", err).
+		Str("key", k).
+		Int("records", len(records)).
+		Msg("Append")
+
+	return err
+}
+
+// Read returns the records of the log in order. A read that races a
+// Compact may fail to find a chunk the compaction deleted and should be
+// retried.
+func (l *AppendLog) Read() iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+
+		objects, err := l.objects()
+		var keys []string
+		if err == nil {
+			keys = append(objects.segments, objects.chunks...)
+		}
+
+		var n int
+	read:
+		for i := 0; err == nil && i < len(keys); i++ {
+			var b []byte
+			if b, err = l.c.read(keys[i]); err != nil {
+				break
+			}
+			for len(b) > 0 {
+				size, w := binary.Uvarint(b)
+				if w <= 0 || uint64(len(b)-w) < size {
+					err = fmt.Errorf("s3: corrupt append log object %s", keys[i])
+					break read
+				}
+				n++
+				if !yield(b[w:w+int(size)], nil) {
+					break read
+				}
+				b = b[w+int(size):]
+			}
+		}
+
+		l.c.log("ReadAppendLog", err).
+			Str("prefix", l.prefix).
+			Int("records", n).
+			Msg("ReadAppendLog")
+
+		if err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// appendObjects is the objects of a log.
+type appendObjects struct {
+	// segments and chunks are the keys of the log's segments and of the
+	// chunks not merged into them, in order.
+	segments, chunks []string
+	// compacted maps the keys of the records of chunks merged into a
+	// segment to the keys of those chunks not yet deleted.
+	compacted map[string][]string
+}
+
+// objects returns the log's objects. Chunks already merged into a segment,
+// which a compaction hasn't deleted yet, are skipped.
+func (l *AppendLog) objects() (appendObjects, error) {
+	o := appendObjects{compacted: map[string][]string{}}
+	var records []string
+	// chunks are listed first so none can be merged into a segment unseen,
+	// and compaction records last so none merged into a segment listed is
+	// missed, as they outlive their chunks
+	err := l.c.walk(l.prefix+appendChunks, func(obj types.Object) error {
+		o.chunks = append(o.chunks, *obj.Key)
+		return nil
+	})
+	if err == nil {
+		err = l.c.walk(l.prefix+appendSegments, func(obj types.Object) error {
+			o.segments = append(o.segments, *obj.Key)
+			return nil
+		})
+	}
+	if err == nil {
+		err = l.c.walk(l.prefix+appendCompacted, func(obj types.Object) error {
+			records = append(records, *obj.Key)
+			return nil
+		})
+	}
+
+	segments := map[string]bool{}
+	for _, k := range o.segments {
+		segments[path.Base(k)] = true
+	}
+	listed := map[string]bool{}
+	for _, k := range o.chunks {
+		listed[k] = true
+	}
+	merged := map[string]bool{}
+	for i := 0; err == nil && i < len(records); i++ {
+		if !segments[path.Base(records[i])] {
+			// the compaction failed before writing its segment
+			o.compacted[records[i]] = nil
+			continue
+		}
+		var b []byte
+		if b, err = l.c.read(records[i]); err != nil {
+			break
+		}
+		var chunks []string
+		for _, id := range strings.Fields(string(b)) {
+			k := l.prefix + appendChunks + id
+			if listed[k] {
+				chunks = append(chunks, k)
+				merged[k] = true
+			}
+		}
+		o.compacted[records[i]] = chunks
+	}
+	if err != nil {
+		return appendObjects{}, err
+	}
+	o.chunks = slices.DeleteFunc(o.chunks, func(k string) bool { return merged[k] })
+	return o, nil
+}
+
+// Compact merges the chunks written more than a minute ago into a segment,
+// streaming them into it, and deletes them, returning how many chunks were
+// merged. It first deletes the chunks earlier compactions merged but failed
+// to delete, and the records of settled compactions whose chunks are all
+// deleted. Only one compaction of a log should run at a time.
+func (l *AppendLog) Compact() (int, error) {
+
+	objects, err := l.objects()
+	cutoff := ulid.Timestamp(time.Now().Add(-appendSettle))
+
+	for _, k := range slices.Sorted(maps.Keys(objects.compacted)) {
+		for _, chunk := range objects.compacted[k] {
+			if err == nil {
+				err = l.c.Delete(chunk)
+			}
+		}
+		if id, perr := ulid.ParseStrict(path.Base(k)); err == nil && perr == nil && id.Time() < cutoff {
+			err = l.c.Delete(k)
+		}
+	}
+
+	chunks := objects.chunks
+	var n int
+	for err == nil && n < len(chunks) {
+		var id ulid.ULID
+		if id, err = ulid.ParseStrict(path.Base(chunks[n])); err == nil && id.Time() >= cutoff {
+			break
+		}
+		n++
+	}
+
+	var k string
+	if err == nil && n > 0 {
+		id := l.id()
+		k = l.prefix + appendSegments + id
+		ids := make([]string, n)
+		for i, chunk := range chunks[:n] {
+			ids[i] = path.Base(chunk)
+		}
+		_, err = l.c.putObject(&s3.PutObjectInput{
+			Bucket:      l.c.Bucket,
+			Key:         aws.String(l.prefix + appendCompacted + id),
+			ContentType: aws.String("text/plain"),
+		}, []byte(strings.Join(ids, "\n")))
+		if err == nil {
+			err = l.merge(k, chunks[:n])
+		}
+	}
+	for i := 0; err == nil && i < n; i++ {
+		err = l.c.Delete(chunks[i])
+	}
+
+	l.c.log("Compact", err).
+		Str("prefix", l.prefix).
+		Str("segment", k).
+		Int("chunks", n).
+		Msg("Compact")
+
+	return n, err
+}
+
+// merge streams the chunks into a segment.
+func (l *AppendLog) merge(k string, chunks []string) error {
+	u := l.c.newUploader(&s3.PutObjectInput{Key: &k, ContentType: aws.String("application/octet-stream")})
+	for _, chunk := range chunks {
+		out, err := l.c.getObject(&s3.GetObjectInput{Bucket: l.c.Bucket, Key: &chunk})
+		if err == nil {
+			_, err = io.Copy(u, out.Body)
+			out.Body.Close()
+		}
+		if err != nil {
+			u.CloseWithError(err)
+			return err
+		}
+	}
+	return u.Close()
+}
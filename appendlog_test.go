@@ -0,0 +1,102 @@
+package s3
+
+import (
+	"crypto/rand"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func readAppendLog(t *testing.T, l *AppendLog) []string {
+	t.Helper()
+	var records []string
+	for r, err := range l.Read() {
+		assert.NoError(t, err)
+		records = append(records, string(r))
+	}
+	return records
+}
+
+func TestAppendLog(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	l := c.AppendLog("events/")
+
+	assert.NoError(t, l.Append([]byte("a"), []byte("")))
+	assert.NoError(t, l.Append())
+	assert.NoError(t, l.Append([]byte("b")))
+	assert.NoError(t, l.Append([]byte("c"), []byte("d")))
+	assert.Equal(t, []string{"a", "", "b", "c", "d"}, readAppendLog(t, l))
+	assert.Len(t, b.keys(), 3)
+
+	// chunks must settle before they're merged
+	n, err := l.Compact()
+	assert.NoError(t, err)
+	assert.Zero(t, n)
+
+	defer func(d time.Duration) { appendSettle = d }(appendSettle)
+	appendSettle = -time.Minute
+
+	n, err = l.Compact()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Len(t, b.keys(), 2)
+	assert.True(t, strings.HasPrefix(b.keys()[0], "events/compacted/"))
+	assert.True(t, strings.HasPrefix(b.keys()[1], "events/segments/"))
+
+	assert.NoError(t, l.Append([]byte("e")))
+	assert.Equal(t, []string{"a", "", "b", "c", "d", "e"}, readAppendLog(t, l))
+
+	// records of settled compactions are deleted by the next
+	n, err = l.Compact()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Len(t, b.keys(), 3)
+	assert.Equal(t, []string{"a", "", "b", "c", "d", "e"}, readAppendLog(t, l))
+
+	for r, err := range l.Read() {
+		assert.NoError(t, err)
+		assert.Equal(t, "a", string(r))
+		break
+	}
+}
+
+func TestAppendLog_Objects(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	l := c.AppendLog("events")
+	assert.NoError(t, l.Append([]byte("a")))
+	assert.NoError(t, l.Append([]byte("b")))
+
+	// a compaction that wrote its segment but hasn't deleted its chunks yet
+	chunks := b.keys()
+	id := l.id()
+	b.put("events/compacted/"+id, []byte(path.Base(chunks[0])+"\n"+path.Base(chunks[1])), nil)
+	b.put("events/segments/"+id, append(b.object(chunks[0]).body, b.object(chunks[1]).body...), nil)
+	assert.Equal(t, []string{"a", "b"}, readAppendLog(t, l))
+
+	// chunks written late, or whose compaction failed before its segment,
+	// aren't hidden
+	late := "events/chunks/" + ulid.MustNew(0, rand.Reader).String()
+	b.put(late, []byte("\x01c"), nil)
+	b.put("events/compacted/"+l.id(), []byte(path.Base(late)), nil)
+	assert.Equal(t, []string{"a", "b", "c"}, readAppendLog(t, l))
+
+	// the next compaction deletes merged chunks and merges the others
+	defer func(d time.Duration) { appendSettle = d }(appendSettle)
+	appendSettle = -time.Minute
+	n, err := l.Compact()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Len(t, b.keys(), 3)
+	assert.Equal(t, []string{"a", "b", "c"}, readAppendLog(t, l))
+
+	b.put("events/chunks/"+strings.Repeat("Z", len(id)), []byte("\x05ab"), nil)
+	for _, err = range l.Read() {
+	}
+	assert.ErrorContains(t, err, "corrupt append log object")
+}
@@ -0,0 +1,279 @@
+package s3
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ArchiveFormat is the container format of an archive object.
+type ArchiveFormat string
+
+const (
+	TarGz ArchiveFormat = "tar.gz"
+	Zip   ArchiveFormat = "zip"
+)
+
+// ErrArchiveFormat is returned for an unsupported ArchiveFormat.
+var ErrArchiveFormat = errors.New("s3: unsupported archive format")
+
+func (f ArchiveFormat) contentType() string {
+	if f == Zip {
+		return "application/zip"
+	}
+	return "application/gzip"
+}
+
+// archiveWriter writes files into an archive stream.
+type archiveWriter interface {
+	add(name string, size int64, mode fs.FileMode, r io.Reader) error
+	Close() error
+}
+
+func newArchiveWriter(w io.Writer, f ArchiveFormat) (archiveWriter, error) {
+	switch f {
+	case TarGz:
+		gz := gzip.NewWriter(w)
+		return &tarWriter{tar.NewWriter(gz), gz}, nil
+	case Zip:
+		return &zipWriter{zip.NewWriter(w)}, nil
+	}
+	return nil, ErrArchiveFormat
+}
+
+type tarWriter struct {
+	*tar.Writer
+	gz *gzip.Writer
+}
+
+func (t *tarWriter) add(name string, size int64, mode fs.FileMode, r io.Reader) error {
+	if err := t.WriteHeader(&tar.Header{Name: name, Size: size, Mode: int64(mode.Perm())}); err != nil {
+		return err
+	}
+	_, err := io.Copy(t, r)
+	return err
+}
+
+func (t *tarWriter) Close() error {
+	if err := t.Writer.Close(); err != nil {
+		return err
+	}
+	return t.gz.Close()
+}
+
+type zipWriter struct {
+	*zip.Writer
+}
+
+func (z *zipWriter) add(name string, size int64, mode fs.FileMode, r io.Reader) error {
+	h := &zip.FileHeader{Name: name, Method: zip.Deflate, UncompressedSize64: uint64(size)}
+	h.SetMode(mode)
+	w, err := z.CreateHeader(h)
+	if err == nil {
+		_, err = io.Copy(w, r)
+	}
+	return err
+}
+
+// PutArchive streams an archive of the files in dir to the key as a multipart
+// upload, without staging the archive on disk or buffering it in memory, even
+// when the key is validated or scanned, as parts are inspected as they upload.
+func (c *client) PutArchive(k, dir string, f ArchiveFormat) error {
+
+	u := c.newUploader(&s3.PutObjectInput{
+		Key:         &k,
+		ContentType: aws.String(f.contentType()),
+	})
+
+	var files int
+	aw, err := newArchiveWriter(u, f)
+	if err == nil {
+		err = filepath.WalkDir(dir, func(name string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(dir, name)
+			if err != nil {
+				return err
+			}
+			fi, err := d.Info()
+			if err != nil {
+				return err
+			}
+			r, err := os.Open(name)
+			if err != nil {
+				return err
+			}
+			defer r.Close()
+			files++
+			return aw.add(filepath.ToSlash(rel), fi.Size(), fi.Mode(), r)
+		})
+		if err == nil {
+			err = aw.Close()
+		}
+	}
+
+	if err == nil {
+		err = u.Close()
+	} else {
+		u.CloseWithError(err)
+	}
+
+	c.log("PutArchive", err).
+		Str("key", k).
+		Str("dir", dir).
+		Str("format", string(f)).
+		Int("files", files).
+		Msg("PutArchive")
+
+	return err
+}
+
+// StreamArchive writes an archive of every object under the prefix to w,
+// naming entries by their key relative to the prefix. The prefix is a
+// directory, so archiving "site" leaves out "site2/" and "sitemap.xml".
+func (c *client) StreamArchive(p string, w io.Writer, f ArchiveFormat) error {
+
+	if p != "" && !strings.HasSuffix(p, "/") {
+		p += "/"
+	}
+
+	var objects int
+	aw, err := newArchiveWriter(w, f)
+	if err == nil {
+		err = c.walk(p, func(obj types.Object) error {
+			name := strings.TrimPrefix(*obj.Key, p)
+			if name == "" || strings.HasSuffix(name, "/") {
+				return nil
+			}
+			body, err := c.GetReader(*obj.Key)
+			if err != nil {
+				return err
+			}
+			defer body.Close()
+			objects++
+			return aw.add(name, aws.ToInt64(obj.Size), 0o644, body)
+		})
+		if err == nil {
+			err = aw.Close()
+		}
+	}
+
+	c.log("StreamArchive", err).
+		Str("prefix", p).
+		Str("format", string(f)).
+		Int("objects", objects).
+		Msg("StreamArchive")
+
+	return err
+}
+
+// archiveFormat returns the format of an archive key from its extension.
+func archiveFormat(k string) (ArchiveFormat, error) {
+	switch {
+	case strings.HasSuffix(k, ".zip"):
+		return Zip, nil
+	case strings.HasSuffix(k, ".tar.gz"), strings.HasSuffix(k, ".tgz"):
+		return TarGz, nil
+	}
+	return "", ErrArchiveFormat
+}
+
+// Unpack writes every file in the zip or tar.gz archive object as its own
+// object under the destination prefix, streaming one entry at a time. Zip
+// archives are read in ranged chunks of 8 MiB so they are never buffered in
+// full, and fail if replaced while they're unpacked.
+func (c *client) Unpack(k, dst string) error {
+
+	var entries int
+	put := func(name string, r io.Reader) error {
+		name = path.Clean("/" + name)[1:]
+		if name == "" {
+			return nil
+		}
+		ek := path.Join(dst, name)
+		u := c.newUploader(&s3.PutObjectInput{Key: &ek})
+		if ct := mime.TypeByExtension(path.Ext(name)); ct != "" {
+			u.in.ContentType = &ct
+		}
+		if _, err := io.Copy(u, r); err != nil {
+			return u.CloseWithError(err)
+		}
+		entries++
+		return u.Close()
+	}
+
+	f, err := archiveFormat(k)
+	switch {
+	case err != nil:
+	case f == Zip:
+		var ra *objectReaderAt
+		if ra, err = c.newReaderAt(k); err != nil {
+			break
+		}
+		var zr *zip.Reader
+		if zr, err = zip.NewReader(ra, ra.size); err != nil {
+			break
+		}
+		for _, zf := range zr.File {
+			if zf.FileInfo().IsDir() {
+				continue
+			}
+			var r io.ReadCloser
+			if r, err = zf.Open(); err != nil {
+				break
+			}
+			err = put(zf.Name, r)
+			r.Close()
+			if err != nil {
+				break
+			}
+		}
+	default:
+		var body io.ReadCloser
+		if body, err = c.GetReader(k); err != nil {
+			break
+		}
+		defer body.Close()
+		var gz *gzip.Reader
+		if gz, err = gzip.NewReader(body); err != nil {
+			break
+		}
+		tr := tar.NewReader(gz)
+		for {
+			var h *tar.Header
+			if h, err = tr.Next(); err != nil {
+				if errors.Is(err, io.EOF) {
+					err = nil
+				}
+				break
+			}
+			if h.Typeflag != tar.TypeReg {
+				continue
+			}
+			if err = put(h.Name, tr); err != nil {
+				break
+			}
+		}
+	}
+
+	c.log("Unpack", err).
+		Str("key", k).
+		Str("dst", dst).
+		Int("entries", entries).
+		Msg("Unpack")
+
+	return err
+}
@@ -0,0 +1,185 @@
+package s3
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/assert"
+)
+
+func testDir(t *testing.T) string {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "users"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "users", "_.json"), []byte(testBody()), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), []byte("<html></html>"), 0o644))
+	return dir
+}
+
+func TestClient_PutArchive(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	dir := testDir(t)
+
+	assert.NoError(t, c.PutArchive("archives/site.zip", dir, Zip))
+	o := b.object("archives/site.zip")
+	assert.NotNil(t, o)
+	assert.Equal(t, "application/zip", o.header.Get("Content-Type"))
+	zr, err := zip.NewReader(bytes.NewReader(o.body), int64(len(o.body)))
+	assert.NoError(t, err)
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{"index.html", "users/_.json"}, names)
+
+	assert.NoError(t, c.PutArchive("archives/site.tar.gz", dir, TarGz))
+	gz, err := gzip.NewReader(bytes.NewReader(b.object("archives/site.tar.gz").body))
+	assert.NoError(t, err)
+	tr := tar.NewReader(gz)
+	names = nil
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		names = append(names, h.Name)
+	}
+	sort.Strings(names)
+	assert.Equal(t, []string{"index.html", "users/_.json"}, names)
+
+	assert.ErrorIs(t, c.PutArchive("archives/site.rar", dir, "rar"), ErrArchiveFormat)
+}
+
+func TestClient_PutArchive_scanned(t *testing.T) {
+
+	var parts atomic.Int32
+	c, b := newTestBucket(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Has("partNumber") {
+				parts.Add(1)
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	s := &eicarScanner{}
+	WithContentScanner("archives/*", s, "quarantine/")(c.options)
+
+	// archives of scanned keys still stream in parts, scanned as they upload
+	dir := testDir(t)
+	large := make([]byte, partSize+1024)
+	_, _ = rand.Read(large)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "large.bin"), large, 0o644))
+
+	assert.NoError(t, c.PutArchive("archives/site.zip", dir, Zip))
+	assert.Equal(t, int32(2), parts.Load())
+	assert.Equal(t, []string{"archives/site.zip"}, s.scanned)
+	o := b.object("archives/site.zip")
+	zr, err := zip.NewReader(bytes.NewReader(o.body), int64(len(o.body)))
+	assert.NoError(t, err)
+	assert.Len(t, zr.File, 3)
+}
+
+func TestUploader_multipart(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	body := make([]byte, partSize*2+1024)
+	_, _ = rand.Read(body)
+
+	u := c.newUploader(&s3.PutObjectInput{Key: aws.String("large.bin")})
+	_, err := io.Copy(u, bytes.NewReader(body))
+	assert.NoError(t, err)
+	assert.NoError(t, u.Close())
+	assert.Len(t, u.parts, 3)
+	assert.Equal(t, body, b.object("large.bin").body)
+}
+
+func TestClient_StreamArchive(t *testing.T) {
+
+	c, _ := newTestBucket(t)
+	assert.NoError(t, c.Put("site/users/_.json", testBody()))
+	assert.NoError(t, c.Put("site/index.html", "<html></html>"))
+	assert.NoError(t, c.Put("other/index.html", "<html></html>"))
+	assert.NoError(t, c.Put("site2/index.html", "<html></html>"))
+	assert.NoError(t, c.Put("sitemap.xml", "<urlset/>"))
+
+	var buf bytes.Buffer
+	assert.NoError(t, c.StreamArchive("site", &buf, Zip))
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+	assert.Len(t, zr.File, 2)
+	assert.Equal(t, "index.html", zr.File[0].Name)
+	r, err := zr.File[1].Open()
+	assert.NoError(t, err)
+	b, _ := io.ReadAll(r)
+	assert.Equal(t, testBody(), string(b))
+}
+
+func TestClient_Unpack(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	dir := testDir(t)
+
+	for _, k := range []string{"archives/site.zip", "archives/site.tar.gz"} {
+		f, err := archiveFormat(k)
+		assert.NoError(t, err)
+		assert.NoError(t, c.PutArchive(k, dir, f))
+		assert.NoError(t, c.Unpack(k, "unpacked/"+string(f)))
+		assert.Equal(t, testBody(), string(b.object("unpacked/"+string(f)+"/users/_.json").body))
+		assert.Equal(t, "text/html; charset=utf-8", b.object("unpacked/"+string(f)+"/index.html").header.Get("Content-Type"))
+	}
+
+	assert.ErrorIs(t, c.Unpack("archives/site.rar", "unpacked"), ErrArchiveFormat)
+}
+
+func TestClient_Unpack_chunked(t *testing.T) {
+
+	defer func(chunk int64) { readerAtChunk = chunk }(readerAtChunk)
+	readerAtChunk = 4096
+
+	var gets atomic.Int32
+	var replace atomic.Bool
+	var b *testBucket
+	c, b := newTestBucket(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet && r.Header.Get("Range") != "" {
+				gets.Add(1)
+				if replace.Load() {
+					b.mu.Lock()
+					b.put("archives/site.zip", []byte("replaced"), nil)
+					b.mu.Unlock()
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	dir := testDir(t)
+	large := make([]byte, 64<<10)
+	_, _ = rand.Read(large)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "large.bin"), large, 0o644))
+	assert.NoError(t, c.PutArchive("archives/site.zip", dir, Zip))
+	size := int32(len(b.object("archives/site.zip").body))
+
+	// small reads are served from the chunks fetched
+	assert.NoError(t, c.Unpack("archives/site.zip", "unpacked"))
+	assert.Equal(t, large, b.object("unpacked/large.bin").body)
+	assert.LessOrEqual(t, gets.Load(), size/4096+4)
+
+	// every chunk comes from the version first read
+	replace.Store(true)
+	assert.True(t, isPreconditionFailed(c.Unpack("archives/site.zip", "unpacked")))
+}
@@ -0,0 +1,79 @@
+package s3
+
+import (
+	"io"
+	"mime"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/oklog/ulid/v2"
+)
+
+// stagingPrefix is where PutAtomic uploads values before promoting them.
+const stagingPrefix = ".staging/"
+
+// stagingKey returns a new key under .staging/ to stage an object for the
+// key before promoting it.
+func stagingKey(k string) string {
+	return stagingPrefix + ulid.Make().String() + "/" + k
+}
+
+// stagedKey returns the key an object staged under the staging key returned
+// by stagingKey is for.
+func stagedKey(staging string) (string, bool) {
+	id, k, ok := strings.Cut(strings.TrimPrefix(staging, stagingPrefix), "/")
+	if !ok || !strings.HasPrefix(staging, stagingPrefix) {
+		return "", false
+	}
+	_, err := ulid.ParseStrict(id)
+	return k, err == nil
+}
+
+// promote copies an object staged under .staging/ to the key, inspecting
+// it first if writes to the key are validated or scanned, and deletes it.
+func (c *client) promote(staging, k string) error {
+	err := c.Copy(staging, k)
+	if _, derr := c.deleteObject(&s3.DeleteObjectInput{Bucket: c.Bucket, Key: &staging}); err == nil {
+		err = derr
+	}
+	return err
+}
+
+// PutAtomic uploads the value to a staging key under .staging/ and then copies
+// it over the key server-side, so readers see either the previous object or
+// the complete new one however long the upload takes. Values are encoded as
+// Put does, except that an io.Reader is streamed. Values written to keys
+// that are validated or scanned are inspected when promoted. The staging
+// object is deleted once promoted or if the upload fails.
+func (c *client) PutAtomic(k string, a any) error {
+
+	staging := stagingKey(k)
+
+	var err error
+	if r, ok := a.(io.Reader); ok {
+		u := c.newUploader(&s3.PutObjectInput{Key: &staging})
+		if ct := mime.TypeByExtension(path.Ext(k)); ct != "" {
+			u.in.ContentType = &ct
+		}
+		if _, err = io.Copy(u, r); err != nil {
+			u.CloseWithError(err)
+		} else {
+			err = u.Close()
+		}
+	} else {
+		_, err = c.put("Put", staging, a, &s3.PutObjectInput{})
+	}
+	if err == nil {
+		err = c.promote(staging, k)
+	} else {
+		_, _ = c.deleteObject(&s3.DeleteObjectInput{Bucket: c.Bucket, Key: &staging})
+	}
+
+	c.log("PutAtomic", err).
+		Str("key", k).
+		Str("staging", staging).
+		Msg("PutAtomic")
+
+	return err
+}
@@ -0,0 +1,53 @@
+package s3
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_PutAtomic(t *testing.T) {
+
+	c, b := newTestBucket(t)
+
+	assert.NoError(t, c.PutAtomic("users/a.json", map[string]string{"id": "a"}))
+	assert.Equal(t, `{"id":"a"}`, strings.TrimSpace(string(b.object("users/a.json").body)))
+	assert.Equal(t, "application/json", b.object("users/a.json").header.Get("Content-Type"))
+
+	body := bytes.Repeat([]byte{'a'}, partSize+1024)
+	assert.NoError(t, c.PutAtomic("large.txt", bytes.NewReader(body)))
+	assert.Equal(t, body, b.object("large.txt").body)
+	assert.Equal(t, "text/plain; charset=utf-8", b.object("large.txt").header.Get("Content-Type"))
+
+	// staging objects are deleted when the upload fails
+	WithMaxPutSize(4)(c.options)
+	assert.ErrorIs(t, c.PutAtomic("large.txt", "too large"), ErrPutTooLarge)
+	assert.Equal(t, body, b.object("large.txt").body)
+
+	assert.Equal(t, []string{"large.txt", "users/a.json"}, b.keys())
+}
+
+func TestClient_PutAtomic_staging(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	WithQuota("", 10, 0)(c.options)
+	var events []string
+	WithHooks(Hooks{
+		AfterPut: func(e HookEvent) error {
+			events = append(events, "put "+e.Key)
+			return nil
+		},
+		AfterDelete: func(e HookEvent) error {
+			events = append(events, "delete "+e.Key)
+			return nil
+		},
+	})(c.options)
+
+	// staging objects aren't passed to hooks, and count once promoted
+	assert.NoError(t, c.PutAtomic("a.txt", "hello"))
+	assert.Equal(t, []string{"put a.txt"}, events)
+	assert.ErrorIs(t, c.PutAtomic("b.txt", "hello!"), ErrQuotaExceeded)
+	assert.Equal(t, []string{"a.txt"}, b.keys())
+}
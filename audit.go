@@ -0,0 +1,76 @@
+package s3
+
+import (
+	"bytes"
+	"encoding/json"
+	"path"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/oklog/ulid/v2"
+)
+
+// AuditRecord describes a single mutation made through the client.
+type AuditRecord struct {
+	Time      time.Time `json:"time"`
+	Actor     string    `json:"actor"`
+	Operation string    `json:"operation"`
+	Key       string    `json:"key"`
+	ETag      string    `json:"etag,omitempty"`
+	Size      int64     `json:"size"`
+}
+
+type audit struct {
+	prefix string
+	actor  string
+}
+
+// WithAudit records every Put, Delete and Copy made through the client as an
+// AuditRecord object under the prefix, partitioned by UTC hour, attributing
+// the mutation to the given actor. A failure to record fails the mutation.
+func WithAudit(prefix, actor string) Option {
+	return func(o *options) {
+		o.audit = &audit{prefix, actor}
+	}
+}
+
+// key returns the key of the record, e.g. audit/2025/09/04/17/<ulid>.json.
+func (a *audit) key(r *AuditRecord) string {
+	id := ulid.MustNew(ulid.Timestamp(r.Time), ulid.DefaultEntropy())
+	return path.Join(a.prefix, r.Time.Format("2006/01/02/15"), id.String()+".json")
+}
+
+// record writes an audit record of the mutation when auditing is enabled.
+func (c *client) record(op, k string, etag *string, size int64) error {
+	if c.audit == nil {
+		return nil
+	}
+
+	r := &AuditRecord{
+		Time:      time.Now().UTC(),
+		Actor:     c.audit.actor,
+		Operation: op,
+		Key:       k,
+		ETag:      aws.ToString(etag),
+		Size:      size,
+	}
+	ak := c.audit.key(r)
+
+	b, err := json.Marshal(r)
+	if err == nil {
+		_, err = c.PutObject(c.Context, &s3.PutObjectInput{
+			Bucket:      c.Bucket,
+			Key:         &ak,
+			Body:        bytes.NewReader(b),
+			ContentType: aws.String("application/json"),
+		})
+	}
+
+	c.log("Audit", err).
+		Str("key", ak).
+		Bytes("body", b).
+		Msg("Audit")
+
+	return err
+}
@@ -0,0 +1,45 @@
+package s3
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Audit(t *testing.T) {
+
+	var records []AuditRecord
+	c := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/bytelyon-db/audit/") {
+			var rec AuditRecord
+			b, _ := io.ReadAll(r.Body)
+			assert.NoError(t, json.Unmarshal(b, &rec))
+			records = append(records, rec)
+			return
+		}
+		if r.Header.Get("X-Amz-Copy-Source") != "" {
+			_, _ = w.Write([]byte(`<CopyObjectResult><ETag>"copied"</ETag></CopyObjectResult>`))
+			return
+		}
+		w.Header().Set("ETag", `"put"`)
+	})
+	WithAudit("audit", "tester")(c.options)
+
+	assert.NoError(t, c.Put(testKey(), testBody()))
+	assert.NoError(t, c.Copy(testKey(), "users/copy.json"))
+	assert.NoError(t, c.Delete(testKey()))
+
+	assert.Len(t, records, 3)
+	assert.Equal(t, "Put", records[0].Operation)
+	assert.Equal(t, testKey(), records[0].Key)
+	assert.Equal(t, `"put"`, records[0].ETag)
+	assert.Equal(t, int64(len(testBody())), records[0].Size)
+	assert.Equal(t, "tester", records[0].Actor)
+	assert.Equal(t, "Copy", records[1].Operation)
+	assert.Equal(t, `"copied"`, records[1].ETag)
+	assert.Equal(t, "Delete", records[2].Operation)
+}
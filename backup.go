@@ -0,0 +1,138 @@
+package s3
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ErrManifestMismatch is returned by Import when a restored object
+// doesn't match its manifest entry.
+var ErrManifestMismatch = errors.New("s3: object does not match manifest")
+
+// manifestName is the name of the manifest object written by Export.
+const manifestName = "manifest.json"
+
+// Manifest lists the objects captured by an export.
+type Manifest struct {
+	Source  string          `json:"source"`
+	Created time.Time       `json:"created"`
+	Objects []ManifestEntry `json:"objects"`
+}
+
+// ManifestEntry describes an exported object by its key relative to the
+// exported prefix and the version of the source object that was copied.
+type ManifestEntry struct {
+	Key       string `json:"key"`
+	Size      int64  `json:"size"`
+	ETag      string `json:"etag"`
+	VersionID string `json:"versionId,omitempty"`
+}
+
+// Export copies every object under the prefix to the destination prefix and
+// writes a manifest of the copies to <dst>/manifest.json. Objects too large
+// for a single server-side copy are copied in ranged parts, and every copy
+// keeps the encryption of its source. The prefix is listed once before
+// copying so the export captures the objects that existed at that point in
+// time.
+func (c *client) Export(p, dst string) error {
+
+	var objects []types.Object
+	err := c.walk(p, func(obj types.Object) error {
+		objects = append(objects, obj)
+		return nil
+	})
+
+	m := Manifest{Source: p, Created: time.Now().UTC()}
+	for i := 0; err == nil && i < len(objects); i++ {
+		obj := objects[i]
+		rel := strings.TrimPrefix(*obj.Key, p)
+		var head *s3.HeadObjectOutput
+		head, _, err = c.copySized(&s3.CopyObjectInput{
+			Bucket:            c.Bucket,
+			Key:               aws.String(path.Join(dst, rel)),
+			CopySource:        aws.String(c.copySource(*obj.Key)),
+			CopySourceIfMatch: obj.ETag,
+		}, *obj.Key, "")
+		if err == nil {
+			m.Objects = append(m.Objects, ManifestEntry{
+				Key:       rel,
+				Size:      aws.ToInt64(obj.Size),
+				ETag:      aws.ToString(obj.ETag),
+				VersionID: aws.ToString(head.VersionId),
+			})
+		}
+	}
+
+	var b []byte
+	if err == nil {
+		if b, err = json.Marshal(m); err == nil {
+			err = c.Put(path.Join(dst, manifestName), b)
+		}
+	}
+
+	c.log("Export", err).
+		Str("prefix", p).
+		Str("dst", dst).
+		Int("objects", len(m.Objects)).
+		Msg("Export")
+
+	return err
+}
+
+// Import recreates the objects listed in the manifest written by Export under
+// the destination prefix, copying them from the export, in parts for objects
+// over 5 GiB, encrypted as the exported copies are. Each copy is verified
+// against the ETag in the manifest when that is an MD5 of the body, and
+// otherwise, as for multipart and SSE-KMS objects and copies made in parts,
+// against the size.
+func (c *client) Import(manifestKey, dst string) error {
+
+	var m Manifest
+	err := c.readJSON(manifestKey, &m)
+
+	src := path.Dir(manifestKey)
+	var restored int
+	for i := 0; err == nil && i < len(m.Objects); i++ {
+		e := m.Objects[i]
+		k := path.Join(dst, e.Key)
+		var head *s3.HeadObjectOutput
+		var etag string
+		head, etag, err = c.copySized(&s3.CopyObjectInput{
+			Bucket:     c.Bucket,
+			Key:        &k,
+			CopySource: aws.String(c.copySource(path.Join(src, e.Key))),
+		}, path.Join(src, e.Key), "")
+		if err != nil {
+			break
+		}
+		if etag == "" || !etagIsMD5(e.ETag, head.ServerSideEncryption, head.SSECustomerAlgorithm) {
+			// ETags other than MD5s change on copy, and copies in parts are
+			// multipart uploads
+			var info ObjectInfo
+			if info, err = c.Stat(k); err == nil && info.Size != e.Size {
+				err = fmt.Errorf("%w: %s is %d bytes, expected %d", ErrManifestMismatch, k, info.Size, e.Size)
+			}
+		} else if etag != e.ETag {
+			err = fmt.Errorf("%w: %s has ETag %s, expected %s", ErrManifestMismatch, k, etag, e.ETag)
+		}
+		if err == nil {
+			restored++
+		}
+	}
+
+	c.log("Import", err).
+		Str("key", manifestKey).
+		Str("dst", dst).
+		Int("objects", restored).
+		Msg("Import")
+
+	return err
+}
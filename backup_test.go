@@ -0,0 +1,83 @@
+package s3
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Export(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	assert.NoError(t, c.Put("users/1.json", testBody()))
+	assert.NoError(t, c.Put("users/2/_.json", testBody()))
+
+	assert.NoError(t, c.Export("users/", "backups/1"))
+	assert.Equal(t, testBody(), string(b.object("backups/1/2/_.json").body))
+
+	var m Manifest
+	assert.NoError(t, json.Unmarshal(b.object("backups/1/manifest.json").body, &m))
+	assert.Equal(t, "users/", m.Source)
+	assert.Len(t, m.Objects, 2)
+	assert.Equal(t, "1.json", m.Objects[0].Key)
+	assert.Equal(t, b.object("users/1.json").etag, m.Objects[0].ETag)
+	assert.Equal(t, int64(len(testBody())), m.Objects[1].Size)
+}
+
+func TestClient_Import(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	assert.NoError(t, c.Put("users/1.json", testBody()))
+	assert.NoError(t, c.Put("users/2/_.json", testBody()))
+	assert.NoError(t, c.Export("users/", "backups/1"))
+
+	assert.NoError(t, c.Import("backups/1/manifest.json", "restored"))
+	assert.Equal(t, testBody(), string(b.object("restored/1.json").body))
+	assert.Equal(t, testBody(), string(b.object("restored/2/_.json").body))
+
+	assert.NoError(t, c.Put("backups/1/1.json", "corrupt"))
+	assert.ErrorIs(t, c.Import("backups/1/manifest.json", "restored"), ErrManifestMismatch)
+}
+
+func TestClient_Import_large(t *testing.T) {
+
+	defer func(size, part int64) { maxCopySize, copyPartSize = size, part }(maxCopySize, copyPartSize)
+	maxCopySize, copyPartSize = 16, 10
+
+	c, b := newTestBucket(t)
+	assert.NoError(t, c.Put("users/1.json", testBody()))
+	assert.NoError(t, c.Put("users/2.json", "small"))
+	assert.NoError(t, c.Export("users/", "backups/1"))
+	assert.Equal(t, testBody(), string(b.object("backups/1/1.json").body))
+
+	assert.NoError(t, c.Import("backups/1/manifest.json", "restored"))
+	assert.Equal(t, testBody(), string(b.object("restored/1.json").body))
+	assert.Equal(t, "small", string(b.object("restored/2.json").body))
+	assert.Empty(t, b.uploads)
+}
+
+func TestClient_Import_kms(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	assert.NoError(t, c.Put("users/1.json", testBody()))
+	b.object("users/1.json").header.Set("X-Amz-Server-Side-Encryption", "aws:kms")
+	b.object("users/1.json").header.Set("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id", "arn:aws:kms:us-east-1:123456789012:key/k1")
+	assert.NoError(t, c.Export("users/", "backups/1"))
+
+	// exported copies keep their encryption
+	h := b.object("backups/1/1.json").header
+	assert.Equal(t, "aws:kms", h.Get("X-Amz-Server-Side-Encryption"))
+	assert.Equal(t, "arn:aws:kms:us-east-1:123456789012:key/k1", h.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"))
+
+	// the ETags of SSE-KMS objects aren't MD5s and change on copy
+	var m Manifest
+	assert.NoError(t, json.Unmarshal(b.object("backups/1/manifest.json").body, &m))
+	m.Objects[0].ETag = `"0123456789abcdef0123456789abcdef"`
+	mb, _ := json.Marshal(m)
+	b.object("backups/1/manifest.json").body = mb
+
+	assert.NoError(t, c.Import("backups/1/manifest.json", "restored"))
+	assert.Equal(t, testBody(), string(b.object("restored/1.json").body))
+	assert.Equal(t, "aws:kms", b.object("restored/1.json").header.Get("X-Amz-Server-Side-Encryption"))
+}
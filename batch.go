@@ -0,0 +1,332 @@
+package s3
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/oklog/ulid/v2"
+)
+
+// batchPrefix is where SubmitBatchJob writes job manifests and completion reports.
+const batchPrefix = "batch/"
+
+const controlNamespace = "http://awss3control.amazonaws.com/doc/2018-08-20/"
+
+// BatchOperation is the operation an S3 Batch Operations job performs on
+// every object in its manifest.
+type BatchOperation struct {
+	Copy    *batchCopy    `xml:"S3PutObjectCopy,omitempty"`
+	Tagging *batchTagging `xml:"S3PutObjectTagging,omitempty"`
+	Restore *batchRestore `xml:"S3InitiateRestoreObject,omitempty"`
+	Lambda  *batchLambda  `xml:"LambdaInvoke,omitempty"`
+}
+
+type batchCopy struct {
+	TargetResource  string
+	TargetKeyPrefix string `xml:",omitempty"`
+}
+
+type batchTag struct {
+	Key   string
+	Value string
+}
+
+type batchTagging struct {
+	TagSet []batchTag `xml:"TagSet>member"`
+}
+
+type batchRestore struct {
+	ExpirationInDays int32
+	GlacierJobTier   string
+}
+
+type batchLambda struct {
+	FunctionArn string
+}
+
+// BatchCopy copies every object to the bucket, prepending the key prefix.
+func BatchCopy(bucket, prefix string) BatchOperation {
+	return BatchOperation{Copy: &batchCopy{"arn:aws:s3:::" + bucket, prefix}}
+}
+
+// BatchTag replaces the tag set of every object.
+func BatchTag(tags map[string]string) BatchOperation {
+	t := &batchTagging{}
+	for k, v := range tags {
+		t.TagSet = append(t.TagSet, batchTag{k, v})
+	}
+	sort.Slice(t.TagSet, func(i, j int) bool { return t.TagSet[i].Key < t.TagSet[j].Key })
+	return BatchOperation{Tagging: t}
+}
+
+// BatchRestore restores every archived object for the number of days using
+// the retrieval tier, either "BULK" or "STANDARD".
+func BatchRestore(days int32, tier string) BatchOperation {
+	return BatchOperation{Restore: &batchRestore{days, tier}}
+}
+
+// BatchInvoke invokes the Lambda function once per object.
+func BatchInvoke(functionARN string) BatchOperation {
+	return BatchOperation{Lambda: &batchLambda{functionARN}}
+}
+
+// BatchJob identifies an S3 Batch Operations job.
+type BatchJob struct {
+	AccountID string
+	ID        string
+}
+
+// BatchStatus is the progress of an S3 Batch Operations job.
+type BatchStatus struct {
+	Status         string
+	Total          int64
+	Succeeded      int64
+	Failed         int64
+	FailureReasons []string
+}
+
+// Done reports whether the job has reached a terminal status.
+func (s BatchStatus) Done() bool {
+	switch s.Status {
+	case "Complete", "Failed", "Cancelled":
+		return true
+	}
+	return false
+}
+
+// writeBatchManifest writes a Bucket,Key CSV manifest of the objects under the
+// prefix and returns the manifest's ETag. Keys are URL encoded as S3 Batch
+// Operations expects.
+func (c *client) writeBatchManifest(p, k string) (string, error) {
+	u := c.newUploader(&s3.PutObjectInput{Key: &k, ContentType: aws.String("text/csv")})
+	w := csv.NewWriter(u)
+	err := c.walk(p, func(obj types.Object) error {
+		return w.Write([]string{*c.Bucket, url.PathEscape(*obj.Key)})
+	})
+	if w.Flush(); err == nil {
+		err = w.Error()
+	}
+	if err != nil {
+		return "", u.CloseWithError(err)
+	}
+	if err = u.Close(); err != nil {
+		return "", err
+	}
+	info, err := c.Stat(k)
+	return info.ETag, err
+}
+
+// SubmitBatchJob writes a manifest of the objects under the prefix and submits
+// an S3 Batch Operations job that runs the operation on them as the IAM role,
+// in the role's account. The manifest and the completion report of failed
+// tasks are written under batch/ in the client's bucket.
+func (c *client) SubmitBatchJob(p string, op BatchOperation, roleARN string) (BatchJob, error) {
+
+	var job BatchJob
+	role, err := arn.Parse(roleARN)
+	if err == nil {
+		job.AccountID = role.AccountID
+	}
+
+	id := ulid.Make().String()
+	mk := batchPrefix + id + ".csv"
+
+	var etag string
+	if err == nil {
+		etag, err = c.writeBatchManifest(p, mk)
+	}
+
+	if err == nil {
+		manifestARN, bucketARN := batchARNs(*c.Bucket, mk)
+		in := struct {
+			XMLName              xml.Name `xml:"CreateJobRequest"`
+			Xmlns                string   `xml:"xmlns,attr"`
+			ConfirmationRequired bool
+			Operation            BatchOperation
+			Report               struct {
+				Bucket      string
+				Format      string
+				Enabled     bool
+				Prefix      string
+				ReportScope string
+			}
+			ClientRequestToken string
+			Manifest           struct {
+				Spec struct {
+					Format string
+					Fields []string `xml:"Fields>member"`
+				}
+				Location struct {
+					ObjectArn string
+					ETag      string
+				}
+			}
+			Priority int32
+			RoleArn  string
+		}{Xmlns: controlNamespace, Operation: op, ClientRequestToken: id, Priority: 10, RoleArn: roleARN}
+		in.Report.Bucket = bucketARN
+		in.Report.Format = "Report_CSV_20180820"
+		in.Report.Enabled = true
+		in.Report.Prefix = path.Join(batchPrefix, "reports")
+		in.Report.ReportScope = "FailedTasksOnly"
+		in.Manifest.Spec.Format = "S3BatchOperations_CSV_20180820"
+		in.Manifest.Spec.Fields = []string{"Bucket", "Key"}
+		in.Manifest.Location.ObjectArn = manifestARN
+		in.Manifest.Location.ETag = etag
+
+		var out struct {
+			JobId string
+		}
+		err = c.control(http.MethodPost, job.AccountID, "/v20180820/jobs", in, &out)
+		job.ID = out.JobId
+	}
+
+	c.log("SubmitBatchJob", err).
+		Str("prefix", p).
+		Str("manifest", mk).
+		Str("job", job.ID).
+		Msg("SubmitBatchJob")
+
+	return job, err
+}
+
+// batchARNs returns the ARNs of the object in the bucket or access point and
+// of the bucket or access point itself, which hold job manifests and reports.
+func batchARNs(b, k string) (string, string) {
+	if arn.IsARN(b) {
+		return b + "/object/" + k, b
+	}
+	return "arn:aws:s3:::" + b + "/" + k, "arn:aws:s3:::" + b
+}
+
+// BatchJobStatus returns the progress of the job.
+func (c *client) BatchJobStatus(job BatchJob) (BatchStatus, error) {
+
+	var out struct {
+		Job struct {
+			Status          string
+			ProgressSummary struct {
+				TotalNumberOfTasks     int64
+				NumberOfTasksSucceeded int64
+				NumberOfTasksFailed    int64
+			}
+			FailureReasons []struct {
+				FailureCode   string
+				FailureReason string
+			} `xml:"FailureReasons>JobFailure"`
+		}
+	}
+	err := c.control(http.MethodGet, job.AccountID, "/v20180820/jobs/"+url.PathEscape(job.ID), nil, &out)
+
+	s := BatchStatus{
+		Status:    out.Job.Status,
+		Total:     out.Job.ProgressSummary.TotalNumberOfTasks,
+		Succeeded: out.Job.ProgressSummary.NumberOfTasksSucceeded,
+		Failed:    out.Job.ProgressSummary.NumberOfTasksFailed,
+	}
+	for _, f := range out.Job.FailureReasons {
+		s.FailureReasons = append(s.FailureReasons, f.FailureCode+": "+f.FailureReason)
+	}
+
+	c.log("BatchJobStatus", err).
+		Str("job", job.ID).
+		Str("status", s.Status).
+		Int64("succeeded", s.Succeeded).
+		Int64("failed", s.Failed).
+		Msg("BatchJobStatus")
+
+	return s, err
+}
+
+// WaitBatchJob polls the job at the interval until it completes, fails or is
+// cancelled, or the client's context is done.
+func (c *client) WaitBatchJob(job BatchJob, interval time.Duration) (BatchStatus, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s, err := c.BatchJobStatus(job)
+		if err != nil || s.Done() {
+			return s, err
+		}
+		select {
+		case <-c.Done():
+			return s, c.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// control makes a signed S3 Control API request on behalf of the account,
+// encoding in and decoding the response into out as XML.
+func (c *client) control(method, account, p string, in, out any) error {
+
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = xml.Marshal(in); err != nil {
+			return err
+		}
+	}
+
+	endpoint := fmt.Sprintf("https://%s.s3-control.%s.amazonaws.com", account, c.cfg.Region)
+	if c.cfg.BaseEndpoint != nil {
+		endpoint = *c.cfg.BaseEndpoint
+	}
+	req, err := http.NewRequestWithContext(c.Context, method, endpoint+p, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(body)
+	hash := hex.EncodeToString(sum[:])
+	req.Header.Set("X-Amz-Account-Id", account)
+	req.Header.Set("X-Amz-Content-Sha256", hash)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/xml")
+	}
+
+	creds, err := c.cfg.Credentials.Retrieve(c.Context)
+	if err != nil {
+		return err
+	}
+	if err = v4.NewSigner().SignHTTP(c.Context, creds, req, hash, "s3", c.cfg.Region, time.Now()); err != nil {
+		return err
+	}
+
+	res, err := c.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode >= 300 {
+		var e struct {
+			Code    string `xml:"Error>Code"`
+			Message string `xml:"Error>Message"`
+		}
+		if xml.Unmarshal(b, &e) == nil && e.Code != "" {
+			return &smithy.GenericAPIError{Code: e.Code, Message: e.Message}
+		}
+		return fmt.Errorf("s3 control: %s %s: %s", method, p, res.Status)
+	}
+	return xml.Unmarshal(b, out)
+}
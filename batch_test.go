@@ -0,0 +1,93 @@
+package s3
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_SubmitBatchJob(t *testing.T) {
+
+	var created string
+	polls := 0
+	c, b := newTestBucket(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.URL.Path, "/v20180820/jobs") {
+				next.ServeHTTP(w, r)
+				return
+			}
+			assert.Equal(t, "123456789012", r.Header.Get("X-Amz-Account-Id"))
+			assert.Contains(t, r.Header.Get("Authorization"), "AWS4-HMAC-SHA256")
+			if r.Method == http.MethodPost {
+				body, _ := io.ReadAll(r.Body)
+				created = string(body)
+				_, _ = io.WriteString(w, `<CreateJobResult><JobId>job-1</JobId></CreateJobResult>`)
+				return
+			}
+			assert.Equal(t, "/v20180820/jobs/job-1", r.URL.Path)
+			status := "Active"
+			if polls++; polls > 1 {
+				status = "Complete"
+			}
+			_, _ = io.WriteString(w, `<DescribeJobResult><Job><Status>`+status+`</Status><ProgressSummary><TotalNumberOfTasks>2</TotalNumberOfTasks><NumberOfTasksSucceeded>2</NumberOfTasksSucceeded><NumberOfTasksFailed>0</NumberOfTasksFailed></ProgressSummary></Job></DescribeJobResult>`)
+		})
+	})
+	c.cfg.BaseEndpoint = c.Options().BaseEndpoint
+
+	assert.NoError(t, c.Put("users/a b.json", "{}"))
+	assert.NoError(t, c.Put("users/c.json", "{}"))
+
+	job, err := c.SubmitBatchJob("users/", BatchTag(map[string]string{"team": "data"}), "arn:aws:iam::123456789012:role/batch")
+	assert.NoError(t, err)
+	assert.Equal(t, BatchJob{"123456789012", "job-1"}, job)
+
+	var in struct {
+		Operation struct {
+			Tags []batchTag `xml:"S3PutObjectTagging>TagSet>member"`
+		}
+		Manifest struct {
+			ObjectArn string `xml:"Location>ObjectArn"`
+			ETag      string `xml:"Location>ETag"`
+		}
+	}
+	assert.NoError(t, xml.Unmarshal([]byte(created), &in))
+	assert.Equal(t, []batchTag{{"team", "data"}}, in.Operation.Tags)
+
+	mk := strings.TrimPrefix(in.Manifest.ObjectArn, "arn:aws:s3:::bytelyon-db/")
+	assert.True(t, strings.HasPrefix(mk, batchPrefix))
+	assert.Equal(t, b.object(mk).etag, in.Manifest.ETag)
+	assert.Equal(t, "bytelyon-db,users%2Fa%20b.json\nbytelyon-db,users%2Fc.json\n", string(b.object(mk).body))
+
+	s, err := c.WaitBatchJob(job, time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, BatchStatus{Status: "Complete", Total: 2, Succeeded: 2}, s)
+	assert.Equal(t, 2, polls)
+}
+
+func TestClient_BatchJobStatus_Error(t *testing.T) {
+
+	c := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, `<ErrorResponse><Error><Code>NoSuchJob</Code><Message>missing</Message></Error></ErrorResponse>`)
+	})
+	c.cfg.BaseEndpoint = c.Options().BaseEndpoint
+
+	_, err := c.BatchJobStatus(BatchJob{"123456789012", "job-1"})
+	assert.Equal(t, "NoSuchJob", errorCode(err))
+}
+
+func Test_batchARNs(t *testing.T) {
+	object, bucket := batchARNs("db", "batch/1.csv")
+	assert.Equal(t, "arn:aws:s3:::db/batch/1.csv", object)
+	assert.Equal(t, "arn:aws:s3:::db", bucket)
+
+	const ap = "arn:aws:s3:us-east-1:123456789012:accesspoint/db"
+	object, bucket = batchARNs(ap, "batch/1.csv")
+	assert.Equal(t, ap+"/object/batch/1.csv", object)
+	assert.Equal(t, ap, bucket)
+}
@@ -0,0 +1,386 @@
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testObject is an object stored by testBucket.
+type testObject struct {
+	body     []byte
+	etag     string
+	header   http.Header
+	modified time.Time
+}
+
+// testBucket is a minimal in-memory S3 emulation covering the requests
+// made by the client, for tests that don't run against AWS.
+type testBucket struct {
+	mu            sync.Mutex
+	objects       map[string]*testObject
+	uploads       map[string]map[int][]byte
+	uploadHeaders map[string]http.Header
+	nextID        int
+}
+
+// newBucket returns an empty in-memory bucket.
+func newBucket() *testBucket {
+	return &testBucket{objects: map[string]*testObject{}, uploads: map[string]map[int][]byte{}, uploadHeaders: map[string]http.Header{}}
+}
+
+// newTestBucket returns a client for an in-memory "bytelyon-db" bucket. The
+// middleware, if any, wraps the bucket's handler in order, so the first sees
+// requests first, for tests that observe or fail requests.
+func newTestBucket(t *testing.T, mw ...func(http.Handler) http.Handler) (*client, *testBucket) {
+	b := newBucket()
+	var h http.Handler = b
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+	return testServer(t, h.ServeHTTP), b
+}
+
+func (b *testBucket) object(k string) *testObject {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.objects[k]
+}
+
+func (b *testBucket) keys() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var keys []string
+	for k := range b.objects {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (b *testBucket) put(k string, body []byte, h http.Header) *testObject {
+	sum := md5.Sum(body)
+	o := &testObject{body, `"` + hex.EncodeToString(sum[:]) + `"`, http.Header{}, time.Now().UTC()}
+	for name, v := range h {
+		if strings.HasPrefix(strings.ToLower(name), "x-amz-meta-") ||
+			name == "Content-Type" || name == "Content-Encoding" || name == "Cache-Control" ||
+			name == "X-Amz-Tagging" || name == "X-Amz-Server-Side-Encryption" ||
+			name == "X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id" || name == "X-Amz-Storage-Class" {
+			o.header[name] = v
+		}
+	}
+	b.objects[k] = o
+	return o
+}
+
+func writeXML(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, s3Code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(code)
+	_, _ = fmt.Fprintf(w, "<Error><Code>%s</Code></Error>", s3Code)
+}
+
+func (b *testBucket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/bytelyon-db")
+	k := strings.TrimPrefix(path, "/")
+	q := r.URL.Query()
+
+	switch {
+	case k == "" && r.Method == http.MethodGet:
+		b.list(w, q)
+	case r.Method == http.MethodPost && q.Has("uploads"):
+		b.nextID++
+		id := strconv.Itoa(b.nextID)
+		b.uploads[id] = map[int][]byte{}
+		b.uploadHeaders[id] = r.Header
+		writeXML(w, struct {
+			XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+			Key      string
+			UploadId string
+		}{Key: k, UploadId: id})
+	case r.Method == http.MethodPost && q.Has("uploadId"):
+		parts := b.uploads[q.Get("uploadId")]
+		var body, sums []byte
+		for i := 1; i <= len(parts); i++ {
+			body = append(body, parts[i]...)
+			sum := md5.Sum(parts[i])
+			sums = append(sums, sum[:]...)
+		}
+		delete(b.uploads, q.Get("uploadId"))
+		o := b.put(k, body, b.uploadHeaders[q.Get("uploadId")])
+		sum := md5.Sum(sums)
+		o.etag = fmt.Sprintf(`"%s-%d"`, hex.EncodeToString(sum[:]), len(parts))
+		for _, name := range []string{"X-Amz-Server-Side-Encryption", "X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"} {
+			w.Header()[name] = o.header[name]
+		}
+		writeXML(w, struct {
+			XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+			Key     string
+			ETag    string
+		}{Key: k, ETag: o.etag})
+	case r.Method == http.MethodDelete && q.Has("uploadId"):
+		delete(b.uploads, q.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet && q.Has("uploadId"):
+		type part struct {
+			PartNumber int
+			ETag       string
+			Size       int
+		}
+		var parts []part
+		for i := 1; i <= len(b.uploads[q.Get("uploadId")]); i++ {
+			parts = append(parts, part{i, `"part` + strconv.Itoa(i) + `"`, len(b.uploads[q.Get("uploadId")][i])})
+		}
+		writeXML(w, struct {
+			XMLName xml.Name `xml:"ListPartsResult"`
+			Part    []part
+		}{Part: parts})
+	case q.Has("tagging"):
+		o, ok := b.objects[k]
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		type tag struct{ Key, Value string }
+		var tagging struct {
+			XMLName xml.Name `xml:"Tagging"`
+			TagSet  []tag    `xml:"TagSet>Tag"`
+		}
+		if r.Method == http.MethodGet {
+			tags, _ := url.ParseQuery(o.header.Get("X-Amz-Tagging"))
+			for name := range tags {
+				tagging.TagSet = append(tagging.TagSet, tag{name, tags.Get(name)})
+			}
+			writeXML(w, tagging)
+			return
+		}
+		_ = xml.NewDecoder(r.Body).Decode(&tagging)
+		tags := url.Values{}
+		for _, t := range tagging.TagSet {
+			tags.Set(t.Key, t.Value)
+		}
+		o.header.Set("X-Amz-Tagging", tags.Encode())
+	case r.Method == http.MethodPut && q.Has("uploadId"):
+		body, _ := io.ReadAll(r.Body)
+		n, _ := strconv.Atoi(q.Get("partNumber"))
+		if src := r.Header.Get("X-Amz-Copy-Source"); src != "" {
+			o := b.source(src)
+			if o == nil {
+				writeError(w, http.StatusNotFound, "NoSuchKey")
+				return
+			}
+			if m := r.Header.Get("X-Amz-Copy-Source-If-Match"); m != "" && m != o.etag {
+				writeError(w, http.StatusPreconditionFailed, "PreconditionFailed")
+				return
+			}
+			body = o.body
+			if rng := r.Header.Get("X-Amz-Copy-Source-Range"); rng != "" {
+				var from, to int
+				_, _ = fmt.Sscanf(rng, "bytes=%d-%d", &from, &to)
+				body = body[from : to+1]
+			}
+			b.uploads[q.Get("uploadId")][n] = body
+			writeXML(w, struct {
+				XMLName xml.Name `xml:"CopyPartResult"`
+				ETag    string
+			}{ETag: `"part"`})
+			return
+		}
+		b.uploads[q.Get("uploadId")][n] = body
+		w.Header().Set("ETag", `"part`+q.Get("partNumber")+`"`)
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		o := b.source(r.Header.Get("X-Amz-Copy-Source"))
+		if o == nil {
+			writeError(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		if m := r.Header.Get("X-Amz-Copy-Source-If-Match"); m != "" && m != o.etag {
+			writeError(w, http.StatusPreconditionFailed, "PreconditionFailed")
+			return
+		}
+		h := o.header
+		if r.Header.Get("X-Amz-Metadata-Directive") == "REPLACE" {
+			h = r.Header.Clone()
+			if r.Header.Get("X-Amz-Tagging-Directive") != "REPLACE" {
+				h["X-Amz-Tagging"] = o.header["X-Amz-Tagging"]
+			}
+		}
+		// copies are encrypted as requested, not as their source
+		h = h.Clone()
+		h.Del("X-Amz-Server-Side-Encryption")
+		h.Del("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id")
+		if sse := r.Header.Get("X-Amz-Server-Side-Encryption"); sse != "" {
+			h.Set("X-Amz-Server-Side-Encryption", sse)
+			h.Set("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id", r.Header.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"))
+		}
+		c := b.put(k, o.body, h)
+		writeXML(w, struct {
+			XMLName      xml.Name `xml:"CopyObjectResult"`
+			ETag         string
+			LastModified string
+		}{ETag: c.etag, LastModified: c.modified.Format(time.RFC3339)})
+	case r.Method == http.MethodPut:
+		o, exists := b.objects[k]
+		if r.Header.Get("If-None-Match") == "*" && exists ||
+			r.Header.Get("If-Match") != "" && (!exists || o.etag != r.Header.Get("If-Match")) {
+			writeError(w, http.StatusPreconditionFailed, "PreconditionFailed")
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("ETag", b.put(k, body, r.Header).etag)
+	case r.Method == http.MethodDelete:
+		if m := r.Header.Get("If-Match"); m != "" {
+			if o, exists := b.objects[k]; !exists || o.etag != m {
+				writeError(w, http.StatusPreconditionFailed, "PreconditionFailed")
+				return
+			}
+		}
+		delete(b.objects, k)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		o, ok := b.objects[k]
+		if !ok {
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			writeError(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		if m := r.Header.Get("If-None-Match"); m != "" && m == o.etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if m := r.Header.Get("If-Match"); m != "" && m != o.etag {
+			writeError(w, http.StatusPreconditionFailed, "PreconditionFailed")
+			return
+		}
+		for name, v := range o.header {
+			w.Header()[name] = v
+		}
+		if tags, _ := url.ParseQuery(o.header.Get("X-Amz-Tagging")); len(tags) > 0 {
+			w.Header().Set("X-Amz-Tagging-Count", strconv.Itoa(len(tags)))
+		}
+		w.Header().Set("ETag", o.etag)
+		w.Header().Set("Last-Modified", o.modified.Format(http.TimeFormat))
+		body := o.body
+		if rng := r.Header.Get("Range"); rng != "" {
+			var from, to int
+			if _, err := fmt.Sscanf(rng, "bytes=-%d", &to); err == nil {
+				from, to = max(len(body)-to, 0), len(body)-1
+			} else {
+				_, _ = fmt.Sscanf(rng, "bytes=%d-%d", &from, &to)
+			}
+			to = min(to, len(body)-1)
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", from, to, len(body)))
+			body = body[from : to+1]
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			w.WriteHeader(http.StatusPartialContent)
+		} else {
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		}
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(body)
+		}
+	default:
+		writeError(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+func (b *testBucket) source(src string) *testObject {
+	src, _ = url.PathUnescape(src)
+	return b.objects[strings.TrimPrefix(strings.TrimPrefix(src, "/"), "bytelyon-db/")]
+}
+
+type testListObject struct {
+	Key          string
+	Size         int64
+	ETag         string
+	LastModified string
+}
+
+type testPrefix struct {
+	Prefix string
+}
+
+func (b *testBucket) list(w http.ResponseWriter, q url.Values) {
+	p, d := q.Get("prefix"), q.Get("delimiter")
+	after := q.Get("start-after")
+	if t := q.Get("continuation-token"); t != "" {
+		after = t
+	}
+	max := 1000
+	if m := q.Get("max-keys"); m != "" {
+		max, _ = strconv.Atoi(m)
+	}
+
+	var keys []string
+	for k := range b.objects {
+		if strings.HasPrefix(k, p) && k > after {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var contents []testListObject
+	var prefixes []testPrefix
+	seen := map[string]bool{}
+	var next string
+	for _, k := range keys {
+		if len(contents)+len(prefixes) == max {
+			next = contents[len(contents)-1].Key
+			if len(prefixes) > 0 && prefixes[len(prefixes)-1].Prefix > next {
+				next = prefixes[len(prefixes)-1].Prefix
+			}
+			break
+		}
+		if d != "" {
+			if i := strings.Index(k[len(p):], d); i >= 0 {
+				cp := k[:len(p)+i+len(d)]
+				if !seen[cp] {
+					seen[cp] = true
+					prefixes = append(prefixes, testPrefix{cp})
+				}
+				continue
+			}
+		}
+		o := b.objects[k]
+		contents = append(contents, testListObject{k, int64(len(o.body)), o.etag, o.modified.Format(time.RFC3339)})
+	}
+
+	writeXML(w, struct {
+		XMLName               xml.Name `xml:"ListBucketResult"`
+		Name                  string
+		Prefix                string
+		KeyCount              int
+		IsTruncated           bool
+		NextContinuationToken string           `xml:",omitempty"`
+		Contents              []testListObject `xml:"Contents"`
+		CommonPrefixes        []testPrefix     `xml:"CommonPrefixes"`
+	}{
+		Name:                  "bytelyon-db",
+		Prefix:                p,
+		KeyCount:              len(contents) + len(prefixes),
+		IsTruncated:           next != "",
+		NextContinuationToken: next,
+		Contents:              contents,
+		CommonPrefixes:        prefixes,
+	})
+}
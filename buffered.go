@@ -0,0 +1,234 @@
+package s3
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// BufferedService wraps a Service so Puts are buffered and written on an
+// interval, keeping only the latest value put to each key, for chatty
+// workloads such as state checkpoints that would otherwise write the same
+// keys many times a second. Get, GetReader and Find return buffered values
+// before they are written and Delete discards them; every other method,
+// listings included, only sees values that have been flushed. Writes that
+// fail stay buffered and are retried by the next flush, unless they can't
+// succeed, e.g. with ErrInvalidDocument, ErrQuotaExceeded or ErrPutTooLarge,
+// when the value is dropped and passed to the OnDrop callbacks. Err reports
+// the failures of the latest flush. Buffered values are lost if the process
+// exits without calling Close.
+type BufferedService struct {
+	Service
+	mu      sync.Mutex
+	flushMu sync.Mutex
+	pending map[string]bufferedPut
+	seq     uint64
+	err     error
+	drops   []func(string, []byte, error)
+	once    sync.Once
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// bufferedPut is a value buffered by Put, encoded when it was put so later
+// changes to it aren't written.
+type bufferedPut struct {
+	body []byte
+	json bool
+	seq  uint64
+}
+
+// NewBuffered returns a BufferedService that flushes the Puts buffered in
+// front of svc every interval, or only on Flush and Close when it is 0.
+func NewBuffered(svc Service, interval time.Duration) *BufferedService {
+	b := &BufferedService{Service: svc, pending: map[string]bufferedPut{}, done: make(chan struct{})}
+	if interval > 0 {
+		b.wg.Add(1)
+		go b.flushLoop(interval)
+	}
+	return b
+}
+
+func (b *BufferedService) flushLoop(interval time.Duration) {
+	defer b.wg.Done()
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			// failures are reported by Err, and retried by the next flush
+			_ = b.Flush()
+		case <-b.done:
+			return
+		}
+	}
+}
+
+// Put buffers the value, encoded as Service.Put encodes it, replacing any
+// value buffered for the key.
+func (b *BufferedService) Put(k string, a any) error {
+	body, ct, release, err := encode(k, a)
+	if err != nil {
+		return err
+	}
+	p := bufferedPut{body: bytes.Clone(body), json: ct == "application/json"}
+	release()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.seq++
+	p.seq = b.seq
+	b.pending[k] = p
+	return nil
+}
+
+// buffered returns the value buffered for the key, if any.
+func (b *BufferedService) buffered(k string) ([]byte, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	p, ok := b.pending[k]
+	return p.body, ok
+}
+
+// Get returns the value buffered for the key, or else the object.
+func (b *BufferedService) Get(k string) ([]byte, error) {
+	if body, ok := b.buffered(k); ok {
+		return bytes.Clone(body), nil
+	}
+	return b.Service.Get(k)
+}
+
+// GetReader returns the value buffered for the key, or else the object.
+func (b *BufferedService) GetReader(k string) (io.ReadCloser, error) {
+	if body, ok := b.buffered(k); ok {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return b.Service.GetReader(k)
+}
+
+// Find decodes the value buffered for the key, or else the object.
+func (b *BufferedService) Find(k string, a any) error {
+	if body, ok := b.buffered(k); ok {
+		return json.Unmarshal(body, a)
+	}
+	return b.Service.Find(k, a)
+}
+
+// Delete discards the value buffered for the key and deletes the object,
+// waiting for a flush in progress so it can't write the value afterwards.
+func (b *BufferedService) Delete(k string) error {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+	b.mu.Lock()
+	delete(b.pending, k)
+	b.mu.Unlock()
+	return b.Service.Delete(k)
+}
+
+// PutWithResult discards the value buffered for the key and uploads the
+// value immediately, as its result identifies the object written.
+func (b *BufferedService) PutWithResult(k string, a any) (PutResult, error) {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+	b.mu.Lock()
+	delete(b.pending, k)
+	b.mu.Unlock()
+	return b.Service.PutWithResult(k, a)
+}
+
+// PutWithContentType discards the value buffered for the key and uploads the
+// body immediately, as buffered values are written with detected types.
+func (b *BufferedService) PutWithContentType(k string, body []byte, ct string) error {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+	b.mu.Lock()
+	delete(b.pending, k)
+	b.mu.Unlock()
+	return b.Service.PutWithContentType(k, body, ct)
+}
+
+// Err returns the errors of the writes that failed in the latest flush, or
+// nil if they all succeeded.
+func (b *BufferedService) Err() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.err
+}
+
+// OnDrop calls fn with every value dropped because writing it failed with
+// an error retrying can't fix, e.g. to dead-letter it, from the goroutine
+// flushing.
+func (b *BufferedService) OnDrop(fn func(k string, body []byte, err error)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.drops = append(b.drops, fn)
+}
+
+// permanent reports whether a write that failed with err fails again
+// however often it's retried.
+func permanent(err error) bool {
+	return errors.Is(err, ErrInvalidDocument) ||
+		errors.Is(err, ErrQuotaExceeded) ||
+		errors.Is(err, ErrPutTooLarge) ||
+		errors.Is(err, ErrInfected)
+}
+
+// Flush writes the buffered values in key order, returning the errors of
+// the writes that failed. Values that can't be written are dropped.
+func (b *BufferedService) Flush() error {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	b.mu.Lock()
+	pending := maps.Clone(b.pending)
+	b.mu.Unlock()
+
+	var errs []error
+	for _, k := range slices.Sorted(maps.Keys(pending)) {
+		p := pending[k]
+		var a any = p.body
+		if p.json {
+			// written as is, with the JSON Content-Type it was put with
+			a = json.RawMessage(p.body)
+		}
+		err := b.Service.Put(k, a)
+		if err != nil {
+			errs = append(errs, err)
+			if !permanent(err) {
+				continue
+			}
+		}
+		b.mu.Lock()
+		// keep values put or deleted while this one was written
+		if q, ok := b.pending[k]; ok && q.seq == p.seq {
+			delete(b.pending, k)
+		}
+		drops := b.drops
+		b.mu.Unlock()
+		if err != nil {
+			for _, fn := range drops {
+				fn(k, p.body, err)
+			}
+		}
+	}
+
+	err := errors.Join(errs...)
+	b.mu.Lock()
+	b.err = err
+	b.mu.Unlock()
+	return err
+}
+
+// Close stops the periodic flush and flushes the buffered values.
+func (b *BufferedService) Close() error {
+	b.once.Do(func() {
+		close(b.done)
+	})
+	b.wg.Wait()
+	return b.Flush()
+}
@@ -0,0 +1,120 @@
+package s3
+
+import (
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBufferedService(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	var puts atomic.Int32
+	c.hooks = append(c.hooks, Hooks{AfterPut: func(HookEvent) error {
+		puts.Add(1)
+		return nil
+	}})
+	svc := NewBuffered(c, 0)
+
+	for i := range 10 {
+		assert.NoError(t, svc.Put("state.json", map[string]int{"n": i}))
+	}
+	assert.NoError(t, svc.Put("log.txt", "hello"))
+	assert.Empty(t, b.keys())
+
+	var state map[string]int
+	assert.NoError(t, svc.Find("state.json", &state))
+	assert.Equal(t, 9, state["n"])
+	body, err := svc.Get("log.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(body))
+	r, err := svc.GetReader("log.txt")
+	assert.NoError(t, err)
+	body, _ = io.ReadAll(r)
+	assert.Equal(t, "hello", string(body))
+
+	assert.NoError(t, svc.Flush())
+	assert.Equal(t, int32(2), puts.Load())
+	assert.Equal(t, `{"n":9}`, string(b.object("state.json").body))
+	assert.Equal(t, "application/json", b.object("state.json").header.Get("Content-Type"))
+	assert.Equal(t, "hello", string(b.object("log.txt").body))
+
+	assert.NoError(t, svc.Flush())
+	assert.Equal(t, int32(2), puts.Load())
+
+	assert.NoError(t, svc.Put("log.txt", "bye"))
+	assert.NoError(t, svc.Delete("log.txt"))
+
+	assert.NoError(t, svc.Put("state.json", map[string]int{"n": 10}))
+	res, err := svc.PutWithResult("state.json", map[string]int{"n": 11})
+	assert.NoError(t, err)
+	assert.Equal(t, b.object("state.json").etag, res.ETag)
+	assert.NoError(t, svc.Close())
+	assert.Equal(t, []string{"state.json"}, b.keys())
+	assert.Equal(t, `{"n":11}`, string(b.object("state.json").body))
+}
+
+// flakyService fails Puts while failing is set and records the rest.
+type flakyService struct {
+	Service
+	failing atomic.Bool
+	puts    chan string
+}
+
+func (s *flakyService) Put(k string, _ any) error {
+	if s.failing.Load() {
+		return errUnavailable
+	}
+	s.puts <- k
+	return nil
+}
+
+var errUnavailable = errors.New("unavailable")
+
+func TestBufferedService_Interval(t *testing.T) {
+
+	s := &flakyService{puts: make(chan string, 10)}
+	s.failing.Store(true)
+	svc := NewBuffered(s, 10*time.Millisecond)
+	defer svc.Close()
+
+	assert.NoError(t, svc.Put("a", "1"))
+	assert.ErrorIs(t, svc.Flush(), errUnavailable)
+	assert.ErrorIs(t, svc.Err(), errUnavailable)
+
+	// failed writes are retried by the next flush
+	s.failing.Store(false)
+	select {
+	case k := <-s.puts:
+		assert.Equal(t, "a", k)
+	case <-time.After(time.Second):
+		t.Fatal("buffered put wasn't flushed")
+	}
+}
+
+func TestBufferedService_OnDrop(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	WithMaxPutSize(8)(c.options)
+	svc := NewBuffered(c, 0)
+	var dropped []string
+	svc.OnDrop(func(k string, body []byte, err error) {
+		assert.ErrorIs(t, err, ErrPutTooLarge)
+		dropped = append(dropped, k+"="+string(body))
+	})
+
+	// values that can't be written are dropped rather than retried forever
+	assert.NoError(t, svc.Put("small", "12345678"))
+	assert.NoError(t, svc.Put("large", "123456789"))
+	assert.ErrorIs(t, svc.Flush(), ErrPutTooLarge)
+	assert.ErrorIs(t, svc.Err(), ErrPutTooLarge)
+	assert.Equal(t, []string{"large=123456789"}, dropped)
+
+	assert.NoError(t, svc.Flush())
+	assert.NoError(t, svc.Err())
+	assert.Equal(t, []string{"small"}, b.keys())
+}
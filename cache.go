@@ -0,0 +1,169 @@
+package s3
+
+import (
+	"bytes"
+	"container/list"
+	"sync"
+	"time"
+)
+
+// cache is an LRU cache of object bodies by key. Entries are served without
+// a request for ttl after they were last validated and revalidated with a
+// conditional GET after that.
+type cache struct {
+	maxEntries int
+	maxBytes   int64
+	ttl        time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	size    int64
+	reads   map[string]*cacheRead
+}
+
+// cacheRead counts the reads of a key in flight and the invalidations of the
+// key since the first of them started, so a read never caches a body older
+// than a write made while it was in flight.
+type cacheRead struct {
+	n   int
+	gen uint64
+}
+
+type cacheEntry struct {
+	key       string
+	etag      string
+	body      []byte
+	validated time.Time
+}
+
+// WithCache caches the bodies read by Get and Find in memory, holding at most
+// maxEntries objects and maxBytes bytes, either unlimited when 0, and evicting
+// the least recently used first. Cached objects are returned without a request
+// for ttl, then revalidated with a conditional GET by ETag. Writes and deletes
+// made through the client invalidate the key; changes made elsewhere are seen
+// once the entry is revalidated.
+func WithCache(maxEntries int, maxBytes int64, ttl time.Duration) Option {
+	return func(o *options) {
+		o.cache = &cache{
+			maxEntries: maxEntries,
+			maxBytes:   maxBytes,
+			ttl:        ttl,
+			entries:    map[string]*list.Element{},
+			lru:        list.New(),
+			reads:      map[string]*cacheRead{},
+		}
+	}
+}
+
+// get returns a copy of the cached body of the key, its ETag, and whether it
+// is fresh. A nil cache caches nothing.
+func (c *cache) get(k string) (body []byte, etag string, fresh bool) {
+	if c == nil {
+		return nil, "", false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[k]
+	if !ok {
+		return nil, "", false
+	}
+	c.lru.MoveToFront(el)
+	e := el.Value.(*cacheEntry)
+	return bytes.Clone(e.body), e.etag, time.Since(e.validated) < c.ttl
+}
+
+// read starts a read of the key and returns its generation, which add and
+// validated are given to ignore reads the key was invalidated during. done
+// must be called once the read is over.
+func (c *cache) read(k string) uint64 {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, ok := c.reads[k]
+	if !ok {
+		r = &cacheRead{}
+		c.reads[k] = r
+	}
+	r.n++
+	return r.gen
+}
+
+// done ends a read started with read.
+func (c *cache) done(k string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r := c.reads[k]
+	if r.n--; r.n == 0 {
+		delete(c.reads, k)
+	}
+}
+
+// validated marks the cached body of the key as current, unless the key was
+// invalidated since the read of the generation started.
+func (c *cache) validated(k string, gen uint64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.current(k, gen) {
+		return
+	}
+	if el, ok := c.entries[k]; ok {
+		el.Value.(*cacheEntry).validated = time.Now()
+	}
+}
+
+// add caches a copy of the body read in the generation, evicting least
+// recently used entries to stay within bounds. Bodies larger than the whole
+// cache, or read while the key was invalidated, are not cached.
+func (c *cache) add(k, etag string, body []byte, gen uint64) {
+	if c == nil || c.maxBytes > 0 && int64(len(body)) > c.maxBytes {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.current(k, gen) {
+		return
+	}
+	c.removeLocked(k)
+	c.entries[k] = c.lru.PushFront(&cacheEntry{k, etag, bytes.Clone(body), time.Now()})
+	c.size += int64(len(body))
+	for c.maxEntries > 0 && c.lru.Len() > c.maxEntries || c.maxBytes > 0 && c.size > c.maxBytes {
+		c.removeLocked(c.lru.Back().Value.(*cacheEntry).key)
+	}
+}
+
+// current reports whether the key is yet to be invalidated during the read of
+// the generation.
+func (c *cache) current(k string, gen uint64) bool {
+	r, ok := c.reads[k]
+	return ok && r.gen == gen
+}
+
+// remove invalidates the key.
+func (c *cache) remove(k string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if r, ok := c.reads[k]; ok {
+		r.gen++
+	}
+	c.removeLocked(k)
+}
+
+func (c *cache) removeLocked(k string) {
+	if el, ok := c.entries[k]; ok {
+		c.lru.Remove(el)
+		delete(c.entries, k)
+		c.size -= int64(len(el.Value.(*cacheEntry).body))
+	}
+}
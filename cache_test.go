@@ -0,0 +1,124 @@
+package s3
+
+import (
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithCache(t *testing.T) {
+
+	var gets, notModified int
+	c, b := newTestBucket(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet {
+				gets++
+				if r.Header.Get("If-None-Match") != "" {
+					notModified++
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	WithCache(2, 0, time.Hour)(c.options)
+
+	b.put("a", []byte("1"), nil)
+	for range 3 {
+		out, err := c.Get("a")
+		assert.NoError(t, err)
+		assert.Equal(t, "1", string(out))
+	}
+	assert.Equal(t, 1, gets)
+
+	// stale entries are revalidated, not refetched
+	c.cache.ttl = 0
+	out, err := c.Get("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", string(out))
+	assert.Equal(t, 2, gets)
+	assert.Equal(t, 1, notModified)
+
+	// changes made elsewhere are seen on revalidation
+	b.put("a", []byte("2"), nil)
+	out, err = c.Get("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "2", string(out))
+
+	// writes and deletes through the client invalidate the key
+	c.cache.ttl = time.Hour
+	assert.NoError(t, c.Put("a", "3"))
+	out, err = c.Get("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "3", string(out))
+	assert.NoError(t, c.Delete("a"))
+	_, err = c.Get("a")
+	assert.True(t, IsNotFound(err))
+}
+
+func TestWithCache_invalidatedRead(t *testing.T) {
+
+	var c *client
+	var once sync.Once
+	c, b := newTestBucket(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				next.ServeHTTP(w, r)
+				return
+			}
+			// the key is overwritten while the old body is on its way
+			rec := httptest.NewRecorder()
+			next.ServeHTTP(rec, r)
+			once.Do(func() { assert.NoError(t, c.Put("a", "2")) })
+			maps.Copy(w.Header(), rec.Header())
+			w.WriteHeader(rec.Code)
+			_, _ = w.Write(rec.Body.Bytes())
+		})
+	})
+	WithCache(2, 0, time.Hour)(c.options)
+	b.put("a", []byte("1"), nil)
+
+	out, err := c.Get("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", string(out))
+
+	// the body read before the write isn't cached
+	out, err = c.Get("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "2", string(out))
+	assert.Empty(t, c.cache.reads)
+}
+
+func TestCache_Evict(t *testing.T) {
+
+	o := &options{}
+	add := func(k, body string) {
+		gen := o.cache.read(k)
+		o.cache.add(k, "", []byte(body), gen)
+		o.cache.done(k)
+	}
+	WithCache(2, 0, time.Hour)(o)
+	add("a", "1")
+	add("b", "2")
+	o.cache.get("a")
+	add("c", "3")
+	_, _, ok := o.cache.get("b")
+	assert.False(t, ok, "least recently used entry is evicted")
+	_, _, ok = o.cache.get("a")
+	assert.True(t, ok)
+
+	WithCache(0, 4, time.Hour)(o)
+	add("a", "12")
+	add("b", "34")
+	add("c", "5")
+	_, _, ok = o.cache.get("a")
+	assert.False(t, ok)
+	add("d", "12345")
+	_, _, ok = o.cache.get("d")
+	assert.False(t, ok, "bodies larger than the cache are not cached")
+	assert.Equal(t, int64(3), o.cache.size)
+}
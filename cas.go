@@ -0,0 +1,69 @@
+package s3
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+const blobPrefix = "blobs/sha256/"
+
+// ErrDigestMismatch is returned by GetBlob when the stored content
+// doesn't hash to the requested digest.
+var ErrDigestMismatch = errors.New("s3: blob content does not match digest")
+
+func blobKey(digest string) string {
+	return blobPrefix + digest
+}
+
+// PutBlob stores data under its SHA-256 digest, skipping the upload when an
+// identical blob already exists, and returns the hex encoded digest.
+func (c *client) PutBlob(data []byte) (string, error) {
+
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+	k := blobKey(digest)
+
+	_, err := c.putObject(&s3.PutObjectInput{
+		Bucket:      c.Bucket,
+		Key:         &k,
+		ContentType: aws.String(contentType(k, data)),
+		IfNoneMatch: aws.String("*"),
+	}, data)
+
+	var exists bool
+	if isPreconditionFailed(err) {
+		exists, err = true, nil
+	}
+
+	c.log("PutBlob", err).
+		Str("key", k).
+		Bool("exists", exists).
+		Int("size", len(data)).
+		Msg("PutBlob")
+
+	if err != nil {
+		return "", err
+	}
+	return digest, nil
+}
+
+// GetBlob returns the blob stored under the SHA-256 digest.
+func (c *client) GetBlob(digest string) ([]byte, error) {
+
+	b, err := c.read(blobKey(digest))
+	if err == nil {
+		if sum := sha256.Sum256(b); hex.EncodeToString(sum[:]) != digest {
+			b, err = nil, ErrDigestMismatch
+		}
+	}
+
+	c.log("GetBlob", err).
+		Str("digest", digest).
+		Msg("GetBlob")
+
+	return b, err
+}
@@ -0,0 +1,44 @@
+package s3
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_PutBlob(t *testing.T) {
+
+	blobs := map[string][]byte{}
+	c := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			if _, ok := blobs[r.URL.Path]; ok && r.Header.Get("If-None-Match") == "*" {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				_, _ = w.Write([]byte(`<Error><Code>PreconditionFailed</Code></Error>`))
+				return
+			}
+			blobs[r.URL.Path], _ = io.ReadAll(r.Body)
+		case http.MethodGet:
+			_, _ = w.Write(blobs[r.URL.Path])
+		}
+	})
+
+	digest, err := c.PutBlob([]byte(testBody()))
+	assert.NoError(t, err)
+	assert.Len(t, digest, 64)
+	assert.Contains(t, blobs, "/bytelyon-db/"+blobKey(digest))
+
+	again, err := c.PutBlob([]byte(testBody()))
+	assert.NoError(t, err)
+	assert.Equal(t, digest, again)
+
+	b, err := c.GetBlob(digest)
+	assert.NoError(t, err)
+	assert.Equal(t, testBody(), string(b))
+
+	blobs["/bytelyon-db/"+blobKey(digest)] = []byte("corrupt")
+	_, err = c.GetBlob(digest)
+	assert.ErrorIs(t, err, ErrDigestMismatch)
+}
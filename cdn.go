@@ -0,0 +1,181 @@
+package s3
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoCDN is returned by the CDN helpers when the client was not
+// configured with WithCloudFront.
+var ErrNoCDN = errors.New("s3: CloudFront distribution not configured")
+
+type cdn struct {
+	domain    string
+	keyPairID string
+	key       *rsa.PrivateKey
+}
+
+// WithCloudFront configures the CloudFront distribution fronting the bucket and
+// the key pair used to sign CDN URLs and cookies.
+func WithCloudFront(domain, keyPairID string, key *rsa.PrivateKey) Option {
+	return func(o *options) {
+		o.cdn = &cdn{domain, keyPairID, key}
+	}
+}
+
+// ParseCloudFrontKey parses a PEM encoded PKCS #1 or PKCS #8 RSA private key
+// as downloaded when creating a CloudFront key pair.
+func ParseCloudFrontKey(b []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("s3: no PEM data found in CloudFront key")
+	}
+	if k, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return k, nil
+	}
+	k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rk, ok := k.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("s3: CloudFront key is not an RSA private key")
+	}
+	return rk, nil
+}
+
+type cdnPolicy struct {
+	Statement []cdnStatement `json:"Statement"`
+}
+
+type cdnStatement struct {
+	Resource  string `json:"Resource"`
+	Condition struct {
+		DateLessThan struct {
+			EpochTime int64 `json:"AWS:EpochTime"`
+		} `json:"DateLessThan"`
+	} `json:"Condition"`
+}
+
+func newCDNPolicy(resource string, exp time.Time) ([]byte, error) {
+	var s cdnStatement
+	s.Resource = resource
+	s.Condition.DateLessThan.EpochTime = exp.Unix()
+	// CloudFront rebuilds canned policies verbatim, so HTML characters
+	// in the resource must not be escaped
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(cdnPolicy{[]cdnStatement{s}}); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+}
+
+// cdnEncode base64 encodes b replacing characters that are invalid in
+// URL query strings, as CloudFront expects.
+func cdnEncode(b []byte) string {
+	return strings.NewReplacer("+", "-", "=", "_", "/", "~").Replace(base64.StdEncoding.EncodeToString(b))
+}
+
+func (d *cdn) sign(policy []byte) (string, error) {
+	h := sha1.Sum(policy)
+	sig, err := rsa.SignPKCS1v15(rand.Reader, d.key, crypto.SHA1, h[:])
+	if err != nil {
+		return "", err
+	}
+	return cdnEncode(sig), nil
+}
+
+func (d *cdn) url(k string) string {
+	return (&url.URL{Scheme: "https", Host: d.domain, Path: "/" + k}).String()
+}
+
+// CDNSignedURL returns a CloudFront URL for the key signed with a canned policy
+// that expires after the given duration.
+func (c *client) CDNSignedURL(k string, exp time.Duration) (string, error) {
+
+	var signed string
+	var err error
+	if c.cdn == nil {
+		err = ErrNoCDN
+	} else {
+		u := c.cdn.url(k)
+		t := time.Now().Add(exp)
+		var policy []byte
+		var sig string
+		if policy, err = newCDNPolicy(u, t); err == nil {
+			if sig, err = c.cdn.sign(policy); err == nil {
+				q := url.Values{}
+				q.Set("Expires", strconv.FormatInt(t.Unix(), 10))
+				q.Set("Signature", sig)
+				q.Set("Key-Pair-Id", c.cdn.keyPairID)
+				signed = u + "?" + q.Encode()
+			}
+		}
+	}
+
+	c.log("CDNSignedURL", err).
+		Str("key", k).
+		Dur("exp", exp).
+		Str("url", signed).
+		Msg("CDNSignedURL")
+
+	return signed, err
+}
+
+// CDNSignedCookies returns the CloudFront-Policy, CloudFront-Signature and
+// CloudFront-Key-Pair-Id cookies granting access to every key matching the
+// given pattern (which may contain * and ? wildcards) until expiry.
+func (c *client) CDNSignedCookies(p string, exp time.Duration) ([]*http.Cookie, error) {
+
+	var cookies []*http.Cookie
+	var err error
+	if c.cdn == nil {
+		err = ErrNoCDN
+	} else {
+		t := time.Now().Add(exp)
+		var policy []byte
+		var sig string
+		// wildcards must reach the policy unescaped
+		if policy, err = newCDNPolicy("https://"+c.cdn.domain+"/"+p, t); err == nil {
+			if sig, err = c.cdn.sign(policy); err == nil {
+				for _, kv := range [][2]string{
+					{"CloudFront-Policy", cdnEncode(policy)},
+					{"CloudFront-Signature", sig},
+					{"CloudFront-Key-Pair-Id", c.cdn.keyPairID},
+				} {
+					cookies = append(cookies, &http.Cookie{
+						Name:     kv[0],
+						Value:    kv[1],
+						Domain:   c.cdn.domain,
+						Path:     "/",
+						Expires:  t,
+						Secure:   true,
+						HttpOnly: true,
+					})
+				}
+			}
+		}
+	}
+
+	c.log("CDNSignedCookies", err).
+		Str("prefix", p).
+		Dur("exp", exp).
+		Msg("CDNSignedCookies")
+
+	return cookies, err
+}
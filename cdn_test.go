@@ -0,0 +1,60 @@
+package s3
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testCDNClient(t *testing.T) (*client, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	o := &options{}
+	WithCloudFront("d111111abcdef8.cloudfront.net", "K2JCJMDEHXQW5F", key)(o)
+	return &client{options: o}, key
+}
+
+func cdnDecode(s string) []byte {
+	b, _ := base64.StdEncoding.DecodeString(strings.NewReplacer("-", "+", "_", "=", "~", "/").Replace(s))
+	return b
+}
+
+func TestClient_CDNSignedURL(t *testing.T) {
+
+	c, key := testCDNClient(t)
+
+	signed, err := c.CDNSignedURL(testKey(), time.Minute)
+	assert.NoError(t, err)
+
+	u, err := url.Parse(signed)
+	assert.NoError(t, err)
+	assert.Equal(t, "d111111abcdef8.cloudfront.net", u.Host)
+	assert.Equal(t, "/"+testKey(), u.Path)
+	assert.Equal(t, "K2JCJMDEHXQW5F", u.Query().Get("Key-Pair-Id"))
+
+	policy := `{"Statement":[{"Resource":"https://d111111abcdef8.cloudfront.net/` + testKey() +
+		`","Condition":{"DateLessThan":{"AWS:EpochTime":` + u.Query().Get("Expires") + `}}}]}`
+	h := sha1.Sum([]byte(policy))
+	assert.NoError(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA1, h[:], cdnDecode(u.Query().Get("Signature"))))
+}
+
+func TestClient_CDNSignedCookies(t *testing.T) {
+
+	c, _ := testCDNClient(t)
+
+	cookies, err := c.CDNSignedCookies("users/*", time.Minute)
+	assert.NoError(t, err)
+	assert.Len(t, cookies, 3)
+	assert.Contains(t, string(cdnDecode(cookies[0].Value)), `"Resource":"https://d111111abcdef8.cloudfront.net/users/*"`)
+
+	_, err = (&client{options: &options{}}).CDNSignedURL(testKey(), time.Minute)
+	assert.ErrorIs(t, err, ErrNoCDN)
+}
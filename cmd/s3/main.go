@@ -0,0 +1,238 @@
+// Command s3 exposes the github.com/nelsw/s3 Service operations on the
+// command line. The bucket is read from the S3_BUCKET environment variable
+// unless -bucket is given, and AWS configuration is loaded the same way
+// as the package, from the environment and shared config files.
+//
+// Usage:
+//
+//	s3 [flags] get <key> [file]
+//	s3 [flags] put <key> [file]
+//	s3 [flags] rm <key>...
+//	s3 [flags] ls [prefix]
+//	s3 [flags] cp <src> <dst>
+//	s3 [flags] sync <dir> <prefix>
+//	s3 [flags] url <key> [minutes]
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/nelsw/s3"
+	"github.com/rs/zerolog"
+)
+
+// config is the parsed command line.
+type config struct {
+	bucket  string
+	profile string
+	verbose bool
+	cmd     string
+	args    []string
+}
+
+// errNoCommand is returned by parseArgs when no command is given.
+var errNoCommand = errors.New("no command given")
+
+func main() {
+	cfg, err := parseArgs(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		os.Exit(2)
+	}
+
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	if cfg.verbose {
+		zerolog.SetGlobalLevel(zerolog.TraceLevel)
+	}
+	svc := s3.NewWithOptions(context.Background(), cfg.options()...)
+
+	if err := (&cli{svc, os.Stdin, os.Stdout}).run(cfg.cmd, cfg.args); err != nil {
+		fmt.Fprintln(os.Stderr, "s3:", err)
+		os.Exit(1)
+	}
+}
+
+// parseArgs parses the flags and command from args with the flag set,
+// printing the usage when they're invalid or no command is given.
+func parseArgs(fs *flag.FlagSet, args []string) (config, error) {
+	var cfg config
+	fs.StringVar(&cfg.bucket, "bucket", "", "bucket name or access point ARN (default $S3_BUCKET)")
+	fs.StringVar(&cfg.profile, "profile", "", "shared config profile (default $AWS_PROFILE)")
+	fs.BoolVar(&cfg.verbose, "v", false, "log every operation")
+	fs.Usage = func() { usage(fs) }
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return cfg, errNoCommand
+	}
+	cfg.cmd, cfg.args = fs.Arg(0), fs.Args()[1:]
+	return cfg, nil
+}
+
+// options returns the options of the service the flags select.
+func (cfg config) options() []s3.Option {
+	var opts []s3.Option
+	if cfg.bucket != "" {
+		opts = append(opts, s3.WithBucket(cfg.bucket))
+	}
+	if cfg.profile != "" {
+		opts = append(opts, s3.WithProfile(cfg.profile))
+	}
+	return opts
+}
+
+func usage(fs *flag.FlagSet) {
+	fmt.Fprintln(fs.Output(), `usage: s3 [flags] <command> [args]
+
+commands:
+  get <key> [file]       write an object to file or stdout
+  put <key> [file]       upload file or stdin to an object
+  rm <key>...            delete objects
+  ls [prefix]            list keys under prefix
+  cp <src> <dst>         copy an object
+  sync <dir> <prefix>    upload changed files in a directory under prefix
+  url <key> [minutes]    presign a download URL (default 15 minutes)
+
+flags:`)
+	fs.PrintDefaults()
+}
+
+// cli runs commands against the service, reading and writing objects from
+// stdin and to stdout when no file is given.
+type cli struct {
+	svc    s3.Service
+	stdin  io.Reader
+	stdout io.Writer
+}
+
+func (c *cli) run(cmd string, args []string) error {
+	switch cmd {
+	case "get":
+		return c.get(args)
+	case "put":
+		return c.put(args)
+	case "rm":
+		return c.rm(args)
+	case "ls":
+		return c.ls(args)
+	case "cp":
+		if len(args) != 2 {
+			return fmt.Errorf("cp requires <src> <dst>")
+		}
+		return c.svc.Copy(args[0], args[1])
+	case "sync":
+		return c.sync(args)
+	case "url":
+		return c.url(args)
+	}
+	return fmt.Errorf("unknown command %q", cmd)
+}
+
+func (c *cli) get(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("get requires <key> [file]")
+	}
+	body, err := c.svc.GetReader(args[0])
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	w := c.stdout
+	if len(args) == 2 {
+		f, err := os.Create(args[1])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	_, err = io.Copy(w, body)
+	return err
+}
+
+func (c *cli) put(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("put requires <key> [file]")
+	}
+	var b []byte
+	var err error
+	if len(args) == 2 {
+		b, err = os.ReadFile(args[1])
+	} else {
+		b, err = io.ReadAll(c.stdin)
+	}
+	if err != nil {
+		return err
+	}
+	return c.svc.Put(args[0], b)
+}
+
+func (c *cli) rm(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("rm requires <key>...")
+	}
+	for _, k := range args {
+		if err := c.svc.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *cli) ls(args []string) error {
+	var p string
+	if len(args) > 0 {
+		p = args[0]
+	}
+	var cursor s3.Cursor
+	for {
+		keys, next, err := c.svc.Page(p, cursor, 1000)
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			fmt.Fprintln(c.stdout, k)
+		}
+		if next == "" {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+func (c *cli) sync(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("sync requires <dir> <prefix>")
+	}
+	n, err := c.svc.Sync(args[0], args[1])
+	if err == nil {
+		fmt.Fprintf(c.stdout, "%d uploaded\n", n)
+	}
+	return err
+}
+
+func (c *cli) url(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("url requires <key> [minutes]")
+	}
+	minutes := int64(15)
+	if len(args) == 2 {
+		var err error
+		if minutes, err = strconv.ParseInt(args[1], 10, 64); err != nil {
+			return err
+		}
+	}
+	u, err := c.svc.URL(args[0], minutes)
+	if err == nil {
+		fmt.Fprintln(c.stdout, u)
+	}
+	return err
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/smithy-go"
+	"github.com/nelsw/s3"
+	"github.com/stretchr/testify/assert"
+)
+
+type testService struct {
+	s3.Service
+	objects map[string][]byte
+}
+
+func (s *testService) GetReader(k string) (io.ReadCloser, error) {
+	b, ok := s.objects[k]
+	if !ok {
+		return nil, &smithy.GenericAPIError{Code: "NoSuchKey"}
+	}
+	return io.NopCloser(bytes.NewReader(b)), nil
+}
+
+func (s *testService) Put(k string, a any) error {
+	s.objects[k] = a.([]byte)
+	return nil
+}
+
+func (s *testService) Delete(k string) error {
+	delete(s.objects, k)
+	return nil
+}
+
+func (s *testService) Copy(src, dst string) error {
+	b, ok := s.objects[src]
+	if !ok {
+		return &smithy.GenericAPIError{Code: "NoSuchKey"}
+	}
+	s.objects[dst] = b
+	return nil
+}
+
+// Page returns a key at a time, so ls follows the cursor.
+func (s *testService) Page(p string, c s3.Cursor, _ int32) ([]string, s3.Cursor, error) {
+	var keys []string
+	for k := range s.objects {
+		if strings.HasPrefix(k, p) && k > string(c) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	if len(keys) == 0 {
+		return nil, "", nil
+	}
+	return keys[:1], s3.Cursor(keys[0]), nil
+}
+
+func (s *testService) Sync(dir, p string) (int, error) {
+	entries, err := os.ReadDir(dir)
+	for _, e := range entries {
+		b, _ := os.ReadFile(filepath.Join(dir, e.Name()))
+		s.objects[p+e.Name()] = b
+	}
+	return len(entries), err
+}
+
+func (s *testService) URL(k string, minutes int64) (string, error) {
+	return "https://bytelyon-db.s3.amazonaws.com/" + k + "?expires=" + strings.Repeat("m", int(minutes)), nil
+}
+
+func TestParseArgs(t *testing.T) {
+
+	parse := func(args ...string) (config, error) {
+		fs := flag.NewFlagSet("s3", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		return parseArgs(fs, args)
+	}
+
+	cfg, err := parse("-bucket", "docs", "-profile", "prod", "-v", "get", "a.json", "a.out")
+	assert.NoError(t, err)
+	assert.Equal(t, config{"docs", "prod", true, "get", []string{"a.json", "a.out"}}, cfg)
+	assert.Len(t, cfg.options(), 2)
+
+	cfg, err = parse("ls")
+	assert.NoError(t, err)
+	assert.Equal(t, config{cmd: "ls", args: []string{}}, cfg)
+	assert.Empty(t, cfg.options())
+
+	_, err = parse("-v")
+	assert.ErrorIs(t, err, errNoCommand)
+	_, err = parse("-region", "us-east-1", "ls")
+	assert.Error(t, err)
+}
+
+func TestCLI_run(t *testing.T) {
+
+	svc := &testService{objects: map[string][]byte{}}
+	var stdout bytes.Buffer
+	c := &cli{svc, strings.NewReader("from stdin"), &stdout}
+	dir := t.TempDir()
+
+	assert.NoError(t, c.run("put", []string{"a.txt"}))
+	assert.Equal(t, "from stdin", string(svc.objects["a.txt"]))
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "b.txt"), []byte("from file"), 0o644))
+	assert.NoError(t, c.run("put", []string{"b.txt", filepath.Join(dir, "b.txt")}))
+	assert.Equal(t, "from file", string(svc.objects["b.txt"]))
+
+	assert.NoError(t, c.run("get", []string{"a.txt"}))
+	assert.Equal(t, "from stdin", stdout.String())
+	assert.NoError(t, c.run("get", []string{"b.txt", filepath.Join(dir, "b.out")}))
+	b, err := os.ReadFile(filepath.Join(dir, "b.out"))
+	assert.NoError(t, err)
+	assert.Equal(t, "from file", string(b))
+
+	assert.NoError(t, c.run("cp", []string{"a.txt", "c.txt"}))
+	assert.Equal(t, "from stdin", string(svc.objects["c.txt"]))
+
+	stdout.Reset()
+	assert.NoError(t, c.run("ls", nil))
+	assert.Equal(t, "a.txt\nb.txt\nc.txt\n", stdout.String())
+
+	assert.NoError(t, c.run("rm", []string{"a.txt", "c.txt"}))
+	stdout.Reset()
+	assert.NoError(t, c.run("ls", []string{"b"}))
+	assert.Equal(t, "b.txt\n", stdout.String())
+
+	stdout.Reset()
+	assert.NoError(t, c.run("sync", []string{dir, "backup/"}))
+	assert.Equal(t, "2 uploaded\n", stdout.String())
+	assert.Equal(t, "from file", string(svc.objects["backup/b.out"]))
+
+	stdout.Reset()
+	assert.NoError(t, c.run("url", []string{"b.txt", "2"}))
+	assert.Equal(t, "https://bytelyon-db.s3.amazonaws.com/b.txt?expires=mm\n", stdout.String())
+}
+
+func TestCLI_run_errors(t *testing.T) {
+
+	c := &cli{&testService{objects: map[string][]byte{}}, strings.NewReader(""), io.Discard}
+
+	assert.EqualError(t, c.run("mv", []string{"a", "b"}), `unknown command "mv"`)
+	assert.EqualError(t, c.run("get", nil), "get requires <key> [file]")
+	assert.EqualError(t, c.run("put", []string{"a", "b", "c"}), "put requires <key> [file]")
+	assert.EqualError(t, c.run("rm", nil), "rm requires <key>...")
+	assert.EqualError(t, c.run("cp", []string{"a"}), "cp requires <src> <dst>")
+	assert.EqualError(t, c.run("sync", []string{"dir"}), "sync requires <dir> <prefix>")
+	assert.EqualError(t, c.run("url", nil), "url requires <key> [minutes]")
+	assert.Error(t, c.run("url", []string{"a", "soon"}))
+	assert.True(t, s3.IsNotFound(c.run("get", []string{"missing"})))
+}
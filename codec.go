@@ -0,0 +1,173 @@
+package s3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec is a format documents are stored in, identified by the Content-Type
+// and Content-Encoding of the objects holding them.
+type Codec interface {
+	Marshal(any) ([]byte, error)
+	Unmarshal([]byte, any) error
+	ContentType() string
+	ContentEncoding() string
+}
+
+var (
+	// JSONCodec stores documents as JSON, as Put does.
+	JSONCodec Codec = jsonCodec{}
+	// ProtoCodec stores protocol buffer messages in their binary encoding.
+	// It only marshals and unmarshals proto.Message values.
+	ProtoCodec Codec = protoCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error)   { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(b []byte, v any) error { return json.Unmarshal(b, v) }
+func (jsonCodec) ContentType() string             { return "application/json" }
+func (jsonCodec) ContentEncoding() string         { return "" }
+
+type protoCodec struct{}
+
+func (protoCodec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("s3: %T is not a proto.Message", v)
+	}
+	return proto.Marshal(m)
+}
+
+func (protoCodec) Unmarshal(b []byte, v any) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("s3: %T is not a proto.Message", v)
+	}
+	return proto.Unmarshal(b, m)
+}
+
+func (protoCodec) ContentType() string     { return "application/x-protobuf" }
+func (protoCodec) ContentEncoding() string { return "" }
+
+// GzipCodec returns the codec storing documents in the format of c
+// compressed with gzip, e.g. GzipCodec(JSONCodec) for gzip+JSON.
+func GzipCodec(c Codec) Codec {
+	return gzipCodec{c}
+}
+
+type gzipCodec struct {
+	Codec
+}
+
+func (g gzipCodec) Marshal(v any) ([]byte, error) {
+	b, err := g.Codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err = w.Write(b); err == nil {
+		err = w.Close()
+	}
+	return buf.Bytes(), err
+}
+
+func (g gzipCodec) Unmarshal(b []byte, v any) error {
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	if b, err = io.ReadAll(r); err != nil {
+		return err
+	}
+	return g.Codec.Unmarshal(b, v)
+}
+
+func (gzipCodec) ContentEncoding() string { return "gzip" }
+
+// MigrateCodec rewrites every document under the prefix, read with the from
+// codec, in the to codec, up to workers at a time, setting the Content-Type
+// and Content-Encoding of the to codec and keeping the other headers,
+// metadata and tags. Each document is decoded into a value returned by
+// newValue, which must suit both codecs, e.g. a proto.Message to migrate
+// JSON to protocol buffers; a nil newValue decodes into a json.RawMessage,
+// which keeps JSON documents as they are. Documents already stored in the to
+// codec are skipped, as are those replaced or deleted while migrating. Like
+// Migrate, progress is checkpointed under .migrate/ after every page of up to
+// 1,000 keys, so calling MigrateCodec again after an error, e.g. with a
+// document that doesn't decode, resumes after the last completed page.
+func (c *client) MigrateCodec(p string, from, to Codec, newValue func() any, workers int) error {
+
+	if newValue == nil {
+		newValue = func() any { return &json.RawMessage{} }
+	}
+
+	ck := migrateCheckpointKey("MigrateCodec", p, to.ContentType()+"; "+to.ContentEncoding())
+	cp := migrateCheckpoint{Source: p, Destination: p}
+	resumed, err := c.migrate(ck, &cp, workers, func(ctx context.Context, k string) (bool, error) {
+		return c.withContext(ctx).recode(k, from, to, newValue)
+	})
+
+	c.log("MigrateCodec", err).
+		Str("prefix", p).
+		Str("from", from.ContentType()).
+		Str("to", to.ContentType()).
+		Str("encoding", to.ContentEncoding()).
+		Bool("resumed", resumed).
+		Int64("migrated", cp.Migrated).
+		Msg("MigrateCodec")
+
+	return err
+}
+
+// recode rewrites the document in the to codec, reporting whether it did.
+func (c *client) recode(k string, from, to Codec, newValue func() any) (bool, error) {
+	out, err := c.getObject(&s3.GetObjectInput{Bucket: c.Bucket, Key: &k})
+	if IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer out.Body.Close()
+	if aws.ToString(out.ContentType) == to.ContentType() && aws.ToString(out.ContentEncoding) == to.ContentEncoding() {
+		return false, nil
+	}
+
+	b, err := readAll(out.Body, aws.ToInt64(out.ContentLength))
+	if err != nil {
+		return false, err
+	}
+	v := newValue()
+	if err = from.Unmarshal(b, v); err != nil {
+		return false, fmt.Errorf("s3: decoding %s: %w", k, err)
+	}
+	if b, err = to.Marshal(v); err != nil {
+		return false, fmt.Errorf("s3: encoding %s: %w", k, err)
+	}
+
+	in, err := c.rewriteInput(k, k, out)
+	if err != nil {
+		return false, err
+	}
+	in.IfMatch = out.ETag
+	in.ContentType = aws.String(to.ContentType())
+	in.ContentEncoding = nil
+	if enc := to.ContentEncoding(); enc != "" {
+		in.ContentEncoding = &enc
+	}
+	_, err = c.putObject(in, b)
+	if isPreconditionFailed(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,98 @@
+package s3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestCodecs(t *testing.T) {
+
+	for _, codec := range []Codec{JSONCodec, GzipCodec(JSONCodec)} {
+		b, err := codec.Marshal(map[string]int{"n": 1})
+		assert.NoError(t, err)
+		var v map[string]int
+		assert.NoError(t, codec.Unmarshal(b, &v))
+		assert.Equal(t, map[string]int{"n": 1}, v)
+	}
+
+	msg, err := structpb.NewStruct(map[string]any{"n": 1.0})
+	assert.NoError(t, err)
+	b, err := GzipCodec(ProtoCodec).Marshal(msg)
+	assert.NoError(t, err)
+	var got structpb.Struct
+	assert.NoError(t, GzipCodec(ProtoCodec).Unmarshal(b, &got))
+	assert.Equal(t, 1.0, got.Fields["n"].GetNumberValue())
+
+	_, err = ProtoCodec.Marshal(map[string]int{})
+	assert.Error(t, err)
+	assert.Equal(t, "application/x-protobuf", GzipCodec(ProtoCodec).ContentType())
+	assert.Equal(t, "gzip", GzipCodec(ProtoCodec).ContentEncoding())
+}
+
+func TestClient_MigrateCodec(t *testing.T) {
+
+	c, b := newTestBucket(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// list two keys a page so the migration is checkpointed as it goes
+			if q := r.URL.Query(); q.Has("list-type") {
+				q.Set("max-keys", "2")
+				r.URL.RawQuery = q.Encode()
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	json := http.Header{"Content-Type": {"application/json"}}
+	b.put("docs/a.json", []byte(`{"id": 12345678901234567890}`), http.Header{
+		"Content-Type":      {"application/json"},
+		"Cache-Control":     {"no-cache"},
+		"X-Amz-Meta-Owner":  {"core"},
+		"X-Amz-Meta-Sha256": {"stale"},
+		"X-Amz-Tagging":     {"team=core"},
+	})
+	b.put("docs/b.json", []byte(`{"b":2}`), json)
+	b.put("docs/c.json", []byte(`{"c":`), json)
+	b.put("docs/d.json", []byte(`{"d":4}`), json)
+
+	gzipJSON := GzipCodec(JSONCodec)
+	assert.Error(t, c.MigrateCodec("docs/", JSONCodec, gzipJSON, nil, 1))
+	ck := migrateCheckpointKey("MigrateCodec", "docs/", "application/json; gzip")
+	assert.Contains(t, b.keys(), ck)
+	assert.Equal(t, "gzip", b.object("docs/b.json").header.Get("Content-Encoding"))
+	assert.Empty(t, b.object("docs/d.json").header.Get("Content-Encoding"))
+
+	b.put("docs/c.json", []byte(`{"c":3}`), json)
+	assert.NoError(t, c.MigrateCodec("docs/", JSONCodec, gzipJSON, nil, 2))
+	assert.NotContains(t, b.keys(), ck)
+
+	gunzip := func(k string) string {
+		r, err := gzip.NewReader(bytes.NewReader(b.object(k).body))
+		if !assert.NoError(t, err) {
+			return ""
+		}
+		body, _ := io.ReadAll(r)
+		return string(body)
+	}
+	assert.Equal(t, `{"id":12345678901234567890}`, gunzip("docs/a.json"))
+	assert.Equal(t, `{"c":3}`, gunzip("docs/c.json"))
+	assert.Equal(t, `{"d":4}`, gunzip("docs/d.json"))
+
+	h := b.object("docs/a.json").header
+	assert.Equal(t, "application/json", h.Get("Content-Type"))
+	assert.Equal(t, "gzip", h.Get("Content-Encoding"))
+	assert.Equal(t, "no-cache", h.Get("Cache-Control"))
+	assert.Equal(t, "core", h.Get("X-Amz-Meta-Owner"))
+	assert.Empty(t, h.Get("X-Amz-Meta-Sha256"))
+	assert.Equal(t, "team=core", h.Get("X-Amz-Tagging"))
+
+	// documents already in the new codec are left as they are
+	etag := b.object("docs/b.json").etag
+	assert.NoError(t, c.MigrateCodec("docs/", JSONCodec, gzipJSON, nil, 2))
+	assert.Equal(t, etag, b.object("docs/b.json").etag)
+}
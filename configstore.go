@@ -0,0 +1,215 @@
+package s3
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ConfigStore holds a typed configuration document stored as JSON in the
+// bucket, reloading it when its ETag changes so services pick up new
+// configuration without restarting. A document that fails to decode, or
+// whose value has a Validate() error method that returns an error, is
+// rejected and the previous value kept; so is a deleted document. Err
+// reports why the latest revision wasn't loaded.
+type ConfigStore[T any] struct {
+	svc   Service
+	key   string
+	mu    sync.RWMutex
+	value T
+	etag  string
+	body  []byte
+	err   error
+	subs  []func(T)
+	chans []chan T
+	once  sync.Once
+	done  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// conditionalGetter is implemented by the client to read a document only
+// when its ETag changed, in a single request that bypasses the cache.
+type conditionalGetter interface {
+	getIfNoneMatch(k, etag string) ([]byte, string, error)
+}
+
+// NewConfigStore loads the configuration document stored under the key in
+// svc and checks it for changes every interval, which must be positive. It
+// fails if the document can't be loaded, so services don't start without
+// their configuration.
+func NewConfigStore[T any](svc Service, k string, interval time.Duration) (*ConfigStore[T], error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("s3: config poll interval must be positive, got %s", interval)
+	}
+	s := &ConfigStore[T]{svc: svc, key: k, done: make(chan struct{})}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	s.wg.Add(1)
+	go s.pollLoop(interval)
+	return s, nil
+}
+
+func (s *ConfigStore[T]) pollLoop(interval time.Duration) {
+	defer s.wg.Done()
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			// failures are reported by Err and retried by the next poll
+			_ = s.reload()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// reload loads the document if its ETag changed, notifying subscribers of
+// the new value.
+func (s *ConfigStore[T]) reload() error {
+	s.mu.RLock()
+	prev := s.etag
+	s.mu.RUnlock()
+
+	body, etag, changed, err := s.load(prev)
+	if err == nil && !changed {
+		return s.setErr(nil)
+	}
+	if err != nil {
+		return s.setErr(err)
+	}
+
+	s.mu.RLock()
+	// services other than the client read the document after its ETag, so
+	// the ETag can lag a write that lands in between; the next reload then
+	// reads the same document again
+	same := s.body != nil && bytes.Equal(body, s.body)
+	s.mu.RUnlock()
+
+	var v T
+	if !same {
+		if err = json.Unmarshal(body, &v); err == nil {
+			err = validateConfig(&v)
+		}
+		if err != nil {
+			return s.setErr(fmt.Errorf("s3: config %s rejected: %w", s.key, err))
+		}
+	}
+
+	s.mu.Lock()
+	s.etag, s.err = etag, nil
+	if same {
+		s.mu.Unlock()
+		return nil
+	}
+	s.value, s.body = v, body
+	subs, chans := s.subs, s.chans
+	s.mu.Unlock()
+
+	for _, fn := range subs {
+		fn(v)
+	}
+	for _, ch := range chans {
+		// keep only the latest value for receivers that fall behind
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- v
+	}
+	return nil
+}
+
+// load returns the document and its ETag, or reports it unchanged when its
+// ETag is still etag. The client reads it with a conditional request; other
+// services are asked for its ETag first, then read in full when it changed.
+func (s *ConfigStore[T]) load(etag string) ([]byte, string, bool, error) {
+	if g, ok := s.svc.(conditionalGetter); ok {
+		body, latest, err := g.getIfNoneMatch(s.key, etag)
+		if isNotModified(err) {
+			return nil, etag, false, nil
+		}
+		return body, latest, err == nil, err
+	}
+	info, err := s.svc.Stat(s.key)
+	if err != nil || etag != "" && info.ETag == etag {
+		return nil, etag, false, err
+	}
+	body, err := s.svc.Get(s.key)
+	return body, info.ETag, err == nil, err
+}
+
+// validateConfig returns the error of the value's Validate method, if it
+// has one, with a value or pointer receiver.
+func validateConfig[T any](v *T) error {
+	if val, ok := any(*v).(interface{ Validate() error }); ok {
+		return val.Validate()
+	}
+	if val, ok := any(v).(interface{ Validate() error }); ok {
+		return val.Validate()
+	}
+	return nil
+}
+
+func (s *ConfigStore[T]) setErr(err error) error {
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+	return err
+}
+
+// Get returns the latest valid configuration.
+func (s *ConfigStore[T]) Get() T {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.value
+}
+
+// Err returns why the latest revision of the document wasn't loaded, or nil
+// if Get returns it.
+func (s *ConfigStore[T]) Err() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.err
+}
+
+// OnChange calls fn with every new configuration loaded, from the goroutine
+// polling for changes, so fn must return promptly.
+func (s *ConfigStore[T]) OnChange(fn func(T)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subs = append(s.subs, fn)
+}
+
+// Updates returns a channel receiving every new configuration loaded. A
+// receiver that falls behind only receives the latest. The channel is
+// closed by Close.
+func (s *ConfigStore[T]) Updates() <-chan T {
+	ch := make(chan T, 1)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-s.done:
+		close(ch)
+	default:
+		s.chans = append(s.chans, ch)
+	}
+	return ch
+}
+
+// Close stops polling for changes and closes the channels returned by Updates.
+func (s *ConfigStore[T]) Close() {
+	s.once.Do(func() {
+		close(s.done)
+		s.wg.Wait()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for _, ch := range s.chans {
+			close(ch)
+		}
+		s.chans = nil
+	})
+}
@@ -0,0 +1,97 @@
+package s3
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testConfig struct {
+	Workers int    `json:"workers"`
+	Mode    string `json:"mode"`
+}
+
+func (c testConfig) Validate() error {
+	if c.Workers <= 0 {
+		return errors.New("workers must be positive")
+	}
+	return nil
+}
+
+func TestConfigStore(t *testing.T) {
+
+	var gets, modified atomic.Int32
+	c, b := newTestBucket(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/config/app.json") {
+				gets.Add(1)
+				if r.Header.Get("If-None-Match") == "" {
+					modified.Add(1)
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	_, err := NewConfigStore[testConfig](c, "config/app.json", time.Hour)
+	assert.True(t, IsNotFound(err))
+	_, err = NewConfigStore[testConfig](c, "config/app.json", 0)
+	assert.Error(t, err)
+
+	b.put("config/app.json", []byte(`{"workers":2,"mode":"fast"}`), nil)
+	s, err := NewConfigStore[testConfig](c, "config/app.json", 10*time.Millisecond)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer s.Close()
+	assert.Equal(t, testConfig{2, "fast"}, s.Get())
+
+	// unchanged documents are polled with conditional reads
+	assert.Eventually(t, func() bool { return gets.Load() > 3 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, int32(2), modified.Load())
+
+	updates := s.Updates()
+	changed := make(chan testConfig, 10)
+	s.OnChange(func(cfg testConfig) { changed <- cfg })
+
+	assert.NoError(t, c.Put("config/app.json", testConfig{4, "safe"}))
+	select {
+	case cfg := <-updates:
+		assert.Equal(t, testConfig{4, "safe"}, cfg)
+	case <-time.After(time.Second):
+		t.Fatal("no update")
+	}
+	assert.Equal(t, testConfig{4, "safe"}, <-changed)
+	assert.Equal(t, testConfig{4, "safe"}, s.Get())
+
+	// invalid revisions are rejected and the last valid one kept
+	assert.NoError(t, c.Put("config/app.json", testConfig{0, "broken"}))
+	assert.Eventually(t, func() bool { return s.Err() != nil }, time.Second, 5*time.Millisecond)
+	assert.ErrorContains(t, s.Err(), "workers must be positive")
+	assert.Equal(t, testConfig{4, "safe"}, s.Get())
+
+	assert.NoError(t, c.Put("config/app.json", `{"workers":`))
+	assert.Eventually(t, func() bool {
+		err := s.Err()
+		return err != nil && !strings.Contains(err.Error(), "workers")
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, testConfig{4, "safe"}, s.Get())
+
+	assert.NoError(t, c.Put("config/app.json", testConfig{8, "safe"}))
+	assert.Eventually(t, func() bool { return s.Get().Workers == 8 }, time.Second, 5*time.Millisecond)
+	assert.NoError(t, s.Err())
+	assert.Len(t, changed, 1)
+
+	s.Close()
+	_, open := <-updates
+	for open {
+		_, open = <-updates
+	}
+	_, open = <-s.Updates()
+	assert.False(t, open)
+}
@@ -0,0 +1,147 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ErrInfected is returned by writes refused by a scanner registered with
+// WithContentScanner.
+var ErrInfected = errors.New("s3: content rejected by scanner")
+
+// threatMetadata is the user metadata key holding the threat found in a
+// quarantined object.
+const threatMetadata = "threat"
+
+// ContentScanner scans uploaded content, e.g. with a ClamAV client.
+type ContentScanner interface {
+	// Scan reads the content of the object at the key and returns a
+	// description of the threat found, or "" when it is clean.
+	Scan(ctx context.Context, k string, r io.Reader) (string, error)
+}
+
+type contentScanner struct {
+	pattern    string
+	scanner    ContentScanner
+	quarantine string
+}
+
+// WithContentScanner streams the body of every object written to a key
+// matching the shell pattern, as path.Match matches it, through the scanner
+// before it is uploaded, and refuses writes of content it finds a threat in
+// with ErrInfected. When quarantine isn't empty refused content is written
+// under it instead, to the quarantine prefix followed by the key, with the
+// threat in its metadata. Streamed writes are scanned as their parts upload
+// and complete only if clean; those quarantining content are staged under
+// .staging/ and copied to the key once scanned. Copies scan their source,
+// and presigned and resumable uploads are staged under .staging/ and
+// scanned when complete. Txn scans values before staging them. It panics if
+// the pattern is malformed.
+func WithContentScanner(pattern string, s ContentScanner, quarantine string) Option {
+	if _, err := path.Match(pattern, ""); err != nil {
+		panic("s3: malformed glob " + pattern)
+	}
+	return func(o *options) {
+		o.scanners = append(o.scanners, contentScanner{pattern, s, quarantine})
+	}
+}
+
+// scans reports whether writes to the key are scanned.
+func (c *client) scans(k string) bool {
+	for _, s := range c.scanners {
+		if ok, _ := path.Match(s.pattern, k); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// quarantines reports whether content written to the key is quarantined
+// when a scanner finds a threat in it.
+func (c *client) quarantines(k string) bool {
+	for _, s := range c.scanners {
+		if ok, _ := path.Match(s.pattern, k); ok && s.quarantine != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// quarantineObject copies the object at src, at the ETag, to the quarantine
+// key with the threat in its metadata.
+func (c *client) quarantineObject(src string, etag *string, k, threat string) error {
+	head, err := c.HeadObject(c.Context, &s3.HeadObjectInput{Bucket: c.Bucket, Key: &src, IfMatch: etag})
+	if err == nil {
+		meta := map[string]string{threatMetadata: threat}
+		if aws.ToInt64(head.ContentLength) > maxCopySize {
+			err = c.multipartCopy(src, k, head, func(in *s3.CreateMultipartUploadInput) {
+				in.Metadata = meta
+			})
+		} else {
+			_, err = c.CopyObject(c.Context, &s3.CopyObjectInput{
+				Bucket:               c.Bucket,
+				Key:                  &k,
+				CopySource:           aws.String(c.copySource(src)),
+				CopySourceIfMatch:    etag,
+				MetadataDirective:    types.MetadataDirectiveReplace,
+				Metadata:             meta,
+				ServerSideEncryption: head.ServerSideEncryption,
+				SSEKMSKeyId:          head.SSEKMSKeyId,
+				BucketKeyEnabled:     head.BucketKeyEnabled,
+			})
+		}
+	}
+
+	c.log("Quarantine", err).
+		Str("key", src).
+		Str("quarantine", k).
+		Str("threat", threat).
+		Msg("Quarantine")
+
+	return err
+}
+
+// scanContent runs the scanners matching the key over the body,
+// quarantining it if one finds a threat.
+func (c *client) scanContent(k string, body []byte) error {
+	for _, s := range c.scanners {
+		if ok, _ := path.Match(s.pattern, k); !ok {
+			continue
+		}
+
+		threat, err := s.scanner.Scan(c.Context, k, bytes.NewReader(body))
+		var quarantined string
+		if err == nil && threat != "" && s.quarantine != "" {
+			quarantined = s.quarantine + k
+			_, err = c.PutObject(c.Context, &s3.PutObjectInput{
+				Bucket:        c.Bucket,
+				Key:           &quarantined,
+				Body:          bytes.NewReader(body),
+				ContentLength: aws.Int64(int64(len(body))),
+				Metadata:      map[string]string{threatMetadata: threat},
+			})
+		}
+
+		c.log("ScanContent", err).
+			Str("key", k).
+			Str("threat", threat).
+			Str("quarantine", quarantined).
+			Msg("ScanContent")
+
+		if threat != "" {
+			return errors.Join(fmt.Errorf("%w: %s: %s", ErrInfected, k, threat), err)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
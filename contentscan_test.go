@@ -0,0 +1,114 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// eicarScanner finds the EICAR test signature.
+type eicarScanner struct {
+	scanned []string
+	err     error
+}
+
+func (s *eicarScanner) Scan(_ context.Context, k string, r io.Reader) (string, error) {
+	s.scanned = append(s.scanned, k)
+	if s.err != nil {
+		return "", s.err
+	}
+	b, err := io.ReadAll(r)
+	if bytes.Contains(b, []byte("EICAR-STANDARD-ANTIVIRUS-TEST-FILE")) {
+		return "Eicar-Signature", err
+	}
+	return "", err
+}
+
+func TestWithContentScanner(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	s := &eicarScanner{}
+	WithContentScanner("uploads/*", s, "quarantine/")(c.options)
+
+	assert.NoError(t, c.Put("uploads/clean.txt", "hello"))
+	assert.NoError(t, c.Put("notes/eicar.txt", "EICAR-STANDARD-ANTIVIRUS-TEST-FILE"))
+
+	err := c.Put("uploads/eicar.txt", "X5O!P%@AP EICAR-STANDARD-ANTIVIRUS-TEST-FILE")
+	assert.ErrorIs(t, err, ErrInfected)
+	assert.ErrorContains(t, err, "uploads/eicar.txt: Eicar-Signature")
+	assert.Equal(t, "Eicar-Signature", b.object("quarantine/uploads/eicar.txt").header.Get("X-Amz-Meta-Threat"))
+
+	w := c.NewWriter("uploads/streamed.txt")
+	_, err = io.Copy(w, strings.NewReader("EICAR-STANDARD-ANTIVIRUS-TEST-FILE"))
+	assert.NoError(t, err)
+	assert.ErrorIs(t, w.Close(), ErrInfected)
+
+	assert.ErrorIs(t, c.PutAtomic("uploads/atomic.txt", "EICAR-STANDARD-ANTIVIRUS-TEST-FILE"), ErrInfected)
+
+	assert.Equal(t, []string{"notes/eicar.txt", "quarantine/uploads/atomic.txt", "quarantine/uploads/eicar.txt", "quarantine/uploads/streamed.txt", "uploads/clean.txt"}, b.keys())
+	assert.Equal(t, []string{"uploads/clean.txt", "uploads/eicar.txt", "uploads/streamed.txt", "uploads/atomic.txt"}, s.scanned)
+
+	s.err = errors.New("clamd unavailable")
+	assert.ErrorIs(t, c.Put("uploads/later.txt", "hello"), s.err)
+	assert.Nil(t, b.object("uploads/later.txt"))
+}
+
+func TestWithContentScanner_Streamed(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	s := &eicarScanner{}
+	WithContentScanner("uploads/*", s, "quarantine/")(c.options)
+
+	// large bodies are scanned as their parts upload, staged to be quarantined
+	infected := append(bytes.Repeat([]byte("x"), partSize), "EICAR-STANDARD-ANTIVIRUS-TEST-FILE"...)
+	w := c.NewWriter("uploads/big.bin")
+	_, err := w.Write(infected)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, w.Close(), ErrInfected)
+	assert.Equal(t, infected, b.object("quarantine/uploads/big.bin").body)
+
+	w = c.NewWriter("uploads/clean.bin")
+	_, err = w.Write(infected[:partSize+1])
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	assert.Equal(t, infected[:partSize+1], b.object("uploads/clean.bin").body)
+
+	// copies scan their source
+	assert.NoError(t, c.Put("notes/eicar.txt", "EICAR-STANDARD-ANTIVIRUS-TEST-FILE"))
+	assert.ErrorIs(t, c.Copy("notes/eicar.txt", "uploads/copied.txt"), ErrInfected)
+
+	// presigned uploads are staged and scanned on completion
+	up, err := c.PresignMultipartUpload("uploads/presigned.txt", 34, time.Hour)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(up.Key, ".staging/"))
+	req, _ := http.NewRequest(http.MethodPut, up.URLs[0], strings.NewReader("EICAR-STANDARD-ANTIVIRUS-TEST-FILE"))
+	res, err := http.DefaultClient.Do(req)
+	if assert.NoError(t, err) {
+		res.Body.Close()
+	}
+	assert.ErrorIs(t, c.CompletePresignedUpload(up.Key, up.UploadID, 34), ErrInfected)
+
+	// resumable uploads are staged and scanned on completion
+	h := c.ResumableUploads("uploads/")
+	loc := tusRequest(h, http.MethodPost, "/files", nil, "Upload-Length", "34").Header().Get("Location")
+	rw := tusPatch(h, loc, 0, strings.NewReader("EICAR-STANDARD-ANTIVIRUS-TEST-FILE"))
+	assert.Equal(t, http.StatusUnprocessableEntity, rw.Code)
+	id := strings.TrimPrefix(loc, "/files/")
+
+	assert.Equal(t, []string{
+		"notes/eicar.txt",
+		"quarantine/uploads/" + id,
+		"quarantine/uploads/big.bin",
+		"quarantine/uploads/copied.txt",
+		"quarantine/uploads/presigned.txt",
+		"uploads/clean.bin",
+	}, b.keys())
+	assert.Empty(t, b.uploads)
+}
@@ -0,0 +1,111 @@
+package s3
+
+import "errors"
+
+// StorageClass is an S3 storage class.
+type StorageClass string
+
+const (
+	Standard           StorageClass = "STANDARD"
+	IntelligentTiering StorageClass = "INTELLIGENT_TIERING"
+	StandardIA         StorageClass = "STANDARD_IA"
+	OneZoneIA          StorageClass = "ONEZONE_IA"
+	GlacierIR          StorageClass = "GLACIER_IR"
+	Glacier            StorageClass = "GLACIER"
+	DeepArchive        StorageClass = "DEEP_ARCHIVE"
+	ExpressOneZone     StorageClass = "EXPRESS_ONEZONE"
+)
+
+// ErrStorageClass is returned by EstimateCost for a class without pricing.
+var ErrStorageClass = errors.New("s3: no pricing for storage class")
+
+// pricing is the published us-east-1 list price of a storage class in USD.
+type pricing struct {
+	perGBMonth     float64
+	perThousandPut float64
+	perThousandGet float64
+	perGBRetrieval float64
+	// minObjectSize is the minimum billable size of an object
+	minObjectSize int64
+	// overhead is the billable index metadata added to every archived object
+	overhead int64
+	// monitoring is charged per thousand objects per month
+	monitoring float64
+}
+
+var prices = map[StorageClass]pricing{
+	Standard:           {perGBMonth: 0.023, perThousandPut: 0.005, perThousandGet: 0.0004},
+	IntelligentTiering: {perGBMonth: 0.023, perThousandPut: 0.005, perThousandGet: 0.0004, monitoring: 0.0025},
+	StandardIA:         {perGBMonth: 0.0125, perThousandPut: 0.01, perThousandGet: 0.001, perGBRetrieval: 0.01, minObjectSize: 128 << 10},
+	OneZoneIA:          {perGBMonth: 0.01, perThousandPut: 0.01, perThousandGet: 0.001, perGBRetrieval: 0.01, minObjectSize: 128 << 10},
+	GlacierIR:          {perGBMonth: 0.004, perThousandPut: 0.02, perThousandGet: 0.01, perGBRetrieval: 0.03, minObjectSize: 128 << 10},
+	Glacier:            {perGBMonth: 0.0036, perThousandPut: 0.03, perThousandGet: 0.0004, perGBRetrieval: 0.01, overhead: 40 << 10},
+	DeepArchive:        {perGBMonth: 0.00099, perThousandPut: 0.05, perThousandGet: 0.0004, perGBRetrieval: 0.02, overhead: 40 << 10},
+	ExpressOneZone:     {perGBMonth: 0.11, perThousandPut: 0.00113, perThousandGet: 0.00003},
+}
+
+// Cost is an estimate of what storing a prefix costs in USD.
+type Cost struct {
+	Class   StorageClass
+	Objects int64
+	Bytes   int64
+	// Storage is the monthly storage charge.
+	Storage float64
+	// Monitoring is the monthly Intelligent-Tiering monitoring charge.
+	Monitoring float64
+	// WriteAll is the one-off charge for writing every object once.
+	WriteAll float64
+	// ReadAll is the charge for reading every object once, including retrieval.
+	ReadAll float64
+}
+
+// Monthly returns the recurring monthly charge.
+func (c Cost) Monthly() float64 {
+	return c.Storage + c.Monitoring
+}
+
+const gb = 1 << 30
+
+func estimate(class StorageClass, objects, bytes int64) (Cost, error) {
+	p, ok := prices[class]
+	if !ok {
+		return Cost{}, ErrStorageClass
+	}
+
+	billable := bytes + objects*p.overhead
+	if p.minObjectSize > 0 && objects > 0 && bytes/objects < p.minObjectSize {
+		// approximate using the average object size
+		billable = objects * p.minObjectSize
+	}
+
+	return Cost{
+		Class:      class,
+		Objects:    objects,
+		Bytes:      bytes,
+		Storage:    float64(billable) / gb * p.perGBMonth,
+		Monitoring: float64(objects) / 1000 * p.monitoring,
+		WriteAll:   float64(objects) / 1000 * p.perThousandPut,
+		ReadAll:    float64(objects)/1000*p.perThousandGet + float64(bytes)/gb*p.perGBRetrieval,
+	}, nil
+}
+
+// EstimateCost estimates the cost of storing the objects under the prefix in
+// the storage class, from the prefix Stats and published us-east-1 list prices.
+// Minimum billable sizes are applied using the average object size.
+func (c *client) EstimateCost(p string, class StorageClass) (Cost, error) {
+
+	objects, bytes, err := c.Stats(p)
+
+	var cost Cost
+	if err == nil {
+		cost, err = estimate(class, objects, bytes)
+	}
+
+	c.log("EstimateCost", err).
+		Str("prefix", p).
+		Str("class", string(class)).
+		Float64("monthly", cost.Monthly()).
+		Msg("EstimateCost")
+
+	return cost, err
+}
@@ -0,0 +1,38 @@
+package s3
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimate(t *testing.T) {
+
+	cost, err := estimate(Standard, 1000, 100*gb)
+	assert.NoError(t, err)
+	assert.InDelta(t, 2.3, cost.Storage, 1e-9)
+	assert.InDelta(t, 0.005, cost.WriteAll, 1e-9)
+	assert.InDelta(t, 2.3, cost.Monthly(), 1e-9)
+
+	cost, err = estimate(StandardIA, 1<<20, 1<<30)
+	assert.NoError(t, err)
+	assert.InDelta(t, 128*0.0125, cost.Storage, 1e-9)
+
+	cost, err = estimate(IntelligentTiering, 1000, 0)
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.0025, cost.Monthly(), 1e-9)
+
+	_, err = estimate("REDUCED_REDUNDANCY", 1, 1)
+	assert.ErrorIs(t, err, ErrStorageClass)
+}
+
+func TestClient_EstimateCost(t *testing.T) {
+
+	c, _ := newTestBucket(t)
+	assert.NoError(t, c.Put(testKey(), testBody()))
+
+	cost, err := c.EstimateCost("users/", Standard)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), cost.Objects)
+	assert.Equal(t, int64(len(testBody())), cost.Bytes)
+}
@@ -0,0 +1,56 @@
+package s3
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// WithProfile loads credentials and settings from the named profile of the
+// shared config and credentials files, as AWS_PROFILE would.
+func WithProfile(name string) Option {
+	return WithConfig(config.WithSharedConfigProfile(name))
+}
+
+// WithCredentialsProvider makes requests with credentials from the provider
+// instead of the default credential chain.
+func WithCredentialsProvider(p aws.CredentialsProvider) Option {
+	return WithConfig(config.WithCredentialsProvider(p))
+}
+
+// WithStaticCredentials makes requests with the access key, secret and
+// optional session token instead of the default credential chain.
+func WithStaticCredentials(id, secret, token string) Option {
+	return WithCredentialsProvider(credentials.NewStaticCredentialsProvider(id, secret, token))
+}
+
+// WithAnonymous makes unsigned requests, for reading public buckets from
+// environments without AWS credentials.
+func WithAnonymous() Option {
+	return WithCredentialsProvider(aws.AnonymousCredentials{})
+}
+
+type assumeRole struct {
+	arn        string
+	externalID string
+}
+
+// WithAssumeRole makes requests with credentials of the IAM role, assumed with
+// STS using the default credentials and refreshed before they expire. The
+// external ID is passed when not empty, as partner accounts commonly require.
+func WithAssumeRole(roleARN, externalID string) Option {
+	return func(o *options) {
+		o.assumeRole = &assumeRole{roleARN, externalID}
+	}
+}
+
+// credentials returns the provider for the assumed role.
+func (r *assumeRole) credentials(cfg aws.Config) aws.CredentialsProvider {
+	return aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), r.arn, func(o *stscreds.AssumeRoleOptions) {
+		if r.externalID != "" {
+			o.ExternalID = &r.externalID
+		}
+	}))
+}
@@ -0,0 +1,112 @@
+package s3
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithAssumeRole(t *testing.T) {
+
+	var form url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		form, _ = url.ParseQuery(string(b))
+		w.Header().Set("Content-Type", "text/xml")
+		_, _ = io.WriteString(w, `<AssumeRoleResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/"><AssumeRoleResult><Credentials>`+
+			`<AccessKeyId>partner-id</AccessKeyId><SecretAccessKey>partner-secret</SecretAccessKey><SessionToken>token</SessionToken>`+
+			`<Expiration>2099-01-01T00:00:00Z</Expiration></Credentials></AssumeRoleResult></AssumeRoleResponse>`)
+	}))
+	t.Cleanup(srv.Close)
+
+	c := NewWithOptions(context.Background(),
+		WithBucket("partner-bucket"),
+		WithConfig(
+			config.WithRegion("us-east-1"),
+			config.WithBaseEndpoint(srv.URL),
+		),
+		WithStaticCredentials("id", "secret", ""),
+		WithAssumeRole("arn:aws:iam::123456789012:role/writer", "partner-42"),
+	).(*client)
+
+	creds, err := c.cfg.Credentials.Retrieve(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "partner-id", creds.AccessKeyID)
+	assert.Equal(t, "token", creds.SessionToken)
+	assert.True(t, creds.CanExpire)
+	assert.Equal(t, "AssumeRole", form.Get("Action"))
+	assert.Equal(t, "arn:aws:iam::123456789012:role/writer", form.Get("RoleArn"))
+	assert.Equal(t, "partner-42", form.Get("ExternalId"))
+
+	// the S3 client signs with the assumed role
+	s3Creds, err := c.Options().Credentials.Retrieve(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "partner-id", s3Creds.AccessKeyID)
+}
+
+func TestWithCredentialsProvider(t *testing.T) {
+
+	p := aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+		return aws.Credentials{AccessKeyID: "ci-id", SecretAccessKey: "ci-secret"}, nil
+	})
+	c := NewWithOptions(context.Background(),
+		WithBucket("bytelyon-db"),
+		WithConfig(config.WithRegion("us-east-1")),
+		WithCredentialsProvider(p),
+	).(*client)
+
+	creds, err := c.Options().Credentials.Retrieve(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "ci-id", creds.AccessKeyID)
+}
+
+func TestWithProfile(t *testing.T) {
+
+	dir := t.TempDir()
+	cfgFile, credsFile := dir+"/config", dir+"/credentials"
+	assert.NoError(t, os.WriteFile(cfgFile, []byte("[profile partner]\nregion = eu-west-1\n"), 0o600))
+	assert.NoError(t, os.WriteFile(credsFile, []byte("[partner]\naws_access_key_id = partner-id\naws_secret_access_key = partner-secret\n"), 0o600))
+	t.Setenv("AWS_CONFIG_FILE", cfgFile)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", credsFile)
+
+	c := NewWithOptions(context.Background(), WithBucket("bytelyon-db"), WithProfile("partner")).(*client)
+
+	assert.Equal(t, "eu-west-1", c.cfg.Region)
+	creds, err := c.cfg.Credentials.Retrieve(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "partner-id", creds.AccessKeyID)
+}
+
+func TestWithAnonymous(t *testing.T) {
+
+	var auth []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+		_, _ = io.WriteString(w, "open data")
+	}))
+	t.Cleanup(srv.Close)
+
+	c := NewWithOptions(context.Background(),
+		WithBucket("open-data"),
+		WithConfig(config.WithRegion("us-east-1")),
+		WithS3Options(func(o *s3.Options) {
+			o.BaseEndpoint = &srv.URL
+			o.UsePathStyle = true
+		}),
+		WithAnonymous(),
+	)
+
+	b, err := c.Get("dataset.csv")
+	assert.NoError(t, err)
+	assert.Equal(t, "open data", string(b))
+	assert.Equal(t, []string{""}, auth)
+}
@@ -0,0 +1,287 @@
+// Package davfs implements golang.org/x/net/webdav.FileSystem on top of an
+// s3.Service so the bucket can be mounted by WebDAV capable file browsers.
+//
+// Keys are treated as slash separated paths. Directories are the common
+// prefixes of keys, and Mkdir writes an empty "dir/" marker object so empty
+// directories survive. Files are buffered in memory while open, which suits
+// ad-hoc access to documents rather than multi-gigabyte objects.
+package davfs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/nelsw/s3"
+	"golang.org/x/net/webdav"
+)
+
+type fileSystem struct {
+	svc  s3.Service
+	root string
+}
+
+// New returns a webdav.FileSystem serving the keys under root.
+func New(svc s3.Service, root string) webdav.FileSystem {
+	root = strings.Trim(root, "/")
+	if root != "" {
+		root += "/"
+	}
+	return &fileSystem{svc, root}
+}
+
+// key maps a WebDAV path to an object key.
+func (f *fileSystem) key(name string) string {
+	return f.root + strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+func (f *fileSystem) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	if _, err := f.Stat(ctx, name); err == nil {
+		return os.ErrExist
+	}
+	if _, err := f.Stat(ctx, path.Dir(path.Clean("/"+name))); err != nil {
+		return err
+	}
+	return f.svc.Put(f.key(name)+"/", []byte{})
+}
+
+func (f *fileSystem) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	k := f.key(name)
+
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC) != 0 {
+		fi, err := f.Stat(ctx, name)
+		if err == nil && fi.IsDir() {
+			return nil, os.ErrInvalid
+		}
+		if err != nil && flag&os.O_CREATE == 0 {
+			return nil, err
+		}
+		if err == nil && flag&os.O_EXCL != 0 {
+			return nil, os.ErrExist
+		}
+		var b []byte
+		if err == nil && flag&os.O_TRUNC == 0 {
+			if b, err = f.svc.Get(k); err != nil {
+				return nil, err
+			}
+		}
+		w := &file{fs: f, key: k, info: info{name: path.Base(k), modTime: time.Now()}, writable: true}
+		w.buf.Write(b)
+		return w, nil
+	}
+
+	fi, err := f.Stat(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		return &file{fs: f, key: k, info: *fi.(*info)}, nil
+	}
+	b, err := f.svc.Get(k)
+	if err != nil {
+		return nil, err
+	}
+	return &file{fs: f, key: k, info: *fi.(*info), reader: bytes.NewReader(b)}, nil
+}
+
+func (f *fileSystem) RemoveAll(ctx context.Context, name string) error {
+	k := f.key(name)
+	if k == f.root {
+		return os.ErrInvalid
+	}
+	keys, err := f.keys(k + "/")
+	if err != nil {
+		return err
+	}
+	for _, key := range append(keys, k) {
+		if err = f.svc.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fileSystem) Rename(ctx context.Context, oldName, newName string) error {
+	src, dst := f.key(oldName), f.key(newName)
+	fi, err := f.Stat(ctx, oldName)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		if err = f.svc.Copy(src, dst); err == nil {
+			err = f.svc.Delete(src)
+		}
+		return err
+	}
+	keys, err := f.keys(src + "/")
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if err = f.svc.Copy(k, dst+strings.TrimPrefix(k, src)); err != nil {
+			return err
+		}
+		if err = f.svc.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fileSystem) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	k := f.key(name)
+	if k == f.root {
+		return &info{name: "/", dir: true}, nil
+	}
+	if o, err := f.svc.Stat(k); err == nil {
+		return &info{name: path.Base(k), size: o.Size, modTime: o.LastModified}, nil
+	} else if !s3.IsNotFound(err) {
+		return nil, err
+	}
+	dirs, objects, err := f.svc.ListDir(k + "/")
+	if err != nil {
+		return nil, err
+	}
+	if len(dirs) == 0 && len(objects) == 0 {
+		return nil, os.ErrNotExist
+	}
+	return &info{name: path.Base(k), dir: true}, nil
+}
+
+// keys returns every key under the prefix.
+func (f *fileSystem) keys(p string) ([]string, error) {
+	var keys []string
+	var after string
+	for {
+		page, err := f.svc.Keys(p, after, 1000)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, page...)
+		if len(page) < 1000 {
+			return keys, nil
+		}
+		after = page[len(page)-1]
+	}
+}
+
+type info struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+func (i *info) Name() string       { return i.name }
+func (i *info) Size() int64        { return i.size }
+func (i *info) ModTime() time.Time { return i.modTime }
+func (i *info) IsDir() bool        { return i.dir }
+func (i *info) Sys() any           { return nil }
+
+func (i *info) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0o755
+	}
+	return 0o644
+}
+
+type file struct {
+	fs       *fileSystem
+	key      string
+	info     info
+	reader   *bytes.Reader
+	buf      bytes.Buffer
+	writable bool
+	listed   bool
+	// entries are those of the directory Readdir hasn't returned yet
+	entries []fs.FileInfo
+}
+
+func (f *file) Close() error {
+	if !f.writable {
+		return nil
+	}
+	return f.fs.svc.Put(f.key, f.buf.Bytes())
+}
+
+func (f *file) Read(p []byte) (int, error) {
+	if f.reader == nil {
+		return 0, os.ErrInvalid
+	}
+	return f.reader.Read(p)
+}
+
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	if f.reader == nil {
+		return 0, os.ErrInvalid
+	}
+	return f.reader.Seek(offset, whence)
+}
+
+func (f *file) Write(p []byte) (int, error) {
+	if !f.writable {
+		return 0, os.ErrPermission
+	}
+	return f.buf.Write(p)
+}
+
+func (f *file) Stat() (os.FileInfo, error) {
+	i := f.info
+	if f.writable {
+		i.size = int64(f.buf.Len())
+	}
+	return &i, nil
+}
+
+func (f *file) Readdir(count int) ([]fs.FileInfo, error) {
+	if !f.info.dir {
+		return nil, os.ErrInvalid
+	}
+	if !f.listed {
+		if err := f.list(); err != nil {
+			return nil, err
+		}
+		f.listed = true
+	}
+
+	if count <= 0 {
+		infos := f.entries
+		f.entries = nil
+		return infos, nil
+	}
+	if len(f.entries) == 0 {
+		return nil, io.EOF
+	}
+	n := min(count, len(f.entries))
+	infos := f.entries[:n:n]
+	f.entries = f.entries[n:]
+	return infos, nil
+}
+
+// list reads the entries of the directory.
+func (f *file) list() error {
+	p := f.key
+	if p != "" && !strings.HasSuffix(p, "/") {
+		p += "/"
+	}
+	dirs, objects, err := f.fs.svc.ListDir(p)
+	if err != nil {
+		return err
+	}
+	for _, d := range dirs {
+		f.entries = append(f.entries, &info{name: path.Base(d), dir: true})
+	}
+	for _, o := range objects {
+		if o.Key == p {
+			// directory marker written by Mkdir
+			continue
+		}
+		f.entries = append(f.entries, &info{name: path.Base(o.Key), size: o.Size, modTime: o.LastModified})
+	}
+	return nil
+}
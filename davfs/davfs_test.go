@@ -0,0 +1,162 @@
+package davfs
+
+import (
+	"context"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/smithy-go"
+	"github.com/nelsw/s3"
+	"github.com/stretchr/testify/assert"
+)
+
+type testService struct {
+	s3.Service
+	objects map[string][]byte
+}
+
+func (s *testService) Get(k string) ([]byte, error) {
+	b, ok := s.objects[k]
+	if !ok {
+		return nil, &smithy.GenericAPIError{Code: "NoSuchKey"}
+	}
+	return b, nil
+}
+
+func (s *testService) Put(k string, a any) error {
+	s.objects[k] = a.([]byte)
+	return nil
+}
+
+func (s *testService) Delete(k string) error {
+	delete(s.objects, k)
+	return nil
+}
+
+func (s *testService) Copy(src, dst string) error {
+	s.objects[dst] = s.objects[src]
+	return nil
+}
+
+func (s *testService) Keys(p, a string, n int32, _ ...s3.KeyFilter) ([]string, error) {
+	var keys []string
+	for k := range s.objects {
+		if strings.HasPrefix(k, p) && k > a {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (s *testService) Stat(k string) (s3.ObjectInfo, error) {
+	b, ok := s.objects[k]
+	if !ok {
+		return s3.ObjectInfo{}, &smithy.GenericAPIError{Code: "NotFound"}
+	}
+	return s3.ObjectInfo{Key: k, Size: int64(len(b))}, nil
+}
+
+func (s *testService) ListDir(p string) ([]string, []s3.ObjectInfo, error) {
+	seen := map[string]bool{}
+	var dirs []string
+	var objects []s3.ObjectInfo
+	for k, b := range s.objects {
+		if !strings.HasPrefix(k, p) {
+			continue
+		}
+		if i := strings.Index(k[len(p):], "/"); i >= 0 {
+			if d := k[:len(p)+i+1]; !seen[d] {
+				seen[d] = true
+				dirs = append(dirs, d)
+			}
+			continue
+		}
+		objects = append(objects, s3.ObjectInfo{Key: k, Size: int64(len(b))})
+	}
+	return dirs, objects, nil
+}
+
+func TestFileSystem(t *testing.T) {
+
+	ctx := context.Background()
+	svc := &testService{objects: map[string][]byte{}}
+	fs := New(svc, "dav")
+
+	assert.NoError(t, fs.Mkdir(ctx, "/docs", 0o755))
+	assert.Contains(t, svc.objects, "dav/docs/")
+
+	f, err := fs.OpenFile(ctx, "/docs/a.txt", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	assert.NoError(t, err)
+	_, err = io.WriteString(f, "hello")
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+	assert.Equal(t, "hello", string(svc.objects["dav/docs/a.txt"]))
+
+	fi, err := fs.Stat(ctx, "/docs/a.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), fi.Size())
+
+	f, err = fs.OpenFile(ctx, "/docs", os.O_RDONLY, 0)
+	assert.NoError(t, err)
+	infos, err := f.Readdir(0)
+	assert.NoError(t, err)
+	assert.Len(t, infos, 1)
+	assert.Equal(t, "a.txt", infos[0].Name())
+
+	assert.NoError(t, fs.Rename(ctx, "/docs", "/notes"))
+	f, err = fs.OpenFile(ctx, "/notes/a.txt", os.O_RDONLY, 0)
+	assert.NoError(t, err)
+	b, err := io.ReadAll(f)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(b))
+
+	assert.NoError(t, fs.RemoveAll(ctx, "/notes"))
+	assert.Empty(t, svc.objects)
+	_, err = fs.Stat(ctx, "/notes")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestFile_Readdir(t *testing.T) {
+
+	ctx := context.Background()
+	svc := &testService{objects: map[string][]byte{
+		"dav/docs/":       nil,
+		"dav/docs/a.txt":  []byte("a"),
+		"dav/docs/b.txt":  []byte("b"),
+		"dav/docs/c/d.md": []byte("d"),
+	}}
+	fs := New(svc, "dav")
+
+	f, err := fs.OpenFile(ctx, "/docs", os.O_RDONLY, 0)
+	assert.NoError(t, err)
+	var names []string
+	for range 2 {
+		infos, err := f.Readdir(2)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, infos)
+		for _, fi := range infos {
+			names = append(names, fi.Name())
+		}
+	}
+	sort.Strings(names)
+	assert.Equal(t, []string{"a.txt", "b.txt", "c"}, names)
+	_, err = f.Readdir(2)
+	assert.ErrorIs(t, err, io.EOF)
+	infos, err := f.Readdir(0)
+	assert.NoError(t, err)
+	assert.Empty(t, infos)
+
+	// Readdir(0) returns the entries not yet read
+	f, err = fs.OpenFile(ctx, "/docs", os.O_RDONLY, 0)
+	assert.NoError(t, err)
+	infos, err = f.Readdir(1)
+	assert.NoError(t, err)
+	assert.Len(t, infos, 1)
+	infos, err = f.Readdir(0)
+	assert.NoError(t, err)
+	assert.Len(t, infos, 2)
+}
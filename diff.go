@@ -0,0 +1,76 @@
+package s3
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// location splits an "s3://bucket/prefix" reference into its bucket and
+// prefix. Plain prefixes refer to the client's bucket.
+func (c *client) location(p string) (string, string) {
+	if rest, ok := strings.CutPrefix(p, "s3://"); ok {
+		b, p, _ := strings.Cut(rest, "/")
+		return b, p
+	}
+	return *c.Bucket, p
+}
+
+// Diff compares the objects under two prefixes by key relative to each prefix
+// and returns the keys only under b (added), only under a (removed), and under
+// both with a different size or ETag (changed). Either prefix may be given as
+// "s3://bucket/prefix" to compare against another bucket. ETags of objects
+// uploaded with different multipart part sizes differ even for equal content.
+func (c *client) Diff(a, b string) (added, removed, changed []string, err error) {
+
+	listing := func(ref string) (map[string]types.Object, error) {
+		bucket, p := c.location(ref)
+		m := map[string]types.Object{}
+		return m, c.walkBucket(bucket, p, func(obj types.Object) error {
+			m[strings.TrimPrefix(*obj.Key, p)] = obj
+			return nil
+		})
+	}
+
+	var left, right map[string]types.Object
+	if left, err = listing(a); err == nil {
+		right, err = listing(b)
+	}
+
+	if err == nil {
+		added, removed, changed = diffListings(left, right)
+	}
+
+	c.log("Diff", err).
+		Str("a", a).
+		Str("b", b).
+		Int("added", len(added)).
+		Int("removed", len(removed)).
+		Int("changed", len(changed)).
+		Msg("Diff")
+
+	return
+}
+
+func diffListings(a, b map[string]types.Object) (added, removed, changed []string) {
+	for k, obj := range b {
+		old, ok := a[k]
+		switch {
+		case !ok:
+			added = append(added, k)
+		case aws.ToInt64(old.Size) != aws.ToInt64(obj.Size) || aws.ToString(old.ETag) != aws.ToString(obj.ETag):
+			changed = append(changed, k)
+		}
+	}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			removed = append(removed, k)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+	return
+}
@@ -0,0 +1,24 @@
+package s3
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Diff(t *testing.T) {
+
+	c, _ := newTestBucket(t)
+	assert.NoError(t, c.Put("a/same.json", testBody()))
+	assert.NoError(t, c.Put("a/changed.json", testBody()))
+	assert.NoError(t, c.Put("a/removed.json", testBody()))
+	assert.NoError(t, c.Put("b/same.json", testBody()))
+	assert.NoError(t, c.Put("b/changed.json", "{}"))
+	assert.NoError(t, c.Put("b/added.json", testBody()))
+
+	added, removed, changed, err := c.Diff("a/", "s3://bytelyon-db/b/")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"added.json"}, added)
+	assert.Equal(t, []string{"removed.json"}, removed)
+	assert.Equal(t, []string{"changed.json"}, changed)
+}
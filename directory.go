@@ -0,0 +1,72 @@
+package s3
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+const directoryBucketSuffix = "--x-s3"
+
+// validateDirectoryBucket returns an error when b is not named like a directory
+// bucket, i.e. "bucket-base-name--zone-id--x-s3".
+func validateDirectoryBucket(b string) error {
+	if zoneID(b) == "" {
+		return errors.New("directory bucket name must match bucket-base-name--zone-id--x-s3: " + b)
+	}
+	return nil
+}
+
+// zoneID returns the Availability Zone or Local Zone ID encoded in a directory
+// bucket name, or an empty string if b is not a directory bucket name.
+func zoneID(b string) string {
+	if !strings.HasSuffix(b, directoryBucketSuffix) {
+		return ""
+	}
+	parts := strings.Split(strings.TrimSuffix(b, directoryBucketSuffix), "--")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return ""
+	}
+	return parts[1]
+}
+
+// directoryKeys lists keys in a directory bucket. Directory buckets neither
+// support StartAfter nor return keys in lexicographical order, so the prefix
+// is listed in full and the page is selected client side.
+func (c *client) directoryKeys(p, a string, s int32, filters []KeyFilter) ([]string, error) {
+
+	var keys []string
+	var err error
+	if p != "" && !strings.HasSuffix(p, "/") {
+		err = errors.New("directory bucket prefixes must end with a delimiter (/): " + p)
+	}
+
+	if err == nil {
+		err = c.walk(p, func(obj types.Object) error {
+			if *obj.Key > a && matchKey(*obj.Key, filters) {
+				keys = append(keys, *obj.Key)
+			}
+			return nil
+		})
+	}
+
+	if err != nil {
+		keys = nil
+	} else {
+		sort.Strings(keys)
+		if int32(len(keys)) > s {
+			keys = keys[:s]
+		}
+	}
+
+	c.log("Keys", err).
+		Str("prefix", p).
+		Str("after", a).
+		Int32("size", s).
+		Strs("keys", keys).
+		Msg("Keys")
+
+	return keys, err
+}
@@ -0,0 +1,18 @@
+package s3
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestZoneID(t *testing.T) {
+	assert.Equal(t, "usw2-az1", zoneID("bytelyon-cache--usw2-az1--x-s3"))
+	assert.Empty(t, zoneID("bytelyon-db"))
+	assert.Empty(t, zoneID("--usw2-az1--x-s3"))
+}
+
+func TestValidateDirectoryBucket(t *testing.T) {
+	assert.NoError(t, validateDirectoryBucket("bytelyon-cache--use1-az4--x-s3"))
+	assert.Error(t, validateDirectoryBucket("bytelyon-db"))
+}
@@ -0,0 +1,242 @@
+package s3
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// diskCache stores object bodies under a directory in files named by their
+// ETag, so objects with the same content share a file, evicting the least
+// recently used files beyond maxBytes.
+type diskCache struct {
+	dir      string
+	maxBytes int64
+
+	mu    sync.Mutex
+	etags map[string]string
+	files map[string]*list.Element
+	lru   *list.List
+	size  int64
+}
+
+type diskFile struct {
+	name string
+	size int64
+}
+
+// WithDiskCache caches the bodies streamed by GetReader in files under the
+// directory, holding at most maxBytes bytes, unlimited when 0, and evicting
+// the least recently used first. Cached objects are revalidated with a
+// conditional GET by ETag on every read, so they are never stale, and files
+// left by earlier processes are reused.
+func WithDiskCache(dir string, maxBytes int64) Option {
+	return func(o *options) {
+		d, err := newDiskCache(dir, maxBytes)
+		if err != nil {
+			panic(err)
+		}
+		o.diskCache = d
+	}
+}
+
+func newDiskCache(dir string, maxBytes int64) (*diskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &diskCache{dir: dir, maxBytes: maxBytes, etags: map[string]string{}, files: map[string]*list.Element{}, lru: list.New()}
+	var infos []os.FileInfo
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".tmp-") {
+			_ = os.Remove(filepath.Join(dir, e.Name()))
+			continue
+		}
+		if info, err := e.Info(); err == nil && info.Mode().IsRegular() {
+			infos = append(infos, info)
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ModTime().Before(infos[j].ModTime()) })
+	for _, info := range infos {
+		d.files[info.Name()] = d.lru.PushFront(&diskFile{info.Name(), info.Size()})
+		d.size += info.Size()
+	}
+	d.evictLocked()
+	return d, nil
+}
+
+// diskCacheName returns the name of the file holding the body with the ETag.
+func diskCacheName(etag string) string {
+	sum := sha256.Sum256([]byte(etag))
+	return hex.EncodeToString(sum[:])
+}
+
+// etag returns the ETag of the key's cached body, if it is still cached.
+func (d *diskCache) etag(k string) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	etag := d.etags[k]
+	if _, ok := d.files[diskCacheName(etag)]; !ok {
+		return ""
+	}
+	return etag
+}
+
+// open opens the cached body with the ETag and records the key as holding it.
+func (d *diskCache) open(k, etag string) (*os.File, int64, error) {
+	name := diskCacheName(etag)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	el, ok := d.files[name]
+	if !ok {
+		return nil, 0, os.ErrNotExist
+	}
+	f, err := os.Open(filepath.Join(d.dir, name))
+	if err != nil {
+		return nil, 0, err
+	}
+	now := time.Now()
+	_ = os.Chtimes(f.Name(), now, now)
+	d.lru.MoveToFront(el)
+	d.etags[k] = etag
+	return f, el.Value.(*diskFile).size, nil
+}
+
+// forget drops the key, leaving its body for other keys with the same content.
+func (d *diskCache) forget(k string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.etags, k)
+}
+
+// fill returns body, writing what is read from it to the cache and adding
+// the file once the whole body has been read.
+func (d *diskCache) fill(k, etag string, body io.ReadCloser, size int64) io.ReadCloser {
+	if d.maxBytes > 0 && size > d.maxBytes {
+		return body
+	}
+	f, err := os.CreateTemp(d.dir, ".tmp-*")
+	if err != nil {
+		return body
+	}
+	return &diskCacheFill{ReadCloser: body, d: d, k: k, etag: etag, f: f, size: size}
+}
+
+// add moves the temporary file into the cache and evicts files to stay within maxBytes.
+func (d *diskCache) add(k, etag, tmp string, size int64) {
+	name := diskCacheName(etag)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := os.Rename(tmp, filepath.Join(d.dir, name)); err != nil {
+		_ = os.Remove(tmp)
+		return
+	}
+	if el, ok := d.files[name]; ok {
+		d.lru.MoveToFront(el)
+	} else {
+		d.files[name] = d.lru.PushFront(&diskFile{name, size})
+		d.size += size
+	}
+	d.etags[k] = etag
+	d.evictLocked()
+}
+
+func (d *diskCache) evictLocked() {
+	for d.maxBytes > 0 && d.size > d.maxBytes {
+		f := d.lru.Remove(d.lru.Back()).(*diskFile)
+		delete(d.files, f.name)
+		d.size -= f.size
+		_ = os.Remove(filepath.Join(d.dir, f.name))
+	}
+}
+
+// diskCacheFill copies an object body to a temporary file as it is read.
+type diskCacheFill struct {
+	io.ReadCloser
+	d       *diskCache
+	k, etag string
+	f       *os.File
+	n, size int64
+}
+
+func (r *diskCacheFill) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if r.f != nil && n > 0 {
+		if _, werr := r.f.Write(p[:n]); werr != nil {
+			r.discard()
+		}
+		r.n += int64(n)
+	}
+	if r.f != nil && err == io.EOF {
+		if r.n != r.size || r.f.Close() != nil {
+			r.discard()
+		} else {
+			r.d.add(r.k, r.etag, r.f.Name(), r.size)
+			r.f = nil
+		}
+	}
+	return n, err
+}
+
+func (r *diskCacheFill) Close() error {
+	if r.f != nil {
+		r.discard()
+	}
+	return r.ReadCloser.Close()
+}
+
+// discard removes the temporary file of a body that wasn't read completely.
+func (r *diskCacheFill) discard() {
+	_ = r.f.Close()
+	_ = os.Remove(r.f.Name())
+	r.f = nil
+}
+
+// getCached returns the body of the object from the disk cache when its ETag
+// still matches, and otherwise streams it from the bucket while caching it.
+func (c *client) getCached(k string) (io.ReadCloser, int64, error) {
+	d := c.diskCache
+	in := &s3.GetObjectInput{Key: &k}
+	etag := d.etag(k)
+	if etag != "" {
+		in.IfNoneMatch = &etag
+	}
+
+	out, err := c.getObject(in)
+	if isNotModified(err) {
+		if f, size, err := d.open(k, etag); err == nil {
+			return f, size, nil
+		}
+		// evicted since it was revalidated
+		in.IfNoneMatch = nil
+		out, err = c.getObject(in)
+	}
+	if err != nil {
+		if IsNotFound(err) {
+			d.forget(k)
+		}
+		return nil, 0, err
+	}
+
+	etag = aws.ToString(out.ETag)
+	if f, size, err := d.open(k, etag); err == nil {
+		out.Body.Close()
+		return f, size, nil
+	}
+	size := aws.ToInt64(out.ContentLength)
+	return d.fill(k, etag, out.Body, size), size, nil
+}
@@ -0,0 +1,85 @@
+package s3
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithDiskCache(t *testing.T) {
+
+	dir := t.TempDir()
+	var statuses []int
+	c, b := newTestBucket(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			if r.Method == http.MethodGet {
+				statuses = append(statuses, rec.status)
+			}
+		})
+	})
+	WithDiskCache(dir, 10)(c.options)
+
+	read := func(k string) string {
+		body, err := c.GetReader(k)
+		assert.NoError(t, err)
+		defer body.Close()
+		out, err := io.ReadAll(body)
+		assert.NoError(t, err)
+		return string(out)
+	}
+	files := func() int {
+		entries, err := os.ReadDir(dir)
+		assert.NoError(t, err)
+		return len(entries)
+	}
+
+	b.put("a", []byte("123456"), nil)
+	assert.Equal(t, "123456", read("a"))
+	assert.Equal(t, "123456", read("a"))
+	assert.Equal(t, []int{http.StatusOK, http.StatusNotModified}, statuses)
+	assert.Equal(t, 1, files())
+
+	// the same content under another key shares the file
+	b.put("b", []byte("123456"), nil)
+	assert.Equal(t, "123456", read("b"))
+	assert.Equal(t, 1, files())
+
+	// bodies not read to the end aren't cached
+	b.put("c", []byte("abcdef"), nil)
+	body, err := c.GetReader("c")
+	assert.NoError(t, err)
+	assert.NoError(t, body.Close())
+	assert.Equal(t, 1, files())
+
+	// least recently used files are evicted beyond maxBytes
+	assert.Equal(t, "abcdef", read("c"))
+	assert.Equal(t, 1, files())
+
+	// changes are seen on revalidation
+	b.put("c", []byte("ghi"), nil)
+	assert.Equal(t, "ghi", read("c"))
+
+	// files are reused by new clients
+	WithDiskCache(dir, 10)(c.options)
+	assert.Equal(t, "ghi", read("c"))
+	assert.Equal(t, 2, files())
+
+	_, err = c.GetReader("missing")
+	assert.True(t, IsNotFound(err))
+}
+
+// statusRecorder records the status code written to a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,184 @@
+package s3
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// ErrConflict is returned by updates that kept losing races with concurrent
+// writers of the same document.
+var ErrConflict = errors.New("s3: too many concurrent updates")
+
+// maxUpdateAttempts is how many times an update is retried when the document
+// changes between reading and writing it.
+const maxUpdateAttempts = 10
+
+// GetOrCreate decodes the JSON document at the key into out, first creating
+// it from the value returned by create if it doesn't exist. The document is
+// created with a conditional Put, so when callers race only one creates it
+// and the others decode the winner's document. create isn't called when the
+// document exists.
+func (c *client) GetOrCreate(k string, out any, create func() (any, error)) error {
+
+	err := c.Find(k, out)
+	var created bool
+	if IsNotFound(err) {
+		var v any
+		if v, err = create(); err == nil {
+			created, err = c.create(k, v, out)
+		}
+		if err == nil && !created {
+			err = c.Find(k, out)
+		}
+	}
+
+	c.log("GetOrCreate", err).
+		Str("key", k).
+		Bool("created", created).
+		Msg("GetOrCreate")
+
+	return err
+}
+
+// create stores the value at the key unless it exists and decodes it into
+// out, reporting whether it was created.
+func (c *client) create(k string, v, out any) (bool, error) {
+	body, ct, release, err := encode(k, v)
+	if err != nil {
+		return false, err
+	}
+	defer release()
+
+	_, err = c.putObject(&s3.PutObjectInput{
+		Bucket:      c.Bucket,
+		Key:         &k,
+		ContentType: &ct,
+		IfNoneMatch: aws.String("*"),
+	}, body)
+	if isPreconditionFailed(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal(body, out)
+}
+
+// update rewrites the document at the key with the result of fn under
+// optimistic concurrency, reading it again and retrying fn when it changes
+// before it is written. fn receives nil for a missing document. update
+// returns the written document and its ETag.
+func (c *client) update(k string, fn func(doc []byte) ([]byte, error)) ([]byte, string, error) {
+	for range maxUpdateAttempts {
+		in := &s3.PutObjectInput{
+			Bucket:      c.Bucket,
+			Key:         &k,
+			ContentType: aws.String("application/json"),
+		}
+
+		// documents are read from the bucket, never transformed
+		var doc []byte
+		out, err := c.getObject(&s3.GetObjectInput{Bucket: c.Bucket, Key: &k})
+		switch {
+		case IsNotFound(err):
+			in.IfNoneMatch = aws.String("*")
+		case err != nil:
+			return nil, "", err
+		default:
+			doc, err = readAll(out.Body, aws.ToInt64(out.ContentLength))
+			out.Body.Close()
+			if err != nil {
+				return nil, "", err
+			}
+			in.IfMatch = out.ETag
+		}
+
+		body, err := fn(doc)
+		if err != nil {
+			return nil, "", err
+		}
+		put, err := c.putObject(in, body)
+		if isPreconditionFailed(err) {
+			continue
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		return body, aws.ToString(put.ETag), nil
+	}
+	return nil, "", fmt.Errorf("%w: %s", ErrConflict, k)
+}
+
+// Patch applies the JSON merge patch (RFC 7386) to the JSON document at the
+// key, creating it if it doesn't exist: objects in the patch are merged into
+// the document recursively, nulls delete members, and anything else replaces
+// the member. The patch is raw JSON when it is bytes or a string, and is
+// encoded as JSON otherwise. Concurrent writes are retried, failing with
+// ErrConflict when contention persists.
+func (c *client) Patch(k string, patch any) error {
+
+	p, _, release, err := encode(k, patch)
+	var etag string
+	if err == nil {
+		defer release()
+		var mp any
+		if mp, err = decodeJSON(p); err == nil {
+			_, etag, err = c.update(k, func(doc []byte) ([]byte, error) {
+				var target any
+				if doc != nil {
+					var err error
+					if target, err = decodeJSON(doc); err != nil {
+						return nil, err
+					}
+				}
+				return json.Marshal(mergePatch(target, mp))
+			})
+		}
+	}
+
+	c.log("Patch", err).
+		Str("key", k).
+		Bytes("patch", p).
+		Str("etag", etag).
+		Msg("Patch")
+
+	return err
+}
+
+// decodeJSON decodes the JSON document keeping numbers as json.Number, so
+// they survive patching exactly.
+func decodeJSON(b []byte) (any, error) {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// mergePatch returns the target with the merge patch applied, as specified
+// by RFC 7386.
+func mergePatch(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = map[string]any{}
+	}
+	for name, v := range p {
+		if v == nil {
+			delete(t, name)
+		} else {
+			t[name] = mergePatch(t[name], v)
+		}
+	}
+	return t
+}
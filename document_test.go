@@ -0,0 +1,100 @@
+package s3
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_GetOrCreate(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	type config struct {
+		Name string `json:"name"`
+	}
+
+	var calls atomic.Int32
+	var wg sync.WaitGroup
+	got := make([]config, 8)
+	for i := range got {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, c.GetOrCreate("config.json", &got[i], func() (any, error) {
+				calls.Add(1)
+				return config{Name: "default"}, nil
+			}))
+		}()
+	}
+	wg.Wait()
+	for _, cfg := range got {
+		assert.Equal(t, "default", cfg.Name)
+	}
+	assert.Equal(t, `{"name":"default"}`, string(b.object("config.json").body))
+
+	n := calls.Load()
+	var cfg config
+	assert.NoError(t, c.GetOrCreate("config.json", &cfg, func() (any, error) {
+		calls.Add(1)
+		return config{Name: "other"}, nil
+	}))
+	assert.Equal(t, "default", cfg.Name)
+	assert.Equal(t, n, calls.Load())
+}
+
+func TestMergePatch(t *testing.T) {
+	// examples from RFC 7386 appendix A
+	for _, tc := range [][3]string{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	} {
+		target, err := decodeJSON([]byte(tc[0]))
+		assert.NoError(t, err)
+		patch, err := decodeJSON([]byte(tc[1]))
+		assert.NoError(t, err)
+		out, err := json.Marshal(mergePatch(target, patch))
+		assert.NoError(t, err)
+		assert.JSONEq(t, tc[2], string(out), "%s + %s", tc[0], tc[1])
+	}
+}
+
+func TestClient_Patch(t *testing.T) {
+
+	c, b := newTestBucket(t)
+
+	assert.NoError(t, c.Patch("doc.json", map[string]any{"name": "a", "tags": map[string]any{"x": 1}}))
+	assert.NoError(t, c.Patch("doc.json", `{"tags":{"x":null,"y":12345678901234567890}}`))
+	assert.JSONEq(t, `{"name":"a","tags":{"y":12345678901234567890}}`, string(b.object("doc.json").body))
+
+	// concurrent patches of different members all land
+	var wg sync.WaitGroup
+	for i := range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, c.Patch("doc.json", map[string]any{fmt.Sprint(i): i}))
+		}()
+	}
+	wg.Wait()
+	assert.JSONEq(t, `{"name":"a","tags":{"y":12345678901234567890},"0":0,"1":1,"2":2,"3":3,"4":4}`, string(b.object("doc.json").body))
+
+	b.put("text.json", []byte("not json"), nil)
+	assert.Error(t, c.Patch("text.json", `{"a":1}`))
+}
@@ -0,0 +1,49 @@
+package s3
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	smithyendpoints "github.com/aws/smithy-go/endpoints"
+)
+
+// domainResolver resolves S3 endpoints as usual and then swaps the
+// bucket hostname for a custom domain, keeping the signing properties
+// so that presigned URLs are signed for the host customers will see.
+type domainResolver struct {
+	url url.URL
+	s3.EndpointResolverV2
+}
+
+func newDomainResolver(domain string) *domainResolver {
+	if !strings.Contains(domain, "://") {
+		domain = "https://" + domain
+	}
+	u, err := url.Parse(domain)
+	if err != nil || u.Host == "" {
+		panic("invalid URL domain: " + domain)
+	}
+	return &domainResolver{*u, s3.NewDefaultEndpointResolverV2()}
+}
+
+func (r *domainResolver) ResolveEndpoint(ctx context.Context, p s3.EndpointParameters) (smithyendpoints.Endpoint, error) {
+	e, err := r.EndpointResolverV2.ResolveEndpoint(ctx, p)
+	if err == nil {
+		e.URI.Scheme = r.url.Scheme
+		e.URI.Host = r.url.Host
+		e.URI.Path = strings.TrimSuffix(r.url.Path, "/")
+	}
+	return e, err
+}
+
+func (o *options) presignOptions(po *s3.PresignOptions) {
+	if o.urlDomain == "" {
+		return
+	}
+	r := newDomainResolver(o.urlDomain)
+	po.ClientOptions = append(po.ClientOptions, func(so *s3.Options) {
+		so.EndpointResolverV2 = r
+	})
+}
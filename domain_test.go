@@ -0,0 +1,22 @@
+package s3
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDomainResolver(t *testing.T) {
+
+	e, err := newDomainResolver("files.bytelyon.com").ResolveEndpoint(context.Background(), s3.EndpointParameters{
+		Bucket: aws.String("bytelyon-db"),
+		Region: aws.String("us-east-1"),
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "https://files.bytelyon.com", e.URI.String())
+	assert.NotEmpty(t, e.Properties.Values())
+}
@@ -0,0 +1,228 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+type dualWrite struct {
+	bucket string
+	queue  chan mirror
+
+	mu sync.Mutex
+	// idle is signaled when no mirrors are pending
+	idle    *sync.Cond
+	pending int
+	closed  bool
+	errs    []error
+	// closing stops mirrorLoop, which closes stopped once it has
+	closing chan struct{}
+	stopped chan struct{}
+}
+
+// ErrMirrorsClosed is returned by mutations made once CloseMirrors has
+// stopped mirroring.
+var ErrMirrorsClosed = errors.New("s3: mirrors closed")
+
+// mirror is a mutation to repeat against the secondary bucket.
+type mirror struct {
+	op  string
+	key string
+}
+
+// WithDualWrite mirrors every Put, Copy and Delete made through the client to
+// the secondary bucket, copying written objects server-side from the primary.
+// With a queue of 0 the mirror is made before the mutation returns and a
+// failure to mirror fails the mutation. Otherwise mirrors are made in order in
+// the background, mutations block while queue mirrors are pending, and
+// failures are logged and returned by FlushMirrors. Mirrors already queued
+// are made even once the client's context is done; call CloseMirrors before
+// exiting so none are lost and the background goroutine stops.
+func WithDualWrite(bucket string, queue int) Option {
+	return func(o *options) {
+		d := &dualWrite{bucket: bucket}
+		d.idle = sync.NewCond(&d.mu)
+		if queue > 0 {
+			d.queue = make(chan mirror, queue)
+			d.closing = make(chan struct{})
+			d.stopped = make(chan struct{})
+		}
+		o.dualWrite = d
+	}
+}
+
+// mirror repeats the mutation of the key against the secondary bucket.
+func (c *client) mirror(op, k string) error {
+	d := c.dualWrite
+	if d == nil {
+		return nil
+	}
+	if d.queue == nil {
+		return c.mirrorNow(mirror{op, k})
+	}
+
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return ErrMirrorsClosed
+	}
+	d.pending++
+	d.mu.Unlock()
+
+	select {
+	case d.queue <- mirror{op, k}:
+		return nil
+	case <-c.Done():
+		d.done(nil)
+		return c.Err()
+	}
+}
+
+// done records that a pending mirror was made or dropped, and its error.
+func (d *dualWrite) done(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err != nil {
+		d.errs = append(d.errs, err)
+	}
+	if d.pending--; d.pending == 0 {
+		d.idle.Broadcast()
+	}
+}
+
+// wait waits for the pending mirrors and returns the errors of those that
+// failed since it was last called. d.mu must be held.
+func (d *dualWrite) wait() error {
+	for d.pending > 0 {
+		d.idle.Wait()
+	}
+	err := errors.Join(d.errs...)
+	d.errs = nil
+	return err
+}
+
+// mirrorLoop makes queued mirrors, with the client's context values but
+// regardless of its cancellation so none queued are dropped, until
+// CloseMirrors stops it.
+func (c *client) mirrorLoop() {
+	d := c.dualWrite
+	defer close(d.stopped)
+	mc := *c
+	mc.Context = context.WithoutCancel(c.Context)
+	for {
+		select {
+		case m := <-d.queue:
+			d.done(mc.mirrorNow(m))
+		case <-d.closing:
+			return
+		}
+	}
+}
+
+// FlushMirrors waits for the mirrors queued by WithDualWrite to be made and
+// returns the errors of those that failed since the last flush.
+func (c *client) FlushMirrors() error {
+	d := c.dualWrite
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.wait()
+}
+
+// CloseMirrors flushes the mirrors queued by WithDualWrite as FlushMirrors
+// does and stops the goroutine making them. Mutations made afterwards still
+// take effect on the primary bucket but return ErrMirrorsClosed, as they
+// aren't mirrored.
+func (c *client) CloseMirrors() error {
+	d := c.dualWrite
+	if d == nil || d.queue == nil {
+		return nil
+	}
+	d.mu.Lock()
+	wasClosed := d.closed
+	d.closed = true
+	err := d.wait()
+	d.mu.Unlock()
+
+	if !wasClosed {
+		close(d.closing)
+	}
+	<-d.stopped
+	return err
+}
+
+func (c *client) mirrorNow(m mirror) error {
+	var err error
+	switch m.op {
+	case "Put", "DeleteVersion":
+		var head *s3.HeadObjectOutput
+		head, err = c.HeadObject(c.Context, &s3.HeadObjectInput{Bucket: c.Bucket, Key: &m.key})
+		if err == nil && aws.ToInt64(head.ContentLength) > maxCopySize {
+			err = c.mirrorParts(m.key, head)
+		} else if err == nil {
+			_, err = c.CopyObject(c.Context, &s3.CopyObjectInput{
+				Bucket:     &c.dualWrite.bucket,
+				Key:        &m.key,
+				CopySource: aws.String(c.copySource(m.key)),
+			})
+		}
+		if IsNotFound(err) && m.op == "DeleteVersion" {
+			// no version is left current
+			err = c.mirrorDelete(m.key)
+		} else if IsNotFound(err) {
+			// deleted since, which its own mirror repeats
+			err = nil
+		}
+	case "Delete":
+		err = c.mirrorDelete(m.key)
+	}
+
+	c.log("Mirror", err).
+		Str("op", m.op).
+		Str("key", m.key).
+		Str("bucket", c.dualWrite.bucket).
+		Msg("Mirror")
+
+	return err
+}
+
+// mirrorDelete deletes the object from the secondary bucket.
+func (c *client) mirrorDelete(k string) error {
+	_, err := c.DeleteObject(c.Context, &s3.DeleteObjectInput{
+		Bucket: &c.dualWrite.bucket,
+		Key:    &k,
+	})
+	return err
+}
+
+// mirrorParts copies an object too large for a single copy to the secondary
+// bucket with a multipart upload of ranged part copies.
+func (c *client) mirrorParts(k string, head *s3.HeadObjectOutput) error {
+	uploadID, parts, err := c.copyParts(k, k, head, func(in *s3.CreateMultipartUploadInput) {
+		in.Bucket = &c.dualWrite.bucket
+	})
+	if err != nil {
+		return err
+	}
+	_, err = c.CompleteMultipartUpload(c.Context, &s3.CompleteMultipartUploadInput{
+		Bucket:          &c.dualWrite.bucket,
+		Key:             &k,
+		UploadId:        uploadID,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		_, _ = c.AbortMultipartUpload(c.Context, &s3.AbortMultipartUploadInput{
+			Bucket:   &c.dualWrite.bucket,
+			Key:      &k,
+			UploadId: uploadID,
+		})
+	}
+	return err
+}
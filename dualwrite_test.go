@@ -0,0 +1,221 @@
+package s3
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// dualWriteServer serves the primary bucket and a "bytelyon-db-mirror"
+// secondary whose copies read from the primary.
+func dualWriteServer(t *testing.T, fail bool, mw ...func(http.Handler) http.Handler) (*client, *testBucket, *testBucket) {
+	_, pb := newTestBucket(t)
+	_, sb := newTestBucket(t)
+	var h http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		k, ok := strings.CutPrefix(r.URL.Path, "/bytelyon-db-mirror/")
+		if !ok {
+			pb.ServeHTTP(w, r)
+			return
+		}
+		if fail {
+			writeError(w, http.StatusForbidden, "AccessDenied")
+			return
+		}
+		if r.Method == http.MethodPut && r.URL.Query().Has("partNumber") {
+			pb.mu.Lock()
+			o := pb.source(r.Header.Get("X-Amz-Copy-Source"))
+			pb.mu.Unlock()
+			var from, to int
+			_, _ = fmt.Sscanf(r.Header.Get("X-Amz-Copy-Source-Range"), "bytes=%d-%d", &from, &to)
+			n, _ := strconv.Atoi(r.URL.Query().Get("partNumber"))
+			sb.mu.Lock()
+			sb.uploads[r.URL.Query().Get("uploadId")][n] = o.body[from : to+1]
+			sb.mu.Unlock()
+			writeXML(w, struct {
+				XMLName xml.Name `xml:"CopyPartResult"`
+				ETag    string
+			}{ETag: `"part"`})
+			return
+		}
+		if r.Method == http.MethodPut {
+			pb.mu.Lock()
+			o := pb.source(r.Header.Get("X-Amz-Copy-Source"))
+			pb.mu.Unlock()
+			if o == nil {
+				// the source was deleted before an async mirror copied it
+				writeError(w, http.StatusNotFound, "NoSuchKey")
+				return
+			}
+			sb.mu.Lock()
+			sb.put(k, o.body, o.header)
+			sb.mu.Unlock()
+			writeXML(w, struct {
+				XMLName xml.Name `xml:"CopyObjectResult"`
+				ETag    string
+			}{ETag: o.etag})
+			return
+		}
+		r.URL.Path = "/bytelyon-db/" + k
+		sb.ServeHTTP(w, r)
+	})
+	for _, m := range mw {
+		h = m(h)
+	}
+	return testServer(t, h.ServeHTTP), pb, sb
+}
+
+func TestWithDualWrite(t *testing.T) {
+
+	c, _, sb := dualWriteServer(t, false)
+	WithDualWrite("bytelyon-db-mirror", 0)(c.options)
+
+	assert.NoError(t, c.Put("a.json", "{}"))
+	assert.NoError(t, c.Copy("a.json", "b.json"))
+	assert.Equal(t, []string{"a.json", "b.json"}, sb.keys())
+
+	assert.NoError(t, c.Delete("a.json"))
+	assert.Equal(t, []string{"b.json"}, sb.keys())
+}
+
+func TestWithDualWrite_DeleteVersion(t *testing.T) {
+
+	var pb *testBucket
+	c, pb, sb := dualWriteServer(t, false, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// deleting b.json's version leaves an earlier one current
+			if r.Method == http.MethodDelete && r.URL.Path == "/bytelyon-db/b.json" {
+				pb.mu.Lock()
+				pb.put("b.json", []byte(`{"v":1}`), nil)
+				pb.mu.Unlock()
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	WithDualWrite("bytelyon-db-mirror", 0)(c.options)
+	var deleted []string
+	WithHooks(Hooks{AfterDelete: func(e HookEvent) error {
+		deleted = append(deleted, e.Key)
+		return nil
+	}})(c.options)
+
+	assert.NoError(t, c.Put("a.json", "{}"))
+	assert.NoError(t, c.Put("b.json", `{"v":2}`))
+
+	assert.NoError(t, c.DeleteVersion("a.json", "v1"))
+	assert.NoError(t, c.DeleteVersion("b.json", "v2"))
+	assert.Equal(t, []string{"b.json"}, sb.keys())
+	assert.Equal(t, `{"v":1}`, string(sb.object("b.json").body))
+	assert.Empty(t, deleted)
+}
+
+func TestWithDualWrite_Async(t *testing.T) {
+
+	c, _, sb := dualWriteServer(t, false)
+	WithDualWrite("bytelyon-db-mirror", 2)(c.options)
+	go c.mirrorLoop()
+
+	for _, k := range []string{"a.json", "b.json", "c.json"} {
+		assert.NoError(t, c.Put(k, "{}"))
+	}
+	assert.NoError(t, c.Delete("b.json"))
+	assert.NoError(t, c.FlushMirrors())
+
+	assert.Equal(t, []string{"a.json", "c.json"}, sb.keys())
+}
+
+func TestWithDualWrite_CloseMirrors(t *testing.T) {
+
+	c, pb, sb := dualWriteServer(t, false)
+	WithDualWrite("bytelyon-db-mirror", 2)(c.options)
+	go c.mirrorLoop()
+
+	// flushes may run while mutations queue more mirrors
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, c.Put(fmt.Sprintf("%d.json", i), "{}"))
+		}()
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, c.FlushMirrors())
+		}()
+	}
+	wg.Wait()
+
+	assert.NoError(t, c.CloseMirrors())
+	assert.Len(t, sb.keys(), 8)
+	select {
+	case <-c.dualWrite.stopped:
+	default:
+		t.Fatal("mirrorLoop still running")
+	}
+
+	assert.ErrorIs(t, c.Put("late.json", "{}"), ErrMirrorsClosed)
+	assert.Contains(t, pb.keys(), "late.json")
+	assert.NoError(t, c.CloseMirrors())
+}
+
+func TestWithDualWrite_Canceled(t *testing.T) {
+
+	release := make(chan struct{})
+	c, _, sb := dualWriteServer(t, false, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasPrefix(r.URL.Path, "/bytelyon-db-mirror/") {
+				<-release
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	c.Context = ctx
+	WithDualWrite("bytelyon-db-mirror", 2)(c.options)
+	go c.mirrorLoop()
+
+	// mirrors queued when the context is done are still made
+	assert.NoError(t, c.Put("a.json", "{}"))
+	assert.NoError(t, c.Put("b.json", "{}"))
+	cancel()
+	close(release)
+	assert.NoError(t, c.FlushMirrors())
+	assert.Equal(t, []string{"a.json", "b.json"}, sb.keys())
+}
+
+func TestWithDualWrite_Multipart(t *testing.T) {
+
+	c, pb, sb := dualWriteServer(t, false)
+	WithDualWrite("bytelyon-db-mirror", 0)(c.options)
+	defer func(size, part int64) { maxCopySize, copyPartSize = size, part }(maxCopySize, copyPartSize)
+	maxCopySize, copyPartSize = 16, 10
+
+	body := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+	assert.NoError(t, c.Put("large.txt", body))
+	assert.Equal(t, body, sb.object("large.txt").body)
+	assert.Equal(t, []string{"large.txt"}, pb.keys())
+	assert.Empty(t, sb.uploads)
+}
+
+func TestWithDualWrite_Error(t *testing.T) {
+
+	c, pb, _ := dualWriteServer(t, true)
+	WithDualWrite("bytelyon-db-mirror", 0)(c.options)
+	assert.Equal(t, "AccessDenied", errorCode(c.Put("a.json", "{}")))
+	assert.Equal(t, []string{"a.json"}, pb.keys())
+
+	c, _, _ = dualWriteServer(t, true)
+	WithDualWrite("bytelyon-db-mirror", 1)(c.options)
+	go c.mirrorLoop()
+	assert.NoError(t, c.Put("a.json", "{}"))
+	assert.Equal(t, "AccessDenied", errorCode(c.FlushMirrors()))
+	assert.NoError(t, c.FlushMirrors())
+}
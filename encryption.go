@@ -0,0 +1,93 @@
+package s3
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// SSEAlgorithm is the server-side encryption applied to new objects.
+type SSEAlgorithm string
+
+const (
+	SSES3      SSEAlgorithm = "AES256"
+	SSEKMS     SSEAlgorithm = "aws:kms"
+	SSEKMSDSSE SSEAlgorithm = "aws:kms:dsse"
+)
+
+// SSEConfig is the default encryption of a bucket. An empty Algorithm is
+// SSEKMS when KMSKeyID is set and SSES3 otherwise. KMSKeyID is the ID, ARN
+// or alias of the key, or empty for the AWS managed aws/s3 key. BucketKey
+// enables S3 Bucket Keys to reduce the KMS requests made for SSE-KMS.
+type SSEConfig struct {
+	Algorithm SSEAlgorithm
+	KMSKeyID  string
+	BucketKey bool
+}
+
+// SetBucketEncryption replaces the bucket's default encryption, applied to
+// objects written without encryption headers of their own.
+func (c *client) SetBucketEncryption(cfg SSEConfig) error {
+
+	_, err := c.PutBucketEncryption(c.Context, &s3.PutBucketEncryptionInput{
+		Bucket:                            c.Bucket,
+		ServerSideEncryptionConfiguration: encryptionConfiguration(cfg),
+	})
+
+	c.log("SetBucketEncryption", err).
+		Str("algorithm", string(cfg.Algorithm)).
+		Str("kmsKeyId", cfg.KMSKeyID).
+		Bool("bucketKey", cfg.BucketKey).
+		Msg("SetBucketEncryption")
+
+	return err
+}
+
+func encryptionConfiguration(cfg SSEConfig) *types.ServerSideEncryptionConfiguration {
+	alg := cfg.Algorithm
+	if alg == "" {
+		alg = SSES3
+		if cfg.KMSKeyID != "" {
+			alg = SSEKMS
+		}
+	}
+	def := &types.ServerSideEncryptionByDefault{SSEAlgorithm: types.ServerSideEncryption(alg)}
+	if cfg.KMSKeyID != "" {
+		def.KMSMasterKeyID = aws.String(cfg.KMSKeyID)
+	}
+	return &types.ServerSideEncryptionConfiguration{
+		Rules: []types.ServerSideEncryptionRule{{
+			ApplyServerSideEncryptionByDefault: def,
+			BucketKeyEnabled:                   aws.Bool(cfg.BucketKey),
+		}},
+	}
+}
+
+// GetBucketEncryption returns the bucket's default encryption.
+func (c *client) GetBucketEncryption() (SSEConfig, error) {
+
+	out, err := c.Client.GetBucketEncryption(c.Context, &s3.GetBucketEncryptionInput{
+		Bucket: c.Bucket,
+	})
+
+	var cfg SSEConfig
+	if err == nil && out.ServerSideEncryptionConfiguration != nil {
+		for _, r := range out.ServerSideEncryptionConfiguration.Rules {
+			if def := r.ApplyServerSideEncryptionByDefault; def != nil {
+				cfg = SSEConfig{
+					Algorithm: SSEAlgorithm(def.SSEAlgorithm),
+					KMSKeyID:  aws.ToString(def.KMSMasterKeyID),
+					BucketKey: aws.ToBool(r.BucketKeyEnabled),
+				}
+				break
+			}
+		}
+	}
+
+	c.log("GetBucketEncryption", err).
+		Str("algorithm", string(cfg.Algorithm)).
+		Str("kmsKeyId", cfg.KMSKeyID).
+		Msg("GetBucketEncryption")
+
+	return cfg, err
+}
@@ -0,0 +1,50 @@
+package s3
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryptionConfiguration(t *testing.T) {
+
+	def := encryptionConfiguration(SSEConfig{}).Rules[0].ApplyServerSideEncryptionByDefault
+	assert.Equal(t, types.ServerSideEncryptionAes256, def.SSEAlgorithm)
+	assert.Nil(t, def.KMSMasterKeyID)
+
+	rule := encryptionConfiguration(SSEConfig{KMSKeyID: "alias/app", BucketKey: true}).Rules[0]
+	assert.Equal(t, types.ServerSideEncryptionAwsKms, rule.ApplyServerSideEncryptionByDefault.SSEAlgorithm)
+	assert.Equal(t, "alias/app", aws.ToString(rule.ApplyServerSideEncryptionByDefault.KMSMasterKeyID))
+	assert.True(t, aws.ToBool(rule.BucketKeyEnabled))
+
+	def = encryptionConfiguration(SSEConfig{Algorithm: SSEKMSDSSE}).Rules[0].ApplyServerSideEncryptionByDefault
+	assert.Equal(t, types.ServerSideEncryptionAwsKmsDsse, def.SSEAlgorithm)
+}
+
+func TestClient_BucketEncryption(t *testing.T) {
+
+	var stored []byte
+	c := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if !r.URL.Query().Has("encryption") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			stored, _ = io.ReadAll(r.Body)
+		case http.MethodGet:
+			w.Write(stored)
+		}
+	})
+
+	kms := "arn:aws:kms:us-east-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+	assert.NoError(t, c.SetBucketEncryption(SSEConfig{KMSKeyID: kms, BucketKey: true}))
+
+	cfg, err := c.GetBucketEncryption()
+	assert.NoError(t, err)
+	assert.Equal(t, SSEConfig{Algorithm: SSEKMS, KMSKeyID: kms, BucketKey: true}, cfg)
+}
@@ -0,0 +1,50 @@
+package s3
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+)
+
+// errorCode returns the S3 error code carried by err, if any.
+func errorCode(err error) string {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode()
+	}
+	return ""
+}
+
+// IsNotFound reports whether err is returned for a key that doesn't exist.
+func IsNotFound(err error) bool {
+	switch errorCode(err) {
+	case "NoSuchKey", "NotFound":
+		return true
+	}
+	return false
+}
+
+// isPreconditionFailed reports whether err is a failed conditional request.
+func isPreconditionFailed(err error) bool {
+	switch errorCode(err) {
+	case "PreconditionFailed", "ConditionalRequestConflict":
+		return true
+	}
+	return false
+}
+
+// isNotModified reports whether err is the 304 response to a conditional GET.
+func isNotModified(err error) bool {
+	var respErr *awshttp.ResponseError
+	return errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusNotModified
+}
+
+// isTransient reports whether err is one the SDK would retry, e.g. throttling,
+// a 5xx response or a dropped connection.
+func isTransient(err error) bool {
+	return retry.IsErrorRetryables(retry.DefaultRetryables).IsErrorRetryable(err) == aws.TrueTernary
+}
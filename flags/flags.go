@@ -0,0 +1,292 @@
+// Package flags looks up feature flags stored as JSON documents in a bucket,
+// refreshing them as they change.
+//
+// Flags are the top level fields of a document mapping flag names to JSON
+// values, e.g.
+//
+//	{"new-checkout": true, "max-cart-items": 50, "theme": "dark"}
+//
+// A tenant's flags override the global ones from a document with the same
+// key under the tenant's prefix, e.g. acme/config/flags.json overrides
+// config/flags.json for the tenant acme. A single goroutine checks every
+// document for changes each interval, reading it again only when its ETag
+// changed; a malformed or deleted revision keeps the previous flags, and
+// missing documents are looked for less often the longer they stay missing.
+// Lookups never fail or wait on the bucket, except for a tenant's first: a
+// flag that is missing, or whose value has another type, takes the default
+// given.
+package flags
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nelsw/s3"
+)
+
+// maxMissingBackoff bounds how many intervals apart a missing document is
+// looked for.
+const maxMissingBackoff = 32
+
+// tenantIdle is how long a tenant's flags are kept without being looked up
+// before they stop being refreshed and are dropped, to be loaded again on
+// their next lookup.
+var tenantIdle = 10 * time.Minute
+
+// document is the flags stored in a document, mapped to their JSON values.
+type document = map[string]json.RawMessage
+
+// Store is the feature flags of a service and its tenants.
+type Store struct {
+	svc      s3.Service
+	key      string
+	interval time.Duration
+	global   *source
+	mu       sync.Mutex
+	tenants  map[string]*source
+	once     sync.Once
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// source is a flags document, which may not exist yet.
+type source struct {
+	key    string
+	loaded chan struct{}
+	used   atomic.Int64
+
+	mu     sync.RWMutex
+	flags  document
+	etag   string
+	misses int
+	next   time.Time
+}
+
+func newSource(key string) *source {
+	return &source{key: key, loaded: make(chan struct{})}
+}
+
+// New returns the flags stored under the key, checked for changes every
+// interval, which must be positive. It fails if the document exists but
+// can't be loaded; a missing document has no flags until it is created.
+func New(svc s3.Service, key string, interval time.Duration) (*Store, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("flags: poll interval must be positive, got %s", interval)
+	}
+	s := &Store{svc: svc, key: key, interval: interval, global: newSource(key), tenants: map[string]*source{}, done: make(chan struct{})}
+	if err := s.load(s.global); err != nil {
+		return nil, err
+	}
+	close(s.global.loaded)
+	s.wg.Add(1)
+	go s.pollLoop()
+	return s, nil
+}
+
+func (s *Store) pollLoop() {
+	defer s.wg.Done()
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			s.poll()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// poll refreshes every document due to be checked, dropping the tenants
+// that haven't been looked up for tenantIdle.
+func (s *Store) poll() {
+	idle := time.Now().Add(-tenantIdle).UnixNano()
+	sources := []*source{s.global}
+	s.mu.Lock()
+	for tenant, src := range s.tenants {
+		select {
+		case <-src.loaded:
+		default:
+			// still loading for its first lookup
+			continue
+		}
+		if src.used.Load() < idle {
+			delete(s.tenants, tenant)
+			continue
+		}
+		sources = append(sources, src)
+	}
+	s.mu.Unlock()
+
+	for _, src := range sources {
+		src.mu.RLock()
+		due := !time.Now().Before(src.next)
+		src.mu.RUnlock()
+		if due {
+			// failures keep the previous flags and are retried by the next poll
+			_ = s.load(src)
+		}
+	}
+}
+
+// load reads the source's document if its ETag changed. A missing document
+// isn't an error, but is looked for again after a backoff.
+func (s *Store) load(src *source) error {
+	info, err := s.svc.Stat(src.key)
+	var body []byte
+	if err == nil {
+		src.mu.RLock()
+		same := info.ETag == src.etag
+		src.mu.RUnlock()
+		if same {
+			return nil
+		}
+		body, err = s.svc.Get(src.key)
+	}
+	if s3.IsNotFound(err) {
+		src.mu.Lock()
+		src.misses++
+		src.next = time.Now().Add(s.interval * time.Duration(min(1<<(src.misses-1), maxMissingBackoff)))
+		src.mu.Unlock()
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var flags document
+	err = json.Unmarshal(body, &flags)
+
+	src.mu.Lock()
+	defer src.mu.Unlock()
+	// malformed revisions aren't read again until they change
+	src.etag, src.misses, src.next = info.ETag, 0, time.Time{}
+	if err != nil {
+		return fmt.Errorf("flags: %s rejected: %w", src.key, err)
+	}
+	src.flags = flags
+	return nil
+}
+
+// lookup returns the flag's value in the source's document, if set.
+func (src *source) lookup(name string) (json.RawMessage, bool) {
+	src.used.Store(time.Now().UnixNano())
+	src.mu.RLock()
+	defer src.mu.RUnlock()
+	v, ok := src.flags[name]
+	return v, ok
+}
+
+// tenant returns the source of the tenant's flags, loading it if it isn't
+// kept. Concurrent lookups wait for the same load.
+func (s *Store) tenant(tenant string) *source {
+	s.mu.Lock()
+	src, ok := s.tenants[tenant]
+	if !ok {
+		src = newSource(tenant + "/" + s.key)
+		src.used.Store(time.Now().UnixNano())
+		s.tenants[tenant] = src
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		select {
+		case <-s.done:
+		default:
+			// failures leave the tenant with the global flags until the next poll
+			_ = s.load(src)
+		}
+		close(src.loaded)
+	}
+	<-src.loaded
+	return src
+}
+
+// Tenant returns the flags of the tenant, which override the global flags
+// with those stored under the tenant's prefix.
+func (s *Store) Tenant(tenant string) *Tenant {
+	s.tenant(tenant)
+	return &Tenant{s, tenant}
+}
+
+// Bool returns the global flag, or def when it isn't a boolean.
+func (s *Store) Bool(name string, def bool) bool {
+	return value(s.raw(nil, name), def)
+}
+
+// Int returns the global flag, or def when it isn't an integer.
+func (s *Store) Int(name string, def int) int {
+	return value(s.raw(nil, name), def)
+}
+
+// Float64 returns the global flag, or def when it isn't a number.
+func (s *Store) Float64(name string, def float64) float64 {
+	return value(s.raw(nil, name), def)
+}
+
+// String returns the global flag, or def when it isn't a string.
+func (s *Store) String(name, def string) string {
+	return value(s.raw(nil, name), def)
+}
+
+// raw returns the flag's value for the tenant, or the global value when the
+// tenant is nil or doesn't override it.
+func (s *Store) raw(tenant *source, name string) json.RawMessage {
+	if tenant != nil {
+		if v, ok := tenant.lookup(name); ok {
+			return v
+		}
+	}
+	v, _ := s.global.lookup(name)
+	return v
+}
+
+// value decodes the flag's value, returning def when it is missing, null or
+// of another type.
+func value[T any](raw json.RawMessage, def T) T {
+	if raw == nil || string(raw) == "null" {
+		return def
+	}
+	var v T
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return def
+	}
+	return v
+}
+
+// Close stops refreshing the flags, which keep the values last loaded.
+func (s *Store) Close() {
+	s.once.Do(func() {
+		close(s.done)
+		s.wg.Wait()
+	})
+}
+
+// Tenant is the feature flags of a tenant.
+type Tenant struct {
+	s    *Store
+	name string
+}
+
+// Bool returns the tenant's flag, or def when it isn't a boolean.
+func (t *Tenant) Bool(name string, def bool) bool {
+	return value(t.s.raw(t.s.tenant(t.name), name), def)
+}
+
+// Int returns the tenant's flag, or def when it isn't an integer.
+func (t *Tenant) Int(name string, def int) int {
+	return value(t.s.raw(t.s.tenant(t.name), name), def)
+}
+
+// Float64 returns the tenant's flag, or def when it isn't a number.
+func (t *Tenant) Float64(name string, def float64) float64 {
+	return value(t.s.raw(t.s.tenant(t.name), name), def)
+}
+
+// String returns the tenant's flag, or def when it isn't a string.
+func (t *Tenant) String(name, def string) string {
+	return value(t.s.raw(t.s.tenant(t.name), name), def)
+}
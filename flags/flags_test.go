@@ -0,0 +1,160 @@
+package flags
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/smithy-go"
+	"github.com/nelsw/s3"
+	"github.com/stretchr/testify/assert"
+)
+
+// testService keeps documents in memory, with ETags as S3 computes them for
+// single part uploads.
+type testService struct {
+	s3.Service
+	mu      sync.Mutex
+	objects map[string][]byte
+	stats   map[string]int
+}
+
+func newTestService() *testService {
+	return &testService{objects: map[string][]byte{}, stats: map[string]int{}}
+}
+
+func (s *testService) put(k, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[k] = []byte(body)
+}
+
+func (s *testService) Stat(k string) (s3.ObjectInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats[k]++
+	b, ok := s.objects[k]
+	if !ok {
+		return s3.ObjectInfo{}, &smithy.GenericAPIError{Code: "NotFound"}
+	}
+	sum := md5.Sum(b)
+	return s3.ObjectInfo{Key: k, Size: int64(len(b)), ETag: `"` + hex.EncodeToString(sum[:]) + `"`}, nil
+}
+
+func (s *testService) Get(k string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.objects[k]
+	if !ok {
+		return nil, &smithy.GenericAPIError{Code: "NoSuchKey"}
+	}
+	return b, nil
+}
+
+func TestStore(t *testing.T) {
+
+	svc := newTestService()
+	svc.put("config/flags.json", `{"new-checkout":true,"max-cart-items":50,"ratio":0.25,"theme":"dark","beta":null}`)
+	svc.put("acme/config/flags.json", `{"new-checkout":false,"theme":"light"}`)
+
+	s, err := New(svc, "config/flags.json", 10*time.Millisecond)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer s.Close()
+
+	assert.True(t, s.Bool("new-checkout", false))
+	assert.Equal(t, 50, s.Int("max-cart-items", 10))
+	assert.Equal(t, 0.25, s.Float64("ratio", 1))
+	assert.Equal(t, "dark", s.String("theme", ""))
+
+	// missing, null and mistyped flags take the default
+	assert.True(t, s.Bool("missing", true))
+	assert.True(t, s.Bool("beta", true))
+	assert.Equal(t, 10, s.Int("theme", 10))
+
+	acme := s.Tenant("acme")
+	assert.False(t, acme.Bool("new-checkout", true))
+	assert.Equal(t, "light", acme.String("theme", ""))
+	assert.Equal(t, 50, acme.Int("max-cart-items", 10))
+
+	// tenants without overrides see the global flags until theirs are created
+	globex := s.Tenant("globex")
+	assert.Equal(t, "dark", globex.String("theme", ""))
+	svc.put("globex/config/flags.json", `{"theme":"blue"}`)
+	assert.Eventually(t, func() bool { return globex.String("theme", "") == "blue" }, time.Second, 5*time.Millisecond)
+
+	svc.put("config/flags.json", `{"new-checkout":false}`)
+	assert.Eventually(t, func() bool { return !s.Bool("new-checkout", true) }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, 10, s.Int("max-cart-items", 10))
+
+	// a malformed revision keeps the previous flags
+	svc.put("config/flags.json", `{"new-checkout":`)
+	time.Sleep(30 * time.Millisecond)
+	assert.False(t, s.Bool("new-checkout", true))
+}
+
+func TestNew_missing(t *testing.T) {
+
+	svc := newTestService()
+	s, err := New(svc, "config/flags.json", 10*time.Millisecond)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer s.Close()
+	assert.False(t, s.Bool("new-checkout", false))
+
+	svc.put("config/flags.json", `{"new-checkout":true}`)
+	assert.Eventually(t, func() bool { return s.Bool("new-checkout", false) }, time.Second, 5*time.Millisecond)
+}
+
+func (s *testService) statted(k string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats[k]
+}
+
+func TestStore_missing(t *testing.T) {
+
+	svc := newTestService()
+	s, err := New(svc, "config/flags.json", time.Millisecond)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer s.Close()
+
+	// missing documents are looked for less often the longer they're missing
+	time.Sleep(200 * time.Millisecond)
+	assert.Less(t, svc.statted("config/flags.json"), 20)
+
+	_, err = New(svc, "config/flags.json", 0)
+	assert.Error(t, err)
+}
+
+func TestStore_Tenant_idle(t *testing.T) {
+
+	defer func(d time.Duration) { tenantIdle = d }(tenantIdle)
+	tenantIdle = 20 * time.Millisecond
+
+	svc := newTestService()
+	svc.put("acme/config/flags.json", `{"theme":"light"}`)
+	s, err := New(svc, "config/flags.json", 5*time.Millisecond)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer s.Close()
+
+	acme := s.Tenant("acme")
+	assert.Equal(t, "light", acme.String("theme", ""))
+
+	// idle tenants are dropped, and loaded again when looked up
+	assert.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return len(s.tenants) == 0
+	}, time.Second, 5*time.Millisecond)
+	svc.put("acme/config/flags.json", `{"theme":"dark"}`)
+	assert.Equal(t, "dark", acme.String("theme", ""))
+}
@@ -0,0 +1,142 @@
+// Package gateway exposes a bucket as a thin REST API so internal tools
+// can read and write documents without embedding AWS credentials.
+//
+// Routes:
+//
+//	GET    /objects?prefix=&after=&size=  list keys as a JSON array
+//	GET    /objects/{key...}              read an object with its Content-Type
+//	PUT    /objects/{key...}              write the request body to an object,
+//	                                      with its Content-Type when sent
+//	DELETE /objects/{key...}              delete an object
+package gateway
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/nelsw/s3"
+)
+
+// Middleware wraps the gateway handler, e.g. to authenticate requests.
+type Middleware func(http.Handler) http.Handler
+
+// maxBody bounds the size of documents written through the gateway.
+const maxBody = 32 << 20
+
+type gateway struct {
+	s3.Service
+}
+
+// New returns an http.Handler serving the bucket behind svc, wrapped by
+// the middleware in order, so the first middleware sees requests first.
+func New(svc s3.Service, mw ...Middleware) http.Handler {
+	g := &gateway{svc}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /objects", g.list)
+	mux.HandleFunc("GET /objects/{key...}", g.get)
+	mux.HandleFunc("PUT /objects/{key...}", g.put)
+	mux.HandleFunc("DELETE /objects/{key...}", g.delete)
+
+	var h http.Handler = mux
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+	return h
+}
+
+// BearerToken rejects requests without an "Authorization: Bearer <token>" header.
+func BearerToken(token string) Middleware {
+	want := []byte("Bearer " + token)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (g *gateway) list(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	size := int64(1000)
+	if s := q.Get("size"); s != "" {
+		var err error
+		if size, err = strconv.ParseInt(s, 10, 32); err != nil || size < 1 || size > 1000 {
+			http.Error(w, "size must be between 1 and 1000", http.StatusBadRequest)
+			return
+		}
+	}
+	keys, err := g.WithContext(r.Context()).Keys(q.Get("prefix"), q.Get("after"), int32(size))
+	if err != nil {
+		g.fail(w, err)
+		return
+	}
+	if keys == nil {
+		keys = []string{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(keys)
+}
+
+func (g *gateway) get(w http.ResponseWriter, r *http.Request) {
+	body, info, err := g.WithContext(r.Context()).OpenObject(r.PathValue("key"))
+	if err != nil {
+		g.fail(w, err)
+		return
+	}
+	defer body.Close()
+	if info.ContentType != "" {
+		w.Header().Set("Content-Type", info.ContentType)
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	if _, err = io.Copy(w, body); err != nil {
+		g.Logger().Trace().Err(err).Str("key", r.PathValue("key")).Msg("gateway")
+	}
+}
+
+func (g *gateway) put(w http.ResponseWriter, r *http.Request) {
+	b, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBody))
+	if err != nil {
+		code := http.StatusBadRequest
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			code = http.StatusRequestEntityTooLarge
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+	svc := g.WithContext(r.Context())
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		err = svc.PutWithContentType(r.PathValue("key"), b, ct)
+	} else {
+		err = svc.Put(r.PathValue("key"), b)
+	}
+	if err != nil {
+		g.fail(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (g *gateway) delete(w http.ResponseWriter, r *http.Request) {
+	if err := g.WithContext(r.Context()).Delete(r.PathValue("key")); err != nil {
+		g.fail(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (g *gateway) fail(w http.ResponseWriter, err error) {
+	code := http.StatusBadGateway
+	if s3.IsNotFound(err) {
+		code = http.StatusNotFound
+	}
+	g.Logger().Trace().Err(err).Int("code", code).Msg("gateway")
+	http.Error(w, http.StatusText(code), code)
+}
@@ -0,0 +1,116 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/nelsw/s3"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+type testService struct {
+	s3.Service
+	objects map[string][]byte
+	types   map[string]string
+}
+
+func (s *testService) WithContext(context.Context) s3.Service {
+	return s
+}
+
+func (s *testService) OpenObject(k string) (io.ReadCloser, s3.ObjectInfo, error) {
+	info := s3.ObjectInfo{Key: k, Size: int64(len(s.objects[k])), ContentType: mime.TypeByExtension(path.Ext(k))}
+	return io.NopCloser(bytes.NewReader(s.objects[k])), info, nil
+}
+
+func (s *testService) Logger() *zerolog.Logger {
+	l := zerolog.Nop()
+	return &l
+}
+
+func (s *testService) Put(k string, a any) error {
+	s.objects[k] = a.([]byte)
+	return nil
+}
+
+func (s *testService) PutWithContentType(k string, b []byte, ct string) error {
+	s.objects[k] = b
+	s.types[k] = ct
+	return nil
+}
+
+func (s *testService) Delete(k string) error {
+	delete(s.objects, k)
+	return nil
+}
+
+func (s *testService) Keys(p, a string, n int32, _ ...s3.KeyFilter) ([]string, error) {
+	var keys []string
+	for k := range s.objects {
+		if strings.HasPrefix(k, p) && k > a {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+func TestGateway(t *testing.T) {
+
+	svc := &testService{objects: map[string][]byte{}, types: map[string]string{}}
+	srv := httptest.NewServer(New(svc, BearerToken("secret")))
+	defer srv.Close()
+
+	var contentType string
+	do := func(method, path, body string, header ...string) (int, string) {
+		req, _ := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		for i := 0; i < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		res, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		b, _ := io.ReadAll(res.Body)
+		contentType = res.Header.Get("Content-Type")
+		return res.StatusCode, string(b)
+	}
+
+	code, _ := do(http.MethodPut, "/objects/users/1.json", `{"id":"1"}`)
+	assert.Equal(t, http.StatusNoContent, code)
+
+	code, body := do(http.MethodGet, "/objects/users/1.json", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, `{"id":"1"}`, body)
+	assert.Equal(t, "application/json", contentType)
+
+	code, body = do(http.MethodGet, "/objects?prefix=users/", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `["users/1.json"]`, body)
+
+	code, _ = do(http.MethodPut, "/objects/users/1.csv", "id\n1", "Content-Type", "text/csv")
+	assert.Equal(t, http.StatusNoContent, code)
+	assert.Equal(t, "text/csv", svc.types["users/1.csv"])
+	assert.NotContains(t, svc.types, "users/1.json")
+
+	code, _ = do(http.MethodPut, "/objects/users/2.json", strings.Repeat("x", maxBody+1))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, code)
+	assert.NotContains(t, svc.objects, "users/2.json")
+
+	code, _ = do(http.MethodDelete, "/objects/users/1.json", "")
+	assert.Equal(t, http.StatusNoContent, code)
+	code, _ = do(http.MethodDelete, "/objects/users/1.csv", "")
+	assert.Equal(t, http.StatusNoContent, code)
+	assert.Empty(t, svc.objects)
+
+	res, err := http.Get(srv.URL + "/objects")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+}
@@ -0,0 +1,73 @@
+package s3
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// GCOption configures GC.
+type GCOption func(*gc)
+
+type gc struct {
+	dryRun bool
+}
+
+// WithDryRun makes GC report the orphans it would delete without deleting them.
+func WithDryRun() GCOption {
+	return func(g *gc) {
+		g.dryRun = true
+	}
+}
+
+// GC deletes the objects under the prefix that are older than olderThan and
+// not referenced, according to isReferenced, and returns the keys deleted, or
+// with WithDryRun those that would be. The age guards objects written before
+// the application indexed them. Objects are deleted only if unchanged since
+// they were listed, so those rewritten meanwhile are kept.
+func (c *client) GC(p string, isReferenced func(string) bool, olderThan time.Duration, opts ...GCOption) ([]string, error) {
+
+	g := &gc{}
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	var orphans []types.Object
+	err := c.walk(p, func(obj types.Object) error {
+		if aws.ToTime(obj.LastModified).Before(cutoff) && !isReferenced(*obj.Key) {
+			orphans = append(orphans, obj)
+		}
+		return nil
+	})
+
+	var keys []string
+	for i := 0; err == nil && i < len(orphans); i++ {
+		if !g.dryRun {
+			_, err = c.deleteObject(&s3.DeleteObjectInput{
+				Bucket:  c.Bucket,
+				Key:     orphans[i].Key,
+				IfMatch: orphans[i].ETag,
+			})
+			if IsNotFound(err) || isPreconditionFailed(err) {
+				// replaced or deleted since it was listed
+				err = nil
+				continue
+			}
+		}
+		if err == nil {
+			keys = append(keys, *orphans[i].Key)
+		}
+	}
+
+	c.log("GC", err).
+		Str("prefix", p).
+		Bool("dryRun", g.dryRun).
+		Int("orphans", len(orphans)).
+		Strs("deleted", keys).
+		Msg("GC")
+
+	return keys, err
+}
@@ -0,0 +1,38 @@
+package s3
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_GC(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	for _, k := range []string{"attachments/a", "attachments/b", "attachments/c", "attachments/new"} {
+		assert.NoError(t, c.Put(k, "x"))
+	}
+	for _, k := range []string{"attachments/a", "attachments/b", "attachments/c"} {
+		b.object(k).modified = time.Now().Add(-48 * time.Hour)
+	}
+	referenced := func(k string) bool { return k == "attachments/a" }
+
+	orphans, err := c.GC("attachments/", referenced, 24*time.Hour, WithDryRun())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"attachments/b", "attachments/c"}, orphans)
+	assert.Len(t, b.keys(), 4)
+
+	// attachments/c is rewritten after it's listed, so it's kept
+	orphans, err = c.GC("attachments/", func(k string) bool {
+		if k == "attachments/c" {
+			b.mu.Lock()
+			b.put(k, []byte("y"), nil)
+			b.mu.Unlock()
+		}
+		return referenced(k)
+	}, 24*time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"attachments/b"}, orphans)
+	assert.Equal(t, []string{"attachments/a", "attachments/c", "attachments/new"}, b.keys())
+}
@@ -0,0 +1,142 @@
+package s3
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+)
+
+// Revision is a version of an object in a versioned bucket.
+type Revision struct {
+	VersionID    string
+	ETag         string
+	Size         int64
+	LastModified time.Time
+	Latest       bool
+	Deleted      bool
+}
+
+// errRevisionsStopped stops listing revisions once enough have been seen.
+var errRevisionsStopped = errors.New("s3: revisions stopped")
+
+// revisions calls fn with the versions and delete markers of the object,
+// newest first, until fn returns false.
+func (c *client) revisions(k string, fn func(Revision) bool) error {
+	paginator := s3.NewListObjectVersionsPaginator(c.Client, &s3.ListObjectVersionsInput{
+		Bucket: c.Bucket,
+		Prefix: &k,
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(c.Context)
+		if err != nil {
+			return err
+		}
+
+		var revs []Revision
+		for _, v := range page.Versions {
+			if aws.ToString(v.Key) == k {
+				revs = append(revs, Revision{
+					VersionID:    aws.ToString(v.VersionId),
+					ETag:         aws.ToString(v.ETag),
+					Size:         aws.ToInt64(v.Size),
+					LastModified: aws.ToTime(v.LastModified),
+					Latest:       aws.ToBool(v.IsLatest),
+				})
+			}
+		}
+		for _, m := range page.DeleteMarkers {
+			if aws.ToString(m.Key) == k {
+				revs = append(revs, Revision{
+					VersionID:    aws.ToString(m.VersionId),
+					LastModified: aws.ToTime(m.LastModified),
+					Latest:       aws.ToBool(m.IsLatest),
+					Deleted:      true,
+				})
+			}
+		}
+		sort.SliceStable(revs, func(i, j int) bool { return revs[i].LastModified.After(revs[j].LastModified) })
+		for _, r := range revs {
+			if !fn(r) {
+				return errRevisionsStopped
+			}
+		}
+
+		// versions are listed by key, so later pages only hold longer keys
+		if aws.ToString(page.NextKeyMarker) != k {
+			return nil
+		}
+	}
+	return nil
+}
+
+// History returns up to limit revisions of the object, newest first,
+// including the delete markers left by deletes, or all of them when limit
+// is 0. The bucket must be versioned for objects to have more than one.
+func (c *client) History(k string, limit int) ([]Revision, error) {
+
+	var revs []Revision
+	err := c.revisions(k, func(r Revision) bool {
+		revs = append(revs, r)
+		return limit <= 0 || len(revs) < limit
+	})
+	if err == errRevisionsStopped {
+		err = nil
+	}
+
+	c.log("History", err).
+		Str("key", k).
+		Int("revisions", len(revs)).
+		Msg("History")
+
+	return revs, err
+}
+
+// At decodes the JSON document at the key as it was at the time into out,
+// reading the version that was current then. It returns a NotFound error if
+// the document didn't exist or was deleted at the time.
+func (c *client) At(k string, t time.Time, out any) error {
+
+	var rev *Revision
+	err := c.revisions(k, func(r Revision) bool {
+		if r.LastModified.After(t) {
+			return true
+		}
+		rev = &r
+		return false
+	})
+	if err == errRevisionsStopped {
+		err = nil
+	}
+	if err == nil && (rev == nil || rev.Deleted) {
+		err = &smithy.GenericAPIError{Code: "NoSuchKey", Message: k + " did not exist at " + t.Format(time.RFC3339)}
+	}
+
+	var body []byte
+	if err == nil {
+		var obj *s3.GetObjectOutput
+		obj, err = c.GetObject(c.Context, &s3.GetObjectInput{
+			Bucket:    c.Bucket,
+			Key:       &k,
+			VersionId: &rev.VersionID,
+		})
+		if err == nil {
+			defer obj.Body.Close()
+			if body, err = readAll(obj.Body, aws.ToInt64(obj.ContentLength)); err == nil {
+				err = json.Unmarshal(body, out)
+			}
+		}
+	}
+
+	c.log("At", err).
+		Str("key", k).
+		Time("at", t).
+		Bytes("body", body).
+		Msg("At")
+
+	return err
+}
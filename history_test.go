@@ -0,0 +1,59 @@
+package s3
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_History(t *testing.T) {
+
+	c := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		switch {
+		case q.Has("versions"):
+			assert.Equal(t, "doc.json", q.Get("prefix"))
+			w.Header().Set("Content-Type", "application/xml")
+			_, _ = w.Write([]byte(`<ListVersionsResult>
+<Version><Key>doc.json</Key><VersionId>v3</VersionId><IsLatest>false</IsLatest><LastModified>2026-01-03T00:00:00Z</LastModified><ETag>"c"</ETag><Size>9</Size></Version>
+<Version><Key>doc.json</Key><VersionId>v1</VersionId><IsLatest>false</IsLatest><LastModified>2026-01-01T00:00:00Z</LastModified><ETag>"a"</ETag><Size>9</Size></Version>
+<Version><Key>doc.json.bak</Key><VersionId>x</VersionId><IsLatest>true</IsLatest><LastModified>2026-01-05T00:00:00Z</LastModified><ETag>"x"</ETag><Size>1</Size></Version>
+<DeleteMarker><Key>doc.json</Key><VersionId>v4</VersionId><IsLatest>true</IsLatest><LastModified>2026-01-04T00:00:00Z</LastModified></DeleteMarker>
+<DeleteMarker><Key>doc.json</Key><VersionId>v2</VersionId><IsLatest>false</IsLatest><LastModified>2026-01-02T00:00:00Z</LastModified></DeleteMarker>
+</ListVersionsResult>`))
+		case q.Get("versionId") == "v1":
+			_, _ = w.Write([]byte(`{"rev":1}`))
+		case q.Get("versionId") == "v3":
+			_, _ = w.Write([]byte(`{"rev":3}`))
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+		}
+	})
+
+	revs, err := c.History("doc.json", 0)
+	assert.NoError(t, err)
+	var ids []string
+	for _, r := range revs {
+		ids = append(ids, r.VersionID)
+	}
+	assert.Equal(t, []string{"v4", "v3", "v2", "v1"}, ids)
+	assert.True(t, revs[0].Latest)
+	assert.True(t, revs[0].Deleted)
+	assert.Equal(t, `"c"`, revs[1].ETag)
+
+	revs, err = c.History("doc.json", 2)
+	assert.NoError(t, err)
+	assert.Len(t, revs, 2)
+
+	day := func(d int) time.Time { return time.Date(2026, 1, d, 12, 0, 0, 0, time.UTC) }
+	var doc struct{ Rev int }
+	assert.NoError(t, c.At("doc.json", day(1), &doc))
+	assert.Equal(t, 1, doc.Rev)
+	assert.NoError(t, c.At("doc.json", day(3), &doc))
+	assert.Equal(t, 3, doc.Rev)
+	assert.True(t, IsNotFound(c.At("doc.json", day(2), &doc)))
+	assert.True(t, IsNotFound(c.At("doc.json", day(4), &doc)))
+	assert.True(t, IsNotFound(c.At("doc.json", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), &doc)))
+}
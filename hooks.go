@@ -0,0 +1,69 @@
+package s3
+
+// HookEvent describes an operation observed by Hooks. Body is nil for
+// deletes, copies and streamed uploads. It may be a pooled buffer or the
+// cached copy of the object, so hooks must not modify it, and it is only
+// valid until the hook returns: hooks that keep it must copy it, e.g. with
+// slices.Clone.
+type HookEvent struct {
+	Key  string
+	ETag string
+	Body []byte
+}
+
+// Hooks are callbacks invoked synchronously after operations made through
+// the client succeed. An error returned by a hook is returned by the
+// operation, although the operation itself has already taken effect.
+type Hooks struct {
+	AfterPut    func(HookEvent) error
+	AfterGet    func(HookEvent) error
+	AfterDelete func(HookEvent) error
+}
+
+// WithHooks registers lifecycle hooks. Hooks registered by repeated use of
+// the option run in the order they were registered.
+func WithHooks(h Hooks) Option {
+	return func(o *options) {
+		o.hooks = append(o.hooks, h)
+	}
+}
+
+// AddHooks registers hooks after the client is built, as WithHooks does, for
+// packages extending a Service. Register them before sharing the client
+// between goroutines.
+func (c *client) AddHooks(h Hooks) {
+	c.hooks = append(c.hooks, h)
+}
+
+func (o *options) afterPut(e HookEvent) error {
+	for _, h := range o.hooks {
+		if h.AfterPut != nil {
+			if err := h.AfterPut(e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (o *options) afterGet(e HookEvent) error {
+	for _, h := range o.hooks {
+		if h.AfterGet != nil {
+			if err := h.AfterGet(e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (o *options) afterDelete(e HookEvent) error {
+	for _, h := range o.hooks {
+		if h.AfterDelete != nil {
+			if err := h.AfterDelete(e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
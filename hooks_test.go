@@ -0,0 +1,43 @@
+package s3
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Hooks(t *testing.T) {
+
+	c := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"etag"`)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(testBody()))
+		}
+	})
+
+	var events []string
+	WithHooks(Hooks{
+		AfterPut: func(e HookEvent) error {
+			assert.Equal(t, testBody(), string(e.Body))
+			events = append(events, "put "+e.Key+" "+e.ETag)
+			return nil
+		},
+		AfterGet: func(e HookEvent) error {
+			events = append(events, "get "+e.Key)
+			return nil
+		},
+	})(c.options)
+	WithHooks(Hooks{
+		AfterDelete: func(e HookEvent) error {
+			return errors.New("boom")
+		},
+	})(c.options)
+
+	assert.NoError(t, c.Put(testKey(), testBody()))
+	_, err := c.Get(testKey())
+	assert.NoError(t, err)
+	assert.EqualError(t, c.Delete(testKey()), "boom")
+	assert.Equal(t, []string{"put " + testKey() + ` "etag"`, "get " + testKey()}, events)
+}
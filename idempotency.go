@@ -0,0 +1,79 @@
+package s3
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// idempotencyMetadata is the user metadata key holding the idempotency key
+// of the write that stored an object with PutIdempotent.
+const idempotencyMetadata = "idempotency-key"
+
+// PutIdempotent uploads the value, encoded as Put does, recording the
+// idempotency key in its metadata, and returns the object's ETag. When the
+// object was last written with the same idempotency key the write is skipped
+// and the ETag of that write returned, so a retried or duplicated operation
+// is applied once. Only the latest write's key is remembered: one repeated
+// after the object was written again is applied again. The write is
+// conditional on the object read, so of concurrent writes with the same key
+// only one is applied, and the rest are retried, failing with ErrConflict
+// when contention persists.
+func (c *client) PutIdempotent(k string, a any, idempotencyKey string) (string, error) {
+
+	body, ct, release, err := encode(k, a)
+	var etag string
+	var skipped bool
+	if err == nil {
+		defer release()
+		etag, skipped, err = c.putIdempotent(k, body, ct, idempotencyKey)
+	}
+
+	c.log("PutIdempotent", err).
+		Str("key", k).
+		Str("idempotencyKey", idempotencyKey).
+		Bool("skipped", skipped).
+		Str("etag", etag).
+		Msg("PutIdempotent")
+
+	return etag, err
+}
+
+// putIdempotent writes the body unless the object was last written with the
+// idempotency key, reporting whether the write was skipped.
+func (c *client) putIdempotent(k string, body []byte, ct, idempotencyKey string) (string, bool, error) {
+	for range maxUpdateAttempts {
+		in := &s3.PutObjectInput{
+			Bucket:      c.Bucket,
+			Key:         &k,
+			ContentType: &ct,
+			Metadata:    map[string]string{idempotencyMetadata: idempotencyKey},
+		}
+
+		head, err := c.HeadObject(c.Context, &s3.HeadObjectInput{
+			Bucket: c.Bucket,
+			Key:    &k,
+		})
+		switch {
+		case IsNotFound(err):
+			in.IfNoneMatch = aws.String("*")
+		case err != nil:
+			return "", false, err
+		case head.Metadata[idempotencyMetadata] == idempotencyKey:
+			return aws.ToString(head.ETag), true, nil
+		default:
+			in.IfMatch = head.ETag
+		}
+
+		out, err := c.putObject(in, body)
+		if isPreconditionFailed(err) {
+			continue
+		}
+		if err != nil {
+			return "", false, err
+		}
+		return aws.ToString(out.ETag), false, nil
+	}
+	return "", false, fmt.Errorf("%w: %s", ErrConflict, k)
+}
@@ -0,0 +1,61 @@
+package s3
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_PutIdempotent(t *testing.T) {
+
+	c, b := newTestBucket(t)
+
+	etag, err := c.PutIdempotent("order.json", map[string]int{"qty": 1}, "req-1")
+	assert.NoError(t, err)
+	assert.Equal(t, b.object("order.json").etag, etag)
+	assert.Equal(t, "req-1", b.object("order.json").header.Get("X-Amz-Meta-Idempotency-Key"))
+	assert.Equal(t, "application/json", b.object("order.json").header.Get("Content-Type"))
+
+	// a retry of the same write is skipped
+	again, err := c.PutIdempotent("order.json", map[string]int{"qty": 2}, "req-1")
+	assert.NoError(t, err)
+	assert.Equal(t, etag, again)
+	assert.Equal(t, `{"qty":1}`, string(b.object("order.json").body))
+
+	next, err := c.PutIdempotent("order.json", map[string]int{"qty": 2}, "req-2")
+	assert.NoError(t, err)
+	assert.NotEqual(t, etag, next)
+	assert.Equal(t, `{"qty":2}`, string(b.object("order.json").body))
+}
+
+func TestClient_PutIdempotent_Concurrent(t *testing.T) {
+
+	c, _ := newTestBucket(t)
+	var writes int
+	var mu sync.Mutex
+	c.hooks = append(c.hooks, Hooks{AfterPut: func(HookEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+		writes++
+		return nil
+	}})
+
+	var wg sync.WaitGroup
+	etags := make([]string, 8)
+	for i := range etags {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			etags[i], err = c.PutIdempotent("order.json", i, "req-1")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, writes)
+	for _, etag := range etags {
+		assert.Equal(t, etags[0], etag)
+	}
+}
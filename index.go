@@ -0,0 +1,185 @@
+package s3
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// indexPrefix is where Index keeps its entries, as idx/<name>/<value>/<key>,
+// and the values last indexed for each key, under idx/.keys/<name>/<key>.
+// Values and keys are path escaped.
+const indexPrefix = "idx/"
+
+func indexValuePrefix(name, value string) string {
+	return indexPrefix + name + "/" + url.PathEscape(value) + "/"
+}
+
+func indexKeysKey(name, k string) string {
+	return indexPrefix + ".keys/" + name + "/" + url.PathEscape(k)
+}
+
+// Index maintains a secondary index named name over the documents written
+// through the client, mapping each of the values extract returns for a
+// document to its key, so QueryIndex can find documents by value without
+// reading them all. The index is updated after every Put, copy and Delete,
+// reading back copied and streamed objects to extract their values, but not
+// atomically with them, so concurrent writes of the same key may leave
+// stale entries until it is written again. Objects written before the index
+// was registered are indexed by Reindex. Register indexes before sharing the
+// client between goroutines.
+func (c *client) Index(name string, extract func(doc []byte) []string) {
+	if c.indexes == nil {
+		c.indexes = map[string]func([]byte) []string{}
+	}
+	c.indexes[name] = extract
+	c.hooks = append(c.hooks, Hooks{
+		AfterPut: func(e HookEvent) error {
+			if strings.HasPrefix(e.Key, indexPrefix) {
+				return nil
+			}
+			if e.Body == nil {
+				return c.reindexObject(name, e.Key, extract)
+			}
+			return c.reindex(name, e.Key, extract(e.Body))
+		},
+		AfterDelete: func(e HookEvent) error {
+			if strings.HasPrefix(e.Key, indexPrefix) {
+				return nil
+			}
+			return c.reindex(name, e.Key, nil)
+		},
+	})
+}
+
+// Reindex indexes the objects under the prefix with the named index, for
+// objects written before it was registered or by other clients.
+func (c *client) Reindex(name, p string) error {
+	extract, ok := c.indexes[name]
+	var n int
+	var err error
+	if !ok {
+		err = fmt.Errorf("s3: no index named %q", name)
+	} else {
+		err = c.walk(p, func(obj types.Object) error {
+			if strings.HasPrefix(*obj.Key, indexPrefix) {
+				return nil
+			}
+			n++
+			return c.reindexObject(name, *obj.Key, extract)
+		})
+	}
+
+	c.log("Reindex", err).
+		Str("index", name).
+		Str("prefix", p).
+		Int("objects", n).
+		Msg("Reindex")
+
+	return err
+}
+
+// reindexObject reads the object and replaces the values indexed for it.
+// Objects deleted in the meantime are skipped.
+func (c *client) reindexObject(name, k string, extract func([]byte) []string) error {
+	out, err := c.GetObject(c.Context, &s3.GetObjectInput{Bucket: c.Bucket, Key: &k})
+	if IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer out.Body.Close()
+	body, err := readAll(out.Body, aws.ToInt64(out.ContentLength))
+	if err != nil {
+		return err
+	}
+	return c.reindex(name, k, extract(body))
+}
+
+// reindex replaces the values indexed for the key. Index objects are written
+// directly so they don't trigger hooks themselves.
+func (c *client) reindex(name, k string, values []string) error {
+
+	slices.Sort(values)
+	values = slices.Compact(values)
+
+	kk := indexKeysKey(name, k)
+	var old []string
+	out, err := c.GetObject(c.Context, &s3.GetObjectInput{Bucket: c.Bucket, Key: &kk})
+	if err == nil {
+		err = json.NewDecoder(out.Body).Decode(&old)
+		out.Body.Close()
+	} else if IsNotFound(err) {
+		err = nil
+	}
+
+	for i := 0; err == nil && i < len(old); i++ {
+		if !slices.Contains(values, old[i]) {
+			_, err = c.DeleteObject(c.Context, &s3.DeleteObjectInput{
+				Bucket: c.Bucket,
+				Key:    aws.String(indexValuePrefix(name, old[i]) + url.PathEscape(k)),
+			})
+		}
+	}
+	for i := 0; err == nil && i < len(values); i++ {
+		if !slices.Contains(old, values[i]) {
+			_, err = c.PutObject(c.Context, &s3.PutObjectInput{
+				Bucket: c.Bucket,
+				Key:    aws.String(indexValuePrefix(name, values[i]) + url.PathEscape(k)),
+			})
+		}
+	}
+	if err == nil {
+		if len(values) == 0 {
+			_, err = c.DeleteObject(c.Context, &s3.DeleteObjectInput{Bucket: c.Bucket, Key: &kk})
+		} else {
+			var b []byte
+			if b, err = json.Marshal(values); err == nil {
+				_, err = c.PutObject(c.Context, &s3.PutObjectInput{
+					Bucket:      c.Bucket,
+					Key:         &kk,
+					Body:        strings.NewReader(string(b)),
+					ContentType: aws.String("application/json"),
+				})
+			}
+		}
+	}
+
+	c.log("IndexKey", err).
+		Str("index", name).
+		Str("key", k).
+		Strs("values", values).
+		Msg("IndexKey")
+
+	return err
+}
+
+// QueryIndex returns the keys of the documents the named index maps the
+// value to.
+func (c *client) QueryIndex(name, value string) ([]string, error) {
+
+	p := indexValuePrefix(name, value)
+	var keys []string
+	err := c.walk(p, func(obj types.Object) error {
+		k, err := url.PathUnescape(strings.TrimPrefix(*obj.Key, p))
+		if err == nil {
+			keys = append(keys, k)
+		}
+		return err
+	})
+
+	c.log("QueryIndex", err).
+		Str("index", name).
+		Str("value", value).
+		Strs("keys", keys).
+		Msg("QueryIndex")
+
+	return keys, err
+}
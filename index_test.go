@@ -0,0 +1,85 @@
+package s3
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Index(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	type user struct {
+		Email  string   `json:"email"`
+		Groups []string `json:"groups"`
+	}
+	c.Index("email", func(doc []byte) []string {
+		var u user
+		if json.Unmarshal(doc, &u) != nil || u.Email == "" {
+			return nil
+		}
+		return []string{u.Email}
+	})
+	c.Index("group", func(doc []byte) []string {
+		var u user
+		_ = json.Unmarshal(doc, &u)
+		return u.Groups
+	})
+
+	assert.NoError(t, c.Put("users/1.json", user{"a@example.com", []string{"admin", "dev", "dev"}}))
+	assert.NoError(t, c.Put("users/2.json", user{"b@example.com", []string{"dev"}}))
+
+	keys, err := c.QueryIndex("email", "a@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"users/1.json"}, keys)
+	keys, err = c.QueryIndex("group", "dev")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"users/1.json", "users/2.json"}, keys)
+
+	// stale values are removed when documents change
+	assert.NoError(t, c.Put("users/1.json", user{"c@example.com", []string{"admin"}}))
+	keys, err = c.QueryIndex("email", "a@example.com")
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+	keys, err = c.QueryIndex("email", "c@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"users/1.json"}, keys)
+
+	// copies are indexed too
+	assert.NoError(t, c.Copy("users/2.json", "users/3.json"))
+	keys, err = c.QueryIndex("email", "b@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"users/2.json", "users/3.json"}, keys)
+
+	assert.NoError(t, c.Delete("users/1.json"))
+	assert.NoError(t, c.Delete("users/2.json"))
+	assert.NoError(t, c.Delete("users/3.json"))
+	assert.Equal(t, []string(nil), b.keys())
+}
+
+func TestClient_Reindex(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	b.put("docs/a.txt", []byte("red"), nil)
+	b.put("docs/b.txt", []byte("blue"), nil)
+	b.put("other/c.txt", []byte("red"), nil)
+
+	assert.Error(t, c.Reindex("color", "docs/"))
+
+	c.Index("color", func(doc []byte) []string { return []string{string(doc)} })
+	keys, err := c.QueryIndex("color", "red")
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+
+	assert.NoError(t, c.Reindex("color", "docs/"))
+	keys, err = c.QueryIndex("color", "red")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"docs/a.txt"}, keys)
+
+	// reindexing is idempotent
+	assert.NoError(t, c.Reindex("color", ""))
+	keys, err = c.QueryIndex("color", "red")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"docs/a.txt", "other/c.txt"}, keys)
+}
@@ -0,0 +1,122 @@
+package s3
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"maps"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// crc32cMetadata is the user metadata key holding the hex encoded CRC32C
+// (Castagnoli) of an object written by a streamed upload.
+const crc32cMetadata = "crc32c"
+
+// maxCopySize is the largest object a single CopyObject can copy.
+var maxCopySize int64 = 5 << 30
+
+// ErrChecksumMismatch is returned by streamed uploads whose body doesn't
+// match the SHA-256 given in their metadata.
+var ErrChecksumMismatch = errors.New("s3: checksum mismatch")
+
+// digest computes the SHA-256 and CRC32C of a streamed upload as it is written.
+type digest struct {
+	sha256 hash.Hash
+	crc32c hash.Hash32
+}
+
+func newDigest() *digest {
+	return &digest{sha256.New(), crc32.New(crc32.MakeTable(crc32.Castagnoli))}
+}
+
+func (d *digest) Write(p []byte) (int, error) {
+	d.sha256.Write(p)
+	return d.crc32c.Write(p)
+}
+
+// metadata returns a copy of the metadata with the digests added, failing if
+// it already holds a different SHA-256.
+func (d *digest) metadata(k string, m map[string]string) (map[string]string, error) {
+	sum := hex.EncodeToString(d.sha256.Sum(nil))
+	if want := m[sha256Metadata]; want != "" && want != sum {
+		return nil, fmt.Errorf("%w: %s has SHA-256 %s, metadata says %s", ErrChecksumMismatch, k, sum, want)
+	}
+	m = maps.Clone(m)
+	if m == nil {
+		m = map[string]string{}
+	}
+	m[sha256Metadata] = sum
+	m[crc32cMetadata] = hex.EncodeToString(d.crc32c.Sum(nil))
+	return m, nil
+}
+
+// replaceMetadata copies the object completed by a multipart upload over
+// itself with the headers of in, for metadata only known once the upload
+// has completed, keeping the encryption it was completed with, and returns
+// the new ETag. Objects too large for a single copy are copied in parts.
+func (c *client) replaceMetadata(in *s3.PutObjectInput, completed *s3.CompleteMultipartUploadOutput, size int64) (*string, error) {
+	var etag *string
+	var err error
+	if size > maxCopySize {
+		var head *s3.HeadObjectOutput
+		head, err = c.HeadObject(c.Context, &s3.HeadObjectInput{Bucket: c.Bucket, Key: in.Key, IfMatch: completed.ETag})
+		var uploadID *string
+		var parts []types.CompletedPart
+		if err == nil {
+			uploadID, parts, err = c.copyParts(*in.Key, *in.Key, head, func(cin *s3.CreateMultipartUploadInput) {
+				cin.Metadata = in.Metadata
+				cin.ContentType = in.ContentType
+				cin.ContentEncoding = in.ContentEncoding
+				cin.CacheControl = in.CacheControl
+			})
+		}
+		if err == nil {
+			var out *s3.CompleteMultipartUploadOutput
+			out, err = c.CompleteMultipartUpload(c.Context, &s3.CompleteMultipartUploadInput{
+				Bucket:          c.Bucket,
+				Key:             in.Key,
+				UploadId:        uploadID,
+				MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+			})
+			if err != nil {
+				c.abortMultipartUpload(*in.Key, uploadID)
+			} else {
+				etag = out.ETag
+			}
+		}
+	} else {
+		var out *s3.CopyObjectOutput
+		out, err = c.CopyObject(c.Context, &s3.CopyObjectInput{
+			Bucket:               c.Bucket,
+			Key:                  in.Key,
+			CopySource:           aws.String(c.copySource(*in.Key)),
+			CopySourceIfMatch:    completed.ETag,
+			MetadataDirective:    types.MetadataDirectiveReplace,
+			Metadata:             in.Metadata,
+			ContentType:          in.ContentType,
+			ContentEncoding:      in.ContentEncoding,
+			ContentDisposition:   in.ContentDisposition,
+			ContentLanguage:      in.ContentLanguage,
+			CacheControl:         in.CacheControl,
+			StorageClass:         in.StorageClass,
+			ServerSideEncryption: completed.ServerSideEncryption,
+			SSEKMSKeyId:          completed.SSEKMSKeyId,
+			BucketKeyEnabled:     completed.BucketKeyEnabled,
+		})
+		if err == nil {
+			etag = out.CopyObjectResult.ETag
+		}
+	}
+
+	c.log("ReplaceMetadata", err).
+		Str("key", *in.Key).
+		Msg("ReplaceMetadata")
+
+	return etag, err
+}
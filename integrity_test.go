@@ -0,0 +1,51 @@
+package s3
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUploader_Digest(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	sum := func(body []byte) string {
+		s := sha256.Sum256(body)
+		return hex.EncodeToString(s[:])
+	}
+
+	w := c.NewWriter("small.txt")
+	_, err := w.Write([]byte("hello"))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	h := b.object("small.txt").header
+	assert.Equal(t, sum([]byte("hello")), h.Get("X-Amz-Meta-Sha256"))
+	assert.Equal(t, "9a71bb4c", h.Get("X-Amz-Meta-Crc32c"))
+
+	body := bytes.Repeat([]byte{'a'}, partSize+1024)
+	w = c.NewWriter("large.txt")
+	_, err = w.Write(body)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	o := b.object("large.txt")
+	assert.Equal(t, body, o.body)
+	assert.Equal(t, sum(body), o.header.Get("X-Amz-Meta-Sha256"))
+	assert.NotEmpty(t, o.header.Get("X-Amz-Meta-Crc32c"))
+	assert.Equal(t, "text/plain; charset=utf-8", o.header.Get("Content-Type"))
+
+	report, err := c.Scan("")
+	assert.NoError(t, err)
+	assert.Empty(t, report.Issues)
+
+	// bodies must match the SHA-256 they were uploaded with
+	u := c.newUploader(&s3.PutObjectInput{Key: aws.String("wrong"), Metadata: map[string]string{sha256Metadata: sum([]byte("other"))}})
+	_, err = u.Write([]byte("hello"))
+	assert.NoError(t, err)
+	assert.ErrorIs(t, u.Close(), ErrChecksumMismatch)
+	assert.Nil(t, b.object("wrong"))
+}
@@ -0,0 +1,345 @@
+package s3
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/source"
+)
+
+// ErrInventoryFormat is returned by ReadInventory for reports in a file
+// format other than CSV and Parquet. Configure ORC inventories to deliver
+// either.
+var ErrInventoryFormat = errors.New("s3: unsupported inventory format")
+
+// InventoryManifest is the manifest.json written with each S3 Inventory report.
+type InventoryManifest struct {
+	SourceBucket      string          `json:"sourceBucket"`
+	DestinationBucket string          `json:"destinationBucket"`
+	Version           string          `json:"version"`
+	CreationTimestamp string          `json:"creationTimestamp"`
+	FileFormat        string          `json:"fileFormat"`
+	FileSchema        string          `json:"fileSchema"`
+	Files             []InventoryFile `json:"files"`
+}
+
+// InventoryFile is a report file listed in an InventoryManifest.
+type InventoryFile struct {
+	Key         string `json:"key"`
+	Size        int64  `json:"size"`
+	MD5Checksum string `json:"MD5checksum"`
+}
+
+// InventoryRecord is a row of an S3 Inventory report. Fields holds every
+// column of the row by its schema name, including the ones parsed into the
+// typed fields.
+type InventoryRecord struct {
+	Bucket         string
+	Key            string
+	VersionID      string
+	IsLatest       bool
+	IsDeleteMarker bool
+	Size           int64
+	LastModified   time.Time
+	ETag           string
+	StorageClass   string
+	Fields         map[string]string
+}
+
+// set assigns a column value to the typed field it maps to.
+func (r *InventoryRecord) set(name, v string) {
+	r.Fields[name] = v
+	switch name {
+	case "Bucket":
+		r.Bucket = v
+	case "Key":
+		r.Key = v
+	case "VersionId":
+		r.VersionID = v
+	case "IsLatest":
+		r.IsLatest, _ = strconv.ParseBool(v)
+	case "IsDeleteMarker":
+		r.IsDeleteMarker, _ = strconv.ParseBool(v)
+	case "Size":
+		r.Size, _ = strconv.ParseInt(v, 10, 64)
+	case "LastModifiedDate":
+		r.LastModified, _ = time.Parse(time.RFC3339, v)
+	case "ETag":
+		r.ETag = v
+	case "StorageClass":
+		r.StorageClass = v
+	}
+}
+
+// ReadInventory iterates over the records of the S3 Inventory report described
+// by the manifest. The manifest key may be given as "s3://bucket/key" when the
+// report is delivered to another bucket. Report files are read one at a time
+// from the manifest's destination bucket; iteration stops after the first error.
+// Columns of Parquet reports are named as in CSV schemas, e.g. last_modified_date
+// as LastModifiedDate, with timestamps formatted as RFC 3339.
+func (c *client) ReadInventory(manifestKey string) iter.Seq2[InventoryRecord, error] {
+	return func(yield func(InventoryRecord, error) bool) {
+
+		m, err := c.inventoryManifest(manifestKey)
+
+		var n int
+		if err == nil {
+			bucket := strings.TrimPrefix(m.DestinationBucket, "arn:aws:s3:::")
+			for _, f := range m.Files {
+				var stop bool
+				err = c.readInventoryFile(bucket, f, m, func(r InventoryRecord) bool {
+					n++
+					stop = !yield(r, nil)
+					return !stop
+				})
+				if err != nil || stop {
+					break
+				}
+			}
+		}
+
+		c.log("ReadInventory", err).
+			Str("manifest", manifestKey).
+			Int("records", n).
+			Msg("ReadInventory")
+
+		if err != nil {
+			yield(InventoryRecord{}, err)
+		}
+	}
+}
+
+func (c *client) inventoryManifest(k string) (*InventoryManifest, error) {
+	bucket, k := c.location(k)
+	r, err := c.getBucket(bucket, k)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var m InventoryManifest
+	return &m, json.NewDecoder(r).Decode(&m)
+}
+
+// getBucket returns the body of an object in a bucket other than the client's own.
+func (c *client) getBucket(b, k string) (io.ReadCloser, error) {
+	out, err := c.GetObject(c.Context, &s3.GetObjectInput{
+		Bucket: &b,
+		Key:    &k,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Body, nil
+}
+
+func (c *client) readInventoryFile(bucket string, f InventoryFile, m *InventoryManifest, fn func(InventoryRecord) bool) error {
+	switch strings.ToUpper(m.FileFormat) {
+	case "CSV":
+		body, err := c.getBucket(bucket, f.Key)
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+		return readInventoryCSV(body, m.FileSchema, fn)
+	case "PARQUET":
+		pf := &parquetObject{c: c, bucket: bucket, key: f.Key, size: f.Size}
+		defer pf.Close()
+		return readInventoryParquet(pf, fn)
+	}
+	return fmt.Errorf("%w: %s", ErrInventoryFormat, m.FileFormat)
+}
+
+// readInventoryCSV reads a gzipped CSV report whose columns are named by the
+// comma separated manifest schema. Keys are URL encoded in CSV reports.
+func readInventoryCSV(r io.Reader, schema string, fn func(InventoryRecord) bool) error {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	var names []string
+	for _, name := range strings.Split(schema, ",") {
+		names = append(names, strings.TrimSpace(name))
+	}
+
+	cr := csv.NewReader(zr)
+	cr.FieldsPerRecord = len(names)
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		rec := InventoryRecord{Fields: make(map[string]string, len(names))}
+		for i, v := range row {
+			if names[i] == "Key" {
+				if v, err = url.QueryUnescape(v); err != nil {
+					return err
+				}
+			}
+			rec.set(names[i], v)
+		}
+		if !fn(rec) {
+			return nil
+		}
+	}
+}
+
+// parquetRows is how many rows of a Parquet report are decoded at a time.
+const parquetRows = 1000
+
+// readInventoryParquet reads a Parquet report, column by column, a batch of
+// rows at a time. Columns are named as in CSV schemas.
+func readInventoryParquet(pf source.ParquetFile, fn func(InventoryRecord) bool) error {
+	pr, err := reader.NewParquetColumnReader(pf, 1)
+	if err != nil {
+		return err
+	}
+	defer pr.ReadStop()
+
+	sh := pr.SchemaHandler
+	names := make([]string, len(sh.ValueColumns))
+	elems := make([]*parquet.SchemaElement, len(sh.ValueColumns))
+	for i, p := range sh.ValueColumns {
+		idx := int(sh.MapIndex[p])
+		names[i] = inventoryColumn(sh.GetExName(idx))
+		elems[i] = sh.SchemaElements[idx]
+	}
+
+	columns := make([][]any, len(names))
+	for rows := pr.GetNumRows(); rows > 0; rows -= parquetRows {
+		n := min(rows, parquetRows)
+		for i := range columns {
+			if columns[i], _, _, err = pr.ReadColumnByIndex(int64(i), n); err != nil {
+				return err
+			}
+		}
+		for j := range int(n) {
+			rec := InventoryRecord{Fields: make(map[string]string, len(names))}
+			for i, name := range names {
+				if j < len(columns[i]) {
+					rec.set(name, parquetValue(elems[i], columns[i][j]))
+				}
+			}
+			if !fn(rec) {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// inventoryColumn returns the CSV schema name of a Parquet column, e.g.
+// LastModifiedDate for last_modified_date.
+func inventoryColumn(name string) string {
+	parts := strings.Split(name, "_")
+	for i, p := range parts {
+		if p != "" {
+			parts[i] = strings.ToUpper(p[:1]) + p[1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// parquetValue formats a Parquet value as it appears in CSV reports.
+func parquetValue(e *parquet.SchemaElement, v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case int64:
+		switch {
+		case e.IsSetConvertedType() && e.GetConvertedType() == parquet.ConvertedType_TIMESTAMP_MILLIS:
+			return time.UnixMilli(v).UTC().Format(time.RFC3339Nano)
+		case e.IsSetConvertedType() && e.GetConvertedType() == parquet.ConvertedType_TIMESTAMP_MICROS:
+			return time.UnixMicro(v).UTC().Format(time.RFC3339Nano)
+		}
+	}
+	return fmt.Sprint(v)
+}
+
+// parquetObject reads an object for the Parquet reader, streaming from the
+// offset it's seeked to with a ranged GET, so reading the footer and each
+// column doesn't download the whole report.
+type parquetObject struct {
+	c           *client
+	bucket, key string
+	size, off   int64
+	body        io.ReadCloser
+}
+
+func (o *parquetObject) Open(string) (source.ParquetFile, error) {
+	return &parquetObject{c: o.c, bucket: o.bucket, key: o.key, size: o.size}, nil
+}
+
+func (o *parquetObject) Create(string) (source.ParquetFile, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func (o *parquetObject) Write([]byte) (int, error) {
+	return 0, errors.ErrUnsupported
+}
+
+func (o *parquetObject) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += o.off
+	case io.SeekEnd:
+		offset += o.size
+	}
+	if offset < 0 || offset > o.size {
+		return o.off, fmt.Errorf("s3: seeking %s to %d of %d bytes", o.key, offset, o.size)
+	}
+	if offset != o.off {
+		o.Close()
+		o.off = offset
+	}
+	return o.off, nil
+}
+
+func (o *parquetObject) Read(b []byte) (int, error) {
+	if o.off >= o.size {
+		return 0, io.EOF
+	}
+	if o.body == nil {
+		out, err := o.c.GetObject(o.c.Context, &s3.GetObjectInput{
+			Bucket: &o.bucket,
+			Key:    &o.key,
+			Range:  aws.String(fmt.Sprintf("bytes=%d-%d", o.off, o.size-1)),
+		})
+		if err != nil {
+			return 0, err
+		}
+		o.body = out.Body
+	}
+	n, err := o.body.Read(b)
+	o.off += int64(n)
+	if err == io.EOF && o.off < o.size {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func (o *parquetObject) Close() error {
+	if o.body == nil {
+		return nil
+	}
+	err := o.body.Close()
+	o.body = nil
+	return err
+}
@@ -0,0 +1,115 @@
+package s3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+func putInventory(t *testing.T, c *client, format, schema string, files map[string][]byte) {
+	m := InventoryManifest{
+		SourceBucket:      "bytelyon-db",
+		DestinationBucket: "arn:aws:s3:::bytelyon-db",
+		FileFormat:        format,
+		FileSchema:        schema,
+	}
+	for k, b := range files {
+		assert.NoError(t, c.Put(k, b))
+		m.Files = append(m.Files, InventoryFile{Key: k, Size: int64(len(b))})
+	}
+	b, _ := json.Marshal(m)
+	assert.NoError(t, c.Put("inventory/manifest.json", b))
+}
+
+func TestClient_ReadInventory_CSV(t *testing.T) {
+
+	c, _ := newTestBucket(t)
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, _ = zw.Write([]byte(`"bytelyon-db","users/a%20b.json","12","2024-01-02T03:04:05.000Z","STANDARD"` + "\n" +
+		`"bytelyon-db","users/c.json","34","2024-01-02T03:04:05.000Z","GLACIER"` + "\n"))
+	_ = zw.Close()
+	putInventory(t, c, "CSV", "Bucket, Key, Size, LastModifiedDate, StorageClass", map[string][]byte{"inventory/data/1.csv.gz": buf.Bytes()})
+
+	var records []InventoryRecord
+	for r, err := range c.ReadInventory("inventory/manifest.json") {
+		assert.NoError(t, err)
+		records = append(records, r)
+	}
+
+	assert.Len(t, records, 2)
+	assert.Equal(t, "users/a b.json", records[0].Key)
+	assert.Equal(t, int64(12), records[0].Size)
+	assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), records[0].LastModified)
+	assert.Equal(t, "GLACIER", records[1].StorageClass)
+	assert.Equal(t, "34", records[1].Fields["Size"])
+}
+
+// parquetRecord is a row of a Parquet report, named as S3 Inventory names its columns.
+type parquetRecord struct {
+	Bucket           string  `parquet:"name=bucket, type=UTF8"`
+	Key              string  `parquet:"name=key, type=UTF8"`
+	Size             *int64  `parquet:"name=size, type=INT64, repetitiontype=OPTIONAL"`
+	IsLatest         *bool   `parquet:"name=is_latest, type=BOOLEAN, repetitiontype=OPTIONAL"`
+	LastModifiedDate *int64  `parquet:"name=last_modified_date, type=TIMESTAMP_MILLIS, repetitiontype=OPTIONAL"`
+	StorageClass     *string `parquet:"name=storage_class, type=UTF8, repetitiontype=OPTIONAL"`
+}
+
+func TestClient_ReadInventory_Parquet(t *testing.T) {
+
+	c, _ := newTestBucket(t)
+
+	modified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	var buf bytes.Buffer
+	pw, err := writer.NewParquetWriterFromWriter(&buf, new(parquetRecord), 1)
+	assert.NoError(t, err)
+	pw.CompressionType = parquet.CompressionCodec_SNAPPY
+	for i := range 2500 {
+		r := parquetRecord{Bucket: "bytelyon-db", Key: fmt.Sprintf("users/%04d.json", i), Size: aws.Int64(int64(i))}
+		if i%2 == 0 {
+			r.IsLatest = aws.Bool(true)
+			r.LastModifiedDate = aws.Int64(modified.UnixMilli())
+			r.StorageClass = aws.String("GLACIER")
+		}
+		assert.NoError(t, pw.Write(r))
+	}
+	assert.NoError(t, pw.WriteStop())
+	putInventory(t, c, "Parquet", "message s3.inventory { required binary bucket (UTF8); ... }", map[string][]byte{"inventory/data/1.parquet": buf.Bytes()})
+
+	var records []InventoryRecord
+	for r, err := range c.ReadInventory("inventory/manifest.json") {
+		assert.NoError(t, err)
+		records = append(records, r)
+	}
+
+	assert.Len(t, records, 2500)
+	assert.Equal(t, "users/0000.json", records[0].Key)
+	assert.Equal(t, "bytelyon-db", records[0].Bucket)
+	assert.True(t, records[0].IsLatest)
+	assert.Equal(t, modified, records[0].LastModified)
+	assert.Equal(t, "GLACIER", records[0].StorageClass)
+	assert.Equal(t, "users/2499.json", records[2499].Key)
+	assert.Equal(t, int64(2499), records[2499].Size)
+	assert.False(t, records[2499].IsLatest)
+	assert.Equal(t, "", records[2499].Fields["StorageClass"])
+	assert.Equal(t, "1001", records[1001].Fields["Size"])
+}
+
+func TestClient_ReadInventory_Format(t *testing.T) {
+
+	c, _ := newTestBucket(t)
+	putInventory(t, c, "ORC", "", map[string][]byte{"inventory/data/1.orc": []byte("ORC")})
+
+	for _, err := range c.ReadInventory("inventory/manifest.json") {
+		assert.ErrorIs(t, err, ErrInventoryFormat)
+	}
+}
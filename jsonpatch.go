@@ -0,0 +1,283 @@
+package s3
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrPatchFailed is returned by ApplyPatch when an operation can't be
+// applied or a test operation fails. The document is left unchanged.
+var ErrPatchFailed = errors.New("s3: JSON patch failed")
+
+// PatchOp is a JSON Patch (RFC 6902) operation: "add", "remove", "replace",
+// "move", "copy" or "test". Path and From are JSON Pointers (RFC 6901).
+type PatchOp struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	From  string `json:"from,omitempty"`
+	Value any    `json:"value,omitempty"`
+}
+
+// ApplyPatch applies the JSON Patch operations to the JSON document at the
+// key and returns the patched document and its ETag. The operations are
+// applied atomically: if any fails the document is left unchanged and
+// ErrPatchFailed is returned. A missing document is patched as null, so it
+// can be created by adding its root. Concurrent writes are retried, failing
+// with ErrConflict when contention persists.
+func (c *client) ApplyPatch(k string, ops []PatchOp) ([]byte, string, error) {
+
+	values := make([]any, len(ops))
+	var err error
+	for i := 0; err == nil && i < len(ops); i++ {
+		var b []byte
+		if b, err = json.Marshal(ops[i].Value); err == nil {
+			values[i], err = decodeJSON(b)
+		}
+	}
+
+	var body []byte
+	var etag string
+	if err == nil {
+		body, etag, err = c.update(k, func(doc []byte) ([]byte, error) {
+			var target any
+			if doc != nil {
+				var err error
+				if target, err = decodeJSON(doc); err != nil {
+					return nil, err
+				}
+			}
+			for i, op := range ops {
+				var err error
+				if target, err = applyPatchOp(target, op, values[i]); err != nil {
+					return nil, fmt.Errorf("%w: operation %d: %s %s: %w", ErrPatchFailed, i, op.Op, op.Path, err)
+				}
+			}
+			return json.Marshal(target)
+		})
+	}
+
+	c.log("ApplyPatch", err).
+		Str("key", k).
+		Int("ops", len(ops)).
+		Str("etag", etag).
+		Msg("ApplyPatch")
+
+	return body, etag, err
+}
+
+// applyPatchOp applies the operation, whose value is v, to the document and
+// returns the patched document.
+func applyPatchOp(doc any, op PatchOp, v any) (any, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch op.Op {
+	case "add":
+		return pointerAdd(doc, path, copyJSON(v))
+	case "remove":
+		return pointerRemove(doc, path)
+	case "replace":
+		if doc, err = pointerRemove(doc, path); err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, path, copyJSON(v))
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		if v, err = pointerGet(doc, from); err != nil {
+			return nil, err
+		}
+		if op.Op == "copy" {
+			return pointerAdd(doc, path, copyJSON(v))
+		}
+		if len(path) > len(from) && slices.Equal(path[:len(from)], from) {
+			return nil, errors.New("can't move a value into itself")
+		}
+		if doc, err = pointerRemove(doc, from); err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, path, v)
+	case "test":
+		got, err := pointerGet(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !equalJSON(got, v) {
+			return nil, errors.New("test failed")
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("unknown operation %q", op.Op)
+}
+
+// parsePointer splits the JSON Pointer into its unescaped reference tokens.
+func parsePointer(p string) ([]string, error) {
+	if p == "" {
+		return nil, nil
+	}
+	if p[0] != '/' {
+		return nil, fmt.Errorf("invalid JSON pointer %q", p)
+	}
+	tokens := strings.Split(p[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// arrayIndex parses the reference token as an index into an array of n
+// elements.
+func arrayIndex(token string, n int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i >= n || token != strconv.Itoa(i) {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	return i, nil
+}
+
+func pointerGet(doc any, path []string) (any, error) {
+	for _, t := range path {
+		switch d := doc.(type) {
+		case map[string]any:
+			v, ok := d[t]
+			if !ok {
+				return nil, fmt.Errorf("member %q not found", t)
+			}
+			doc = v
+		case []any:
+			i, err := arrayIndex(t, len(d))
+			if err != nil {
+				return nil, err
+			}
+			doc = d[i]
+		default:
+			return nil, fmt.Errorf("can't reference %q in a scalar", t)
+		}
+	}
+	return doc, nil
+}
+
+// pointerUpdate replaces the container the path's last token references into
+// with the result of fn and returns the document.
+func pointerUpdate(doc any, path []string, fn func(container any, token string) (any, error)) (any, error) {
+	if len(path) == 1 {
+		return fn(doc, path[0])
+	}
+	child, err := pointerGet(doc, path[:1])
+	if err != nil {
+		return nil, err
+	}
+	if child, err = pointerUpdate(child, path[1:], fn); err != nil {
+		return nil, err
+	}
+	switch d := doc.(type) {
+	case map[string]any:
+		d[path[0]] = child
+	case []any:
+		i, _ := arrayIndex(path[0], len(d))
+		d[i] = child
+	}
+	return doc, nil
+}
+
+func pointerAdd(doc any, path []string, v any) (any, error) {
+	if len(path) == 0 {
+		return v, nil
+	}
+	return pointerUpdate(doc, path, func(container any, t string) (any, error) {
+		switch d := container.(type) {
+		case map[string]any:
+			d[t] = v
+			return d, nil
+		case []any:
+			if t == "-" {
+				return append(d, v), nil
+			}
+			i, err := arrayIndex(t, len(d)+1)
+			if err != nil {
+				return nil, err
+			}
+			return slices.Insert(d, i, v), nil
+		}
+		return nil, fmt.Errorf("can't add %q to a scalar", t)
+	})
+}
+
+func pointerRemove(doc any, path []string) (any, error) {
+	if len(path) == 0 {
+		return nil, nil
+	}
+	return pointerUpdate(doc, path, func(container any, t string) (any, error) {
+		switch d := container.(type) {
+		case map[string]any:
+			if _, ok := d[t]; !ok {
+				return nil, fmt.Errorf("member %q not found", t)
+			}
+			delete(d, t)
+			return d, nil
+		case []any:
+			i, err := arrayIndex(t, len(d))
+			if err != nil {
+				return nil, err
+			}
+			return slices.Delete(d, i, i+1), nil
+		}
+		return nil, fmt.Errorf("can't remove %q from a scalar", t)
+	})
+}
+
+// copyJSON returns a deep copy of the decoded JSON value.
+func copyJSON(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, e := range v {
+			m[k] = copyJSON(e)
+		}
+		return m
+	case []any:
+		s := make([]any, len(v))
+		for i, e := range v {
+			s[i] = copyJSON(e)
+		}
+		return s
+	}
+	return v
+}
+
+// equalJSON reports whether the decoded JSON values are equal, comparing
+// numbers by value.
+func equalJSON(a, b any) bool {
+	switch a := a.(type) {
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, e := range a {
+			if f, ok := b[k]; !ok || !equalJSON(e, f) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		b, ok := b.([]any)
+		return ok && slices.EqualFunc(a, b, equalJSON)
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		x, errA := a.Float64()
+		y, errB := b.Float64()
+		return a == b || errA == nil && errB == nil && x == y
+	}
+	return a == b
+}
@@ -0,0 +1,87 @@
+package s3
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyPatchOp(t *testing.T) {
+	// examples from RFC 6902 appendix A
+	for _, tc := range []struct {
+		doc, ops, want string
+	}{
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{`{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{`{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2.0}]`, `{"baz":"qux","foo":["a",2,"c"]}`},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"foo":"bar","child":{"grandchild":{}}}`},
+		{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+		{`{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10},{"op":"copy","from":"/~1","path":"/a"}]`, `{"/":9,"~1":10,"a":9}`},
+		{`null`, `[{"op":"add","path":"","value":{"a":1}}]`, `{"a":1}`},
+	} {
+		got, err := applyPatch(t, tc.doc, tc.ops)
+		assert.NoError(t, err, tc.ops)
+		assert.JSONEq(t, tc.want, got, tc.ops)
+	}
+
+	for _, tc := range []struct {
+		doc, ops string
+	}{
+		{`{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`},
+		{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/01","value":"qux"}]`},
+		{`{"foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`},
+		{`{"foo":"bar"}`, `[{"op":"replace","path":"/baz","value":1}]`},
+		{`{"foo":{"bar":1}}`, `[{"op":"move","from":"/foo","path":"/foo/bar/baz"}]`},
+		{`{"foo":"bar"}`, `[{"op":"frobnicate","path":"/foo"}]`},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"foo","value":1}]`},
+	} {
+		_, err := applyPatch(t, tc.doc, tc.ops)
+		assert.Error(t, err, tc.ops)
+	}
+}
+
+func applyPatch(t *testing.T, doc, ops string) (string, error) {
+	var patch []PatchOp
+	assert.NoError(t, json.Unmarshal([]byte(ops), &patch))
+	v, err := decodeJSON([]byte(doc))
+	assert.NoError(t, err)
+	for _, op := range patch {
+		b, _ := json.Marshal(op.Value)
+		value, _ := decodeJSON(b)
+		if v, err = applyPatchOp(v, op, value); err != nil {
+			return "", err
+		}
+	}
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+func TestClient_ApplyPatch(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	b.put("doc.json", []byte(`{"tags":["a"],"n":1}`), nil)
+
+	doc, etag, err := c.ApplyPatch("doc.json", []PatchOp{
+		{Op: "test", Path: "/n", Value: 1},
+		{Op: "add", Path: "/tags/-", Value: "b"},
+		{Op: "replace", Path: "/n", Value: 2},
+	})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"tags":["a","b"],"n":2}`, string(doc))
+	assert.Equal(t, b.object("doc.json").etag, etag)
+
+	// failed patches leave the document unchanged
+	_, _, err = c.ApplyPatch("doc.json", []PatchOp{
+		{Op: "remove", Path: "/tags/0"},
+		{Op: "test", Path: "/n", Value: 1},
+	})
+	assert.ErrorIs(t, err, ErrPatchFailed)
+	assert.Equal(t, etag, b.object("doc.json").etag)
+}
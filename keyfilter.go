@@ -0,0 +1,46 @@
+package s3
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// KeyFilter selects the keys Keys returns. Filters are applied client side
+// to each page as it is listed, so Keys keeps listing until it has a full
+// page of matching keys or the prefix is exhausted.
+type KeyFilter func(k string) bool
+
+// WithSuffix selects keys ending in the suffix, such as an extension.
+func WithSuffix(s string) KeyFilter {
+	return func(k string) bool {
+		return strings.HasSuffix(k, s)
+	}
+}
+
+// WithGlob selects keys matching the shell pattern, as path.Match matches
+// it, so "*" doesn't match "/". It panics if the pattern is malformed.
+func WithGlob(pattern string) KeyFilter {
+	if _, err := path.Match(pattern, ""); err != nil {
+		panic("s3: malformed glob " + pattern)
+	}
+	return func(k string) bool {
+		ok, _ := path.Match(pattern, k)
+		return ok
+	}
+}
+
+// WithRegex selects keys the regular expression matches.
+func WithRegex(re *regexp.Regexp) KeyFilter {
+	return re.MatchString
+}
+
+// matchKey reports whether the key passes every filter.
+func matchKey(k string, filters []KeyFilter) bool {
+	for _, f := range filters {
+		if !f(k) {
+			return false
+		}
+	}
+	return true
+}
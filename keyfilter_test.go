@@ -0,0 +1,45 @@
+package s3
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Keys_Filters(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	for _, k := range []string{
+		"users/1/avatar.png",
+		"users/1/profile.json",
+		"users/2/avatar.png",
+		"users/2/photos/avatar.png",
+		"users/3/profile.json",
+		"users/4/avatar.png",
+	} {
+		b.put(k, []byte(k), nil)
+	}
+
+	keys, err := c.Keys("users/", "", 10, WithSuffix(".json"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"users/1/profile.json", "users/3/profile.json"}, keys)
+
+	keys, err = c.Keys("users/", "", 2, WithGlob("users/*/avatar.png"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"users/1/avatar.png", "users/2/avatar.png"}, keys)
+	keys, err = c.Keys("users/", keys[1], 2, WithGlob("users/*/avatar.png"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"users/4/avatar.png"}, keys)
+
+	keys, err = c.Keys("users/", "", 10, WithRegex(regexp.MustCompile(`^users/[12]/`)), WithSuffix(".png"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"users/1/avatar.png", "users/2/avatar.png", "users/2/photos/avatar.png"}, keys)
+
+	// unfiltered pages are unchanged
+	keys, err = c.Keys("users/", "users/2/avatar.png", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"users/2/photos/avatar.png", "users/3/profile.json"}, keys)
+
+	assert.Panics(t, func() { WithGlob("users/[") })
+}
@@ -0,0 +1,151 @@
+package s3
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+var errLatestDone = errors.New("s3: latest done")
+
+// latestIndex names the index TrackLatest maintains for a prefix. Its
+// values are reversed write times, so listing it returns the most
+// recently written keys first.
+func latestIndex(p string) string {
+	return ".latest/" + url.PathEscape(p)
+}
+
+// latestValue returns the reversed, fixed width timestamp of a write.
+func latestValue(t time.Time) string {
+	return fmt.Sprintf("%019d", math.MaxInt64-t.UnixNano())
+}
+
+// TrackLatest maintains an index of the objects written through the client
+// under the prefix, ordered newest first, so Latest can return the most
+// recently written without listing the prefix. Like Index, it is updated
+// after every Put, copy and Delete but not atomically with them, and objects
+// written before it was registered or by other clients aren't tracked until
+// they are written again. Register it before sharing the client between
+// goroutines.
+func (c *client) TrackLatest(p string) {
+	name := latestIndex(p)
+	c.latest = append(c.latest, p)
+	c.hooks = append(c.hooks, Hooks{
+		AfterPut: func(e HookEvent) error {
+			if !strings.HasPrefix(e.Key, p) || strings.HasPrefix(e.Key, indexPrefix) {
+				return nil
+			}
+			return c.reindex(name, e.Key, []string{latestValue(time.Now())})
+		},
+		AfterDelete: func(e HookEvent) error {
+			if !strings.HasPrefix(e.Key, p) || strings.HasPrefix(e.Key, indexPrefix) {
+				return nil
+			}
+			return c.reindex(name, e.Key, nil)
+		},
+	})
+}
+
+// Latest returns the keys of the n most recently written objects under the
+// prefix, newest first. Prefixes within one tracked by TrackLatest are read
+// from its index, others by listing the prefix and ordering the objects by
+// their last modified time.
+func (c *client) Latest(p string, n int) ([]string, error) {
+
+	tracked := ""
+	ok := false
+	for _, q := range c.latest {
+		if strings.HasPrefix(p, q) && (!ok || len(q) > len(tracked)) {
+			tracked, ok = q, true
+		}
+	}
+
+	var keys []string
+	var err error
+	if n > 0 && ok {
+		keys, err = c.latestIndexed(tracked, p, n)
+	} else if n > 0 {
+		keys, err = c.latestListed(p, n)
+	}
+
+	c.log("Latest", err).
+		Str("prefix", p).
+		Int("n", n).
+		Bool("indexed", ok).
+		Strs("keys", keys).
+		Msg("Latest")
+
+	return keys, err
+}
+
+// latestIndexed reads the newest keys under p from the index of the tracked
+// prefix containing it.
+func (c *client) latestIndexed(tracked, p string, n int) ([]string, error) {
+	ip := indexPrefix + latestIndex(tracked) + "/"
+	var keys []string
+	err := c.walk(ip, func(obj types.Object) error {
+		_, escaped, _ := strings.Cut(strings.TrimPrefix(*obj.Key, ip), "/")
+		k, err := url.PathUnescape(escaped)
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(k, p) {
+			keys = append(keys, k)
+		}
+		if len(keys) == n {
+			return errLatestDone
+		}
+		return nil
+	})
+	if errors.Is(err, errLatestDone) {
+		err = nil
+	}
+	return keys, err
+}
+
+// latestListed lists p for the keys of the newest objects under it.
+func (c *client) latestListed(p string, n int) ([]string, error) {
+	h := &modifiedHeap{}
+	err := c.walk(p, func(obj types.Object) error {
+		if strings.HasPrefix(*obj.Key, indexPrefix) {
+			return nil
+		}
+		o := objectInfo(obj)
+		if h.Len() < n {
+			heap.Push(h, o)
+		} else if o.LastModified.After((*h)[0].LastModified) {
+			(*h)[0] = o
+			heap.Fix(h, 0)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, h.Len())
+	for i := len(keys) - 1; i >= 0; i-- {
+		keys[i] = heap.Pop(h).(ObjectInfo).Key
+	}
+	return keys, nil
+}
+
+// modifiedHeap is a min-heap of objects ordered by last modified time.
+type modifiedHeap []ObjectInfo
+
+func (h modifiedHeap) Len() int           { return len(h) }
+func (h modifiedHeap) Less(i, j int) bool { return h[i].LastModified.Before(h[j].LastModified) }
+func (h modifiedHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *modifiedHeap) Push(x any)        { *h = append(*h, x.(ObjectInfo)) }
+
+func (h *modifiedHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,51 @@
+package s3
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Latest(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	c.TrackLatest("docs/")
+
+	for _, k := range []string{"docs/b.txt", "docs/a.txt", "docs/sub/c.txt", "other/d.txt"} {
+		assert.NoError(t, c.Put(k, []byte(k)))
+		time.Sleep(time.Millisecond)
+	}
+
+	keys, err := c.Latest("docs/", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"docs/sub/c.txt", "docs/a.txt"}, keys)
+
+	// rewrites move keys to the front without leaving stale entries
+	assert.NoError(t, c.Put("docs/b.txt", []byte("again")))
+	keys, err = c.Latest("docs/", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"docs/b.txt", "docs/sub/c.txt", "docs/a.txt"}, keys)
+
+	keys, err = c.Latest("docs/sub/", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"docs/sub/c.txt"}, keys)
+
+	assert.NoError(t, c.Delete("docs/sub/c.txt"))
+	keys, err = c.Latest("docs/", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"docs/b.txt", "docs/a.txt"}, keys)
+
+	// untracked prefixes are listed
+	b.objects["docs/a.txt"].modified = time.Now().Add(-3 * time.Hour)
+	b.objects["docs/b.txt"].modified = time.Now().Add(-2 * time.Hour)
+	b.objects["other/d.txt"].modified = time.Now().Add(-time.Hour)
+	b.put("other/e.txt", []byte("e"), nil)
+	keys, err = c.Latest("", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"other/e.txt", "other/d.txt"}, keys)
+
+	keys, err = c.Latest("docs/", 0)
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+}
@@ -0,0 +1,234 @@
+package s3
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/oklog/ulid/v2"
+)
+
+// ErrLeaseLost is returned when a lease expired or was taken over: by Ack
+// and Nack when a queue message was claimed, or acknowledged, by another
+// consumer since, and by Lease.Err once a Lease is lost.
+var ErrLeaseLost = errors.New("s3: lease lost")
+
+// ErrLeaseHeld is returned by Lease when another owner holds the lease.
+var ErrLeaseHeld = errors.New("s3: lease held")
+
+// Lease is ownership of a key, e.g. of a partition of work, held by one
+// owner at a time until it is released or lost. Unlike a lock taken for a
+// single operation, a lease is renewed by a heartbeat for as long as its
+// owner runs. Owners must stop the work the lease guards once Done is
+// closed.
+type Lease struct {
+	c     *client
+	key   string
+	ttl   time.Duration
+	owner string
+	mu    sync.Mutex
+	etag  *string
+	err   error
+	once  sync.Once
+	done  chan struct{}
+	halt  sync.Once
+	stop  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// leaseRecord is the stored form of a lease. Every renewal changes it, so
+// its ETag identifies the owner's latest renewal.
+type leaseRecord struct {
+	Owner string    `json:"owner"`
+	Until time.Time `json:"until"`
+}
+
+// Lease acquires the lease stored under the key for the ttl and renews it
+// every heartbeat, which must be shorter than the ttl, until it is released.
+// It fails with ErrLeaseHeld while another owner holds an unexpired lease.
+// The lease is lost, closing Done, when another owner takes it over or it
+// expires because renewals failed.
+func (c *client) Lease(k string, ttl, heartbeat time.Duration) (*Lease, error) {
+
+	l := &Lease{c: c, key: k, ttl: ttl, owner: ulid.Make().String(), done: make(chan struct{}), stop: make(chan struct{})}
+	until, err := l.acquire()
+	if err == nil {
+		l.wg.Add(1)
+		go l.renewLoop(heartbeat, until)
+	}
+
+	c.log("Lease", err).
+		Str("key", k).
+		Str("owner", l.owner).
+		Dur("ttl", ttl).
+		Dur("heartbeat", heartbeat).
+		Msg("Lease")
+
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// acquire takes the lease if no other owner holds it and returns when it
+// expires.
+func (l *Lease) acquire() (time.Time, error) {
+	in := &s3.PutObjectInput{IfNoneMatch: aws.String("*")}
+	out, err := l.c.getObject(&s3.GetObjectInput{Bucket: l.c.Bucket, Key: &l.key})
+	if err == nil {
+		var rec leaseRecord
+		err = json.NewDecoder(out.Body).Decode(&rec)
+		out.Body.Close()
+		if err != nil {
+			return time.Time{}, fmt.Errorf("s3: decoding lease %s: %w", l.key, err)
+		}
+		if rec.Until.After(time.Now()) {
+			return time.Time{}, fmt.Errorf("%w: %s by %s until %s", ErrLeaseHeld, l.key, rec.Owner, rec.Until.Format(time.RFC3339))
+		}
+		in = &s3.PutObjectInput{IfMatch: out.ETag}
+	} else if !IsNotFound(err) {
+		return time.Time{}, err
+	}
+
+	until, err := l.write(in)
+	if isPreconditionFailed(err) {
+		err = fmt.Errorf("%w: %s", ErrLeaseHeld, l.key)
+	}
+	return until, err
+}
+
+// write stores the lease until a ttl from now with the input's conditions
+// and returns when it expires, measured from before the write so the owner
+// never outlives the stored lease.
+func (l *Lease) write(in *s3.PutObjectInput) (time.Time, error) {
+	until := time.Now().Add(l.ttl)
+	b, err := json.Marshal(leaseRecord{Owner: l.owner, Until: until})
+	if err != nil {
+		return time.Time{}, err
+	}
+	in.Bucket = l.c.Bucket
+	in.Key = &l.key
+	in.ContentType = aws.String("application/json")
+	out, err := l.c.putObject(in, b)
+	if err != nil {
+		return time.Time{}, err
+	}
+	l.mu.Lock()
+	l.etag = out.ETag
+	l.mu.Unlock()
+	return until, nil
+}
+
+// renewLoop renews the lease every heartbeat until it is released, or lost
+// to another owner or to expiry when renewals fail. Expiry is timed apart
+// from renewals so a renewal that hangs can't outlive the lease.
+func (l *Lease) renewLoop(heartbeat time.Duration, until time.Time) {
+	defer l.wg.Done()
+	expiry := time.AfterFunc(time.Until(until), func() {
+		l.lose(fmt.Errorf("%w: %s expired", ErrLeaseLost, l.key))
+	})
+	defer expiry.Stop()
+	t := time.NewTicker(heartbeat)
+	defer t.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-l.done:
+			return
+		case <-t.C:
+			l.mu.Lock()
+			etag := l.etag
+			l.mu.Unlock()
+			renewed, err := l.write(&s3.PutObjectInput{IfMatch: etag})
+			if isPreconditionFailed(err) || IsNotFound(err) {
+				l.lose(fmt.Errorf("%w: %s taken over", ErrLeaseLost, l.key))
+				return
+			}
+			if err != nil {
+				// retried on the next heartbeat until the lease expires
+				l.c.warn().
+					Err(err).
+					Str("key", l.key).
+					Time("until", until).
+					Msg("Renewing lease failed")
+				continue
+			}
+			until = renewed
+			expiry.Reset(time.Until(until))
+		}
+	}
+}
+
+// lose records why the lease ended, nil when it was released, and closes
+// Done.
+func (l *Lease) lose(err error) {
+	l.once.Do(func() {
+		l.mu.Lock()
+		l.err = err
+		l.mu.Unlock()
+		close(l.done)
+		if err != nil {
+			l.c.warn().
+				Err(err).
+				Str("key", l.key).
+				Str("owner", l.owner).
+				Msg("Lease lost")
+		}
+	})
+}
+
+// Key returns the key the lease is stored under.
+func (l *Lease) Key() string {
+	return l.key
+}
+
+// Done returns a channel closed once the lease is lost or released.
+func (l *Lease) Done() <-chan struct{} {
+	return l.done
+}
+
+// Err returns an error wrapping ErrLeaseLost once the lease is lost, and
+// nil while it is held or after it was released.
+func (l *Lease) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err
+}
+
+// Release stops renewing the lease and deletes it, unless it was lost, so
+// another owner can acquire it at once.
+func (l *Lease) Release() error {
+
+	l.halt.Do(func() {
+		close(l.stop)
+	})
+	l.wg.Wait()
+
+	var err error
+	if l.Err() == nil {
+		l.mu.Lock()
+		etag := l.etag
+		l.mu.Unlock()
+		_, err = l.c.deleteObject(&s3.DeleteObjectInput{
+			Bucket:  l.c.Bucket,
+			Key:     &l.key,
+			IfMatch: etag,
+		})
+		if isPreconditionFailed(err) || IsNotFound(err) {
+			err = nil
+		}
+	}
+	l.lose(nil)
+
+	l.c.log("Release", err).
+		Str("key", l.key).
+		Str("owner", l.owner).
+		Msg("Release")
+
+	return err
+}
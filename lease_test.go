@@ -0,0 +1,96 @@
+package s3
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Lease(t *testing.T) {
+
+	c, b := newTestBucket(t)
+
+	l, err := c.Lease("leases/p0", time.Second, 10*time.Millisecond)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = c.Lease("leases/p0", time.Second, 10*time.Millisecond)
+	assert.ErrorIs(t, err, ErrLeaseHeld)
+
+	var rec leaseRecord
+	assert.NoError(t, json.Unmarshal(b.object("leases/p0").body, &rec))
+	assert.Eventually(t, func() bool {
+		var renewed leaseRecord
+		_ = c.Find("leases/p0", &renewed)
+		return renewed.Until.After(rec.Until)
+	}, time.Second, 5*time.Millisecond)
+
+	assert.NoError(t, l.Release())
+	<-l.Done()
+	assert.NoError(t, l.Err())
+	assert.NotContains(t, b.keys(), "leases/p0")
+
+	// an expired lease can be acquired by another owner
+	b.put("leases/p1", []byte(`{"owner":"gone","until":"2020-01-01T00:00:00Z"}`), nil)
+	l, err = c.Lease("leases/p1", time.Second, 10*time.Millisecond)
+	if assert.NoError(t, err) {
+		assert.NoError(t, l.Release())
+	}
+}
+
+func TestLease_takenOver(t *testing.T) {
+
+	c, b := newTestBucket(t)
+
+	l, err := c.Lease("leases/p0", time.Second, 10*time.Millisecond)
+	if !assert.NoError(t, err) {
+		return
+	}
+	b.mu.Lock()
+	b.put("leases/p0", []byte(`{"owner":"other","until":"2999-01-01T00:00:00Z"}`), nil)
+	b.mu.Unlock()
+
+	select {
+	case <-l.Done():
+	case <-time.After(time.Second):
+		t.Fatal("lease not lost")
+	}
+	assert.ErrorIs(t, l.Err(), ErrLeaseLost)
+
+	// a lost lease is left to its new owner
+	assert.NoError(t, l.Release())
+	assert.Contains(t, string(b.object("leases/p0").body), "other")
+}
+
+func TestLease_expired(t *testing.T) {
+
+	var down atomic.Bool
+	c, _ := newTestBucket(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if down.Load() && r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/leases/p0") {
+				writeError(w, http.StatusForbidden, "AccessDenied")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	l, err := c.Lease("leases/p0", 100*time.Millisecond, 20*time.Millisecond)
+	if !assert.NoError(t, err) {
+		return
+	}
+	down.Store(true)
+
+	select {
+	case <-l.Done():
+	case <-time.After(time.Second):
+		t.Fatal("lease not lost")
+	}
+	assert.ErrorIs(t, l.Err(), ErrLeaseLost)
+	assert.NoError(t, l.Release())
+}
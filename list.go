@@ -0,0 +1,347 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ObjectInfo describes an object without its body.
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ETag         string
+	LastModified time.Time
+	// ContentType is only set by Stat and OpenObject, as listings omit it.
+	ContentType string
+}
+
+func objectInfo(obj types.Object) ObjectInfo {
+	return ObjectInfo{
+		Key:          aws.ToString(obj.Key),
+		Size:         aws.ToInt64(obj.Size),
+		ETag:         aws.ToString(obj.ETag),
+		LastModified: aws.ToTime(obj.LastModified),
+	}
+}
+
+// walk lists every object under the prefix, calling fn for each one in
+// listing order until fn returns an error or the listing is exhausted.
+func (c *client) walk(p string, fn func(types.Object) error) error {
+	return c.walkBucket(*c.Bucket, p, fn)
+}
+
+// walkBucket is walk over a bucket other than the client's own.
+func (c *client) walkBucket(b, p string, fn func(types.Object) error) error {
+	paginator := s3.NewListObjectsV2Paginator(c.Client, &s3.ListObjectsV2Input{
+		Bucket: &b,
+		Prefix: &p,
+	})
+	for paginator.HasMorePages() {
+		out, err := paginator.NextPage(c.Context)
+		if err != nil {
+			return err
+		}
+		for _, obj := range out.Contents {
+			if err = fn(obj); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Stat returns the metadata of the object without reading its body.
+func (c *client) Stat(k string) (ObjectInfo, error) {
+	var out *s3.HeadObjectOutput
+	err := c.negativeCache.get(k)
+	if err == nil {
+		out, err = c.HeadObject(c.Context, &s3.HeadObjectInput{
+			Bucket: c.Bucket,
+			Key:    &k,
+		})
+		c.negativeCache.add(k, err)
+	}
+
+	info := ObjectInfo{Key: k}
+	if err == nil {
+		info.Size = aws.ToInt64(out.ContentLength)
+		info.ETag = aws.ToString(out.ETag)
+		info.LastModified = aws.ToTime(out.LastModified)
+		info.ContentType = aws.ToString(out.ContentType)
+	}
+
+	c.log("Stat", err).
+		Str("key", k).
+		Int64("size", info.Size).
+		Msg("Stat")
+
+	return info, err
+}
+
+// ListDir lists the prefix one level deep, treating "/" as the directory
+// separator, and returns the sub-prefixes and the objects directly under it.
+func (c *client) ListDir(p string) ([]string, []ObjectInfo, error) {
+
+	paginator := s3.NewListObjectsV2Paginator(c.Client, &s3.ListObjectsV2Input{
+		Bucket:    c.Bucket,
+		Prefix:    &p,
+		Delimiter: aws.String("/"),
+	})
+
+	var dirs []string
+	var objects []ObjectInfo
+	var err error
+	for err == nil && paginator.HasMorePages() {
+		var out *s3.ListObjectsV2Output
+		if out, err = paginator.NextPage(c.Context); err == nil {
+			for _, cp := range out.CommonPrefixes {
+				dirs = append(dirs, aws.ToString(cp.Prefix))
+			}
+			for _, obj := range out.Contents {
+				objects = append(objects, objectInfo(obj))
+			}
+		}
+	}
+
+	c.log("ListDir", err).
+		Str("prefix", p).
+		Strs("dirs", dirs).
+		Int("objects", len(objects)).
+		Msg("ListDir")
+
+	return dirs, objects, err
+}
+
+// ListModifiedBetween lists the objects under the prefix last modified in
+// the window from, inclusive, to to, exclusive. A zero to leaves the window
+// open ended, for picking up every change since the last run of a job.
+func (c *client) ListModifiedBetween(p string, from, to time.Time) ([]ObjectInfo, error) {
+
+	var objects []ObjectInfo
+	err := c.walk(p, func(obj types.Object) error {
+		t := aws.ToTime(obj.LastModified)
+		if !t.Before(from) && (to.IsZero() || t.Before(to)) {
+			objects = append(objects, objectInfo(obj))
+		}
+		return nil
+	})
+	if err != nil {
+		objects = nil
+	}
+
+	c.log("ListModifiedBetween", err).
+		Str("prefix", p).
+		Time("from", from).
+		Time("to", to).
+		Int("objects", len(objects)).
+		Msg("ListModifiedBetween")
+
+	return objects, err
+}
+
+// Cursor marks where a page of keys returned by Page ends. It is the opaque
+// continuation token of the listing, so it can be handed to clients and
+// passed back to Page to continue without keeping any state. The zero
+// Cursor starts a listing.
+type Cursor string
+
+// defaultPageSize is the number of keys Page returns when no limit is given,
+// the most a single listing returns.
+const defaultPageSize int32 = 1000
+
+// Page returns up to limit keys under the prefix from the cursor on, and the
+// cursor of the next page, which is zero after the last page. A limit of 0
+// or less returns pages of 1,000 keys.
+func (c *client) Page(p string, cursor Cursor, limit int32) ([]string, Cursor, error) {
+
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	in := &s3.ListObjectsV2Input{
+		Bucket:  c.Bucket,
+		Prefix:  &p,
+		MaxKeys: &limit,
+	}
+	if cursor != "" {
+		in.ContinuationToken = aws.String(string(cursor))
+	}
+
+	var keys []string
+	var next Cursor
+	out, err := c.ListObjectsV2(c.Context, in)
+	if err == nil {
+		for _, obj := range out.Contents {
+			keys = append(keys, *obj.Key)
+		}
+		if aws.ToBool(out.IsTruncated) {
+			next = Cursor(aws.ToString(out.NextContinuationToken))
+		}
+	}
+
+	c.log("Page", err).
+		Str("prefix", p).
+		Str("cursor", string(cursor)).
+		Int32("limit", limit).
+		Strs("keys", keys).
+		Str("next", string(next)).
+		Msg("Page")
+
+	return keys, next, err
+}
+
+// StreamKeys lists the objects under the prefix in the background, sending
+// each one on the returned channel as its page arrives. The channel is
+// unbuffered, so the listing only runs ahead of the consumer by a page.
+// Once the listing ends, or ctx is done, the channel is closed and its
+// error, if any, is sent on the error channel, which is then closed.
+func (c *client) StreamKeys(ctx context.Context, p string) (<-chan ObjectInfo, <-chan error) {
+
+	ch := make(chan ObjectInfo)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(errc)
+
+		var n int
+		paginator := s3.NewListObjectsV2Paginator(c.Client, &s3.ListObjectsV2Input{
+			Bucket: c.Bucket,
+			Prefix: &p,
+		})
+		var err error
+	pages:
+		for err == nil && paginator.HasMorePages() {
+			var out *s3.ListObjectsV2Output
+			if out, err = paginator.NextPage(ctx); err != nil {
+				break
+			}
+			for _, obj := range out.Contents {
+				select {
+				case ch <- objectInfo(obj):
+					n++
+				case <-ctx.Done():
+					err = ctx.Err()
+					break pages
+				}
+			}
+		}
+		close(ch)
+
+		c.log("StreamKeys", err).
+			Str("prefix", p).
+			Int("objects", n).
+			Msg("StreamKeys")
+
+		if err != nil {
+			errc <- err
+		}
+	}()
+
+	return ch, errc
+}
+
+// WalkParallel lists the objects under the prefix and calls fn for each one
+// from up to workers goroutines. The first error stops the listing and no
+// further objects are dispatched, and the errors returned by fn for objects
+// already dispatched are joined with it. The context fn is given is canceled
+// once fn fails, or with the client's, so work in progress can stop too.
+func (c *client) WalkParallel(p string, workers int, fn func(context.Context, ObjectInfo) error) error {
+
+	n, err := parallel(c.Context, workers, func(send func(ObjectInfo) bool) error {
+		return c.walk(p, func(obj types.Object) error {
+			if !send(objectInfo(obj)) {
+				return errWalkStopped
+			}
+			return nil
+		})
+	}, fn)
+
+	c.log("WalkParallel", err).
+		Str("prefix", p).
+		Int("workers", workers).
+		Int("objects", n).
+		Msg("WalkParallel")
+
+	return err
+}
+
+// errWalkStopped stops a feed of parallel once it stops dispatching.
+var errWalkStopped = errors.New("s3: walk stopped")
+
+// parallel calls fn with every item feed sends from up to workers
+// goroutines, returning how many were dispatched. Once fn fails, the context
+// it's given, derived from ctx, is canceled, send reports false so feed can
+// stop, and no further items are dispatched; the error of feed, unless it
+// stopped because send reported false, is joined with those of fn. Errors of
+// work canceled because another item failed aren't reported.
+func parallel[T any](ctx context.Context, workers int, feed func(send func(T) bool) error, fn func(context.Context, T) error) (int, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	items := make(chan T)
+	var mu sync.Mutex
+	var errs []error
+
+	var wg sync.WaitGroup
+	for range max(workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range items {
+				err := fn(ctx, item)
+				if err == nil {
+					continue
+				}
+				mu.Lock()
+				if failed := context.Cause(ctx); failed == nil || !errors.Is(err, context.Canceled) || errors.Is(failed, context.Canceled) {
+					errs = append(errs, err)
+				}
+				mu.Unlock()
+				cancel(err)
+			}
+		}()
+	}
+
+	var n int
+	var stopped bool
+	err := feed(func(item T) bool {
+		select {
+		case items <- item:
+			n++
+			return true
+		case <-ctx.Done():
+			stopped = true
+			return false
+		}
+	})
+	close(items)
+	wg.Wait()
+	if stopped {
+		err = nil
+		if context.Cause(ctx) != nil && len(errs) == 0 {
+			// canceled by ctx rather than by fn failing
+			err = context.Cause(ctx)
+		}
+	}
+	return n, errors.Join(append([]error{err}, errs...)...)
+}
+
+// sendEach returns a feed of parallel sending the items in order.
+func sendEach[T any](items []T) func(func(T) bool) error {
+	return func(send func(T) bool) error {
+		for _, item := range items {
+			if !send(item) {
+				break
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,140 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_ListModifiedBetween(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	now := time.Now().Truncate(time.Second)
+	for i, k := range []string{"logs/a", "logs/b", "logs/c", "other/d"} {
+		b.put(k, []byte(k), nil).modified = now.Add(time.Duration(-i) * time.Hour)
+	}
+
+	objects, err := c.ListModifiedBetween("logs/", now.Add(-2*time.Hour), now)
+	assert.NoError(t, err)
+	if assert.Len(t, objects, 2) {
+		assert.Equal(t, "logs/b", objects[0].Key)
+		assert.Equal(t, "logs/c", objects[1].Key)
+		assert.True(t, now.Add(-time.Hour).Equal(objects[0].LastModified))
+	}
+
+	objects, err = c.ListModifiedBetween("", now.Add(-time.Hour), time.Time{})
+	assert.NoError(t, err)
+	if assert.Len(t, objects, 2) {
+		assert.Equal(t, "logs/a", objects[0].Key)
+		assert.Equal(t, "logs/b", objects[1].Key)
+	}
+}
+
+func TestClient_Page(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	for _, k := range []string{"users/1", "users/2", "users/3", "users/4", "users/5", "other/6"} {
+		b.put(k, nil, nil)
+	}
+
+	var pages [][]string
+	var cursor Cursor
+	for {
+		keys, next, err := c.Page("users/", cursor, 2)
+		assert.NoError(t, err)
+		pages = append(pages, keys)
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	assert.Equal(t, [][]string{{"users/1", "users/2"}, {"users/3", "users/4"}, {"users/5"}}, pages)
+
+	keys, next, err := c.Page("users/", "", 5)
+	assert.NoError(t, err)
+	assert.Len(t, keys, 5)
+	assert.Empty(t, next)
+
+	// no limit returns full pages rather than none
+	keys, next, err = c.Page("users/", "", 0)
+	assert.NoError(t, err)
+	assert.Len(t, keys, 5)
+	assert.Empty(t, next)
+}
+
+func TestClient_StreamKeys(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	for i := range 1500 {
+		b.put("users/"+strconv.Itoa(10000+i), nil, nil)
+	}
+
+	ch, errc := c.StreamKeys(context.Background(), "users/")
+	var n int
+	for o := range ch {
+		assert.Equal(t, "users/"+strconv.Itoa(10000+n), o.Key)
+		n++
+	}
+	assert.NoError(t, <-errc)
+	assert.Equal(t, 1500, n)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, errc = c.StreamKeys(ctx, "users/")
+	<-ch
+	cancel()
+	for range ch {
+	}
+	assert.ErrorIs(t, <-errc, context.Canceled)
+}
+
+func TestClient_WalkParallel(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	for i := range 100 {
+		b.put("users/"+strconv.Itoa(i), make([]byte, i), nil)
+	}
+
+	var size atomic.Int64
+	assert.NoError(t, c.WalkParallel("users/", 8, func(_ context.Context, o ObjectInfo) error {
+		size.Add(o.Size)
+		return nil
+	}))
+	assert.Equal(t, int64(99*100/2), size.Load())
+
+	errFailed := errors.New("failed")
+	var calls atomic.Int64
+	err := c.WalkParallel("users/", 4, func(context.Context, ObjectInfo) error {
+		calls.Add(1)
+		return errFailed
+	})
+	assert.ErrorIs(t, err, errFailed)
+	assert.Less(t, calls.Load(), int64(100))
+
+	// work in progress is canceled once an object fails
+	calls.Store(0)
+	err = c.WalkParallel("users/", 4, func(ctx context.Context, o ObjectInfo) error {
+		if calls.Add(1) == 1 {
+			return errFailed
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	assert.EqualError(t, err, errFailed.Error())
+
+	// as is all work when the client's context is
+	ctx, cancel := context.WithCancel(context.Background())
+	calls.Store(0)
+	err = c.withContext(ctx).WalkParallel("users/", 4, func(ctx context.Context, o ObjectInfo) error {
+		if calls.Add(1) == 10 {
+			cancel()
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, calls.Load(), int64(100))
+}
@@ -0,0 +1,194 @@
+package s3
+
+import (
+	"context"
+	"maps"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// correlationMetadata is the metadata written objects carry the correlation
+// ID of the client's context in, as x-amz-meta-correlation-id.
+const correlationMetadata = "correlation-id"
+
+type correlationIDKey struct{}
+
+// ContextWithCorrelationID returns a copy of ctx carrying the ID of the
+// request it serves, so the operations of a client bound to it with
+// WithContext can be tied back to the request in logs.
+func ContextWithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// CorrelationID returns the correlation ID ctx carries, if any.
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
+
+// WithCorrelationIDFunc extracts correlation IDs from the client's context
+// with fn, such as the trace ID of a tracing library's span, rather than
+// with CorrelationID.
+func WithCorrelationIDFunc(fn func(context.Context) string) Option {
+	return func(o *options) {
+		o.correlationID = fn
+	}
+}
+
+// WithContext returns a client sharing the receiver's configuration,
+// connections and caches that makes its requests with ctx, e.g. to bind
+// operations to the lifetime and correlation ID of a request.
+func (c *client) WithContext(ctx context.Context) Service {
+	return c.withContext(ctx)
+}
+
+// withContext returns a copy of the client making its requests with ctx.
+func (c *client) withContext(ctx context.Context) *client {
+	cc := *c
+	cc.Context = ctx
+	return &cc
+}
+
+// correlationID returns the correlation ID of the client's context.
+func (c *client) correlationID() string {
+	if c.Context == nil {
+		return ""
+	}
+	if c.options != nil && c.options.correlationID != nil {
+		return c.options.correlationID(c.Context)
+	}
+	return CorrelationID(c.Context)
+}
+
+// WithLogger logs through the logger rather than zerolog's global logger.
+// A logger carried by the client's context, as zerolog's Logger.WithContext
+// stores it, is used in preference to either, so per request fields can be
+// added to the entries of a client bound to the request with WithContext.
+func WithLogger(l zerolog.Logger) Option {
+	return func(o *options) {
+		o.logger = &l
+	}
+}
+
+// Logger returns the logger the client writes its log to, so packages built on
+// a Service can log alongside it.
+func (c *client) Logger() *zerolog.Logger {
+	return c.logger()
+}
+
+// logger returns the logger of the client's context, falling back to the
+// configured logger and then the global one.
+func (c *client) logger() *zerolog.Logger {
+	if c.Context != nil {
+		if l := zerolog.Ctx(c.Context); l != zerolog.DefaultContextLogger && l.GetLevel() != zerolog.Disabled {
+			return l
+		}
+	}
+	if c.options != nil && c.options.logger != nil {
+		return c.options.logger
+	}
+	return &log.Logger
+}
+
+// WithNoLogging disables the client's log, so no entries are built at all,
+// saving their allocations on every call.
+func WithNoLogging() Option {
+	return func(o *options) {
+		o.noLogging = true
+	}
+}
+
+// WithLogLevel logs the named operations, such as "Put" or "Get", at the
+// level, or all operations without a level of their own when none are
+// named. Operations are logged at trace level by default.
+func WithLogLevel(level zerolog.Level, ops ...string) Option {
+	return func(o *options) {
+		if o.logLevels == nil {
+			o.logLevels = map[string]zerolog.Level{}
+		}
+		if len(ops) == 0 {
+			o.logLevels[""] = level
+		}
+		for _, op := range ops {
+			o.logLevels[op] = level
+		}
+	}
+}
+
+// WithErrorLogLevel logs operations that fail at the level, whatever the
+// level of the operation, e.g. to log errors at error level and everything
+// else at debug level.
+func WithErrorLogLevel(level zerolog.Level) Option {
+	return func(o *options) {
+		o.errorLogLevel = &level
+	}
+}
+
+// WithLogSampling logs only every nth successful call of the named
+// operations, for high volume reads whose every entry isn't worth keeping.
+// Failures are always logged.
+func WithLogSampling(n uint32, ops ...string) Option {
+	return func(o *options) {
+		if o.logSamplers == nil {
+			o.logSamplers = map[string]zerolog.Sampler{}
+		}
+		for _, op := range ops {
+			o.logSamplers[op] = &zerolog.BasicSampler{N: n}
+		}
+	}
+}
+
+// log starts an entry of the client's log for the outcome of the operation,
+// at the level configured for it, or returns nil, which zerolog ignores, if
+// the entry isn't sampled.
+func (c *client) log(op string, err error) *zerolog.Event {
+	if c.options != nil && c.noLogging {
+		return nil
+	}
+	level := zerolog.TraceLevel
+	if c.options != nil {
+		if l, ok := c.logLevels[op]; ok {
+			level = l
+		} else if l, ok := c.logLevels[""]; ok {
+			level = l
+		}
+		if err != nil && c.errorLogLevel != nil {
+			level = *c.errorLogLevel
+		} else if s := c.logSamplers[op]; err == nil && s != nil && !s.Sample(level) {
+			return nil
+		}
+	}
+	return c.correlate(c.logger().WithLevel(level)).Err(err)
+}
+
+// warn starts a warn level entry of the client's log.
+func (c *client) warn() *zerolog.Event {
+	if c.options != nil && c.noLogging {
+		return nil
+	}
+	return c.correlate(c.logger().Warn())
+}
+
+// correlate adds the correlation ID, if any, to the log entry.
+func (c *client) correlate(e *zerolog.Event) *zerolog.Event {
+	if id := c.correlationID(); id != "" {
+		e = e.Str("correlation_id", id)
+	}
+	return e
+}
+
+// correlateMetadata returns the metadata of an object being written with
+// the correlation ID, if any, added.
+func (c *client) correlateMetadata(meta map[string]string) map[string]string {
+	id := c.correlationID()
+	if id == "" {
+		return meta
+	}
+	meta = maps.Clone(meta)
+	if meta == nil {
+		meta = map[string]string{}
+	}
+	meta[correlationMetadata] = id
+	return meta
+}
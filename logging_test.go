@@ -0,0 +1,123 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// captureLog sends the global logger's entries to the returned buffer for
+// the rest of the test.
+func captureLog(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	logger := log.Logger
+	log.Logger = zerolog.New(&buf).Level(zerolog.TraceLevel)
+	t.Cleanup(func() { log.Logger = logger })
+	return &buf
+}
+
+func TestClient_WithContext(t *testing.T) {
+
+	buf := captureLog(t)
+	c, b := newTestBucket(t)
+	rc := c.WithContext(ContextWithCorrelationID(context.Background(), "req-1"))
+
+	assert.NoError(t, rc.Put("a.json", []byte("{}")))
+	assert.Equal(t, "req-1", b.object("a.json").header.Get("X-Amz-Meta-Correlation-Id"))
+	assert.Contains(t, buf.String(), `"correlation_id":"req-1"`)
+
+	// the receiver is unchanged
+	buf.Reset()
+	assert.NoError(t, c.Put("b.json", []byte("{}")))
+	assert.Empty(t, b.object("b.json").header.Get("X-Amz-Meta-Correlation-Id"))
+	assert.NotContains(t, buf.String(), "correlation_id")
+}
+
+func TestWithCorrelationIDFunc(t *testing.T) {
+
+	type traceKey struct{}
+	c, b := newTestBucket(t)
+	WithCorrelationIDFunc(func(ctx context.Context) string {
+		id, _ := ctx.Value(traceKey{}).(string)
+		return id
+	})(c.options)
+
+	rc := c.WithContext(context.WithValue(context.Background(), traceKey{}, "trace-1"))
+	w := rc.NewWriter("c.txt")
+	_, err := w.Write([]byte("c"))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	assert.Equal(t, "trace-1", b.object("c.txt").header.Get("X-Amz-Meta-Correlation-Id"))
+}
+
+func TestWithLogLevel(t *testing.T) {
+
+	buf := captureLog(t)
+	c, _ := newTestBucket(t)
+	WithLogLevel(zerolog.DebugLevel)(c.options)
+	WithLogLevel(zerolog.InfoLevel, "Put", "Delete")(c.options)
+	WithErrorLogLevel(zerolog.ErrorLevel)(c.options)
+	WithLogSampling(3, "Get")(c.options)
+
+	assert.NoError(t, c.Put("a.json", []byte("{}")))
+	assert.Contains(t, buf.String(), `{"level":"info","key":"a.json"`)
+	buf.Reset()
+
+	for range 6 {
+		_, err := c.Get("a.json")
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 2, strings.Count(buf.String(), `"message":"Get"`))
+	assert.Contains(t, buf.String(), `{"level":"debug","key":"a.json"`)
+	buf.Reset()
+
+	_, err := c.Get("missing.json")
+	assert.Error(t, err)
+	assert.Contains(t, buf.String(), `{"level":"error","error":`)
+	buf.Reset()
+
+	_, err = c.Stat("a.json")
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), `{"level":"debug","key":"a.json"`)
+}
+
+func TestWithLogger(t *testing.T) {
+
+	global := captureLog(t)
+	var configured, scoped bytes.Buffer
+	c, _ := newTestBucket(t)
+	WithLogger(zerolog.New(&configured).Level(zerolog.TraceLevel))(c.options)
+
+	assert.NoError(t, c.Put("a.json", []byte("{}")))
+	assert.Contains(t, configured.String(), `"message":"Put"`)
+	assert.Empty(t, global.String())
+
+	l := zerolog.New(&scoped).Level(zerolog.TraceLevel).With().Str("request", "r1").Logger()
+	rc := c.WithContext(l.WithContext(context.Background()))
+	_, err := rc.Get("a.json")
+	assert.NoError(t, err)
+	assert.Contains(t, scoped.String(), `"request":"r1"`)
+	assert.Contains(t, scoped.String(), `"message":"Get"`)
+	assert.NotContains(t, configured.String(), `"message":"Get"`)
+}
+
+func TestWithNoLogging(t *testing.T) {
+
+	buf := captureLog(t)
+	c, _ := newTestBucket(t)
+	WithNoLogging()(c.options)
+	WithLogLevel(zerolog.ErrorLevel)(c.options)
+
+	assert.NoError(t, c.Put("a.json", []byte("{}")))
+	_, err := c.Get("missing.json")
+	assert.Error(t, err)
+	assert.Empty(t, buf.String())
+	assert.Zero(t, testing.AllocsPerRun(10, func() {
+		c.log("Get", err).Str("key", "a.json").Bytes("body", []byte("{}")).Msg("Get")
+	}))
+}
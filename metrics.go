@@ -0,0 +1,105 @@
+package s3
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// WithEMFMetrics writes a line in CloudWatch Embedded Metric Format to w for
+// every S3 operation the client performs, reporting its Latency, the Bytes
+// of object data sent or received and whether it failed as Errors, in the
+// namespace with an Operation dimension. Lambda turns such lines written to
+// stdout, e.g. with w set to os.Stdout, into CloudWatch metrics without an
+// agent or API calls.
+func WithEMFMetrics(namespace string, w io.Writer) Option {
+	m := &emf{namespace: namespace, w: w}
+	return WithS3Options(func(o *s3.Options) {
+		o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+			return stack.Initialize.Add(m, middleware.After)
+		})
+	})
+}
+
+// emf is an initialize middleware timing every operation, retries included.
+type emf struct {
+	namespace string
+	mu        sync.Mutex
+	w         io.Writer
+}
+
+type emfMetric struct {
+	Name string
+	Unit string
+}
+
+type emfDirective struct {
+	Namespace  string
+	Dimensions [][]string
+	Metrics    []emfMetric
+}
+
+type emfMetadata struct {
+	Timestamp         int64
+	CloudWatchMetrics []emfDirective
+}
+
+type emfEntry struct {
+	AWS       emfMetadata `json:"_aws"`
+	Operation string
+	Latency   float64
+	Bytes     int64
+	Errors    int
+}
+
+func (m *emf) ID() string {
+	return "EMFMetrics"
+}
+
+func (m *emf) HandleInitialize(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+	start := time.Now()
+	out, md, err := next.HandleInitialize(ctx, in)
+
+	e := emfEntry{
+		AWS: emfMetadata{
+			Timestamp: start.UnixMilli(),
+			CloudWatchMetrics: []emfDirective{{
+				Namespace:  m.namespace,
+				Dimensions: [][]string{{"Operation"}},
+				Metrics: []emfMetric{
+					{"Latency", "Milliseconds"},
+					{"Bytes", "Bytes"},
+					{"Errors", "Count"},
+				},
+			}},
+		},
+		Operation: awsmiddleware.GetOperationName(ctx),
+		Latency:   float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		e.Errors = 1
+	}
+	switch v := in.Parameters.(type) {
+	case *s3.PutObjectInput:
+		e.Bytes = aws.ToInt64(v.ContentLength)
+	case *s3.UploadPartInput:
+		e.Bytes = aws.ToInt64(v.ContentLength)
+	}
+	if v, ok := out.Result.(*s3.GetObjectOutput); ok {
+		e.Bytes = aws.ToInt64(v.ContentLength)
+	}
+
+	if b, merr := json.Marshal(e); merr == nil {
+		m.mu.Lock()
+		_, _ = m.w.Write(append(b, '\n'))
+		m.mu.Unlock()
+	}
+	return out, md, err
+}
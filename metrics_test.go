@@ -0,0 +1,43 @@
+package s3
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithEMFMetrics(t *testing.T) {
+
+	var buf bytes.Buffer
+	b := newBucket()
+	srv := httptest.NewServer(b)
+	t.Cleanup(srv.Close)
+	c := transportClient(srv.URL, WithEMFMetrics("S3", &buf))
+
+	assert.NoError(t, c.Put("a.json", []byte(`{"a":1}`)))
+	_, err := c.Get("a.json")
+	assert.NoError(t, err)
+	_, err = c.Get("missing.json")
+	assert.Error(t, err)
+
+	var entries []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var e map[string]any
+		assert.NoError(t, json.Unmarshal([]byte(line), &e))
+		entries = append(entries, e)
+	}
+	if assert.Len(t, entries, 3) {
+		assert.Equal(t, "PutObject", entries[0]["Operation"])
+		assert.Equal(t, float64(7), entries[0]["Bytes"])
+		assert.Equal(t, "GetObject", entries[1]["Operation"])
+		assert.Equal(t, float64(7), entries[1]["Bytes"])
+		assert.Equal(t, float64(0), entries[1]["Errors"])
+		assert.Equal(t, float64(1), entries[2]["Errors"])
+		assert.Contains(t, entries[0], "Latency")
+		assert.Equal(t, "S3", entries[0]["_aws"].(map[string]any)["CloudWatchMetrics"].([]any)[0].(map[string]any)["Namespace"])
+	}
+}
@@ -0,0 +1,49 @@
+package s3
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithMFA(t *testing.T) {
+
+	var reqs []*http.Request
+	var bodies []string
+	c := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		reqs = append(reqs, r)
+		bodies = append(bodies, string(b))
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+
+	assert.NoError(t, c.DeleteVersion("a.json", "v1"))
+	assert.Empty(t, reqs[0].Header.Get("X-Amz-Mfa"))
+	assert.Equal(t, "v1", reqs[0].URL.Query().Get("versionId"))
+
+	var codes int
+	WithMFA("arn:aws:iam::123456789012:mfa/ann", func() (string, error) {
+		codes++
+		return "123456", nil
+	})(c.options)
+
+	assert.NoError(t, c.DeleteVersion("a.json", "v2"))
+	assert.Equal(t, "arn:aws:iam::123456789012:mfa/ann 123456", reqs[1].Header.Get("X-Amz-Mfa"))
+
+	assert.NoError(t, c.SetVersioning(true, true))
+	assert.Equal(t, "arn:aws:iam::123456789012:mfa/ann 123456", reqs[2].Header.Get("X-Amz-Mfa"))
+	assert.True(t, reqs[2].URL.Query().Has("versioning"))
+	assert.Contains(t, bodies[2], "<MfaDelete>Enabled</MfaDelete>")
+	assert.Contains(t, bodies[2], "<Status>Enabled</Status>")
+	assert.Equal(t, 2, codes)
+
+	errNoCode := errors.New("no code")
+	WithMFA("serial", func() (string, error) { return "", errNoCode })(c.options)
+	assert.ErrorIs(t, c.DeleteVersion("a.json", "v3"), errNoCode)
+	assert.Len(t, reqs, 3)
+}
@@ -0,0 +1,201 @@
+package s3
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"maps"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// migratePrefix is where Migrate keeps the checkpoints of unfinished migrations.
+const migratePrefix = ".migrate/"
+
+// migrateCheckpoint records how far a migration got, so it can be resumed.
+// Every key up to and including After has been migrated.
+type migrateCheckpoint struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	After       string `json:"after,omitempty"`
+	Migrated    int64  `json:"migrated"`
+}
+
+// migrateCheckpointKey returns the key of the checkpoint of the operation
+// migrating src to dst.
+func migrateCheckpointKey(op, src, dst string) string {
+	sum := sha256.Sum256([]byte(op + "\n" + src + "\n" + dst))
+	return migratePrefix + hex.EncodeToString(sum[:]) + ".json"
+}
+
+// Migrate rewrites every object under the src prefix to the dst prefix with
+// the transform, run by up to workers workers. transform receives the key
+// without the src prefix and the body, and returns the key to write under the
+// dst prefix and the body to write there, or an empty key to skip the object.
+// Sources are kept, and the objects written keep their Content-Type and other
+// headers, metadata, tags, storage class and encryption. Objects are migrated
+// a page of up to 1,000 keys at a time in key order, and a checkpoint under
+// .migrate/ is saved after each page, so calling Migrate again with the same
+// prefixes and transform after a crash or error resumes after the last
+// completed page. Transforms are told apart by the name of their function,
+// which the closures declared in a function don't share. The checkpoint is
+// deleted once the migration completes. Objects of the page that was
+// interrupted are migrated again, so transforms must be safe to repeat. When
+// dst is under src the objects already under dst are skipped. Objects
+// re-keyed in place, with dst equal to src, to keys that sort after their
+// source are migrated twice.
+func (c *client) Migrate(src, dst string, transform func(string, []byte) (string, []byte, error), workers int) error {
+
+	cp := migrateCheckpoint{Source: src, Destination: dst}
+	nested := dst != src && strings.HasPrefix(dst, src)
+	ck := migrateCheckpointKey("Migrate", src, dst+"\n"+funcName(transform))
+	resumed, err := c.migrate(ck, &cp, workers, func(ctx context.Context, k string) (bool, error) {
+		if nested && strings.HasPrefix(k, dst) {
+			return false, nil
+		}
+		return c.withContext(ctx).migrateObject(k, src, dst, transform)
+	})
+
+	c.log("Migrate", err).
+		Str("src", src).
+		Str("dst", dst).
+		Bool("resumed", resumed).
+		Str("after", cp.After).
+		Int64("migrated", cp.Migrated).
+		Msg("Migrate")
+
+	return err
+}
+
+// migrateObject writes the object transformed under dst, reporting whether
+// the transform kept it.
+func (c *client) migrateObject(k, src, dst string, transform func(string, []byte) (string, []byte, error)) (bool, error) {
+	out, err := c.getObject(&s3.GetObjectInput{Bucket: c.Bucket, Key: &k})
+	if IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	body, err := readAll(out.Body, aws.ToInt64(out.ContentLength))
+	out.Body.Close()
+
+	var nk string
+	if err == nil {
+		nk, body, err = transform(strings.TrimPrefix(k, src), body)
+	}
+	if err != nil || nk == "" {
+		return false, err
+	}
+	in, err := c.rewriteInput(k, dst+nk, out)
+	if err == nil {
+		_, err = c.putObject(in, body)
+	}
+	return err == nil, err
+}
+
+// rewriteInput returns the input writing a new body for the object k, read
+// with out, to dst, keeping its headers, metadata, tags, storage class and
+// encryption. The checksums of the old body no longer apply and are dropped.
+func (c *client) rewriteInput(k, dst string, out *s3.GetObjectOutput) (*s3.PutObjectInput, error) {
+	var tagging *string
+	if aws.ToInt32(out.TagCount) > 0 {
+		var err error
+		if tagging, err = c.tagging(k); err != nil {
+			return nil, err
+		}
+	}
+	metadata := maps.Clone(out.Metadata)
+	delete(metadata, sha256Metadata)
+	delete(metadata, crc32cMetadata)
+
+	return &s3.PutObjectInput{
+		Bucket:               c.Bucket,
+		Key:                  &dst,
+		ContentType:          out.ContentType,
+		ContentEncoding:      out.ContentEncoding,
+		ContentDisposition:   out.ContentDisposition,
+		ContentLanguage:      out.ContentLanguage,
+		CacheControl:         out.CacheControl,
+		Metadata:             metadata,
+		StorageClass:         out.StorageClass,
+		Tagging:              tagging,
+		ServerSideEncryption: out.ServerSideEncryption,
+		SSEKMSKeyId:          out.SSEKMSKeyId,
+		BucketKeyEnabled:     out.BucketKeyEnabled,
+	}, nil
+}
+
+// funcName returns the name of the function f, e.g. main.upgrade, or
+// main.run.func1 for the first closure declared in main.run.
+func funcName(f any) string {
+	if fn := runtime.FuncForPC(reflect.ValueOf(f).Pointer()); fn != nil {
+		return fn.Name()
+	}
+	return ""
+}
+
+// migrate calls fn, from up to workers workers, with every key under the
+// checkpoint's Source a page at a time in key order, saving the checkpoint
+// under ck after each page and deleting it once every page is done. A
+// checkpoint left by an earlier call is resumed, in which case migrate
+// reports true. fn reports whether it wrote an object, which the checkpoint
+// counts, and is given a context canceled once another key fails. Keys under
+// .migrate/ are skipped.
+func (c *client) migrate(ck string, cp *migrateCheckpoint, workers int, fn func(context.Context, string) (bool, error)) (bool, error) {
+
+	err := c.readJSON(ck, cp)
+	resumed := err == nil
+	if IsNotFound(err) {
+		err = nil
+	}
+
+	if err == nil {
+		in := &s3.ListObjectsV2Input{Bucket: c.Bucket, Prefix: &cp.Source}
+		if cp.After != "" {
+			in.StartAfter = &cp.After
+		}
+		paginator := s3.NewListObjectsV2Paginator(c.Client, in)
+		for err == nil && paginator.HasMorePages() {
+			var out *s3.ListObjectsV2Output
+			if out, err = paginator.NextPage(c.Context); err != nil || len(out.Contents) == 0 {
+				break
+			}
+			var keys []string
+			for _, obj := range out.Contents {
+				if !strings.HasPrefix(*obj.Key, migratePrefix) {
+					keys = append(keys, *obj.Key)
+				}
+			}
+			var n int64
+			if n, err = c.migrateKeys(keys, workers, fn); err == nil {
+				cp.After = *out.Contents[len(out.Contents)-1].Key
+				cp.Migrated += n
+				err = c.Put(ck, cp)
+			}
+		}
+	}
+	if err == nil {
+		err = c.Delete(ck)
+	}
+	return resumed, err
+}
+
+// migrateKeys calls fn with the keys, stopping at the first error, and
+// returns how many objects were written.
+func (c *client) migrateKeys(keys []string, workers int, fn func(context.Context, string) (bool, error)) (int64, error) {
+	var migrated atomic.Int64
+	_, err := parallel(c.Context, workers, sendEach(keys), func(ctx context.Context, k string) error {
+		wrote, err := fn(ctx, k)
+		if wrote {
+			migrated.Add(1)
+		}
+		return err
+	})
+	return migrated.Load(), err
+}
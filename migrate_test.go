@@ -0,0 +1,95 @@
+package s3
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Migrate(t *testing.T) {
+
+	c, b := newTestBucket(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// list two keys a page so the migration is checkpointed as it goes
+			if q := r.URL.Query(); q.Has("list-type") {
+				q.Set("max-keys", "2")
+				r.URL.RawQuery = q.Encode()
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	for _, k := range []string{"v1/a.txt", "v1/b.txt", "v1/c.txt", "v1/skip.txt", "v10.txt"} {
+		b.put(k, []byte(strings.ToLower(k)), nil)
+	}
+	b.put("v1/d.txt", []byte("v1/d.txt"), http.Header{
+		"Content-Type":      {"text/plain"},
+		"Cache-Control":     {"no-cache"},
+		"X-Amz-Meta-Owner":  {"core"},
+		"X-Amz-Meta-Sha256": {"stale"},
+		"X-Amz-Tagging":     {"team=core"},
+	})
+
+	var fail bool
+	transform := func(k string, body []byte) (string, []byte, error) {
+		if k == "skip.txt" {
+			return "", nil, nil
+		}
+		if fail && k == "c.txt" {
+			return "", nil, errors.New("transform failed")
+		}
+		return strings.TrimSuffix(k, ".txt") + ".md", bytes.ToUpper(body), nil
+	}
+
+	fail = true
+	assert.Error(t, c.Migrate("v1/", "v2/", transform, 1))
+	ck := migrateCheckpointKey("Migrate", "v1/", "v2/\n"+funcName(transform))
+	assert.Contains(t, b.keys(), ck)
+
+	// a checkpoint isn't resumed by another transform
+	other := func(k string, body []byte) (string, []byte, error) { return "", nil, nil }
+	assert.NotEqual(t, ck, migrateCheckpointKey("Migrate", "v1/", "v2/\n"+funcName(other)))
+	assert.Contains(t, b.keys(), "v2/b.md")
+	assert.NotContains(t, b.keys(), "v2/c.md")
+
+	// the completed page isn't migrated again when the migration resumes
+	b.put("v2/a.md", []byte("kept"), nil)
+
+	fail = false
+	assert.NoError(t, c.Migrate("v1/", "v2/", transform, 2))
+	assert.Equal(t, []string{
+		"v1/a.txt", "v1/b.txt", "v1/c.txt", "v1/d.txt", "v1/skip.txt", "v10.txt",
+		"v2/a.md", "v2/b.md", "v2/c.md", "v2/d.md",
+	}, b.keys())
+	assert.Equal(t, "kept", string(b.object("v2/a.md").body))
+	assert.Equal(t, "V1/D.TXT", string(b.object("v2/d.md").body))
+	assert.NotContains(t, b.keys(), ck)
+
+	h := b.object("v2/d.md").header
+	assert.Equal(t, "text/plain", h.Get("Content-Type"))
+	assert.Equal(t, "no-cache", h.Get("Cache-Control"))
+	assert.Equal(t, "core", h.Get("X-Amz-Meta-Owner"))
+	assert.Empty(t, h.Get("X-Amz-Meta-Sha256"))
+	assert.Equal(t, "team=core", h.Get("X-Amz-Tagging"))
+}
+
+func TestClient_Migrate_nested(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	b.put("docs/a.json", []byte(`{}`), nil)
+	b.put("docs/v2/a.json", []byte(`{"old":true}`), nil)
+
+	var seen []string
+	transform := func(k string, body []byte) (string, []byte, error) {
+		seen = append(seen, k)
+		return k, []byte(`{"v":2}`), nil
+	}
+	assert.NoError(t, c.Migrate("docs/", "docs/v2/", transform, 1))
+	assert.Equal(t, []string{"a.json"}, seen)
+	assert.Equal(t, `{"v":2}`, string(b.object("docs/v2/a.json").body))
+	assert.NotContains(t, b.keys(), migrateCheckpointKey("Migrate", "docs/", "docs/v2/\n"+funcName(transform)))
+}
@@ -0,0 +1,484 @@
+package s3
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// partSize is the size of the parts uploaded by an uploader, comfortably
+// above the 5 MiB minimum S3 requires for every part but the last.
+const partSize = 8 << 20
+
+// minPartSize is the smallest part S3 accepts for all but the last part.
+const minPartSize = 5 << 20
+
+// maxPartSize is the largest part S3 accepts.
+const maxPartSize = 5 << 30
+
+// partsPerGrowth is how many parts an uploader uploads before doubling their
+// size, so bodies up to the 5 TiB S3 allows fit in the 10,000 parts of a
+// multipart upload.
+var partsPerGrowth = 1000
+
+var errUploadClosed = errors.New("s3: write to closed upload")
+
+// uploader is an io.WriteCloser that streams writes to an object. Bodies
+// smaller than a part are uploaded with a single Put on Close, larger ones
+// as a multipart upload that is completed on Close. The SHA-256 and CRC32C
+// of the body are stored in its metadata. Bodies written to keys that are
+// validated or scanned are inspected as their parts upload, and staged
+// under .staging/ when refused content is quarantined.
+type uploader struct {
+	c         *client
+	in        *s3.PutObjectInput
+	buf       bytes.Buffer
+	uploadID  *string
+	parts     []types.CompletedPart
+	size      int64
+	digest    *digest
+	err       error
+	closed    bool
+	inspector *inspector
+	staging   string
+
+	partSize int
+	minFlush int
+	interval time.Duration
+	flushed  time.Time
+}
+
+func (c *client) newUploader(in *s3.PutObjectInput) *uploader {
+	in.Bucket = c.Bucket
+	return &uploader{c: c, in: in, digest: newDigest(), partSize: partSize, minFlush: minPartSize, flushed: time.Now()}
+}
+
+// key returns the key the parts are uploaded to: the staging key, if the
+// upload is staged, or the object's.
+func (u *uploader) key() *string {
+	if u.staging != "" {
+		return &u.staging
+	}
+	return u.in.Key
+}
+
+// WithWriterFlush sets when writers returned by NewWriter upload buffered
+// bytes as a part: once size bytes are buffered, and on the first write
+// after interval has passed since the last part when at least 5 MiB, the
+// smallest part S3 accepts, are buffered. Zero keeps the 8 MiB default size
+// and disables the interval. Both sizes double every 1,000 parts so writers
+// can stream objects up to the 5 TiB S3 allows.
+func WithWriterFlush(size int, interval time.Duration) Option {
+	if size != 0 && size < minPartSize {
+		panic(fmt.Sprintf("s3: writer flush size %d is below the 5 MiB minimum part size", size))
+	}
+	return func(o *options) {
+		o.writerPartSize = size
+		o.writerInterval = interval
+	}
+}
+
+// NewWriter returns a writer that streams to the object, buffering writes
+// into parts uploaded as a multipart upload as configured by WithWriterFlush,
+// and committing the object on Close. Objects smaller than a part are written
+// with a single Put. Nothing is written if Close isn't called or a write fails.
+func (c *client) NewWriter(k string) io.WriteCloser {
+	u := c.newUploader(&s3.PutObjectInput{Key: &k})
+	if ct := mime.TypeByExtension(path.Ext(k)); ct != "" {
+		u.in.ContentType = &ct
+	}
+	if c.writerPartSize > 0 {
+		u.partSize = c.writerPartSize
+	}
+	u.interval = c.writerInterval
+
+	c.log("NewWriter", nil).
+		Str("key", k).
+		Int("part", u.partSize).
+		Dur("interval", u.interval).
+		Msg("NewWriter")
+
+	return u
+}
+
+// Pipe returns a writer that streams to the object from a background upload,
+// for producers that can only write to an io.Writer, such as encoders,
+// compressors and command output. Closing the writer commits the object and
+// the upload's result is sent on the channel, which is then closed. Closing it
+// with (*io.PipeWriter).CloseWithError aborts the upload instead, and writes
+// fail once the upload has failed.
+func (c *client) Pipe(k string) (io.WriteCloser, <-chan error) {
+	pr, pw := io.Pipe()
+	errc := make(chan error, 1)
+	go func() {
+		u := c.NewWriter(k).(*uploader)
+		n, err := io.Copy(u, pr)
+		if err != nil {
+			u.CloseWithError(err)
+		} else {
+			err = u.Close()
+		}
+		pr.CloseWithError(err)
+
+		c.log("Pipe", err).
+			Str("key", k).
+			Int64("size", n).
+			Msg("Pipe")
+
+		errc <- err
+		close(errc)
+	}()
+	return pw, errc
+}
+
+func (u *uploader) Write(p []byte) (int, error) {
+	if u.closed {
+		return 0, errUploadClosed
+	}
+	if u.err != nil {
+		return 0, u.err
+	}
+	if u.err = u.c.checkPutSize(*u.in.Key, u.size+int64(u.buf.Len()+len(p))); u.err != nil {
+		return 0, u.err
+	}
+	n, _ := u.buf.Write(p)
+	u.digest.Write(p)
+	if u.inspector != nil {
+		u.inspector.Write(p)
+	}
+	for u.err == nil && u.buf.Len() >= u.partSize {
+		u.err = u.uploadPart(u.buf.Next(u.partSize))
+	}
+	if u.err == nil && u.interval > 0 && u.buf.Len() >= u.minFlush && time.Since(u.flushed) >= u.interval {
+		u.err = u.uploadPart(u.buf.Next(u.buf.Len()))
+	}
+	return n, u.err
+}
+
+// uploadPart uploads the next part, starting the multipart upload if needed.
+func (u *uploader) uploadPart(b []byte) error {
+	if u.uploadID == nil {
+		// bodies smaller than a part are inspected whole by putObject, larger
+		// ones from their first part, with the bytes written since
+		if u.inspector = u.c.newInspector(*u.in.Key); u.inspector != nil {
+			u.inspector.Write(b)
+			u.inspector.Write(u.buf.Bytes())
+			if u.c.quarantines(*u.in.Key) {
+				// refused content is copied to the quarantine from the staging key
+				u.staging = stagingKey(*u.in.Key)
+			}
+		}
+		out, err := u.c.CreateMultipartUpload(u.c.Context, &s3.CreateMultipartUploadInput{
+			Bucket:               u.in.Bucket,
+			Key:                  u.key(),
+			ContentType:          u.in.ContentType,
+			ContentEncoding:      u.in.ContentEncoding,
+			ContentDisposition:   u.in.ContentDisposition,
+			ContentLanguage:      u.in.ContentLanguage,
+			CacheControl:         u.in.CacheControl,
+			Metadata:             u.c.correlateMetadata(u.in.Metadata),
+			StorageClass:         u.in.StorageClass,
+			Tagging:              u.in.Tagging,
+			ServerSideEncryption: u.in.ServerSideEncryption,
+			SSEKMSKeyId:          u.in.SSEKMSKeyId,
+			BucketKeyEnabled:     u.in.BucketKeyEnabled,
+		})
+		if err != nil {
+			return err
+		}
+		u.uploadID = out.UploadId
+	}
+
+	n := int32(len(u.parts) + 1)
+	out, err := u.c.UploadPart(u.c.Context, &s3.UploadPartInput{
+		Bucket:        u.in.Bucket,
+		Key:           u.key(),
+		UploadId:      u.uploadID,
+		PartNumber:    &n,
+		Body:          bytes.NewReader(b),
+		ContentLength: aws.Int64(int64(len(b))),
+	})
+	if err != nil {
+		return err
+	}
+	u.parts = append(u.parts, types.CompletedPart{ETag: out.ETag, PartNumber: &n})
+	u.size += int64(len(b))
+	u.flushed = time.Now()
+	if len(u.parts)%partsPerGrowth == 0 {
+		u.partSize = min(2*u.partSize, maxPartSize)
+		u.minFlush = min(2*u.minFlush, maxPartSize)
+	}
+
+	u.c.log("UploadPart", nil).
+		Str("key", *u.in.Key).
+		Int32("part", n).
+		Int("size", len(b)).
+		Msg("UploadPart")
+
+	return nil
+}
+
+// Close uploads any buffered bytes and commits the object. If an earlier
+// write failed, or the body is refused once inspected, the multipart upload
+// is aborted and the error returned.
+func (u *uploader) Close() error {
+	if u.closed {
+		return u.err
+	}
+	u.closed = true
+
+	meta, err := u.digest.metadata(*u.in.Key, u.in.Metadata)
+	if u.err == nil {
+		u.err = err
+	}
+	if u.err == nil && u.uploadID == nil {
+		u.in.Metadata = meta
+		_, u.err = u.c.putObject(u.in, append([]byte{}, u.buf.Bytes()...))
+		return u.err
+	}
+	if u.err == nil && u.buf.Len() > 0 {
+		u.err = u.uploadPart(u.buf.Bytes())
+	}
+	var quarantine, threat string
+	if u.err == nil && u.inspector != nil {
+		quarantine, threat, u.err = u.inspector.finish()
+		u.inspector = nil
+	}
+	if u.err == nil || quarantine != "" {
+		// the multipart upload was created before the digests were known
+		var replace *s3.PutObjectInput
+		if u.err == nil && u.in.Metadata[sha256Metadata] == "" {
+			in := *u.in
+			in.Key = u.key()
+			in.Metadata = meta
+			replace = &in
+		}
+		err = u.c.completeMultipartUpload(&s3.CompleteMultipartUploadInput{
+			Bucket:          u.in.Bucket,
+			Key:             u.key(),
+			UploadId:        u.uploadID,
+			MultipartUpload: &types.CompletedMultipartUpload{Parts: u.parts},
+		}, u.size, replace)
+		if err == nil {
+			if u.staging != "" {
+				err = u.promote(quarantine, threat)
+			}
+			u.err = errors.Join(u.err, err)
+			return u.err
+		}
+		u.err = errors.Join(u.err, err)
+	}
+	u.abort()
+	return u.err
+}
+
+// promote copies the staged upload to the object, or to the quarantine when
+// a scanner found a threat in it, and deletes it.
+func (u *uploader) promote(quarantine, threat string) error {
+	var err error
+	if quarantine != "" {
+		err = u.c.quarantineObject(u.staging, nil, quarantine+*u.in.Key, threat)
+	} else {
+		// the body was inspected as it was uploaded
+		_, err = u.c.copy(u.staging, *u.in.Key, false)
+	}
+	_, derr := u.c.deleteObject(&s3.DeleteObjectInput{Bucket: u.c.Bucket, Key: &u.staging})
+	return errors.Join(err, derr)
+}
+
+// CloseWithError aborts the upload so no object is written.
+func (u *uploader) CloseWithError(err error) error {
+	if !u.closed {
+		u.closed = true
+		u.err = err
+		u.abort()
+	}
+	return err
+}
+
+func (u *uploader) abort() {
+	u.abortInspection()
+	if u.uploadID != nil {
+		u.c.abortMultipartUpload(*u.key(), u.uploadID)
+	}
+}
+
+// abortInspection stops inspecting a body that won't be uploaded in parts.
+func (u *uploader) abortInspection() {
+	if u.inspector != nil {
+		u.inspector.abort()
+		u.inspector = nil
+	}
+}
+
+// abortMultipartUpload aborts the upload so its parts are deleted.
+func (c *client) abortMultipartUpload(k string, uploadID *string) error {
+	_, err := c.AbortMultipartUpload(c.Context, &s3.AbortMultipartUploadInput{
+		Bucket:   c.Bucket,
+		Key:      &k,
+		UploadId: uploadID,
+	})
+
+	c.log("AbortMultipartUpload", err).
+		Str("key", k).
+		Msg("AbortMultipartUpload")
+
+	return err
+}
+
+// tagging returns the object's tags URL encoded as uploads take them, or nil
+// if it has none.
+func (c *client) tagging(k string) (*string, error) {
+	tags, err := c.GetObjectTagging(c.Context, &s3.GetObjectTaggingInput{
+		Bucket: c.Bucket,
+		Key:    &k,
+	})
+	if err != nil || len(tags.TagSet) == 0 {
+		return nil, err
+	}
+	v := url.Values{}
+	for _, t := range tags.TagSet {
+		v.Set(aws.ToString(t.Key), aws.ToString(t.Value))
+	}
+	return aws.String(v.Encode()), nil
+}
+
+// copyPartSize is the smallest part copied by multipartCopy. Larger objects
+// use larger parts to stay within the 10,000 parts S3 allows.
+var copyPartSize int64 = 512 << 20
+
+// copyWorkers is how many parts multipartCopy copies concurrently.
+const copyWorkers = 8
+
+// multipartCopy copies the object described by head with a multipart upload
+// of ranged part copies, keeping its headers, metadata, tags and encryption.
+// Every part is copied from the same version, the one head describes when it
+// has a version ID, so the copy fails if src is replaced midway. Callers
+// such as ReEncrypt pass opts to adjust the upload before it is created.
+func (c *client) multipartCopy(src, dst string, head *s3.HeadObjectOutput, opts ...func(*s3.CreateMultipartUploadInput)) error {
+	uploadID, parts, err := c.copyParts(src, dst, head, opts...)
+	if err == nil {
+		err = c.completeMultipartUpload(&s3.CompleteMultipartUploadInput{
+			Bucket:          c.Bucket,
+			Key:             &dst,
+			UploadId:        uploadID,
+			MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+		}, aws.ToInt64(head.ContentLength), nil)
+		if err != nil {
+			c.abortMultipartUpload(dst, uploadID)
+		}
+	}
+
+	c.log("MultipartCopy", err).
+		Str("src", src).
+		Str("dst", dst).
+		Int("parts", len(parts)).
+		Msg("MultipartCopy")
+
+	return err
+}
+
+// copyParts starts a multipart upload to dst and copies the object described
+// by head into its parts, returning the upload to complete. The opts may
+// change the bucket uploaded to, e.g. to a mirror's. The upload is aborted if
+// a part fails to copy.
+func (c *client) copyParts(src, dst string, head *s3.HeadObjectOutput, opts ...func(*s3.CreateMultipartUploadInput)) (*string, []types.CompletedPart, error) {
+	size := aws.ToInt64(head.ContentLength)
+	ps := max(copyPartSize, (size+9999)/10000)
+
+	tagging, err := c.tagging(src)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	in := &s3.CreateMultipartUploadInput{
+		Bucket:               c.Bucket,
+		Key:                  &dst,
+		ContentType:          head.ContentType,
+		ContentEncoding:      head.ContentEncoding,
+		ContentDisposition:   head.ContentDisposition,
+		ContentLanguage:      head.ContentLanguage,
+		CacheControl:         head.CacheControl,
+		Metadata:             head.Metadata,
+		StorageClass:         head.StorageClass,
+		Tagging:              tagging,
+		ServerSideEncryption: head.ServerSideEncryption,
+		SSEKMSKeyId:          head.SSEKMSKeyId,
+		BucketKeyEnabled:     head.BucketKeyEnabled,
+	}
+	for _, opt := range opts {
+		opt(in)
+	}
+	out, err := c.CreateMultipartUpload(c.Context, in)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	source := c.copySource(src)
+	if v := aws.ToString(head.VersionId); v != "" {
+		source += "?versionId=" + url.QueryEscape(v)
+	}
+
+	parts := make([]types.CompletedPart, (size+ps-1)/ps)
+	numbers := make(chan int32)
+	var werr error
+	var once sync.Once
+	done := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for range min(copyWorkers, len(parts)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range numbers {
+				off := int64(n-1) * ps
+				part, err := c.UploadPartCopy(c.Context, &s3.UploadPartCopyInput{
+					Bucket:            in.Bucket,
+					Key:               &dst,
+					UploadId:          out.UploadId,
+					PartNumber:        &n,
+					CopySource:        &source,
+					CopySourceIfMatch: head.ETag,
+					CopySourceRange:   aws.String(fmt.Sprintf("bytes=%d-%d", off, min(off+ps, size)-1)),
+				})
+				if err != nil {
+					once.Do(func() {
+						werr = err
+						close(done)
+					})
+					continue
+				}
+				parts[n-1] = types.CompletedPart{ETag: part.CopyPartResult.ETag, PartNumber: &n}
+			}
+		}()
+	}
+
+feed:
+	for n := range int32(len(parts)) {
+		select {
+		case numbers <- n + 1:
+		case <-done:
+			break feed
+		}
+	}
+	close(numbers)
+	wg.Wait()
+
+	if werr != nil {
+		dc := *c
+		dc.Bucket = in.Bucket
+		dc.abortMultipartUpload(dst, out.UploadId)
+		return nil, nil, werr
+	}
+	return out.UploadId, parts, nil
+}
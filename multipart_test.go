@@ -0,0 +1,127 @@
+package s3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_NewWriter(t *testing.T) {
+
+	c, b := newTestBucket(t)
+
+	w := c.NewWriter("small.json")
+	_, err := w.Write([]byte(`{}`))
+	assert.NoError(t, err)
+	assert.Nil(t, b.object("small.json"))
+	assert.NoError(t, w.Close())
+	assert.Equal(t, `{}`, string(b.object("small.json").body))
+	assert.Equal(t, "application/json", b.object("small.json").header.Get("Content-Type"))
+
+	// parts are uploaded once the size is buffered
+	WithWriterFlush(minPartSize, 0)(c.options)
+	body := bytes.Repeat([]byte{'a'}, minPartSize+1024)
+	w = c.NewWriter("large")
+	_, err = w.Write(body)
+	assert.NoError(t, err)
+	assert.Len(t, w.(*uploader).parts, 1)
+	assert.NoError(t, w.Close())
+	assert.Equal(t, body, b.object("large").body)
+
+	// or once the interval has passed
+	WithWriterFlush(0, time.Millisecond)(c.options)
+	w = c.NewWriter("timed")
+	time.Sleep(2 * time.Millisecond)
+	_, err = w.Write(body[:minPartSize])
+	assert.NoError(t, err)
+	assert.Len(t, w.(*uploader).parts, 1)
+	_, err = w.Write(body[minPartSize:])
+	assert.NoError(t, err)
+	assert.Len(t, w.(*uploader).parts, 1)
+	assert.NoError(t, w.Close())
+	assert.Equal(t, body, b.object("timed").body)
+
+	assert.Panics(t, func() { WithWriterFlush(1024, 0) })
+}
+
+func TestClient_NewWriter_partGrowth(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	defer func(n int) { partsPerGrowth = n }(partsPerGrowth)
+	partsPerGrowth = 1
+
+	// parts double in size as they accumulate
+	body := bytes.Repeat([]byte{'a'}, 3*partSize+1)
+	w := c.NewWriter("large")
+	_, err := w.Write(body)
+	assert.NoError(t, err)
+	assert.Len(t, w.(*uploader).parts, 2)
+	assert.Equal(t, 4*partSize, w.(*uploader).partSize)
+	assert.Equal(t, 4*minPartSize, w.(*uploader).minFlush)
+	assert.NoError(t, w.Close())
+	assert.Equal(t, body, b.object("large").body)
+}
+
+func TestClient_Pipe(t *testing.T) {
+
+	c, b := newTestBucket(t)
+
+	w, errc := c.Pipe("piped.gz")
+	zw := gzip.NewWriter(w)
+	_, err := zw.Write([]byte("hello"))
+	assert.NoError(t, err)
+	assert.NoError(t, zw.Close())
+	assert.NoError(t, w.Close())
+	assert.NoError(t, <-errc)
+
+	zr, err := gzip.NewReader(bytes.NewReader(b.object("piped.gz").body))
+	assert.NoError(t, err)
+	out, err := io.ReadAll(zr)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(out))
+
+	// aborting writes nothing
+	w, errc = c.Pipe("aborted")
+	_, err = w.Write([]byte("partial"))
+	assert.NoError(t, err)
+	w.(*io.PipeWriter).CloseWithError(io.ErrUnexpectedEOF)
+	assert.ErrorIs(t, <-errc, io.ErrUnexpectedEOF)
+	assert.Nil(t, b.object("aborted"))
+
+	// writes fail once the upload has failed
+	WithMaxPutSize(4)(c.options)
+	w, errc = c.Pipe("large")
+	_, _ = w.Write([]byte("too large"))
+	assert.ErrorIs(t, <-errc, ErrPutTooLarge)
+	_, err = w.Write([]byte("more"))
+	assert.ErrorIs(t, err, ErrPutTooLarge)
+}
+
+func TestClient_Copy_Multipart(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	defer func(size, part int64) { maxCopySize, copyPartSize = size, part }(maxCopySize, copyPartSize)
+	maxCopySize, copyPartSize = 16, 10
+
+	b.put("small.txt", []byte("0123456789"), nil)
+	assert.NoError(t, c.Copy("small.txt", "small-copy.txt"))
+	assert.Equal(t, b.object("small.txt").etag, b.object("small-copy.txt").etag)
+
+	body := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+	b.put("large.txt", body, http.Header{"Content-Type": {"text/plain"}, "X-Amz-Meta-Owner": {"a"}, "X-Amz-Tagging": {"ttl-days=1"}})
+	assert.NoError(t, c.Copy("large.txt", "large-copy.txt"))
+	o := b.object("large-copy.txt")
+	assert.Equal(t, body, o.body)
+	assert.True(t, strings.HasSuffix(o.etag, `-4"`))
+	assert.Equal(t, "text/plain", o.header.Get("Content-Type"))
+	assert.Equal(t, "a", o.header.Get("X-Amz-Meta-Owner"))
+	assert.Equal(t, "ttl-days=1", o.header.Get("X-Amz-Tagging"))
+
+	assert.True(t, IsNotFound(c.Copy("missing", "missing-copy")))
+}
@@ -0,0 +1,174 @@
+package s3
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// staged reports whether the key is an object staged under .staging/ to be
+// promoted, whose writes aren't audited, mirrored or passed to hooks as
+// only their promotion writes the object.
+func staged(k string) bool {
+	return strings.HasPrefix(k, stagingPrefix)
+}
+
+// putObject uploads an object on behalf of any write made through the client
+// so every mutation is recorded and observed consistently. A non-nil body
+// becomes the request body, otherwise in.Body is streamed as-is.
+func (c *client) putObject(in *s3.PutObjectInput, body []byte) (*s3.PutObjectOutput, error) {
+	c.cache.remove(*in.Key)
+	in.Metadata = c.correlateMetadata(in.Metadata)
+	if body != nil {
+		in.Body = bytes.NewReader(body)
+		in.ContentLength = aws.Int64(int64(len(body)))
+	}
+	if err := c.checkPutSize(*in.Key, aws.ToInt64(in.ContentLength)); err != nil {
+		return nil, err
+	}
+	if err := c.inspect(*in.Key, body); err != nil {
+		return nil, err
+	}
+	release, err := c.reserve(*in.Key, aws.ToInt64(in.ContentLength))
+	if err != nil {
+		return nil, err
+	}
+	out, err := c.PutObject(c.Context, in)
+	c.negativeCache.remove(*in.Key)
+	if err != nil {
+		release()
+		return out, err
+	}
+	if staged(*in.Key) {
+		return out, nil
+	}
+	err = c.record("Put", *in.Key, out.ETag, aws.ToInt64(in.ContentLength))
+	if err == nil {
+		err = c.afterPut(HookEvent{*in.Key, aws.ToString(out.ETag), body})
+	}
+	if err == nil {
+		err = c.mirror("Put", *in.Key)
+	}
+	return out, err
+}
+
+// checkPutSize refuses writes larger than the maximum put size.
+func (c *client) checkPutSize(k string, size int64) error {
+	if c.maxPutSize > 0 && size > c.maxPutSize {
+		return fmt.Errorf("%w: %s is %d bytes, limit %d", ErrPutTooLarge, k, size, c.maxPutSize)
+	}
+	return nil
+}
+
+// completeMultipartUpload commits a multipart upload of size bytes on
+// behalf of any streamed write made through the client. A non-nil replace
+// holds headers to replace the uploaded object's with once it is complete.
+func (c *client) completeMultipartUpload(in *s3.CompleteMultipartUploadInput, size int64, replace *s3.PutObjectInput) error {
+	c.cache.remove(*in.Key)
+	release, err := c.reserve(*in.Key, size)
+	if err != nil {
+		return err
+	}
+	out, err := c.CompleteMultipartUpload(c.Context, in)
+	c.negativeCache.remove(*in.Key)
+	var etag *string
+	if err != nil {
+		release()
+		return err
+	}
+	etag = out.ETag
+	if replace != nil {
+		if etag, err = c.replaceMetadata(replace, out, size); err != nil {
+			return err
+		}
+	}
+	if staged(*in.Key) {
+		return nil
+	}
+	err = c.record("Put", *in.Key, etag, size)
+	if err == nil {
+		err = c.afterPut(HookEvent{Key: *in.Key, ETag: aws.ToString(etag)})
+	}
+	if err == nil {
+		err = c.mirror("Put", *in.Key)
+	}
+	return err
+}
+
+// deleteObject deletes an object on behalf of any delete made through the
+// client. Deleting a version with in.VersionId may leave an earlier version
+// current, so it isn't reported to the AfterDelete hooks or taken off the
+// quotas, and the mirror is brought in line with whichever version is current.
+func (c *client) deleteObject(in *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error) {
+	c.cache.remove(*in.Key)
+	unreserve := func() {}
+	if in.VersionId == nil {
+		var err error
+		if unreserve, err = c.unreserve(*in.Key); err != nil {
+			return nil, err
+		}
+	}
+	out, err := c.DeleteObject(c.Context, in)
+	c.negativeCache.remove(*in.Key)
+	if err != nil {
+		return out, err
+	}
+	unreserve()
+	if staged(*in.Key) {
+		return out, nil
+	}
+	op := "Delete"
+	if in.VersionId != nil {
+		op = "DeleteVersion"
+	}
+	err = c.record(op, *in.Key, nil, 0)
+	if err == nil && in.VersionId == nil {
+		err = c.afterDelete(HookEvent{Key: *in.Key})
+	}
+	if err == nil {
+		err = c.mirror(op, *in.Key)
+	}
+	return out, err
+}
+
+// copyObject copies an object of size bytes on behalf of any copy made
+// through the client, reserving quota for it.
+func (c *client) copyObject(in *s3.CopyObjectInput, size int64) (*s3.CopyObjectOutput, error) {
+	c.cache.remove(*in.Key)
+	release, err := c.reserve(*in.Key, size)
+	if err != nil {
+		return nil, err
+	}
+	out, err := c.CopyObject(c.Context, in)
+	c.negativeCache.remove(*in.Key)
+	if err != nil {
+		release()
+	} else if !staged(*in.Key) {
+		var etag *string
+		if out.CopyObjectResult != nil {
+			etag = out.CopyObjectResult.ETag
+		}
+		err = c.record("Copy", *in.Key, etag, size)
+		if err == nil {
+			err = c.afterPut(HookEvent{Key: *in.Key, ETag: aws.ToString(etag)})
+		}
+		if err == nil {
+			err = c.mirror("Put", *in.Key)
+		}
+	}
+	return out, err
+}
+
+// copySource returns the URL encoded CopySource of a key in the client's
+// bucket, which takes the object form for access point ARNs.
+func (c *client) copySource(k string) string {
+	if arn.IsARN(*c.Bucket) {
+		return *c.Bucket + "/object/" + url.PathEscape(k)
+	}
+	return *c.Bucket + "/" + url.PathEscape(k)
+}
@@ -0,0 +1,80 @@
+package s3
+
+import (
+	"sync"
+	"time"
+)
+
+// negativeCache remembers keys that were not found for ttl.
+type negativeCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	missing map[string]missingKey
+	purgeAt int
+}
+
+type missingKey struct {
+	err error
+	at  time.Time
+}
+
+// WithNegativeCache remembers that a key was not found for ttl, returning the
+// same NotFound error from Get, GetReader and Stat without a request, so loops
+// polling for keys that other processes haven't written yet don't flood the
+// bucket. Writes made through the client forget the key immediately; writes
+// made elsewhere are seen once ttl has passed.
+func WithNegativeCache(ttl time.Duration) Option {
+	return func(o *options) {
+		o.negativeCache = &negativeCache{ttl: ttl, missing: map[string]missingKey{}, purgeAt: 1024}
+	}
+}
+
+// get returns the NotFound error of the key if it was missing within ttl.
+// A nil cache caches nothing.
+func (n *negativeCache) get(k string) error {
+	if n == nil {
+		return nil
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	m, ok := n.missing[k]
+	if !ok {
+		return nil
+	}
+	if time.Since(m.at) >= n.ttl {
+		delete(n.missing, k)
+		return nil
+	}
+	return m.err
+}
+
+// add remembers the key when err is NotFound, purging expired keys as the
+// cache grows.
+func (n *negativeCache) add(k string, err error) {
+	if n == nil || !IsNotFound(err) {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.missing[k] = missingKey{err, time.Now()}
+	if len(n.missing) < n.purgeAt {
+		return
+	}
+	for k, m := range n.missing {
+		if time.Since(m.at) >= n.ttl {
+			delete(n.missing, k)
+		}
+	}
+	n.purgeAt = max(2*len(n.missing), 1024)
+}
+
+// remove forgets the key.
+func (n *negativeCache) remove(k string) {
+	if n == nil {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.missing, k)
+}
@@ -0,0 +1,50 @@
+package s3
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithNegativeCache(t *testing.T) {
+
+	var requests int
+	c, b := newTestBucket(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			next.ServeHTTP(w, r)
+		})
+	})
+	WithNegativeCache(time.Hour)(c.options)
+
+	for range 3 {
+		_, err := c.Get("a")
+		assert.True(t, IsNotFound(err))
+		_, err = c.GetReader("a")
+		assert.True(t, IsNotFound(err))
+	}
+	assert.Equal(t, 1, requests)
+
+	for range 3 {
+		_, err := c.Stat("b")
+		assert.True(t, IsNotFound(err))
+	}
+	assert.Equal(t, 2, requests)
+
+	// writes made elsewhere are seen once the ttl has passed
+	b.put("b", []byte("2"), nil)
+	_, err := c.Stat("b")
+	assert.True(t, IsNotFound(err))
+	c.negativeCache.ttl = 0
+	_, err = c.Stat("b")
+	assert.NoError(t, err)
+
+	// writes made through the client are seen immediately
+	c.negativeCache.ttl = time.Hour
+	assert.NoError(t, c.Put("a", "1"))
+	out, err := c.Get("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", string(out))
+}
@@ -0,0 +1,361 @@
+package s3
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// EventType is the category of an S3 event notification.
+type EventType string
+
+const (
+	ObjectCreated EventType = "ObjectCreated"
+	ObjectRemoved EventType = "ObjectRemoved"
+)
+
+// Event is an S3 event notification decoded from an SQS message.
+type Event struct {
+	Type      EventType
+	Name      string
+	Time      time.Time
+	Bucket    string
+	Key       string
+	Size      int64
+	ETag      string
+	VersionID string
+	Sequencer string
+}
+
+// Handler processes an S3 event notification.
+type Handler func(Event) error
+
+type sqsAPI interface {
+	ReceiveMessage(context.Context, *sqs.ReceiveMessageInput, ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error)
+	DeleteMessage(context.Context, *sqs.DeleteMessageInput, ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error)
+	SendMessage(context.Context, *sqs.SendMessageInput, ...func(*sqs.Options)) (*sqs.SendMessageOutput, error)
+}
+
+// Notifications consumes S3 event notifications delivered to an SQS queue,
+// either directly or through an SNS topic, and dispatches them to handlers.
+type Notifications struct {
+	queueURL   string
+	deadLetter string
+	retries    int
+	backoff    time.Duration
+	sqs        sqsAPI
+	client     *client
+
+	mu       sync.RWMutex
+	handlers map[EventType][]Handler
+}
+
+// NotificationOption configures Notifications.
+type NotificationOption func(*Notifications)
+
+// WithRetries sets how many times a failed handler is retried, waiting
+// backoff multiplied by the attempt number between attempts. Transient
+// failures to receive messages are retried with the same backoff for as
+// long as they last.
+func WithRetries(retries int, backoff time.Duration) NotificationOption {
+	return func(n *Notifications) {
+		n.retries = retries
+		n.backoff = backoff
+	}
+}
+
+// WithDeadLetterQueue forwards messages whose handlers still fail after all
+// retries to the given queue instead of leaving them for redelivery.
+func WithDeadLetterQueue(queueURL string) NotificationOption {
+	return func(n *Notifications) {
+		n.deadLetter = queueURL
+	}
+}
+
+// Notifications returns a consumer of the S3 event notifications sent to the
+// queue, using the same AWS configuration as the client.
+func (c *client) Notifications(queueURL string, opts ...NotificationOption) *Notifications {
+	n := &Notifications{
+		queueURL: queueURL,
+		retries:  3,
+		backoff:  time.Second,
+		sqs:      sqs.NewFromConfig(c.cfg),
+		client:   c,
+		handlers: map[EventType][]Handler{},
+	}
+	for _, opt := range opts {
+		opt(n)
+	}
+	return n
+}
+
+// On registers a handler for events of the given type.
+func (n *Notifications) On(t EventType, h Handler) *Notifications {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.handlers[t] = append(n.handlers[t], h)
+	return n
+}
+
+// OnCreated registers a handler for ObjectCreated events.
+func (n *Notifications) OnCreated(h Handler) *Notifications {
+	return n.On(ObjectCreated, h)
+}
+
+// OnRemoved registers a handler for ObjectRemoved events.
+func (n *Notifications) OnRemoved(h Handler) *Notifications {
+	return n.On(ObjectRemoved, h)
+}
+
+// Run long-polls the queue and dispatches events until ctx is done. Transient
+// errors receiving messages, e.g. throttling or a dropped connection, are
+// retried; others are returned.
+func (n *Notifications) Run(ctx context.Context) error {
+	for attempt := 0; ; {
+		out, err := n.sqs.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            &n.queueURL,
+			MaxNumberOfMessages: 10,
+			WaitTimeSeconds:     20,
+		})
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil && !isTransient(err) {
+			return err
+		}
+		if err != nil {
+			attempt++
+			n.client.warn().Err(err).Int("attempt", attempt).Str("queue", n.queueURL).Msg("Notifications")
+			if !sleep(ctx, n.backoff*time.Duration(attempt)) {
+				return nil
+			}
+			continue
+		}
+		attempt = 0
+		for _, m := range out.Messages {
+			n.process(ctx, m)
+		}
+	}
+}
+
+// process dispatches the events in m and deletes it once handled. Messages
+// that still fail are dead-lettered, or left on the queue for redelivery.
+func (n *Notifications) process(ctx context.Context, m types.Message) {
+
+	events, err := decodeEvents([]byte(aws.ToString(m.Body)))
+	for i := 0; err == nil && i < len(events); i++ {
+		err = n.dispatch(ctx, events[i])
+	}
+
+	var dead bool
+	if err != nil && n.deadLetter != "" {
+		_, err = n.sqs.SendMessage(ctx, &sqs.SendMessageInput{
+			QueueUrl:    &n.deadLetter,
+			MessageBody: m.Body,
+		})
+		dead = err == nil
+	}
+	if err == nil {
+		_, err = n.sqs.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+			QueueUrl:      &n.queueURL,
+			ReceiptHandle: m.ReceiptHandle,
+		})
+	}
+
+	n.client.log("Notification", err).
+		Str("id", aws.ToString(m.MessageId)).
+		Int("events", len(events)).
+		Bool("dead", dead).
+		Msg("Notification")
+}
+
+func (n *Notifications) dispatch(ctx context.Context, e Event) error {
+	n.mu.RLock()
+	handlers := n.handlers[e.Type]
+	n.mu.RUnlock()
+
+	for _, h := range handlers {
+		err := h(e)
+		for attempt := 1; err != nil && attempt <= n.retries; attempt++ {
+			if !sleep(ctx, n.backoff*time.Duration(attempt)) {
+				return ctx.Err()
+			}
+			err = h(e)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type eventRecords struct {
+	Event   string `json:"Event"`
+	Message string `json:"Message"`
+	Records []struct {
+		EventName string    `json:"eventName"`
+		EventTime time.Time `json:"eventTime"`
+		S3        struct {
+			Bucket struct {
+				Name string `json:"name"`
+			} `json:"bucket"`
+			Object struct {
+				Key       string `json:"key"`
+				Size      int64  `json:"size"`
+				ETag      string `json:"eTag"`
+				VersionID string `json:"versionId"`
+				Sequencer string `json:"sequencer"`
+			} `json:"object"`
+		} `json:"s3"`
+	} `json:"Records"`
+}
+
+// decodeEvents decodes an S3 event notification message body, unwrapping
+// SNS envelopes. The s3:TestEvent sent on configuration yields no events.
+func decodeEvents(b []byte) ([]Event, error) {
+	var r eventRecords
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, err
+	}
+	if r.Message != "" {
+		return decodeEvents([]byte(r.Message))
+	}
+	if r.Event == "s3:TestEvent" {
+		return nil, nil
+	}
+	if r.Records == nil {
+		return nil, errors.New("s3: message is not an S3 event notification")
+	}
+
+	var events []Event
+	for _, rec := range r.Records {
+		k, err := url.QueryUnescape(rec.S3.Object.Key)
+		if err != nil {
+			return nil, err
+		}
+		t, _, _ := strings.Cut(rec.EventName, ":")
+		events = append(events, Event{
+			Type:      EventType(t),
+			Name:      rec.EventName,
+			Time:      rec.EventTime,
+			Bucket:    rec.S3.Bucket.Name,
+			Key:       k,
+			Size:      rec.S3.Object.Size,
+			ETag:      rec.S3.Object.ETag,
+			VersionID: rec.S3.Object.VersionID,
+			Sequencer: rec.S3.Object.Sequencer,
+		})
+	}
+	return events, nil
+}
+
+// NotificationTarget is a destination for the bucket's event notifications.
+// The destination kind is taken from the service of the SQS queue, SNS topic
+// or Lambda function ARN, unless EventBridge is set, which delivers every
+// event to Amazon EventBridge and ignores the remaining fields.
+type NotificationTarget struct {
+	ARN         string
+	Events      []string
+	Prefix      string
+	Suffix      string
+	EventBridge bool
+}
+
+// ConfigureNotifications replaces the bucket's notification configuration
+// with the targets. Targets without events receive s3:ObjectCreated:* and
+// s3:ObjectRemoved:* notifications.
+func (c *client) ConfigureNotifications(targets ...NotificationTarget) error {
+
+	cfg, err := notificationConfiguration(targets)
+	if err == nil {
+		_, err = c.PutBucketNotificationConfiguration(c.Context, &s3.PutBucketNotificationConfigurationInput{
+			Bucket:                    c.Bucket,
+			NotificationConfiguration: cfg,
+		})
+	}
+
+	c.log("ConfigureNotifications", err).
+		Int("targets", len(targets)).
+		Msg("ConfigureNotifications")
+
+	return err
+}
+
+func notificationConfiguration(targets []NotificationTarget) (*s3types.NotificationConfiguration, error) {
+	cfg := &s3types.NotificationConfiguration{}
+	for _, t := range targets {
+		if t.EventBridge {
+			cfg.EventBridgeConfiguration = &s3types.EventBridgeConfiguration{}
+			continue
+		}
+
+		a, err := arn.Parse(t.ARN)
+		if err != nil {
+			return nil, err
+		}
+
+		events := []s3types.Event{"s3:ObjectCreated:*", "s3:ObjectRemoved:*"}
+		if len(t.Events) > 0 {
+			events = nil
+			for _, e := range t.Events {
+				events = append(events, s3types.Event(e))
+			}
+		}
+
+		var filter *s3types.NotificationConfigurationFilter
+		if t.Prefix != "" || t.Suffix != "" {
+			filter = &s3types.NotificationConfigurationFilter{Key: &s3types.S3KeyFilter{}}
+			if t.Prefix != "" {
+				filter.Key.FilterRules = append(filter.Key.FilterRules, s3types.FilterRule{
+					Name: s3types.FilterRuleNamePrefix, Value: aws.String(t.Prefix),
+				})
+			}
+			if t.Suffix != "" {
+				filter.Key.FilterRules = append(filter.Key.FilterRules, s3types.FilterRule{
+					Name: s3types.FilterRuleNameSuffix, Value: aws.String(t.Suffix),
+				})
+			}
+		}
+
+		switch a.Service {
+		case "sqs":
+			cfg.QueueConfigurations = append(cfg.QueueConfigurations, s3types.QueueConfiguration{
+				QueueArn: aws.String(t.ARN), Events: events, Filter: filter,
+			})
+		case "sns":
+			cfg.TopicConfigurations = append(cfg.TopicConfigurations, s3types.TopicConfiguration{
+				TopicArn: aws.String(t.ARN), Events: events, Filter: filter,
+			})
+		case "lambda":
+			cfg.LambdaFunctionConfigurations = append(cfg.LambdaFunctionConfigurations, s3types.LambdaFunctionConfiguration{
+				LambdaFunctionArn: aws.String(t.ARN), Events: events, Filter: filter,
+			})
+		default:
+			return nil, errors.New("s3: unsupported notification target: " + t.ARN)
+		}
+	}
+	return cfg, nil
+}
+
+// sleep waits for d, reporting false if ctx is done first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
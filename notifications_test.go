@@ -0,0 +1,126 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+)
+
+const testEvent = `{"Records":[{"eventName":"ObjectCreated:Put","eventTime":"2025-09-04T17:00:00.000Z",
+"s3":{"bucket":{"name":"bytelyon-db"},"object":{"key":"users/a+b%2Cc.json","size":35,"eTag":"abc","sequencer":"01"}}}]}`
+
+type testSQS struct {
+	deleted, sent []string
+	receive       []error
+	received      int
+}
+
+func (q *testSQS) ReceiveMessage(context.Context, *sqs.ReceiveMessageInput, ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
+	q.received++
+	if len(q.receive) > 0 {
+		err := q.receive[0]
+		q.receive = q.receive[1:]
+		return nil, err
+	}
+	return &sqs.ReceiveMessageOutput{}, nil
+}
+
+func (q *testSQS) DeleteMessage(_ context.Context, in *sqs.DeleteMessageInput, _ ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error) {
+	q.deleted = append(q.deleted, *in.ReceiptHandle)
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+func (q *testSQS) SendMessage(_ context.Context, in *sqs.SendMessageInput, _ ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+	q.sent = append(q.sent, *in.QueueUrl)
+	return &sqs.SendMessageOutput{}, nil
+}
+
+func TestDecodeEvents(t *testing.T) {
+
+	events, err := decodeEvents([]byte(testEvent))
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+	assert.Equal(t, ObjectCreated, events[0].Type)
+	assert.Equal(t, "users/a b,c.json", events[0].Key)
+	assert.Equal(t, int64(35), events[0].Size)
+
+	events, err = decodeEvents([]byte(`{"Type":"Notification","Message":` + `"{\"Event\":\"s3:TestEvent\"}"}`))
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+
+	_, err = decodeEvents([]byte(`{}`))
+	assert.Error(t, err)
+}
+
+func TestNotifications_process(t *testing.T) {
+
+	q := &testSQS{}
+	n := &Notifications{queueURL: "queue", sqs: q, client: &client{}, retries: 1, handlers: map[EventType][]Handler{}}
+
+	var created []Event
+	n.OnCreated(func(e Event) error {
+		created = append(created, e)
+		return nil
+	})
+	n.process(context.Background(), types.Message{Body: aws.String(testEvent), ReceiptHandle: aws.String("1")})
+	assert.Len(t, created, 1)
+	assert.Equal(t, []string{"1"}, q.deleted)
+
+	var attempts int
+	n.OnCreated(func(e Event) error {
+		attempts++
+		return errors.New("boom")
+	})
+	n.process(context.Background(), types.Message{Body: aws.String(testEvent), ReceiptHandle: aws.String("2")})
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, []string{"1"}, q.deleted)
+
+	WithDeadLetterQueue("dlq")(n)
+	n.process(context.Background(), types.Message{Body: aws.String(testEvent), ReceiptHandle: aws.String("3")})
+	assert.Equal(t, []string{"dlq"}, q.sent)
+	assert.Equal(t, []string{"1", "3"}, q.deleted)
+}
+
+func TestNotifications_Run(t *testing.T) {
+
+	// transient errors are retried, others returned
+	boom := errors.New("boom")
+	q := &testSQS{receive: []error{&smithy.GenericAPIError{Code: "ThrottlingException"}, boom}}
+	n := &Notifications{queueURL: "queue", sqs: q, client: &client{}, backoff: time.Millisecond, handlers: map[EventType][]Handler{}}
+	assert.ErrorIs(t, n.Run(context.Background()), boom)
+	assert.Equal(t, 2, q.received)
+
+	// retries stop with the context
+	q = &testSQS{receive: []error{&smithy.GenericAPIError{Code: "ThrottlingException"}}}
+	n.sqs, n.backoff = q, time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.NoError(t, n.Run(ctx))
+	assert.Equal(t, 1, q.received)
+}
+
+func TestNotificationConfiguration(t *testing.T) {
+
+	cfg, err := notificationConfiguration([]NotificationTarget{
+		{ARN: "arn:aws:sqs:us-east-1:123456789012:events", Prefix: "users/", Suffix: ".json"},
+		{ARN: "arn:aws:sns:us-east-1:123456789012:events", Events: []string{"s3:ObjectRemoved:*"}},
+		{EventBridge: true},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, cfg.QueueConfigurations, 1)
+	assert.Len(t, cfg.QueueConfigurations[0].Events, 2)
+	assert.Len(t, cfg.QueueConfigurations[0].Filter.Key.FilterRules, 2)
+	assert.Len(t, cfg.TopicConfigurations, 1)
+	assert.Nil(t, cfg.TopicConfigurations[0].Filter)
+	assert.NotNil(t, cfg.EventBridgeConfiguration)
+
+	_, err = notificationConfiguration([]NotificationTarget{{ARN: "arn:aws:dynamodb:us-east-1:123456789012:table/t"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,29 @@
+package s3
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+// WithObjectLambda reads objects through the S3 Object Lambda access point
+// with the ARN, so Get, GetReader and Find return them as transformed by its
+// function, e.g. with fields redacted or converted to another format. Writes,
+// listings and updates of documents still use the bucket, which should be
+// the access point's supporting access point or its bucket. It panics if the
+// ARN isn't an Object Lambda access point ARN.
+func WithObjectLambda(a string) Option {
+	if !isObjectLambdaAccessPoint(a) {
+		panic("s3: not an Object Lambda access point ARN: " + a)
+	}
+	return func(o *options) {
+		o.objectLambda = a
+	}
+}
+
+// isObjectLambdaAccessPoint reports whether a is an Object Lambda access
+// point ARN.
+func isObjectLambdaAccessPoint(a string) bool {
+	p, err := arn.Parse(a)
+	return err == nil && p.Service == "s3-object-lambda" && strings.HasPrefix(p.Resource, "accesspoint")
+}
@@ -0,0 +1,76 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithObjectLambda(t *testing.T) {
+
+	t.Setenv("AWS_CA_BUNDLE", "")
+
+	const olap = "arn:aws:s3-object-lambda:us-east-1:123456789012:accesspoint/redact"
+	_, b := newTestBucket(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.Host, "redact-123456789012.s3-object-lambda.") {
+			o := b.object(strings.TrimPrefix(r.URL.Path, "/"))
+			_, _ = w.Write(bytes.ToUpper(o.body))
+			return
+		}
+		r.URL.Path = "/bytelyon-db" + r.URL.Path
+		b.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	// send requests for every endpoint to the test server
+	u, _ := url.Parse(srv.URL)
+	hc := &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		r.Host = r.URL.Host
+		r.URL.Scheme, r.URL.Host = u.Scheme, u.Host
+		return http.DefaultTransport.RoundTrip(r)
+	})}
+	c := NewWithOptions(context.Background(),
+		WithBucket("bytelyon-db"),
+		WithConfig(config.WithRegion("us-east-1")),
+		WithStaticCredentials("id", "secret", ""),
+		WithHTTPClient(hc),
+		WithS3Options(func(o *s3.Options) {
+			// the test bucket doesn't decode aws-chunked bodies
+			o.RequestChecksumCalculation = aws.RequestChecksumCalculationWhenRequired
+		}),
+		WithObjectLambda(olap),
+	)
+
+	assert.NoError(t, c.Put("users/1.json", []byte(`{"name":"ann"}`)))
+	got, err := c.Get("users/1.json")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"NAME":"ANN"}`, string(got))
+	assert.Equal(t, `{"name":"ann"}`, string(b.object("users/1.json").body))
+
+	// documents are updated from the untransformed object
+	assert.NoError(t, c.Patch("users/1.json", map[string]any{"age": 30}))
+	assert.JSONEq(t, `{"name":"ann","age":30}`, string(b.object("users/1.json").body))
+
+	// objects the client stores or rewrites itself are read untransformed
+	digest, err := c.PutBlob([]byte("blob"))
+	assert.NoError(t, err)
+	got, err = c.GetBlob(digest)
+	assert.NoError(t, err)
+	assert.Equal(t, "blob", string(got))
+	assert.NoError(t, c.Migrate("users/", "people/", func(k string, b []byte) (string, []byte, error) {
+		return k, b, nil
+	}, 1))
+	assert.JSONEq(t, `{"name":"ann","age":30}`, string(b.object("people/1.json").body))
+
+	assert.Panics(t, func() { WithObjectLambda("arn:aws:s3:us-east-1:123456789012:accesspoint/db") })
+}
@@ -0,0 +1,150 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/rs/zerolog"
+)
+
+// Option configures the client returned by NewWithOptions.
+type Option func(*options)
+
+type options struct {
+	bucket        string
+	loadOptions   []func(*config.LoadOptions) error
+	s3Options     []func(*s3.Options)
+	directory     bool
+	cdn           *cdn
+	urlDomain     string
+	maxGetSize    int64
+	maxPutSize    int64
+	audit         *audit
+	hooks         []Hooks
+	assumeRole    *assumeRole
+	httpClient    *http.Client
+	transport     []func(*http.Transport)
+	replica       *replica
+	dualWrite     *dualWrite
+	quotas        []*quota
+	quotaRefresh  time.Duration
+	cache         *cache
+	diskCache     *diskCache
+	negativeCache *negativeCache
+	objectLambda  string
+	correlationID func(context.Context) string
+	logLevels     map[string]zerolog.Level
+	errorLogLevel *zerolog.Level
+	logSamplers   map[string]zerolog.Sampler
+	logger        *zerolog.Logger
+	noLogging     bool
+	mfa           *mfa
+	validators    []validator
+	scanners      []contentScanner
+
+	writerPartSize int
+	writerInterval time.Duration
+
+	indexes map[string]func([]byte) []string
+	latest  []string
+}
+
+// WithBucket sets the bucket the client operates on, taking precedence
+// over the S3_BUCKET environment variable. The value may be a bucket
+// name, an access point ARN, or a Multi-Region Access Point ARN.
+func WithBucket(b string) Option {
+	return func(o *options) {
+		o.bucket = b
+	}
+}
+
+// WithConfig appends a variadic set of Config values that are
+// applied when loading the default AWS configuration.
+func WithConfig(optFns ...func(*config.LoadOptions) error) Option {
+	return func(o *options) {
+		o.loadOptions = append(o.loadOptions, optFns...)
+	}
+}
+
+// WithS3Options appends functions that customize the underlying S3 client options.
+func WithS3Options(optFns ...func(*s3.Options)) Option {
+	return func(o *options) {
+		o.s3Options = append(o.s3Options, optFns...)
+	}
+}
+
+// WithDirectoryBucket marks the bucket as an S3 Express One Zone directory bucket.
+// Requests are authenticated with CreateSession credentials against the zonal
+// endpoint derived from the bucket name, and Keys emulates StartAfter ordering.
+func WithDirectoryBucket() Option {
+	return func(o *options) {
+		o.directory = true
+	}
+}
+
+// WithFIPS sends requests to FIPS 140 validated endpoints, as required in
+// GovCloud and other compliance regimes.
+func WithFIPS() Option {
+	return WithConfig(config.WithUseFIPSEndpoint(aws.FIPSEndpointStateEnabled))
+}
+
+// WithDualStack sends requests to dual-stack endpoints reachable over IPv6.
+func WithDualStack() Option {
+	return WithConfig(config.WithUseDualStackEndpoint(aws.DualStackEndpointStateEnabled))
+}
+
+// WithURLDomain presigns URLs against a custom domain, such as a CNAME or CDN
+// origin mapped to the bucket, instead of the S3 bucket hostname.
+// The domain may include a scheme; https is assumed otherwise.
+func WithURLDomain(domain string) Option {
+	return func(o *options) {
+		o.urlDomain = domain
+	}
+}
+
+// WithMaxGetSize limits the size of objects Get and Find will buffer in memory.
+// Larger objects are refused with ErrObjectTooLarge and must be read with GetReader.
+func WithMaxGetSize(n int64) Option {
+	return func(o *options) {
+		o.maxGetSize = n
+	}
+}
+
+// WithMaxPutSize limits the size of objects written through the client.
+// Larger writes are refused with ErrPutTooLarge before any bytes are sent,
+// and streamed uploads fail as soon as they exceed the limit.
+func WithMaxPutSize(n int64) Option {
+	return func(o *options) {
+		o.maxPutSize = n
+	}
+}
+
+// validateBucket returns an error when b is an ARN that does not identify
+// an S3 access point. Plain bucket names are passed through as-is.
+func validateBucket(b string) error {
+	if !arn.IsARN(b) {
+		return nil
+	}
+	a, err := arn.Parse(b)
+	if err != nil {
+		return err
+	}
+	if a.Service != "s3" || !strings.HasPrefix(a.Resource, "accesspoint") {
+		return errors.New("bucket ARN must identify an S3 access point: " + b)
+	}
+	return nil
+}
+
+// isMultiRegionAccessPoint reports whether b is a Multi-Region Access Point ARN.
+// These carry no region and are signed with SigV4A.
+func isMultiRegionAccessPoint(b string) bool {
+	a, err := arn.Parse(b)
+	return err == nil && a.Region == "" && strings.HasSuffix(a.Resource, ".mrap")
+}
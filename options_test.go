@@ -0,0 +1,43 @@
+package s3
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateBucket(t *testing.T) {
+	assert.NoError(t, validateBucket("bytelyon-db"))
+	assert.NoError(t, validateBucket("arn:aws:s3:us-east-1:123456789012:accesspoint/db"))
+	assert.NoError(t, validateBucket("arn:aws:s3::123456789012:accesspoint/mfzwi23gnjvgw.mrap"))
+	assert.Error(t, validateBucket("arn:aws:sqs:us-east-1:123456789012:queue"))
+}
+
+func TestIsMultiRegionAccessPoint(t *testing.T) {
+	assert.True(t, isMultiRegionAccessPoint("arn:aws:s3::123456789012:accesspoint/mfzwi23gnjvgw.mrap"))
+	assert.False(t, isMultiRegionAccessPoint("arn:aws:s3:us-east-1:123456789012:accesspoint/db"))
+	assert.False(t, isMultiRegionAccessPoint("bytelyon-db"))
+}
+
+func TestWithFIPS_DualStack(t *testing.T) {
+
+	endpoint := func(opts ...Option) string {
+		c := NewWithOptions(context.Background(), append([]Option{
+			WithBucket("bytelyon-db"),
+			WithConfig(config.WithRegion("us-east-1")),
+			WithStaticCredentials("id", "secret", ""),
+		}, opts...)...)
+		u, err := c.URL(testKey(), 1)
+		assert.NoError(t, err)
+		return u[:strings.Index(u, "/"+testKey())]
+	}
+
+	assert.Equal(t, "https://bytelyon-db.s3.us-east-1.amazonaws.com", endpoint())
+	assert.Equal(t, "https://bytelyon-db.s3-fips.us-east-1.amazonaws.com", endpoint(WithFIPS()))
+	assert.Equal(t, "https://bytelyon-db.s3.dualstack.us-east-1.amazonaws.com", endpoint(WithDualStack()))
+	assert.Equal(t, "https://bytelyon-db.s3-fips.dualstack.us-east-1.amazonaws.com", endpoint(WithFIPS(), WithDualStack()))
+}
@@ -0,0 +1,170 @@
+package s3
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+// Feature is a group of the client's operations that need the same IAM
+// permissions, for RequiredPolicy.
+type Feature string
+
+const (
+	// FeatureRead covers reading objects and their metadata, e.g. Get,
+	// GetReader, Find, Stat, and the source of copies.
+	FeatureRead Feature = "read"
+	// FeatureWrite covers writing objects, e.g. Put, Copy, NewWriter and
+	// PutAtomic, including multipart uploads.
+	FeatureWrite Feature = "write"
+	// FeatureDelete covers deleting objects.
+	FeatureDelete Feature = "delete"
+	// FeatureList covers listing the bucket, e.g. Keys, Page, ListDir and
+	// every operation over a prefix.
+	FeatureList Feature = "list"
+	// FeatureTagging covers reading and writing object tags, e.g.
+	// TagPrefix and copies of large objects.
+	FeatureTagging Feature = "tagging"
+	// FeatureVersions covers reading and deleting object versions and
+	// versioning the bucket, e.g. History, At, RestoreSnapshot,
+	// DeleteVersion and SetVersioning.
+	FeatureVersions Feature = "versions"
+	// FeatureBucketConfig covers configuring the bucket, e.g.
+	// ConfigureNotifications, ConfigureReplication and SetBucketEncryption.
+	FeatureBucketConfig Feature = "bucket-config"
+	// FeatureKMS covers objects encrypted with SSE-KMS, e.g. reading,
+	// writing and copying them and ReEncrypt. KMS keys may only be used
+	// through S3 in the client's region.
+	FeatureKMS Feature = "kms"
+)
+
+// featureActions are the object and bucket level actions each feature needs.
+var featureActions = map[Feature]struct{ object, bucket []string }{
+	FeatureRead:    {object: []string{"s3:GetObject"}},
+	FeatureWrite:   {object: []string{"s3:PutObject", "s3:AbortMultipartUpload", "s3:ListMultipartUploadParts"}},
+	FeatureDelete:  {object: []string{"s3:DeleteObject"}},
+	FeatureList:    {bucket: []string{"s3:ListBucket"}},
+	FeatureTagging: {object: []string{"s3:GetObjectTagging", "s3:PutObjectTagging"}},
+	FeatureVersions: {
+		object: []string{"s3:GetObjectVersion", "s3:DeleteObjectVersion"},
+		bucket: []string{"s3:ListBucketVersions", "s3:PutBucketVersioning"},
+	},
+	FeatureBucketConfig: {bucket: []string{
+		"s3:GetBucketNotification", "s3:PutBucketNotification",
+		"s3:GetReplicationConfiguration", "s3:PutReplicationConfiguration",
+		"s3:GetEncryptionConfiguration", "s3:PutEncryptionConfiguration",
+		"s3:GetBucketPublicAccessBlock", "s3:PutBucketPublicAccessBlock",
+	}},
+}
+
+type policyStatement struct {
+	Sid       string
+	Effect    string
+	Action    []string
+	Resource  []string
+	Condition map[string]map[string]string `json:",omitempty"`
+}
+
+type policyDocument struct {
+	Version   string
+	Statement []policyStatement
+}
+
+// RequiredPolicy returns a least privilege IAM policy document granting
+// the permissions the client needs for the features, or for reading,
+// writing, deleting and listing when none are given. Buckets and access
+// points the client is configured to use besides its own, for replicas,
+// dual writes and Object Lambda, are covered too. Object Lambda access
+// points also need permission to invoke their function, which isn't.
+func (c *client) RequiredPolicy(features ...Feature) string {
+	if len(features) == 0 {
+		features = []Feature{FeatureRead, FeatureWrite, FeatureDelete, FeatureList}
+	}
+
+	var object, bucket []string
+	for _, f := range features {
+		object = append(object, featureActions[f].object...)
+		bucket = append(bucket, featureActions[f].bucket...)
+	}
+	partition := awsPartition(c.cfg.Region)
+	objects, buckets := bucketResources(partition, *c.Bucket)
+
+	doc := policyDocument{Version: "2012-10-17"}
+	add := func(sid string, actions, resources []string) {
+		if len(actions) > 0 {
+			slices.Sort(actions)
+			doc.Statement = append(doc.Statement, policyStatement{sid, "Allow", slices.Compact(actions), resources, nil})
+		}
+	}
+	add("Objects", object, []string{objects})
+	add("Bucket", bucket, []string{buckets})
+
+	if c.directory {
+		add("DirectoryBucket", []string{"s3express:CreateSession"}, []string{"arn:" + partition + ":s3express:*:*:bucket/" + *c.Bucket})
+	}
+	if c.replica != nil && slices.Contains(features, FeatureRead) {
+		replica, _ := bucketResources(partition, c.replica.bucket)
+		add("Replica", []string{"s3:GetObject"}, []string{replica})
+	}
+	if c.dualWrite != nil && (slices.Contains(features, FeatureWrite) || slices.Contains(features, FeatureDelete)) {
+		mirror, _ := bucketResources(partition, c.dualWrite.bucket)
+		add("DualWrite", []string{"s3:PutObject", "s3:DeleteObject"}, []string{mirror})
+		if !slices.Contains(features, FeatureRead) {
+			// mirrors are copied from the bucket
+			add("DualWriteSource", []string{"s3:GetObject"}, []string{objects})
+		}
+	}
+	if c.objectLambda != "" && slices.Contains(features, FeatureRead) {
+		add("ObjectLambda", []string{"s3-object-lambda:GetObject"}, []string{c.objectLambda})
+	}
+	if slices.Contains(features, FeatureKMS) {
+		region := c.cfg.Region
+		if region == "" {
+			region = "*"
+		}
+		suffix := ".amazonaws.com"
+		if partition == "aws-cn" {
+			suffix += ".cn"
+		}
+		doc.Statement = append(doc.Statement, policyStatement{
+			Sid:      "KMS",
+			Effect:   "Allow",
+			Action:   []string{"kms:Decrypt", "kms:GenerateDataKey"},
+			Resource: []string{"arn:" + partition + ":kms:" + region + ":*:key/*"},
+			Condition: map[string]map[string]string{
+				"StringLike": {"kms:ViaService": "s3." + region + suffix},
+			},
+		})
+	}
+
+	b, _ := json.MarshalIndent(doc, "", "  ")
+	return string(b)
+}
+
+// bucketResources returns the resource ARNs of the objects in a bucket or
+// access point and of the bucket or access point itself.
+func bucketResources(partition, b string) (string, string) {
+	if arn.IsARN(b) {
+		return b + "/object/*", b
+	}
+	return "arn:" + partition + ":s3:::" + b + "/*", "arn:" + partition + ":s3:::" + b
+}
+
+// awsPartition returns the partition of the region's ARNs.
+func awsPartition(region string) string {
+	switch {
+	case strings.HasPrefix(region, "us-gov-"):
+		return "aws-us-gov"
+	case strings.HasPrefix(region, "cn-"):
+		return "aws-cn"
+	case strings.HasPrefix(region, "us-isob-"):
+		return "aws-iso-b"
+	case strings.HasPrefix(region, "us-iso-"):
+		return "aws-iso"
+	case strings.HasPrefix(region, "eu-isoe-"):
+		return "aws-iso-e"
+	}
+	return "aws"
+}
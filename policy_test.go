@@ -0,0 +1,76 @@
+package s3
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_RequiredPolicy(t *testing.T) {
+
+	c, _ := newTestBucket(t)
+	assert.JSONEq(t, `{
+		"Version": "2012-10-17",
+		"Statement": [
+			{
+				"Sid": "Objects",
+				"Effect": "Allow",
+				"Action": ["s3:AbortMultipartUpload", "s3:DeleteObject", "s3:GetObject", "s3:ListMultipartUploadParts", "s3:PutObject"],
+				"Resource": ["arn:aws:s3:::bytelyon-db/*"]
+			},
+			{
+				"Sid": "Bucket",
+				"Effect": "Allow",
+				"Action": ["s3:ListBucket"],
+				"Resource": ["arn:aws:s3:::bytelyon-db"]
+			}
+		]
+	}`, c.RequiredPolicy())
+
+	var doc policyDocument
+	assert.NoError(t, json.Unmarshal([]byte(c.RequiredPolicy(FeatureRead)), &doc))
+	assert.Len(t, doc.Statement, 1)
+	assert.Equal(t, []string{"s3:GetObject"}, doc.Statement[0].Action)
+
+	WithDualWrite("bytelyon-db-mirror", 0)(c.options)
+	WithObjectLambda("arn:aws:s3-object-lambda:us-east-1:123456789012:accesspoint/redact")(c.options)
+	doc = policyDocument{}
+	assert.NoError(t, json.Unmarshal([]byte(c.RequiredPolicy(FeatureWrite, FeatureVersions)), &doc))
+	sids := map[string]policyStatement{}
+	for _, s := range doc.Statement {
+		sids[s.Sid] = s
+	}
+	assert.Equal(t, []string{"s3:ListBucketVersions", "s3:PutBucketVersioning"}, sids["Bucket"].Action)
+	assert.Contains(t, sids["Objects"].Action, "s3:DeleteObjectVersion")
+	assert.Equal(t, []string{"arn:aws:s3:::bytelyon-db-mirror/*"}, sids["DualWrite"].Resource)
+	assert.Equal(t, []string{"arn:aws:s3:::bytelyon-db/*"}, sids["DualWriteSource"].Resource)
+	assert.NotContains(t, sids, "ObjectLambda")
+
+	c.Bucket = aws.String("arn:aws:s3:us-east-1:123456789012:accesspoint/db")
+	doc = policyDocument{}
+	assert.NoError(t, json.Unmarshal([]byte(c.RequiredPolicy(FeatureRead)), &doc))
+	assert.Equal(t, []string{"arn:aws:s3:us-east-1:123456789012:accesspoint/db/object/*"}, doc.Statement[0].Resource)
+	assert.Equal(t, "ObjectLambda", doc.Statement[1].Sid)
+
+	// buckets are named in the partition of the region, as are KMS keys
+	c.Bucket, c.cfg.Region = aws.String("bytelyon-db"), "us-gov-west-1"
+	doc = policyDocument{}
+	assert.NoError(t, json.Unmarshal([]byte(c.RequiredPolicy(FeatureRead, FeatureKMS)), &doc))
+	assert.Equal(t, []string{"arn:aws-us-gov:s3:::bytelyon-db/*"}, doc.Statement[0].Resource)
+	assert.Equal(t, policyStatement{
+		Sid:       "KMS",
+		Effect:    "Allow",
+		Action:    []string{"kms:Decrypt", "kms:GenerateDataKey"},
+		Resource:  []string{"arn:aws-us-gov:kms:us-gov-west-1:*:key/*"},
+		Condition: map[string]map[string]string{"StringLike": {"kms:ViaService": "s3.us-gov-west-1.amazonaws.com"}},
+	}, doc.Statement[len(doc.Statement)-1])
+}
+
+func TestAWSPartition(t *testing.T) {
+	assert.Equal(t, "aws", awsPartition("us-east-1"))
+	assert.Equal(t, "aws-cn", awsPartition("cn-north-1"))
+	assert.Equal(t, "aws-us-gov", awsPartition("us-gov-east-1"))
+	assert.Equal(t, "aws-iso-b", awsPartition("us-isob-east-1"))
+}
@@ -0,0 +1,57 @@
+package s3
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// maxPooledBuffer bounds the capacity of buffers returned to the pool so a
+// single huge object doesn't pin memory for the lifetime of the process.
+const maxPooledBuffer = 4 << 20
+
+var bufferPool = sync.Pool{
+	New: func() any {
+		return new(bytes.Buffer)
+	},
+}
+
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+func putBuffer(b *bytes.Buffer) {
+	if b.Cap() > maxPooledBuffer {
+		return
+	}
+	b.Reset()
+	bufferPool.Put(b)
+}
+
+// readAll reads r into a pooled buffer sized from the expected length and
+// returns an exactly sized copy, avoiding the repeated growth of io.ReadAll.
+func readAll(r io.Reader, size int64) ([]byte, error) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if size > 0 {
+		buf.Grow(int(size))
+	}
+	if _, err := buf.ReadFrom(r); err != nil {
+		return nil, err
+	}
+	return bytes.Clone(buf.Bytes()), nil
+}
+
+// marshal encodes a as JSON into a pooled buffer which the caller must
+// release with putBuffer once the bytes are no longer referenced.
+func marshal(a any) (*bytes.Buffer, error) {
+	buf := getBuffer()
+	if err := json.NewEncoder(buf).Encode(a); err != nil {
+		putBuffer(buf)
+		return nil, err
+	}
+	// match json.Marshal, which doesn't append a newline
+	buf.Truncate(buf.Len() - 1)
+	return buf, nil
+}
@@ -0,0 +1,65 @@
+package s3
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var benchBody = []byte(strings.Repeat(testBody(), 1<<10))
+
+type benchDoc struct {
+	ID    string   `json:"id"`
+	Name  string   `json:"name"`
+	Email string   `json:"email"`
+	Tags  []string `json:"tags"`
+}
+
+var doc = benchDoc{"01K48PC0BK13BWV2CGWFP8QQH0", "Lyon", "lyon@bytelyon.com", []string{"a", "b", "c"}}
+
+func TestReadAll(t *testing.T) {
+	b, err := readAll(bytes.NewReader(benchBody), int64(len(benchBody)))
+	assert.NoError(t, err)
+	assert.Equal(t, benchBody, b)
+}
+
+func TestMarshal(t *testing.T) {
+	exp, _ := json.Marshal(doc)
+	buf, err := marshal(doc)
+	assert.NoError(t, err)
+	assert.Equal(t, exp, buf.Bytes())
+	putBuffer(buf)
+}
+
+func BenchmarkReadAll(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		_, _ = readAll(bytes.NewReader(benchBody), int64(len(benchBody)))
+	}
+}
+
+func BenchmarkIOReadAll(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		_, _ = io.ReadAll(bytes.NewReader(benchBody))
+	}
+}
+
+func BenchmarkMarshal(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		buf, _ := marshal(doc)
+		putBuffer(buf)
+	}
+}
+
+func BenchmarkJSONMarshal(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		_, _ = json.Marshal(doc)
+	}
+}
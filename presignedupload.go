@@ -0,0 +1,151 @@
+package s3
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"path"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ErrUploadSize is returned by CompletePresignedUpload when the parts
+// uploaded don't add up to the size of the file declared.
+var ErrUploadSize = errors.New("s3: upload size mismatch")
+
+// PresignedUpload is a multipart upload whose parts are uploaded by another
+// party, such as a browser, with presigned URLs, so the bytes never pass
+// through the server. Part n of the file, the PartSize bytes at offset
+// (n-1)*PartSize with only the last part shorter, is uploaded with a PUT of
+// the bytes to URLs[n-1], which are signed for the part's exact length. The
+// upload is committed by CompletePresignedUpload, or discarded by
+// AbortPresignedUpload, given its Key. The parts of files
+// written to keys that are validated or scanned are uploaded to a Key under
+// .staging/, which is inspected and copied to the key on completion.
+type PresignedUpload struct {
+	Key      string
+	UploadID string
+	PartSize int64
+	URLs     []string
+}
+
+// PresignMultipartUpload starts a multipart upload of a file of size bytes to
+// the key and presigns a URL for each of its parts, valid for the duration.
+// Parts are 8 MiB, or larger for files that would otherwise exceed the
+// 10,000 parts S3 allows.
+func (c *client) PresignMultipartUpload(k string, size int64, expires time.Duration) (PresignedUpload, error) {
+
+	up := PresignedUpload{Key: k, PartSize: max(partSize, (size+9999)/10000)}
+	if c.inspects(k) {
+		up.Key = stagingKey(k)
+	}
+
+	err := c.checkPutSize(k, size)
+	if err == nil {
+		in := &s3.CreateMultipartUploadInput{
+			Bucket:   c.Bucket,
+			Key:      &up.Key,
+			Metadata: c.correlateMetadata(nil),
+		}
+		if ct := mime.TypeByExtension(path.Ext(k)); ct != "" {
+			in.ContentType = &ct
+		}
+		var out *s3.CreateMultipartUploadOutput
+		if out, err = c.CreateMultipartUpload(c.Context, in); err == nil {
+			up.UploadID = aws.ToString(out.UploadId)
+		}
+	}
+
+	parts := max(1, (size+up.PartSize-1)/up.PartSize)
+	for n := int32(1); err == nil && int64(n) <= parts; n++ {
+		// signing the length keeps parts from growing past the size declared
+		off := int64(n-1) * up.PartSize
+		req, perr := c.PresignUploadPart(c.Context, &s3.UploadPartInput{
+			Bucket:        c.Bucket,
+			Key:           &up.Key,
+			UploadId:      &up.UploadID,
+			PartNumber:    aws.Int32(n),
+			ContentLength: aws.Int64(min(off+up.PartSize, size) - off),
+		}, s3.WithPresignExpires(expires))
+		if err = perr; err == nil {
+			up.URLs = append(up.URLs, req.URL)
+		}
+	}
+	if err != nil && up.UploadID != "" {
+		c.abortMultipartUpload(up.Key, &up.UploadID)
+	}
+
+	c.log("PresignMultipartUpload", err).
+		Str("key", k).
+		Str("uploadId", up.UploadID).
+		Int64("size", size).
+		Int("parts", len(up.URLs)).
+		Msg("PresignMultipartUpload")
+
+	return up, err
+}
+
+// CompletePresignedUpload commits the upload to the Key of its
+// PresignedUpload once every part has been uploaded, assembling the object
+// from the parts S3 received in order. It fails with ErrUploadSize, leaving
+// the upload to be completed later, unless the parts add up to the size
+// declared to PresignMultipartUpload, and refuses uploads over the maximum
+// put size. Staged uploads are then inspected and copied to their key.
+func (c *client) CompletePresignedUpload(k, uploadID string, declared int64) error {
+
+	var parts []types.CompletedPart
+	var size int64
+	paginator := s3.NewListPartsPaginator(c.Client, &s3.ListPartsInput{
+		Bucket:   c.Bucket,
+		Key:      &k,
+		UploadId: &uploadID,
+	})
+	var err error
+	for err == nil && paginator.HasMorePages() {
+		var out *s3.ListPartsOutput
+		if out, err = paginator.NextPage(c.Context); err == nil {
+			for _, p := range out.Parts {
+				parts = append(parts, types.CompletedPart{ETag: p.ETag, PartNumber: p.PartNumber})
+				size += aws.ToInt64(p.Size)
+			}
+		}
+	}
+	dst, staged := stagedKey(k)
+	if !staged {
+		dst = k
+	}
+	if err == nil && size != declared {
+		err = fmt.Errorf("%w: %s has %d of %d bytes", ErrUploadSize, dst, size, declared)
+	}
+	if err == nil {
+		err = c.checkPutSize(dst, size)
+	}
+	if err == nil {
+		err = c.completeMultipartUpload(&s3.CompleteMultipartUploadInput{
+			Bucket:          c.Bucket,
+			Key:             &k,
+			UploadId:        &uploadID,
+			MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+		}, size, nil)
+	}
+	if staged && err == nil {
+		err = c.promote(k, dst)
+	}
+
+	c.log("CompletePresignedUpload", err).
+		Str("key", k).
+		Str("uploadId", uploadID).
+		Int("parts", len(parts)).
+		Int64("size", size).
+		Msg("CompletePresignedUpload")
+
+	return err
+}
+
+// AbortPresignedUpload discards the upload and the parts uploaded so far.
+func (c *client) AbortPresignedUpload(k, uploadID string) error {
+	return c.abortMultipartUpload(k, &uploadID)
+}
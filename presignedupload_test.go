@@ -0,0 +1,70 @@
+package s3
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_PresignMultipartUpload(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	file := bytes.Repeat([]byte("0123456789abcdef"), partSize/16+1)
+
+	up, err := c.PresignMultipartUpload("videos/a.mp4", int64(len(file)), time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(partSize), up.PartSize)
+	assert.Len(t, up.URLs, 2)
+
+	u, err := url.Parse(up.URLs[1])
+	assert.NoError(t, err)
+	assert.Equal(t, "2", u.Query().Get("partNumber"))
+	assert.Equal(t, up.UploadID, u.Query().Get("uploadId"))
+	assert.Equal(t, "3600", u.Query().Get("X-Amz-Expires"))
+	assert.Contains(t, u.Query().Get("X-Amz-SignedHeaders"), "content-length")
+
+	// parts are uploaded by the browser in any order
+	for n := len(up.URLs); n > 0; n-- {
+		part := file[int64(n-1)*up.PartSize : min(int64(n)*up.PartSize, int64(len(file)))]
+		req, _ := http.NewRequest(http.MethodPut, up.URLs[n-1], bytes.NewReader(part))
+		res, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		res.Body.Close()
+	}
+	assert.Empty(t, b.keys())
+
+	// the parts must add up to the size declared
+	assert.ErrorIs(t, c.CompletePresignedUpload(up.Key, up.UploadID, int64(len(file))+1), ErrUploadSize)
+	WithMaxPutSize(int64(len(file)) - 1)(c.options)
+	assert.ErrorIs(t, c.CompletePresignedUpload(up.Key, up.UploadID, int64(len(file))), ErrPutTooLarge)
+	WithMaxPutSize(0)(c.options)
+
+	assert.NoError(t, c.CompletePresignedUpload(up.Key, up.UploadID, int64(len(file))))
+	assert.Equal(t, file, b.object("videos/a.mp4").body)
+	assert.Equal(t, "video/mp4", b.object("videos/a.mp4").header.Get("Content-Type"))
+}
+
+func TestClient_PresignMultipartUpload_Limits(t *testing.T) {
+
+	c, b := newTestBucket(t)
+
+	up, err := c.PresignMultipartUpload("empty.txt", 0, time.Minute)
+	assert.NoError(t, err)
+	assert.Len(t, up.URLs, 1)
+	assert.NoError(t, c.AbortPresignedUpload(up.Key, up.UploadID))
+	assert.Empty(t, b.uploads)
+
+	up, err = c.PresignMultipartUpload("huge.bin", 1<<40, time.Minute)
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, len(up.URLs), 10000)
+	assert.GreaterOrEqual(t, up.PartSize*int64(len(up.URLs)), int64(1<<40))
+
+	WithMaxPutSize(1 << 20)(c.options)
+	_, err = c.PresignMultipartUpload("big.bin", 1<<21, time.Minute)
+	assert.ErrorIs(t, err, ErrPutTooLarge)
+}
@@ -0,0 +1,70 @@
+package s3
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// PublicAccessBlock is the bucket's Block Public Access configuration.
+type PublicAccessBlock struct {
+	BlockPublicACLs       bool
+	IgnorePublicACLs      bool
+	BlockPublicPolicy     bool
+	RestrictPublicBuckets bool
+}
+
+// Blocked reports whether every public ACL and policy is blocked, as
+// deployments that must never serve public objects can assert at startup.
+func (p PublicAccessBlock) Blocked() bool {
+	return p.BlockPublicACLs && p.IgnorePublicACLs && p.BlockPublicPolicy && p.RestrictPublicBuckets
+}
+
+// SetPublicAccessBlock replaces the bucket's Block Public Access configuration.
+func (c *client) SetPublicAccessBlock(cfg PublicAccessBlock) error {
+
+	_, err := c.PutPublicAccessBlock(c.Context, &s3.PutPublicAccessBlockInput{
+		Bucket: c.Bucket,
+		PublicAccessBlockConfiguration: &types.PublicAccessBlockConfiguration{
+			BlockPublicAcls:       aws.Bool(cfg.BlockPublicACLs),
+			IgnorePublicAcls:      aws.Bool(cfg.IgnorePublicACLs),
+			BlockPublicPolicy:     aws.Bool(cfg.BlockPublicPolicy),
+			RestrictPublicBuckets: aws.Bool(cfg.RestrictPublicBuckets),
+		},
+	})
+
+	c.log("SetPublicAccessBlock", err).
+		Bool("blocked", cfg.Blocked()).
+		Msg("SetPublicAccessBlock")
+
+	return err
+}
+
+// GetPublicAccessBlock returns the bucket's Block Public Access configuration,
+// which blocks nothing when the bucket has none.
+func (c *client) GetPublicAccessBlock() (PublicAccessBlock, error) {
+
+	out, err := c.Client.GetPublicAccessBlock(c.Context, &s3.GetPublicAccessBlockInput{
+		Bucket: c.Bucket,
+	})
+
+	var cfg PublicAccessBlock
+	switch {
+	case errorCode(err) == "NoSuchPublicAccessBlockConfiguration":
+		err = nil
+	case err == nil && out.PublicAccessBlockConfiguration != nil:
+		pab := out.PublicAccessBlockConfiguration
+		cfg = PublicAccessBlock{
+			BlockPublicACLs:       aws.ToBool(pab.BlockPublicAcls),
+			IgnorePublicACLs:      aws.ToBool(pab.IgnorePublicAcls),
+			BlockPublicPolicy:     aws.ToBool(pab.BlockPublicPolicy),
+			RestrictPublicBuckets: aws.ToBool(pab.RestrictPublicBuckets),
+		}
+	}
+
+	c.log("GetPublicAccessBlock", err).
+		Bool("blocked", cfg.Blocked()).
+		Msg("GetPublicAccessBlock")
+
+	return cfg, err
+}
@@ -0,0 +1,45 @@
+package s3
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_PublicAccessBlock(t *testing.T) {
+
+	var stored []byte
+	c := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if !r.URL.Query().Has("publicAccessBlock") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch {
+		case r.Method == http.MethodPut:
+			stored, _ = io.ReadAll(r.Body)
+		case stored == nil:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`<Error><Code>NoSuchPublicAccessBlockConfiguration</Code></Error>`))
+		default:
+			w.Write(stored)
+		}
+	})
+
+	cfg, err := c.GetPublicAccessBlock()
+	assert.NoError(t, err)
+	assert.False(t, cfg.Blocked())
+
+	assert.NoError(t, c.SetPublicAccessBlock(PublicAccessBlock{BlockPublicACLs: true, BlockPublicPolicy: true}))
+	cfg, err = c.GetPublicAccessBlock()
+	assert.NoError(t, err)
+	assert.Equal(t, PublicAccessBlock{BlockPublicACLs: true, BlockPublicPolicy: true}, cfg)
+	assert.False(t, cfg.Blocked())
+
+	all := PublicAccessBlock{true, true, true, true}
+	assert.NoError(t, c.SetPublicAccessBlock(all))
+	cfg, err = c.GetPublicAccessBlock()
+	assert.NoError(t, err)
+	assert.True(t, cfg.Blocked())
+}
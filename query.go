@@ -0,0 +1,202 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// queryWorkers is how many documents a Query fetches concurrently.
+const queryWorkers = 8
+
+// Query finds the JSON documents under a prefix whose fields match all of its
+// predicates. Documents are listed in key order, fetched concurrently and
+// filtered client-side, so a query reads every document up to the limit.
+type Query struct {
+	c      *client
+	prefix string
+	preds  []queryPredicate
+	limit  int
+	err    error
+}
+
+type queryPredicate struct {
+	path  []string
+	op    string
+	value any
+}
+
+// Query starts a query of the JSON documents under the prefix.
+func (c *client) Query(p string) *Query {
+	return &Query{c: c, prefix: p}
+}
+
+// Where keeps documents whose field compares to the value with the operator:
+// "=", "!=", "<", "<=", ">" or ">=". Nested fields are separated by dots, as
+// in "address.city". Numbers and strings are ordered, other values can only
+// be compared for equality, and documents missing the field never match.
+func (q *Query) Where(field, op string, value any) *Query {
+	switch op {
+	case "=", "!=", "<", "<=", ">", ">=":
+	default:
+		q.err = fmt.Errorf("s3: unknown query operator %q", op)
+		return q
+	}
+	b, err := json.Marshal(value)
+	if err == nil {
+		value, err = decodeJSON(b)
+	}
+	if err != nil {
+		q.err = err
+		return q
+	}
+	q.preds = append(q.preds, queryPredicate{strings.Split(field, "."), op, value})
+	return q
+}
+
+// Limit stops the query once n documents match.
+func (q *Query) Limit(n int) *Query {
+	q.limit = n
+	return q
+}
+
+// errQueryDone stops listing once a Query has enough documents.
+var errQueryDone = errors.New("s3: query done")
+
+// Run runs the query and decodes the matching documents into out, a pointer
+// to a slice, in key order. Objects that aren't JSON documents are skipped.
+func (q *Query) Run(out any) error {
+
+	var matches [][]byte
+	var scanned int
+	batch := make([]string, 0, queryWorkers*4)
+	flush := func() error {
+		docs := make([][]byte, len(batch))
+		found := make([]bool, len(batch))
+		_, err := parallel(q.c.Context, queryWorkers, func(send func(int) bool) error {
+			for i := range batch {
+				if !send(i) {
+					break
+				}
+			}
+			return nil
+		}, func(ctx context.Context, i int) error {
+			var err error
+			docs[i], err = q.c.withContext(ctx).Get(batch[i])
+			if IsNotFound(err) {
+				return nil
+			}
+			found[i] = err == nil
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		scanned += len(batch)
+		batch = batch[:0]
+		for i, doc := range docs {
+			if !found[i] {
+				continue
+			}
+			if q.match(doc) {
+				if matches = append(matches, doc); q.limit > 0 && len(matches) == q.limit {
+					return errQueryDone
+				}
+			}
+		}
+		return nil
+	}
+
+	err := q.err
+	if err == nil {
+		err = q.c.walk(q.prefix, func(obj types.Object) error {
+			if batch = append(batch, *obj.Key); len(batch) == cap(batch) {
+				return flush()
+			}
+			return nil
+		})
+	}
+	if err == nil && len(batch) > 0 {
+		err = flush()
+	}
+	if err == nil || err == errQueryDone {
+		err = json.Unmarshal(append(append([]byte("["), bytes.Join(matches, []byte(","))...), ']'), out)
+	}
+
+	q.c.log("Query", err).
+		Str("prefix", q.prefix).
+		Int("predicates", len(q.preds)).
+		Int("scanned", scanned).
+		Int("matches", len(matches)).
+		Msg("Query")
+
+	return err
+}
+
+// match reports whether the document matches every predicate.
+func (q *Query) match(doc []byte) bool {
+	v, err := decodeJSON(doc)
+	if err != nil {
+		return false
+	}
+	for _, p := range q.preds {
+		field, err := pointerGet(v, p.path)
+		if err != nil || !p.compare(field) {
+			return false
+		}
+	}
+	return true
+}
+
+// compare reports whether the field compares to the predicate's value.
+func (p queryPredicate) compare(field any) bool {
+	switch p.op {
+	case "=":
+		return equalJSON(field, p.value)
+	case "!=":
+		return !equalJSON(field, p.value)
+	}
+
+	var cmp int
+	switch a := field.(type) {
+	case json.Number:
+		b, ok := p.value.(json.Number)
+		if !ok {
+			return false
+		}
+		x, errA := a.Float64()
+		y, errB := b.Float64()
+		if errA != nil || errB != nil {
+			return false
+		}
+		switch {
+		case x < y:
+			cmp = -1
+		case x > y:
+			cmp = 1
+		}
+	case string:
+		b, ok := p.value.(string)
+		if !ok {
+			return false
+		}
+		cmp = strings.Compare(a, b)
+	default:
+		return false
+	}
+
+	switch p.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	}
+	return cmp >= 0
+}
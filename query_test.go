@@ -0,0 +1,53 @@
+package s3
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Query(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	type user struct {
+		ID      int    `json:"id"`
+		Status  string `json:"status"`
+		Address struct {
+			City string `json:"city"`
+		} `json:"address"`
+	}
+	for i := range 100 {
+		u := user{ID: i, Status: "inactive"}
+		if i%3 == 0 {
+			u.Status = "active"
+		}
+		u.Address.City = "Paris"
+		if i%2 == 0 {
+			u.Address.City = "Rome"
+		}
+		assert.NoError(t, c.Put(fmt.Sprintf("users/%03d.json", i), u))
+	}
+	b.put("users/notes.txt", []byte("not json"), nil)
+
+	var out []user
+	assert.NoError(t, c.Query("users/").Where("status", "=", "active").Where("address.city", "=", "Rome").Run(&out))
+	assert.Len(t, out, 17)
+	for _, u := range out {
+		assert.Equal(t, 0, u.ID%6)
+	}
+
+	out = nil
+	assert.NoError(t, c.Query("users/").Where("id", ">=", 40).Where("id", "<", 90.0).Where("status", "!=", "active").Limit(5).Run(&out))
+	var ids []int
+	for _, u := range out {
+		ids = append(ids, u.ID)
+	}
+	assert.Equal(t, []int{40, 41, 43, 44, 46}, ids)
+
+	out = nil
+	assert.NoError(t, c.Query("users/").Where("missing", "=", nil).Run(&out))
+	assert.Empty(t, out)
+
+	assert.Error(t, c.Query("users/").Where("id", "~", 1).Run(&out))
+}
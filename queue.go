@@ -0,0 +1,209 @@
+package s3
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/oklog/ulid/v2"
+)
+
+// Queue is a work queue of messages stored as objects under a prefix, for
+// coordinating small asynchronous jobs through the bucket without SQS.
+// Messages are named with ULIDs, so they are claimed in the order they were
+// enqueued, and leased with conditional writes, so each is claimed by one
+// consumer at a time. Claim lists and reads every message it passes over,
+// so queues are suited to low volumes.
+type Queue struct {
+	c       *client
+	prefix  string
+	mu      sync.Mutex
+	entropy io.Reader
+}
+
+// Message is a message claimed from a Queue. Attempts counts the times it
+// has been claimed, including this one.
+type Message struct {
+	ID       string
+	Body     []byte
+	Attempts int
+	etag     *string
+}
+
+// queueEnvelope is the stored form of a message. Every claim changes it,
+// so its ETag identifies the claim.
+type queueEnvelope struct {
+	Body       []byte    `json:"body"`
+	Attempts   int       `json:"attempts,omitempty"`
+	LeaseUntil time.Time `json:"leaseUntil,omitzero"`
+}
+
+// Queue returns the work queue stored under the prefix.
+func (c *client) Queue(p string) *Queue {
+	return &Queue{c: c, prefix: strings.TrimSuffix(p, "/") + "/", entropy: ulid.Monotonic(rand.Reader, 0)}
+}
+
+// id returns a ULID that sorts after every other returned by the queue.
+func (q *Queue) id() string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return ulid.MustNew(ulid.Now(), q.entropy).String()
+}
+
+// Enqueue adds the message, encoded as Put encodes values, to the queue and
+// returns its ID.
+func (q *Queue) Enqueue(msg any) (string, error) {
+
+	id := q.id()
+	k := q.prefix + id
+	body, _, release, err := encode(k, msg)
+	if err == nil {
+		_, err = q.write(k, queueEnvelope{Body: body}, &s3.PutObjectInput{IfNoneMatch: aws.String("*")})
+		release()
+	}
+
+	q.c.log("Enqueue", err).
+		Str("key", k).
+		Msg("Enqueue")
+
+	return id, err
+}
+
+// write stores the envelope under the key with the input's conditions and
+// returns its ETag.
+func (q *Queue) write(k string, env queueEnvelope, in *s3.PutObjectInput) (*string, error) {
+	b, err := json.Marshal(env)
+	if err != nil {
+		return nil, err
+	}
+	in.Bucket = q.c.Bucket
+	in.Key = &k
+	in.ContentType = aws.String("application/json")
+	out, err := q.c.putObject(in, b)
+	if err != nil {
+		return nil, err
+	}
+	return out.ETag, nil
+}
+
+// errClaimed stops listing once Claim has leased a message.
+var errClaimed = errors.New("s3: message claimed")
+
+// Claim leases the oldest message that isn't leased, or whose lease has
+// expired, for the ttl, during which no other consumer can claim it. The
+// message must be acknowledged with Ack once processed, or released with
+// Nack; otherwise it is claimed again once the lease expires. Claim returns
+// nil when there is no message to claim.
+func (q *Queue) Claim(ttl time.Duration) (*Message, error) {
+
+	var m *Message
+	err := q.c.walk(q.prefix, func(obj types.Object) error {
+		var err error
+		if m, err = q.claim(*obj.Key, ttl); err == nil && m != nil {
+			return errClaimed
+		}
+		return err
+	})
+	if errors.Is(err, errClaimed) {
+		err = nil
+	}
+
+	var id string
+	if m != nil {
+		id = m.ID
+	}
+	q.c.log("Claim", err).
+		Str("prefix", q.prefix).
+		Str("id", id).
+		Dur("ttl", ttl).
+		Msg("Claim")
+
+	return m, err
+}
+
+// claim leases the message stored under the key, returning nil when it is
+// leased, or was acknowledged or claimed concurrently.
+func (q *Queue) claim(k string, ttl time.Duration) (*Message, error) {
+	out, err := q.c.getObject(&s3.GetObjectInput{Bucket: q.c.Bucket, Key: &k})
+	if IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var env queueEnvelope
+	err = json.NewDecoder(out.Body).Decode(&env)
+	out.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("s3: decoding queue message %s: %w", k, err)
+	}
+
+	now := time.Now()
+	if env.LeaseUntil.After(now) {
+		return nil, nil
+	}
+	env.Attempts++
+	env.LeaseUntil = now.Add(ttl)
+	etag, err := q.write(k, env, &s3.PutObjectInput{IfMatch: out.ETag})
+	if isPreconditionFailed(err) || IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Message{ID: strings.TrimPrefix(k, q.prefix), Body: env.Body, Attempts: env.Attempts, etag: etag}, nil
+}
+
+// Ack deletes the processed message from the queue. It fails with
+// ErrLeaseLost if the message was claimed again after its lease expired.
+func (q *Queue) Ack(m *Message) error {
+
+	k := q.prefix + m.ID
+	_, err := q.c.deleteObject(&s3.DeleteObjectInput{
+		Bucket:  q.c.Bucket,
+		Key:     &k,
+		IfMatch: m.etag,
+	})
+	if isPreconditionFailed(err) || IsNotFound(err) {
+		err = fmt.Errorf("%w: %s", ErrLeaseLost, m.ID)
+	}
+
+	q.c.log("Ack", err).
+		Str("key", k).
+		Int("attempts", m.Attempts).
+		Msg("Ack")
+
+	return err
+}
+
+// Nack releases the message so it can be claimed again after the delay,
+// e.g. to back off from a failure. It fails with ErrLeaseLost if the message
+// was claimed again after its lease expired.
+func (q *Queue) Nack(m *Message, delay time.Duration) error {
+
+	k := q.prefix + m.ID
+	env := queueEnvelope{Body: m.Body, Attempts: m.Attempts}
+	if delay > 0 {
+		env.LeaseUntil = time.Now().Add(delay)
+	}
+	_, err := q.write(k, env, &s3.PutObjectInput{IfMatch: m.etag})
+	if isPreconditionFailed(err) || IsNotFound(err) {
+		err = fmt.Errorf("%w: %s", ErrLeaseLost, m.ID)
+	}
+
+	q.c.log("Nack", err).
+		Str("key", k).
+		Int("attempts", m.Attempts).
+		Dur("delay", delay).
+		Msg("Nack")
+
+	return err
+}
@@ -0,0 +1,107 @@
+package s3
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueue(t *testing.T) {
+
+	c, _ := newTestBucket(t)
+	q := c.Queue("jobs")
+
+	a, err := q.Enqueue("a")
+	assert.NoError(t, err)
+	_, err = q.Enqueue(map[string]int{"b": 1})
+	assert.NoError(t, err)
+
+	m, err := q.Claim(time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, a, m.ID)
+	assert.Equal(t, "a", string(m.Body))
+	assert.Equal(t, 1, m.Attempts)
+
+	b, err := q.Claim(time.Minute)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"b":1}`, string(b.Body))
+
+	none, err := q.Claim(time.Minute)
+	assert.NoError(t, err)
+	assert.Nil(t, none)
+
+	// released messages are claimed again
+	assert.NoError(t, q.Nack(m, 0))
+	assert.ErrorIs(t, q.Ack(m), ErrLeaseLost)
+	m, err = q.Claim(time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, a, m.ID)
+	assert.Equal(t, 2, m.Attempts)
+	assert.NoError(t, q.Ack(m))
+	assert.ErrorIs(t, q.Ack(m), ErrLeaseLost)
+
+	// delayed messages aren't
+	assert.NoError(t, q.Nack(b, time.Minute))
+	none, err = q.Claim(time.Minute)
+	assert.NoError(t, err)
+	assert.Nil(t, none)
+}
+
+func TestQueue_LeaseExpired(t *testing.T) {
+
+	c, _ := newTestBucket(t)
+	q := c.Queue("jobs/")
+	_, err := q.Enqueue("a")
+	assert.NoError(t, err)
+
+	expired, err := q.Claim(-time.Second)
+	assert.NoError(t, err)
+
+	m, err := q.Claim(time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, expired.ID, m.ID)
+	assert.Equal(t, 2, m.Attempts)
+
+	assert.ErrorIs(t, q.Ack(expired), ErrLeaseLost)
+	assert.ErrorIs(t, q.Nack(expired, 0), ErrLeaseLost)
+	assert.NoError(t, q.Ack(m))
+}
+
+func TestQueue_Concurrent(t *testing.T) {
+
+	c, _ := newTestBucket(t)
+	q := c.Queue("jobs")
+	for i := range 20 {
+		_, err := q.Enqueue(strconv.Itoa(i))
+		assert.NoError(t, err)
+	}
+
+	var mu sync.Mutex
+	claimed := map[string]int{}
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				m, err := q.Claim(time.Minute)
+				if !assert.NoError(t, err) || m == nil {
+					return
+				}
+				mu.Lock()
+				claimed[string(m.Body)]++
+				mu.Unlock()
+				assert.NoError(t, q.Ack(m))
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, claimed, 20)
+	for body, n := range claimed {
+		assert.Equal(t, 1, n, body)
+	}
+}
@@ -0,0 +1,192 @@
+package s3
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// ErrQuotaExceeded is returned by writes that would take a prefix over its quota.
+var ErrQuotaExceeded = errors.New("s3: quota exceeded")
+
+// defaultQuotaRefresh is how long quota usage is trusted before it is
+// recomputed from the prefix stats.
+const defaultQuotaRefresh = 5 * time.Minute
+
+type quota struct {
+	prefix     string
+	maxBytes   int64
+	maxObjects int64
+
+	mu        sync.Mutex
+	bytes     int64
+	objects   int64
+	refreshed time.Time
+
+	// refreshing is closed when the refresh in progress, if any, is done
+	refreshing     chan struct{}
+	refreshBytes   int64
+	refreshObjects int64
+}
+
+// WithQuota limits the total size and number of objects under the prefix,
+// such as a tenant's namespace, failing writes that would exceed either limit
+// with ErrQuotaExceeded. A limit of 0 is unlimited. Usage is computed with
+// Stats when first needed and again after the refresh interval, and is kept
+// up to date in between by the writes and deletes made through the client,
+// an overwrite counting the change in the object's size. Copies count the
+// size of their source, so objects staged under .staging/ count once promoted.
+func WithQuota(prefix string, maxBytes, maxObjects int64) Option {
+	return func(o *options) {
+		o.quotas = append(o.quotas, &quota{prefix: prefix, maxBytes: maxBytes, maxObjects: maxObjects})
+	}
+}
+
+// WithQuotaRefresh sets how often quota usage is recomputed, 5 minutes by default.
+func WithQuotaRefresh(d time.Duration) Option {
+	return func(o *options) {
+		o.quotaRefresh = d
+	}
+}
+
+// reserve checks that writing size bytes to the key keeps every quota covering
+// it within its limits and adds the write to their usage, less the size of the
+// object it overwrites, if any. It returns a func that releases the
+// reservation, which writes that fail after reserving must call.
+func (c *client) reserve(k string, size int64) (func(), error) {
+	quotas, old, exists, err := c.quotaUsage(k)
+	if err != nil || len(quotas) == 0 {
+		return func() {}, err
+	}
+	bytes, objects := size, int64(1)
+	if exists {
+		bytes, objects = size-old, 0
+	}
+	for i, q := range quotas {
+		if err = c.reserveQuota(q, bytes, objects); err != nil {
+			adjustQuotas(quotas[:i], -bytes, -objects)
+			return nil, err
+		}
+	}
+	return func() { adjustQuotas(quotas, -bytes, -objects) }, nil
+}
+
+// unreserve returns a func that removes the object at the key from the usage
+// of every quota covering it, which deletes call once the object is deleted.
+func (c *client) unreserve(k string) (func(), error) {
+	quotas, size, exists, err := c.quotaUsage(k)
+	if err != nil || !exists {
+		return func() {}, err
+	}
+	return func() { adjustQuotas(quotas, -size, -1) }, nil
+}
+
+// quotaUsage returns the quotas covering the key and, when there are any, the
+// size of the object at the key and whether there is one.
+func (c *client) quotaUsage(k string) ([]*quota, int64, bool, error) {
+	var quotas []*quota
+	for _, q := range c.quotas {
+		if strings.HasPrefix(k, q.prefix) {
+			quotas = append(quotas, q)
+		}
+	}
+	if len(quotas) == 0 {
+		return nil, 0, false, nil
+	}
+	head, err := c.HeadObject(c.Context, &s3.HeadObjectInput{
+		Bucket: c.Bucket,
+		Key:    &k,
+	})
+	if IsNotFound(err) {
+		return quotas, 0, false, nil
+	} else if err != nil {
+		return nil, 0, false, err
+	}
+	return quotas, aws.ToInt64(head.ContentLength), true, nil
+}
+
+func adjustQuotas(quotas []*quota, bytes, objects int64) {
+	for _, q := range quotas {
+		q.mu.Lock()
+		q.add(bytes, objects)
+		q.mu.Unlock()
+	}
+}
+
+// add adds to the quota's usage, and to the changes made during a refresh,
+// which are applied on top of the usage it computes.
+func (q *quota) add(bytes, objects int64) {
+	q.bytes += bytes
+	q.objects += objects
+	if q.refreshing != nil {
+		q.refreshBytes += bytes
+		q.refreshObjects += objects
+	}
+}
+
+func (c *client) reserveQuota(q *quota, bytes, objects int64) error {
+	if err := c.refreshQuota(q); err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var err error
+	if q.maxBytes > 0 && bytes > 0 && q.bytes+bytes > q.maxBytes {
+		err = fmt.Errorf("%w: %s would use %d of %d bytes", ErrQuotaExceeded, q.prefix, q.bytes+bytes, q.maxBytes)
+	} else if q.maxObjects > 0 && objects > 0 && q.objects+objects > q.maxObjects {
+		err = fmt.Errorf("%w: %s would hold %d of %d objects", ErrQuotaExceeded, q.prefix, q.objects+objects, q.maxObjects)
+	} else {
+		q.add(bytes, objects)
+	}
+
+	c.log("Quota", err).
+		Str("prefix", q.prefix).
+		Int64("bytes", q.bytes).
+		Int64("objects", q.objects).
+		Msg("Quota")
+
+	return err
+}
+
+// refreshQuota recomputes the quota's usage with Stats once it is older than
+// the refresh interval. The prefix is listed without holding the quota's lock
+// and by one caller at a time, the others waiting for its result.
+func (c *client) refreshQuota(q *quota) error {
+	refresh := c.quotaRefresh
+	if refresh == 0 {
+		refresh = defaultQuotaRefresh
+	}
+
+	q.mu.Lock()
+	for q.refreshing != nil {
+		done := q.refreshing
+		q.mu.Unlock()
+		<-done
+		q.mu.Lock()
+	}
+	if time.Since(q.refreshed) <= refresh {
+		q.mu.Unlock()
+		return nil
+	}
+	done := make(chan struct{})
+	q.refreshing, q.refreshBytes, q.refreshObjects = done, 0, 0
+	q.mu.Unlock()
+
+	objects, bytes, err := c.Stats(q.prefix)
+
+	q.mu.Lock()
+	if err == nil {
+		q.objects, q.bytes, q.refreshed = objects+q.refreshObjects, bytes+q.refreshBytes, time.Now()
+	}
+	q.refreshing = nil
+	q.mu.Unlock()
+	close(done)
+	return err
+}
@@ -0,0 +1,116 @@
+package s3
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithQuota(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	assert.NoError(t, c.Put("tenants/a/1", strings.Repeat("x", 60)))
+
+	WithQuota("tenants/a/", 100, 3)(c.options)
+	WithQuota("tenants/b/", 0, 1)(c.options)
+
+	assert.ErrorIs(t, c.Put("tenants/a/2", strings.Repeat("x", 41)), ErrQuotaExceeded)
+	assert.NoError(t, c.Put("tenants/a/2", strings.Repeat("x", 40)))
+	assert.ErrorIs(t, c.Put("tenants/a/3", "x"), ErrQuotaExceeded)
+
+	assert.NoError(t, c.Put("tenants/b/1", "x"))
+	assert.ErrorIs(t, c.Copy("tenants/b/1", "tenants/b/2"), ErrQuotaExceeded)
+	assert.NoError(t, c.Put("tenants/c/1", strings.Repeat("x", 1000)))
+
+	assert.Equal(t, []string{"tenants/a/1", "tenants/a/2", "tenants/b/1", "tenants/c/1"}, b.keys())
+}
+
+func TestWithQuotaRefresh(t *testing.T) {
+
+	c, _ := newTestBucket(t)
+	WithQuota("tenants/a/", 0, 1)(c.options)
+	WithQuotaRefresh(time.Nanosecond)(c.options)
+
+	assert.NoError(t, c.Put("tenants/a/1", "x"))
+	assert.ErrorIs(t, c.Put("tenants/a/2", "x"), ErrQuotaExceeded)
+
+	// objects deleted behind the client's back are picked up by the next refresh
+	_, err := c.DeleteObject(c.Context, &s3.DeleteObjectInput{Bucket: c.Bucket, Key: aws.String("tenants/a/1")})
+	assert.NoError(t, err)
+	assert.NoError(t, c.Put("tenants/a/2", "x"))
+}
+
+func TestWithQuota_overwriteAndDelete(t *testing.T) {
+
+	c, _ := newTestBucket(t)
+	WithQuota("tenants/a/", 20, 2)(c.options)
+
+	assert.NoError(t, c.Put("tenants/a/1", strings.Repeat("x", 10)))
+	assert.NoError(t, c.Put("tenants/a/2", strings.Repeat("x", 10)))
+
+	// overwrites count the change in size, not another object
+	assert.NoError(t, c.Put("tenants/a/1", strings.Repeat("x", 5)))
+	assert.NoError(t, c.Put("tenants/a/2", strings.Repeat("x", 15)))
+	assert.ErrorIs(t, c.Put("tenants/a/2", strings.Repeat("x", 16)), ErrQuotaExceeded)
+	assert.NoError(t, c.Touch("tenants/a/2"))
+
+	// deletes free their object's usage
+	assert.ErrorIs(t, c.Put("tenants/a/3", "x"), ErrQuotaExceeded)
+	assert.NoError(t, c.Delete("tenants/a/2"))
+	assert.NoError(t, c.Put("tenants/a/3", strings.Repeat("x", 15)))
+}
+
+func TestWithQuota_refreshOnce(t *testing.T) {
+
+	var lists atomic.Int32
+	c, _ := newTestBucket(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet && r.URL.Query().Has("list-type") {
+				lists.Add(1)
+				time.Sleep(10 * time.Millisecond)
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	WithQuota("tenants/a/", 0, 100)(c.options)
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, c.Put(fmt.Sprintf("tenants/a/%d", i), "x"))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), lists.Load())
+}
+
+func TestWithQuota_failedWrite(t *testing.T) {
+
+	c, _ := newTestBucket(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasSuffix(r.URL.Path, "/denied") {
+				writeError(w, http.StatusForbidden, "AccessDenied")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	WithQuota("tenants/a/", 20, 2)(c.options)
+
+	// failed writes don't use the quota
+	assert.Error(t, c.Put("tenants/a/denied", strings.Repeat("x", 10)))
+	assert.NoError(t, c.Put("tenants/a/1", strings.Repeat("x", 10)))
+	assert.Error(t, c.Copy("tenants/a/1", "tenants/a/denied"))
+	assert.NoError(t, c.Copy("tenants/a/1", "tenants/a/2"))
+	assert.ErrorIs(t, c.Copy("tenants/a/1", "tenants/a/3"), ErrQuotaExceeded)
+}
@@ -0,0 +1,121 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// readAhead reads an object sequentially in ranged chunks, fetching the
+// next chunks in the background while the current one is consumed.
+type readAhead struct {
+	c      *client
+	ctx    context.Context
+	cancel context.CancelFunc
+	key    string
+	etag   string
+	size   int64
+	chunk  int64
+	next   int64
+	queue  []chan readAheadChunk
+	cur    []byte
+	err    error
+}
+
+type readAheadChunk struct {
+	body []byte
+	err  error
+}
+
+// ReadAhead returns the body of the object for sequential reading, fetching
+// it in chunks of chunkSize bytes with up to n ranged GETs in flight ahead of
+// the reader, which is much faster than GetReader for large objects consumed
+// by slow readers. Every chunk must come from the same version of the
+// object, so reads fail with a precondition error if it is replaced midway.
+// The caller must close it.
+func (c *client) ReadAhead(k string, chunkSize int64, n int) (io.ReadCloser, error) {
+	if chunkSize <= 0 {
+		return nil, fmt.Errorf("s3: read ahead chunk size must be positive, got %d", chunkSize)
+	}
+
+	info, err := c.Stat(k)
+
+	var r *readAhead
+	if err == nil {
+		ctx, cancel := context.WithCancel(c.Context)
+		r = &readAhead{c: c, ctx: ctx, cancel: cancel, key: k, etag: info.ETag, size: info.Size, chunk: chunkSize}
+		for range max(n, 1) {
+			r.fetch()
+		}
+	}
+
+	c.log("ReadAhead", err).
+		Str("key", k).
+		Int64("size", info.Size).
+		Int64("chunk", chunkSize).
+		Int("ahead", n).
+		Msg("ReadAhead")
+
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// fetch starts fetching the next chunk, if any.
+func (r *readAhead) fetch() {
+	if r.next >= r.size {
+		return
+	}
+	off, end := r.next, min(r.next+r.chunk, r.size)-1
+	r.next = end + 1
+	ch := make(chan readAheadChunk, 1)
+	r.queue = append(r.queue, ch)
+	go func() {
+		out, err := r.c.GetObject(r.ctx, &s3.GetObjectInput{
+			Bucket:  r.c.Bucket,
+			Key:     &r.key,
+			IfMatch: &r.etag,
+			Range:   aws.String(fmt.Sprintf("bytes=%d-%d", off, end)),
+		})
+		if err != nil {
+			ch <- readAheadChunk{err: err}
+			return
+		}
+		defer out.Body.Close()
+		body := make([]byte, end-off+1)
+		_, err = io.ReadFull(out.Body, body)
+		ch <- readAheadChunk{body, err}
+	}()
+}
+
+func (r *readAhead) Read(p []byte) (int, error) {
+	for len(r.cur) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if len(r.queue) == 0 {
+			return 0, io.EOF
+		}
+		chunk := <-r.queue[0]
+		r.queue = r.queue[1:]
+		if r.cur, r.err = chunk.body, chunk.err; r.err == nil {
+			r.fetch()
+		}
+	}
+	n := copy(p, r.cur)
+	r.cur = r.cur[n:]
+	return n, nil
+}
+
+// Close cancels the chunks still being fetched.
+func (r *readAhead) Close() error {
+	r.cancel()
+	if r.err == nil {
+		r.err = io.ErrClosedPipe
+	}
+	return nil
+}
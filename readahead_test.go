@@ -0,0 +1,57 @@
+package s3
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_ReadAhead(t *testing.T) {
+
+	var ranged atomic.Int32
+	c, b := newTestBucket(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Range") != "" {
+				ranged.Add(1)
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	body := bytes.Repeat([]byte("0123456789"), 10)
+	b.put("a", body, nil)
+
+	r, err := c.ReadAhead("a", 16, 3)
+	assert.NoError(t, err)
+	assert.NoError(t, iotest.TestReader(r, body))
+	assert.NoError(t, r.Close())
+	assert.Equal(t, int32(7), ranged.Load())
+
+	// chunks must come from the same version
+	r, err = c.ReadAhead("a", 16, 1)
+	assert.NoError(t, err)
+	p := make([]byte, 16)
+	_, err = io.ReadFull(r, p)
+	assert.NoError(t, err)
+	b.mu.Lock()
+	b.put("a", []byte("replaced"), nil)
+	b.mu.Unlock()
+	_, err = io.ReadAll(r)
+	assert.True(t, isPreconditionFailed(err))
+	assert.NoError(t, r.Close())
+
+	b.put("empty", nil, nil)
+	r, err = c.ReadAhead("empty", 16, 3)
+	assert.NoError(t, err)
+	out, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Empty(t, out)
+
+	_, err = c.ReadAhead("missing", 16, 3)
+	assert.True(t, IsNotFound(err))
+}
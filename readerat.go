@@ -0,0 +1,100 @@
+package s3
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// readerAtChunk is how many bytes objectReaderAt fetches with each ranged GET.
+var readerAtChunk int64 = 8 << 20
+
+// readerAtChunks is how many chunks objectReaderAt keeps, enough for a zip
+// entry's data and the reads of its header or the central directory.
+const readerAtChunks = 2
+
+// objectReaderAt reads an object with ranged GETs, for formats such as zip
+// that need random access without buffering the whole object. Reads are
+// served from the last chunks fetched, so the many small reads of archive/zip
+// and flate cost one GET per chunk. Every chunk must come from the version
+// of the object first stat'ed, so reads fail with a precondition error if it
+// is replaced midway.
+type objectReaderAt struct {
+	c    *client
+	key  string
+	etag string
+	size int64
+
+	mu     sync.Mutex
+	chunks []readerAtChunkBody
+}
+
+type readerAtChunkBody struct {
+	off  int64
+	body []byte
+}
+
+func (c *client) newReaderAt(k string) (*objectReaderAt, error) {
+	info, err := c.Stat(k)
+	if err != nil {
+		return nil, err
+	}
+	return &objectReaderAt{c: c, key: k, etag: info.ETag, size: info.Size}, nil
+}
+
+func (r *objectReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off >= r.size {
+		return 0, io.EOF
+	}
+	var n int
+	for n < len(p) && off+int64(n) < r.size {
+		at := off + int64(n)
+		chunk, err := r.chunk(at - at%readerAtChunk)
+		if err != nil {
+			return n, err
+		}
+		n += copy(p[n:], chunk.body[at-chunk.off:])
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// chunk returns the chunk starting at off, fetching it if it isn't kept.
+func (r *objectReaderAt) chunk(off int64) (readerAtChunkBody, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, chunk := range r.chunks {
+		if chunk.off == off {
+			return chunk, nil
+		}
+	}
+
+	end := min(off+readerAtChunk, r.size) - 1
+	out, err := r.c.GetObject(r.c.Context, &s3.GetObjectInput{
+		Bucket:  r.c.Bucket,
+		Key:     &r.key,
+		IfMatch: &r.etag,
+		Range:   aws.String(fmt.Sprintf("bytes=%d-%d", off, end)),
+	})
+	if err != nil {
+		return readerAtChunkBody{}, err
+	}
+	defer out.Body.Close()
+	body := make([]byte, end-off+1)
+	if _, err = io.ReadFull(out.Body, body); err != nil {
+		return readerAtChunkBody{}, err
+	}
+
+	chunk := readerAtChunkBody{off, body}
+	if len(r.chunks) == readerAtChunks {
+		// the oldest chunk is dropped
+		r.chunks = r.chunks[1:]
+	}
+	r.chunks = append(r.chunks, chunk)
+	return chunk, nil
+}
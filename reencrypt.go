@@ -0,0 +1,125 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// reEncryptProgress is how many objects ReEncrypt processes between the
+// progress it logs.
+const reEncryptProgress = 1000
+
+// ReEncryptReport is the outcome of a ReEncrypt.
+type ReEncryptReport struct {
+	ReEncrypted int
+	Skipped     int
+	Bytes       int64
+	Failures    []ReEncryptFailure
+}
+
+// ReEncryptFailure describes an object that couldn't be re-encrypted.
+type ReEncryptFailure struct {
+	Key string
+	Err error
+}
+
+// ReEncrypt copies every object under the prefix over itself encrypted with
+// SSE-KMS under the KMS key, keeping its headers, metadata, tags and storage
+// class, e.g. to rotate to a new key. Objects already encrypted with the key,
+// given by its ID or ARN, and objects deleted while re-encrypting are
+// skipped. A key given by an alias is never matched, as S3 reports the key
+// an object is encrypted with by its ARN, so every object is copied again.
+// Only current versions are re-encrypted: in a versioned bucket the
+// noncurrent versions keep their key, which must stay enabled until they
+// expire or are deleted. Objects that fail, including those replaced midway,
+// are reported as failures rather than stopping the job, so it can be run
+// again to retry them; only a failure to list the prefix is returned as an
+// error. Progress is logged every 1,000 objects and failures are reported in
+// no particular order.
+func (c *client) ReEncrypt(p, kmsKey string, workers int) (ReEncryptReport, error) {
+
+	var mu sync.Mutex
+	var report ReEncryptReport
+	err := c.WalkParallel(p, workers, func(ctx context.Context, obj ObjectInfo) error {
+		copied, err := c.withContext(ctx).reEncrypt(obj.Key, kmsKey)
+		if IsNotFound(err) {
+			copied, err = false, nil
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case err != nil:
+			report.Failures = append(report.Failures, ReEncryptFailure{obj.Key, err})
+		case copied:
+			report.ReEncrypted++
+			report.Bytes += obj.Size
+		default:
+			report.Skipped++
+		}
+		if n := report.ReEncrypted + report.Skipped + len(report.Failures); n%reEncryptProgress == 0 {
+			c.log("ReEncrypt", nil).
+				Str("prefix", p).
+				Int("processed", n).
+				Int("failures", len(report.Failures)).
+				Msg("ReEncrypt progress")
+		}
+		return nil
+	})
+
+	c.log("ReEncrypt", err).
+		Str("prefix", p).
+		Int("reEncrypted", report.ReEncrypted).
+		Int("skipped", report.Skipped).
+		Int("failures", len(report.Failures)).
+		Msg("ReEncrypt")
+
+	return report, err
+}
+
+// reEncrypt copies the object over itself encrypted with the KMS key,
+// returning false if it already is.
+func (c *client) reEncrypt(k, kmsKey string) (bool, error) {
+	head, err := c.HeadObject(c.Context, &s3.HeadObjectInput{
+		Bucket: c.Bucket,
+		Key:    &k,
+	})
+	if err != nil {
+		return false, err
+	}
+	if head.ServerSideEncryption == types.ServerSideEncryptionAwsKms && kmsKeyMatches(aws.ToString(head.SSEKMSKeyId), kmsKey) {
+		return false, nil
+	}
+
+	if aws.ToInt64(head.ContentLength) > maxCopySize {
+		err = c.multipartCopy(k, k, head, func(in *s3.CreateMultipartUploadInput) {
+			in.ServerSideEncryption = types.ServerSideEncryptionAwsKms
+			in.SSEKMSKeyId = &kmsKey
+		})
+	} else {
+		_, err = c.copyObject(&s3.CopyObjectInput{
+			Bucket:               c.Bucket,
+			Key:                  &k,
+			CopySource:           aws.String(c.copySource(k)),
+			CopySourceIfMatch:    head.ETag,
+			StorageClass:         head.StorageClass,
+			ServerSideEncryption: types.ServerSideEncryptionAwsKms,
+			SSEKMSKeyId:          &kmsKey,
+		}, aws.ToInt64(head.ContentLength))
+	}
+	if isPreconditionFailed(err) {
+		err = fmt.Errorf("s3: %s replaced while re-encrypting: %w", k, err)
+	}
+	return err == nil, err
+}
+
+// kmsKeyMatches reports whether the key ARN S3 returns for an object is the
+// KMS key given by its ID or ARN.
+func kmsKeyMatches(arn, key string) bool {
+	return arn == key || strings.HasSuffix(arn, ":key/"+key)
+}
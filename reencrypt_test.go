@@ -0,0 +1,118 @@
+package s3
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_ReEncrypt(t *testing.T) {
+
+	const key = "arn:aws:kms:us-east-1:111122223333:key/new"
+
+	c, b := newTestBucket(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/docs/denied.json") {
+				writeError(w, http.StatusForbidden, "AccessDenied")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	b.put("docs/a.json", []byte(`{"a":1}`), http.Header{
+		"Content-Type":   {"application/json"},
+		"X-Amz-Meta-Foo": {"bar"},
+		"X-Amz-Tagging":  {"team=core"},
+	})
+	b.put("docs/b.txt", []byte("b"), http.Header{
+		"X-Amz-Server-Side-Encryption":                {"aws:kms"},
+		"X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id": {"arn:aws:kms:us-east-1:111122223333:key/old"},
+	})
+	b.put("docs/current.txt", []byte("c"), http.Header{
+		"X-Amz-Server-Side-Encryption":                {"aws:kms"},
+		"X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id": {key},
+	})
+	b.put("docs/denied.json", []byte(`{}`), nil)
+	b.put("other.txt", []byte("o"), nil)
+
+	report, err := c.ReEncrypt("docs/", "new", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, report.ReEncrypted)
+	assert.Equal(t, 1, report.Skipped)
+	assert.Equal(t, int64(8), report.Bytes)
+	if assert.Len(t, report.Failures, 1) {
+		assert.Equal(t, "docs/denied.json", report.Failures[0].Key)
+		assert.Error(t, report.Failures[0].Err)
+	}
+
+	for _, k := range []string{"docs/a.json", "docs/b.txt"} {
+		h := b.object(k).header
+		assert.Equal(t, "aws:kms", h.Get("X-Amz-Server-Side-Encryption"), k)
+		assert.Equal(t, "new", h.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"), k)
+	}
+	h := b.object("docs/a.json").header
+	assert.Equal(t, "application/json", h.Get("Content-Type"))
+	assert.Equal(t, "bar", h.Get("X-Amz-Meta-Foo"))
+	assert.Equal(t, "team=core", h.Get("X-Amz-Tagging"))
+	assert.Equal(t, `{"a":1}`, string(b.object("docs/a.json").body))
+	assert.Empty(t, b.object("other.txt").header.Get("X-Amz-Server-Side-Encryption"))
+}
+
+func TestClient_ReEncrypt_kept(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	kms := http.Header{
+		"X-Amz-Server-Side-Encryption":                {"aws:kms"},
+		"X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id": {"new"},
+		"X-Amz-Storage-Class":                         {"STANDARD_IA"},
+	}
+	stream := func(k string) {
+		u := c.newUploader(&s3.PutObjectInput{
+			Key:                  aws.String(k),
+			StorageClass:         types.StorageClassStandardIa,
+			ServerSideEncryption: types.ServerSideEncryptionAwsKms,
+			SSEKMSKeyId:          aws.String("new"),
+		})
+		_, err := u.Write(bytes.Repeat([]byte("x"), partSize+1))
+		assert.NoError(t, err)
+		assert.NoError(t, u.Close())
+	}
+
+	// rewrites keep the encryption objects were re-encrypted with
+	b.put("docs/a.json", []byte(`{"a":1}`), kms)
+	assert.NoError(t, c.Touch("docs/a.json"))
+	assert.NoError(t, c.Copy("docs/a.json", "docs/copy.json"))
+	assert.NoError(t, c.MigrateCodec("docs/", JSONCodec, GzipCodec(JSONCodec), nil, 1))
+	stream("streamed.bin")
+
+	defer func(size, part int64) { maxCopySize, copyPartSize = size, part }(maxCopySize, copyPartSize)
+	maxCopySize, copyPartSize = 16, 10
+	b.put("large.bin", bytes.Repeat([]byte("x"), 36), kms)
+	assert.NoError(t, c.Touch("large.bin"))
+	assert.NoError(t, c.Copy("large.bin", "large-copy.bin"))
+	stream("large-streamed.bin")
+
+	for _, k := range []string{"docs/a.json", "docs/copy.json", "streamed.bin", "large.bin", "large-copy.bin", "large-streamed.bin"} {
+		h := b.object(k).header
+		assert.Equal(t, "aws:kms", h.Get("X-Amz-Server-Side-Encryption"), k)
+		assert.Equal(t, "new", h.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"), k)
+		assert.Equal(t, "STANDARD_IA", h.Get("X-Amz-Storage-Class"), k)
+	}
+	assert.Equal(t, "gzip", b.object("docs/a.json").header.Get("Content-Encoding"))
+	assert.NotEmpty(t, b.object("large-streamed.bin").header.Get("X-Amz-Meta-Sha256"))
+}
+
+func Test_kmsKeyMatches(t *testing.T) {
+	const arn = "arn:aws:kms:us-east-1:111122223333:key/1234abcd"
+	assert.True(t, kmsKeyMatches(arn, arn))
+	assert.True(t, kmsKeyMatches(arn, "1234abcd"))
+	assert.False(t, kmsKeyMatches(arn, "abcd"))
+	assert.False(t, kmsKeyMatches(arn, "alias/current"))
+}
@@ -0,0 +1,92 @@
+package s3
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// renamePrefix is where RenamePrefix keeps the journals of unfinished renames.
+const renamePrefix = ".rename/"
+
+// renameJournal records the keys a rename moves, so it can be resumed.
+type renameJournal struct {
+	Source      string   `json:"source"`
+	Destination string   `json:"destination"`
+	Keys        []string `json:"keys"`
+}
+
+// renameJournalKey returns the key of the journal of renaming src to dst.
+func renameJournalKey(src, dst string) string {
+	sum := sha256.Sum256([]byte(src + "\n" + dst))
+	return renamePrefix + hex.EncodeToString(sum[:]) + ".json"
+}
+
+// RenamePrefix moves every object under the src prefix to the dst prefix,
+// replacing src with dst in their keys, with server-side copies followed by
+// deletes of the originals made by up to concurrency workers. The keys are
+// listed once and journaled under .rename/ before anything is moved, and the
+// journal is deleted once they all are, so calling RenamePrefix again with
+// the same prefixes after a crash or error completes the rename.
+func (c *client) RenamePrefix(src, dst string, concurrency int) error {
+
+	jk := renameJournalKey(src, dst)
+	var j renameJournal
+	err := c.readJSON(jk, &j)
+	resumed := err == nil
+	if IsNotFound(err) {
+		j = renameJournal{Source: src, Destination: dst}
+		err = c.walk(src, func(obj types.Object) error {
+			if !strings.HasPrefix(*obj.Key, renamePrefix) {
+				j.Keys = append(j.Keys, *obj.Key)
+			}
+			return nil
+		})
+		if err == nil {
+			err = c.Put(jk, j)
+		}
+	}
+
+	var moved int64
+	if err == nil {
+		moved, err = c.renameKeys(j, concurrency)
+	}
+	if err == nil {
+		err = c.Delete(jk)
+	}
+
+	c.log("RenamePrefix", err).
+		Str("src", src).
+		Str("dst", dst).
+		Bool("resumed", resumed).
+		Int("keys", len(j.Keys)).
+		Int64("moved", moved).
+		Msg("RenamePrefix")
+
+	return err
+}
+
+// renameKeys moves the journaled keys, stopping at the first error. Keys that
+// no longer exist were moved before the rename was resumed.
+func (c *client) renameKeys(j renameJournal, concurrency int) (int64, error) {
+	var moved atomic.Int64
+	_, err := parallel(c.Context, concurrency, sendEach(j.Keys), func(ctx context.Context, k string) error {
+		cc := c.withContext(ctx)
+		err := cc.Copy(k, j.Destination+strings.TrimPrefix(k, j.Source))
+		if IsNotFound(err) {
+			return nil
+		}
+		if err == nil {
+			err = cc.Delete(k)
+		}
+		if err == nil {
+			moved.Add(1)
+		}
+		return err
+	})
+	return moved.Load(), err
+}
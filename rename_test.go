@@ -0,0 +1,39 @@
+package s3
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_RenamePrefix(t *testing.T) {
+
+	crash := true
+	c, b := newTestBucket(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if crash && r.Method == http.MethodDelete && strings.HasSuffix(r.URL.Path, "/users/b.json") {
+				writeError(w, http.StatusForbidden, "AccessDenied")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	for _, k := range []string{"users/a.json", "users/b.json", "users/c.json", "usersettings.json"} {
+		b.put(k, []byte(k), nil)
+	}
+
+	assert.Error(t, c.RenamePrefix("users/", "people/", 1))
+	assert.Contains(t, b.keys(), renameJournalKey("users/", "people/"))
+	assert.Contains(t, b.keys(), "users/b.json")
+
+	// objects written after the rename started aren't moved when it resumes
+	b.put("users/d.json", nil, nil)
+
+	crash = false
+	assert.NoError(t, c.RenamePrefix("users/", "people/", 2))
+	assert.Equal(t, []string{"people/a.json", "people/b.json", "people/c.json", "users/d.json", "usersettings.json"}, b.keys())
+	assert.Equal(t, "users/b.json", string(b.object("people/b.json").body))
+}
@@ -0,0 +1,109 @@
+package s3
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+type replica struct {
+	bucket string
+	region string
+	client *s3.Client
+}
+
+// WithReplica makes Get, GetReader and Find read from the replica bucket in
+// the region, such as a Cross-Region Replication destination, when the
+// primary bucket fails after retries. Keys missing from the primary are not
+// looked up in the replica, and failed requests are only retried once the
+// SDK's own retries are exhausted, so bound slow requests with WithHTTPClient.
+func WithReplica(bucket, region string) Option {
+	return func(o *options) {
+		o.replica = &replica{bucket: bucket, region: region}
+	}
+}
+
+// newClient returns an S3 client for the replica's region.
+func (r *replica) newClient(cfg aws.Config, optFns []func(*s3.Options)) *s3.Client {
+	return s3.NewFromConfig(cfg, func(so *s3.Options) {
+		for _, fn := range optFns {
+			fn(so)
+		}
+		so.Region = r.region
+	})
+}
+
+// getObject gets an object from the bucket, failing over to the replica.
+// Objects are read through the Object Lambda access point, if any, unless
+// in.Bucket is set, and never fail over as the replica's aren't transformed.
+func (c *client) getObject(in *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	if err := c.negativeCache.get(*in.Key); err != nil {
+		return nil, err
+	}
+	transformed := in.Bucket == nil && c.objectLambda != ""
+	if transformed {
+		in.Bucket = &c.objectLambda
+	} else {
+		in.Bucket = c.Bucket
+	}
+	out, err := c.GetObject(c.Context, in)
+	c.negativeCache.add(*in.Key, err)
+	if err == nil || c.replica == nil || transformed || IsNotFound(err) || isNotModified(err) || errors.Is(err, c.Err()) {
+		return out, err
+	}
+
+	primary := err
+	rin := *in
+	rin.Bucket = &c.replica.bucket
+	out, err = c.replica.client.GetObject(c.Context, &rin)
+
+	c.warn().
+		Err(err).
+		AnErr("primary", primary).
+		Str("key", *in.Key).
+		Str("replica", c.replica.bucket).
+		Msg("Failover")
+
+	return out, err
+}
+
+// read returns the body of an object the client stores itself, such as a
+// manifest, journal or checkpoint, or one it rewrites. It is read from the
+// bucket rather than the Object Lambda access point, and bypasses the cache
+// and hooks, so it is the object as stored.
+func (c *client) read(k string) ([]byte, error) {
+	out, err := c.getObject(&s3.GetObjectInput{Bucket: c.Bucket, Key: &k})
+	if err != nil {
+		return nil, err
+	}
+	defer out.Body.Close()
+	return readAll(out.Body, aws.ToInt64(out.ContentLength))
+}
+
+// getIfNoneMatch returns the body and ETag of an object read from the bucket
+// as read does, failing with the SDK's 304 error when its ETag is still
+// etag. It lets a ConfigStore poll a document in a single request.
+func (c *client) getIfNoneMatch(k, etag string) ([]byte, string, error) {
+	in := &s3.GetObjectInput{Bucket: c.Bucket, Key: &k}
+	if etag != "" {
+		in.IfNoneMatch = &etag
+	}
+	out, err := c.getObject(in)
+	if err != nil {
+		return nil, "", err
+	}
+	defer out.Body.Close()
+	body, err := readAll(out.Body, aws.ToInt64(out.ContentLength))
+	return body, aws.ToString(out.ETag), err
+}
+
+// readJSON decodes the JSON object read as read does into a.
+func (c *client) readJSON(k string, a any) error {
+	b, err := c.read(k)
+	if err == nil {
+		err = json.Unmarshal(b, a)
+	}
+	return err
+}
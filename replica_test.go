@@ -0,0 +1,69 @@
+package s3
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithReplica(t *testing.T) {
+
+	down := true
+	primary, pb := newTestBucket(t)
+	c := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if down {
+			writeError(w, http.StatusForbidden, "AccessDenied")
+			return
+		}
+		pb.ServeHTTP(w, r)
+	})
+
+	_, rb := newTestBucket(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Contains(t, r.Header.Get("Authorization"), "/us-west-2/s3/")
+		r.URL.Path = "/bytelyon-db" + r.URL.Path[len("/bytelyon-db-replica"):]
+		rb.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	c.replica = &replica{bucket: "bytelyon-db-replica", region: "us-west-2"}
+	c.replica.client = c.replica.newClient(c.cfg, []func(*s3.Options){func(o *s3.Options) {
+		o.BaseEndpoint = &srv.URL
+		o.UsePathStyle = true
+	}})
+
+	rb.put(testKey(), []byte(testBody()), nil)
+
+	b, err := c.Get(testKey())
+	assert.NoError(t, err)
+	assert.Equal(t, testBody(), string(b))
+
+	var v map[string]any
+	assert.NoError(t, c.Find(testKey(), &v))
+
+	// keys missing from a healthy primary are not read from the replica
+	down = false
+	_, err = c.Get(testKey())
+	assert.True(t, IsNotFound(err))
+
+	assert.NoError(t, primary.Put(testKey(), "primary"))
+	b, err = c.Get(testKey())
+	assert.NoError(t, err)
+	assert.Equal(t, "primary", string(b))
+}
+
+func TestWithReplica_Client(t *testing.T) {
+	c := NewWithOptions(context.Background(),
+		WithBucket("bytelyon-db"),
+		WithConfig(config.WithRegion("us-east-1")),
+		WithStaticCredentials("id", "secret", ""),
+		WithReplica("bytelyon-db-replica", "us-west-2"),
+	).(*client)
+	assert.Equal(t, "us-west-2", c.replica.client.Options().Region)
+	assert.Equal(t, "us-east-1", c.Options().Region)
+}
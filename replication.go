@@ -0,0 +1,90 @@
+package s3
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ReplicationRule selects the objects replicated by ConfigureReplication.
+// Rules are prioritized in the order given. An empty StorageClass keeps the
+// storage class of the source object.
+type ReplicationRule struct {
+	ID            string
+	Prefix        string
+	StorageClass  StorageClass
+	DeleteMarkers bool
+}
+
+// ConfigureReplication replaces the bucket's replication configuration so the
+// rules replicate objects to the destination bucket ARN as the IAM role. Every
+// object is replicated when no rules are given. Versioning must be enabled on
+// both buckets.
+func (c *client) ConfigureReplication(destBucketARN, roleARN string, rules ...ReplicationRule) error {
+
+	_, err := c.PutBucketReplication(c.Context, &s3.PutBucketReplicationInput{
+		Bucket:                   c.Bucket,
+		ReplicationConfiguration: replicationConfiguration(destBucketARN, roleARN, rules),
+	})
+
+	c.log("ConfigureReplication", err).
+		Str("destination", destBucketARN).
+		Int("rules", len(rules)).
+		Msg("ConfigureReplication")
+
+	return err
+}
+
+func replicationConfiguration(dest, role string, rules []ReplicationRule) *types.ReplicationConfiguration {
+	if len(rules) == 0 {
+		rules = []ReplicationRule{{}}
+	}
+	cfg := &types.ReplicationConfiguration{Role: aws.String(role)}
+	for i, r := range rules {
+		id := r.ID
+		if id == "" {
+			id = fmt.Sprintf("rule-%d", i+1)
+		}
+		deleteMarkers := types.DeleteMarkerReplicationStatusDisabled
+		if r.DeleteMarkers {
+			deleteMarkers = types.DeleteMarkerReplicationStatusEnabled
+		}
+		rule := types.ReplicationRule{
+			ID:                      aws.String(id),
+			Priority:                aws.Int32(int32(len(rules) - i)),
+			Status:                  types.ReplicationRuleStatusEnabled,
+			Filter:                  &types.ReplicationRuleFilter{Prefix: aws.String(r.Prefix)},
+			DeleteMarkerReplication: &types.DeleteMarkerReplication{Status: deleteMarkers},
+			Destination:             &types.Destination{Bucket: aws.String(dest)},
+		}
+		if r.StorageClass != "" {
+			rule.Destination.StorageClass = types.StorageClass(r.StorageClass)
+		}
+		cfg.Rules = append(cfg.Rules, rule)
+	}
+	return cfg
+}
+
+// ReplicationStatus returns the replication status of the object: PENDING,
+// COMPLETED or FAILED on the source, REPLICA on the destination, or empty for
+// objects no rule replicates.
+func (c *client) ReplicationStatus(k string) (string, error) {
+	out, err := c.HeadObject(c.Context, &s3.HeadObjectInput{
+		Bucket: c.Bucket,
+		Key:    &k,
+	})
+
+	var status string
+	if err == nil {
+		status = string(out.ReplicationStatus)
+	}
+
+	c.log("ReplicationStatus", err).
+		Str("key", k).
+		Str("status", status).
+		Msg("ReplicationStatus")
+
+	return status, err
+}
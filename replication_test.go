@@ -0,0 +1,46 @@
+package s3
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplicationConfiguration(t *testing.T) {
+
+	cfg := replicationConfiguration("arn:aws:s3:::dr", "arn:aws:iam::123456789012:role/crr", nil)
+	assert.Equal(t, "arn:aws:iam::123456789012:role/crr", aws.ToString(cfg.Role))
+	assert.Len(t, cfg.Rules, 1)
+	assert.Equal(t, "", aws.ToString(cfg.Rules[0].Filter.Prefix))
+	assert.Equal(t, "arn:aws:s3:::dr", aws.ToString(cfg.Rules[0].Destination.Bucket))
+
+	cfg = replicationConfiguration("arn:aws:s3:::dr", "role", []ReplicationRule{
+		{ID: "users", Prefix: "users/", DeleteMarkers: true},
+		{Prefix: "logs/", StorageClass: Glacier},
+	})
+	assert.Len(t, cfg.Rules, 2)
+	assert.Equal(t, "users", aws.ToString(cfg.Rules[0].ID))
+	assert.Equal(t, int32(2), aws.ToInt32(cfg.Rules[0].Priority))
+	assert.Equal(t, types.DeleteMarkerReplicationStatusEnabled, cfg.Rules[0].DeleteMarkerReplication.Status)
+	assert.Equal(t, "rule-2", aws.ToString(cfg.Rules[1].ID))
+	assert.Equal(t, int32(1), aws.ToInt32(cfg.Rules[1].Priority))
+	assert.Equal(t, types.StorageClassGlacier, cfg.Rules[1].Destination.StorageClass)
+	assert.Empty(t, cfg.Rules[0].Destination.StorageClass)
+}
+
+func TestClient_ReplicationStatus(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	assert.NoError(t, c.Put(testKey(), testBody()))
+
+	status, err := c.ReplicationStatus(testKey())
+	assert.NoError(t, err)
+	assert.Empty(t, status)
+
+	b.object(testKey()).header.Set("X-Amz-Replication-Status", "COMPLETED")
+	status, err = c.ReplicationStatus(testKey())
+	assert.NoError(t, err)
+	assert.Equal(t, "COMPLETED", status)
+}
@@ -0,0 +1,492 @@
+package s3
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/oklog/ulid/v2"
+)
+
+// resumablePrefix is where ResumableUploads keeps the state of unfinished
+// uploads, <id>.json, and the bytes received after their last part.
+const resumablePrefix = ".uploads/"
+
+// tusVersion is the version of the tus resumable upload protocol served.
+const tusVersion = "1.0.0"
+
+// resumableLease is how long a PATCH keeps other requests from resuming the
+// upload after it last uploaded a part, long enough for a slow client to
+// send a part, so an upload whose server crashed can be resumed later.
+var resumableLease = 5 * time.Minute
+
+// errUploadLocked is returned when another request is appending to an upload.
+var errUploadLocked = errors.New("s3: upload is being appended to")
+
+// resumableUpload is the state of an unfinished upload. Its offset is the
+// size of its parts plus the bytes received since, held in the tail object.
+type resumableUpload struct {
+	Key         string          `json:"key"`
+	Staging     string          `json:"staging,omitempty"`
+	Length      int64           `json:"length"`
+	UploadID    string          `json:"uploadId"`
+	Parts       []resumablePart `json:"parts,omitempty"`
+	Tail        string          `json:"tail,omitempty"`
+	TailSize    int64           `json:"tailSize,omitempty"`
+	Writer      string          `json:"writer,omitempty"`
+	WriterUntil time.Time       `json:"writerUntil,omitzero"`
+	etag        *string
+}
+
+type resumablePart struct {
+	ETag string `json:"etag"`
+	Size int64  `json:"size"`
+}
+
+// partSize returns the size of the upload's parts, large enough for its
+// length to fit in the 10,000 parts a multipart upload allows.
+func (u *resumableUpload) partSize() int64 {
+	return max(partSize, (u.Length+9999)/10000)
+}
+
+// uploadKey returns the key the parts are uploaded to: a staging key when
+// the object is inspected on completion, otherwise the object's.
+func (u *resumableUpload) uploadKey() *string {
+	if u.Staging != "" {
+		return &u.Staging
+	}
+	return &u.Key
+}
+
+// committed returns the size of the upload's parts.
+func (u *resumableUpload) committed() int64 {
+	var n int64
+	for _, p := range u.Parts {
+		n += p.Size
+	}
+	return n
+}
+
+type resumable struct {
+	c      *client
+	prefix string
+}
+
+// ResumableUploads returns an http.Handler serving the tus resumable upload
+// protocol, version 1.0.0 with the creation and termination extensions, so
+// clients on unreliable connections can upload large files in pieces and
+// resume after failures. A POST creates an upload stored under the prefix,
+// keyed by its ID so clients can't overwrite each other's files, and
+// returns its URL in the Location header. The filename in its
+// Upload-Metadata is kept in the object's filename metadata, RFC 2047
+// encoded when it isn't ASCII; HEAD requests to it return the offset to
+// resume from, and PATCH requests append to it. The bytes are staged as a
+// multipart upload, and the state of each upload is kept in the bucket under
+// .uploads/, so uploads can be resumed through any server. Once the whole
+// file is received the object is committed and the upload forgotten.
+// Uploads to keys that are validated or scanned are staged under .staging/
+// and inspected once complete, failing the last PATCH with 422 when they
+// are refused.
+func (c *client) ResumableUploads(p string) http.Handler {
+	return &resumable{c, p}
+}
+
+func (h *resumable) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Tus-Version", tusVersion)
+		w.Header().Set("Tus-Extension", "creation,termination")
+		if h.c.maxPutSize > 0 {
+			w.Header().Set("Tus-Max-Size", strconv.FormatInt(h.c.maxPutSize, 10))
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Header.Get("Tus-Resumable") != tusVersion {
+		w.Header().Set("Tus-Version", tusVersion)
+		http.Error(w, "unsupported Tus-Resumable version", http.StatusPreconditionFailed)
+		return
+	}
+
+	var id string
+	var err error
+	switch r.Method {
+	case http.MethodPost:
+		id, err = h.create(w, r)
+	case http.MethodHead:
+		id = path.Base(r.URL.Path)
+		err = h.head(w, id)
+	case http.MethodPatch:
+		id = path.Base(r.URL.Path)
+		err = h.patch(w, r, id)
+	case http.MethodDelete:
+		id = path.Base(r.URL.Path)
+		err = h.terminate(w, id)
+	default:
+		err = errMethodNotAllowed
+	}
+
+	code := http.StatusOK
+	switch {
+	case err == nil:
+	case errors.Is(err, errMethodNotAllowed):
+		code = http.StatusMethodNotAllowed
+	case errors.Is(err, errBadUploadRequest):
+		code = http.StatusBadRequest
+	case errors.Is(err, ErrPutTooLarge):
+		code = http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrInvalidDocument) || errors.Is(err, ErrInfected):
+		code = http.StatusUnprocessableEntity
+	case errors.Is(err, errUploadLocked):
+		code = http.StatusLocked
+	case errors.Is(err, errOffsetMismatch) || isPreconditionFailed(err):
+		code = http.StatusConflict
+	case IsNotFound(err):
+		code = http.StatusNotFound
+	default:
+		code = http.StatusBadGateway
+	}
+	if err != nil {
+		http.Error(w, err.Error(), code)
+	}
+
+	h.c.log("ResumableUpload", err).
+		Str("method", r.Method).
+		Str("id", id).
+		Int("code", code).
+		Msg("ResumableUpload")
+}
+
+var (
+	errMethodNotAllowed = errors.New("s3: method not allowed")
+	errBadUploadRequest = errors.New("s3: bad upload request")
+	errOffsetMismatch   = errors.New("s3: Upload-Offset does not match the upload")
+)
+
+// stateKey returns the key of the upload's state.
+func stateKey(id string) string {
+	return resumablePrefix + id + ".json"
+}
+
+// load reads the state of the upload.
+func (h *resumable) load(id string) (*resumableUpload, error) {
+	if _, err := ulid.ParseStrict(id); err != nil {
+		return nil, &types.NoSuchKey{}
+	}
+	k := stateKey(id)
+	out, err := h.c.getObject(&s3.GetObjectInput{Bucket: h.c.Bucket, Key: &k})
+	if err != nil {
+		return nil, err
+	}
+	defer out.Body.Close()
+	u := &resumableUpload{etag: out.ETag}
+	return u, json.NewDecoder(out.Body).Decode(u)
+}
+
+// save writes the state of the upload, provided it hasn't changed since it
+// was read, and extends the lease of its writer.
+func (h *resumable) save(id string, u *resumableUpload) error {
+	if u.Writer != "" {
+		u.WriterUntil = time.Now().Add(resumableLease)
+	}
+	b, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+	in := &s3.PutObjectInput{
+		Bucket:      h.c.Bucket,
+		Key:         aws.String(stateKey(id)),
+		ContentType: aws.String("application/json"),
+		IfMatch:     u.etag,
+	}
+	if u.etag == nil {
+		in.IfNoneMatch = aws.String("*")
+	}
+	out, err := h.c.putObject(in, b)
+	if err == nil {
+		u.etag = out.ETag
+	}
+	return err
+}
+
+// create starts an upload of the length given by the Upload-Length header.
+func (h *resumable) create(w http.ResponseWriter, r *http.Request) (string, error) {
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		return "", errors.Join(errBadUploadRequest, errors.New("s3: invalid Upload-Length"))
+	}
+	meta, err := tusMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		return "", err
+	}
+
+	id := ulid.Make().String()
+	u := &resumableUpload{Key: h.prefix + id, Length: length}
+	if err = h.c.checkPutSize(u.Key, length); err != nil {
+		return id, err
+	}
+	if h.c.inspects(u.Key) {
+		u.Staging = stagingKey(u.Key)
+	}
+
+	var md map[string]string
+	name := meta["filename"]
+	if name != "" {
+		md = map[string]string{"filename": mime.QEncoding.Encode("utf-8", name)}
+	}
+	in := &s3.CreateMultipartUploadInput{
+		Bucket:   h.c.Bucket,
+		Key:      u.uploadKey(),
+		Metadata: h.c.correlateMetadata(md),
+	}
+	if ct := meta["filetype"]; ct != "" {
+		in.ContentType = &ct
+	} else if ct = mime.TypeByExtension(path.Ext(name)); ct != "" {
+		in.ContentType = &ct
+	}
+	out, err := h.c.CreateMultipartUpload(h.c.Context, in)
+	if err != nil {
+		return id, err
+	}
+	u.UploadID = aws.ToString(out.UploadId)
+	if err = h.save(id, u); err != nil {
+		h.c.abortMultipartUpload(*u.uploadKey(), out.UploadId)
+		return id, err
+	}
+	if length == 0 {
+		if err = h.finish(id, u, nil); err != nil {
+			return id, err
+		}
+	}
+
+	w.Header().Set("Location", strings.TrimSuffix(r.URL.Path, "/")+"/"+id)
+	w.WriteHeader(http.StatusCreated)
+	return id, nil
+}
+
+// tusMetadata decodes an Upload-Metadata header of comma separated keys
+// and base64 encoded values.
+func tusMetadata(header string) (map[string]string, error) {
+	meta := map[string]string{}
+	for pair := range strings.SplitSeq(header, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if k == "" {
+			continue
+		}
+		b, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, errors.Join(errBadUploadRequest, err)
+		}
+		meta[k] = string(b)
+	}
+	return meta, nil
+}
+
+// head returns the offset to resume the upload from.
+func (h *resumable) head(w http.ResponseWriter, id string) error {
+	u, err := h.load(id)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Upload-Offset", strconv.FormatInt(u.committed()+u.TailSize, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(u.Length, 10))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+// patch appends the request body to the upload at the offset given by the
+// Upload-Offset header, which must be the upload's. Parts are uploaded as
+// the bytes arrive and the upload's state saved after each, so a request
+// that fails keeps its progress. The bytes after the last part are kept
+// in a tail object until more arrive.
+func (h *resumable) patch(w http.ResponseWriter, r *http.Request, id string) error {
+	if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
+		return errors.Join(errBadUploadRequest, errors.New("s3: Content-Type must be application/offset+octet-stream"))
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil {
+		return errors.Join(errBadUploadRequest, errors.New("s3: invalid Upload-Offset"))
+	}
+	u, err := h.load(id)
+	if err != nil {
+		return err
+	}
+	if u.Writer != "" && u.WriterUntil.After(time.Now()) {
+		return errUploadLocked
+	}
+	base := u.committed()
+	if offset != base+u.TailSize {
+		return errOffsetMismatch
+	}
+
+	// lease the upload so no other request appends to it concurrently
+	u.Writer = ulid.Make().String()
+	if err = h.save(id, u); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if u.Tail != "" {
+		out, err := h.c.getObject(&s3.GetObjectInput{Bucket: h.c.Bucket, Key: &u.Tail})
+		if err == nil {
+			_, err = buf.ReadFrom(out.Body)
+			out.Body.Close()
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	body := io.LimitReader(r.Body, u.Length-offset)
+	size := u.partSize()
+	var rerr error
+	for base+int64(buf.Len()) < u.Length {
+		if int64(buf.Len()) == size {
+			if err = h.uploadPart(id, u, buf.Bytes()); err != nil {
+				return err
+			}
+			base += int64(buf.Len())
+			buf.Reset()
+		}
+		if _, rerr = io.CopyN(&buf, body, size-int64(buf.Len())); rerr != nil {
+			break
+		}
+	}
+	if rerr == io.EOF {
+		rerr = nil
+	} else if rerr != nil {
+		// keep what was received for the client to resume from
+		rerr = errors.Join(errBadUploadRequest, rerr)
+	}
+
+	if base+int64(buf.Len()) == u.Length {
+		err = h.finish(id, u, buf.Bytes())
+	} else {
+		err = h.saveTail(id, u, buf.Bytes())
+	}
+	if err == nil && rerr == nil {
+		w.Header().Set("Upload-Offset", strconv.FormatInt(base+int64(buf.Len()), 10))
+		w.WriteHeader(http.StatusNoContent)
+	}
+	return errors.Join(err, rerr)
+}
+
+// uploadPart uploads the next part of the upload and saves its state,
+// failing if another request took over the upload. A part that fails to
+// upload releases the upload so the client can resume it at once.
+func (h *resumable) uploadPart(id string, u *resumableUpload, b []byte) error {
+	n := int32(len(u.Parts) + 1)
+	out, err := h.c.UploadPart(h.c.Context, &s3.UploadPartInput{
+		Bucket:        h.c.Bucket,
+		Key:           u.uploadKey(),
+		UploadId:      &u.UploadID,
+		PartNumber:    &n,
+		Body:          bytes.NewReader(b),
+		ContentLength: aws.Int64(int64(len(b))),
+	})
+	if err != nil {
+		u.Writer, u.WriterUntil = "", time.Time{}
+		// a failed release leaves the upload to its lease's expiry
+		_ = h.save(id, u)
+		return err
+	}
+	u.Parts = append(u.Parts, resumablePart{aws.ToString(out.ETag), int64(len(b))})
+	tail := u.Tail
+	u.Tail, u.TailSize = "", 0
+	if err = h.save(id, u); err != nil {
+		return err
+	}
+	h.deleteTail(tail)
+	return nil
+}
+
+// saveTail stores the bytes received after the last part, saves the state
+// of the upload and releases it for the next request.
+func (h *resumable) saveTail(id string, u *resumableUpload, b []byte) error {
+	tail := u.Tail
+	if int64(len(b)) != u.TailSize {
+		u.Tail, u.TailSize = "", int64(len(b))
+		if len(b) > 0 {
+			u.Tail = resumablePrefix + id + "/" + ulid.Make().String()
+			if _, err := h.c.putObject(&s3.PutObjectInput{Bucket: h.c.Bucket, Key: &u.Tail}, b); err != nil {
+				return err
+			}
+		}
+	}
+	u.Writer, u.WriterUntil = "", time.Time{}
+	if err := h.save(id, u); err != nil {
+		return err
+	}
+	if tail != u.Tail {
+		h.deleteTail(tail)
+	}
+	return nil
+}
+
+// finish uploads the last part of the upload, commits the object and
+// forgets the upload.
+func (h *resumable) finish(id string, u *resumableUpload, b []byte) error {
+	if len(b) > 0 || len(u.Parts) == 0 {
+		if err := h.uploadPart(id, u, b); err != nil {
+			return err
+		}
+	}
+	parts := make([]types.CompletedPart, len(u.Parts))
+	for i, p := range u.Parts {
+		parts[i] = types.CompletedPart{ETag: aws.String(p.ETag), PartNumber: aws.Int32(int32(i + 1))}
+	}
+	err := h.c.completeMultipartUpload(&s3.CompleteMultipartUploadInput{
+		Bucket:          h.c.Bucket,
+		Key:             u.uploadKey(),
+		UploadId:        &u.UploadID,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	}, u.Length, nil)
+	if err != nil {
+		return err
+	}
+	if u.Staging != "" {
+		// the upload is forgotten even if it is refused once inspected
+		err = h.c.promote(u.Staging, u.Key)
+	}
+	if _, derr := h.c.deleteObject(&s3.DeleteObjectInput{Bucket: h.c.Bucket, Key: aws.String(stateKey(id))}); err == nil {
+		err = derr
+	}
+	return err
+}
+
+// terminate aborts the upload and forgets it.
+func (h *resumable) terminate(w http.ResponseWriter, id string) error {
+	u, err := h.load(id)
+	if err != nil {
+		return err
+	}
+	if err = h.c.abortMultipartUpload(*u.uploadKey(), &u.UploadID); err != nil {
+		return err
+	}
+	h.deleteTail(u.Tail)
+	if _, err = h.c.deleteObject(&s3.DeleteObjectInput{Bucket: h.c.Bucket, Key: aws.String(stateKey(id))}); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// deleteTail deletes a tail object that is no longer referenced. Tails that
+// fail to be deleted are only wasted storage.
+func (h *resumable) deleteTail(tail string) {
+	if tail != "" {
+		_, _ = h.c.deleteObject(&s3.DeleteObjectInput{Bucket: h.c.Bucket, Key: &tail})
+	}
+}
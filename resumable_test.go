@@ -0,0 +1,159 @@
+package s3
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// tusRequest sends a tus request to the handler.
+func tusRequest(h http.Handler, method, url string, body io.Reader, header ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, url, body)
+	r.Header.Set("Tus-Resumable", "1.0.0")
+	for i := 0; i < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func tusPatch(h http.Handler, url string, offset int, body io.Reader) *httptest.ResponseRecorder {
+	return tusRequest(h, http.MethodPatch, url, body,
+		"Content-Type", "application/offset+octet-stream",
+		"Upload-Offset", strconv.Itoa(offset))
+}
+
+func TestClient_ResumableUploads(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	h := c.ResumableUploads("uploads/")
+	file := bytes.Repeat([]byte("0123456789abcdef"), partSize/16+8)
+
+	w := tusRequest(h, http.MethodOptions, "/files/", nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "1.0.0", w.Header().Get("Tus-Version"))
+	assert.Equal(t, "creation,termination", w.Header().Get("Tus-Extension"))
+
+	r := httptest.NewRequest(http.MethodPost, "/files/", nil)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	w = tusRequest(h, http.MethodPost, "/files/", nil,
+		"Upload-Length", strconv.Itoa(len(file)),
+		"Upload-Metadata", "filename "+base64.StdEncoding.EncodeToString([]byte("../é.bin"))+",filetype "+base64.StdEncoding.EncodeToString([]byte("video/mp4")))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	loc := w.Header().Get("Location")
+	assert.True(t, strings.HasPrefix(loc, "/files/"))
+	k := "uploads/" + strings.TrimPrefix(loc, "/files/")
+
+	w = tusRequest(h, http.MethodHead, loc, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "0", w.Header().Get("Upload-Offset"))
+	assert.Equal(t, strconv.Itoa(len(file)), w.Header().Get("Upload-Length"))
+
+	w = tusPatch(h, loc, 0, bytes.NewReader(file[:100]))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "100", w.Header().Get("Upload-Offset"))
+
+	w = tusPatch(h, loc, 0, bytes.NewReader(file[:100]))
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// a connection dropped midway keeps the bytes received
+	w = tusPatch(h, loc, 100, io.MultiReader(bytes.NewReader(file[100:partSize+50]), iotest.ErrReader(errors.New("connection reset"))))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = tusRequest(h, http.MethodHead, loc, nil)
+	assert.Equal(t, strconv.Itoa(partSize+50), w.Header().Get("Upload-Offset"))
+	assert.Empty(t, b.object(k))
+
+	w = tusPatch(h, loc, partSize+50, bytes.NewReader(file[partSize+50:]))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, strconv.Itoa(len(file)), w.Header().Get("Upload-Offset"))
+
+	assert.Equal(t, file, b.object(k).body)
+	assert.Equal(t, "video/mp4", b.object(k).header.Get("Content-Type"))
+	assert.Equal(t, "=?utf-8?q?../=C3=A9.bin?=", b.object(k).header.Get("X-Amz-Meta-Filename"))
+	assert.Equal(t, []string{k}, b.keys())
+	assert.Equal(t, http.StatusNotFound, tusRequest(h, http.MethodHead, loc, nil).Code)
+}
+
+func TestClient_ResumableUploads_Lifecycle(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	h := c.ResumableUploads("uploads/")
+
+	w := tusRequest(h, http.MethodPost, "/files", nil, "Upload-Length", "0")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	id := strings.TrimPrefix(w.Header().Get("Location"), "/files/")
+	assert.Equal(t, []string{"uploads/" + id}, b.keys())
+
+	assert.Equal(t, http.StatusBadRequest, tusRequest(h, http.MethodPost, "/files", nil).Code)
+	assert.Equal(t, http.StatusNotFound, tusRequest(h, http.MethodHead, "/files/missing", nil).Code)
+
+	w = tusRequest(h, http.MethodPost, "/files", nil, "Upload-Length", "10")
+	loc := w.Header().Get("Location")
+
+	// another request is appending to the upload
+	u, err := (&resumable{c, "uploads/"}).load(strings.TrimPrefix(loc, "/files/"))
+	assert.NoError(t, err)
+	u.Writer = "other"
+	assert.NoError(t, (&resumable{c, "uploads/"}).save(strings.TrimPrefix(loc, "/files/"), u))
+	assert.Equal(t, http.StatusLocked, tusPatch(h, loc, 0, strings.NewReader("0123456789")).Code)
+
+	defer func(d time.Duration) { resumableLease = d }(resumableLease)
+	resumableLease = -time.Second
+	assert.NoError(t, (&resumable{c, "uploads/"}).save(strings.TrimPrefix(loc, "/files/"), u))
+	w = tusPatch(h, loc, 0, strings.NewReader("01234"))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	assert.Equal(t, http.StatusNoContent, tusRequest(h, http.MethodDelete, loc, nil).Code)
+	assert.Equal(t, []string{"uploads/" + id}, b.keys())
+	assert.Empty(t, b.uploads)
+
+	WithMaxPutSize(5)(c.options)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, tusRequest(h, http.MethodPost, "/files", nil, "Upload-Length", "10").Code)
+}
+
+func TestClient_ResumableUploads_partFailure(t *testing.T) {
+
+	var fail atomic.Bool
+	fail.Store(true)
+	c, b := newTestBucket(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Has("partNumber") && fail.Swap(false) {
+				writeError(w, http.StatusForbidden, "AccessDenied")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	h := c.ResumableUploads("uploads/")
+	file := bytes.Repeat([]byte("x"), partSize+10)
+
+	w := tusRequest(h, http.MethodPost, "/files", nil, "Upload-Length", strconv.Itoa(len(file)))
+	loc := w.Header().Get("Location")
+
+	// a part that fails to upload releases the upload to be resumed at once
+	assert.Equal(t, http.StatusBadGateway, tusPatch(h, loc, 0, bytes.NewReader(file)).Code)
+	w = tusPatch(h, loc, 0, bytes.NewReader(file))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, file, b.object("uploads/"+strings.TrimPrefix(loc, "/files/")).body)
+}
+
+func Test_resumableUpload_partSize(t *testing.T) {
+	assert.Equal(t, int64(partSize), (&resumableUpload{Length: 10}).partSize())
+	assert.Equal(t, int64(partSize), (&resumableUpload{Length: partSize * 10000}).partSize())
+	assert.Equal(t, int64(partSize+1), (&resumableUpload{Length: partSize*10000 + 1}).partSize())
+}
@@ -0,0 +1,150 @@
+// Package rpc provides the Storage gRPC service, a protobuf definition of the
+// Service operations, and a server implementation backed by an s3.Service so
+// non-Go services can share the same bucket conventions.
+package rpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative s3.proto
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/nelsw/s3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// chunkSize is the size of the body chunks streamed by Get.
+const chunkSize = 64 << 10
+
+type server struct {
+	UnimplementedStorageServer
+	svc s3.Service
+}
+
+// NewServer returns a StorageServer backed by svc.
+func NewServer(svc s3.Service) StorageServer {
+	return &server{svc: svc}
+}
+
+// Register registers a StorageServer backed by svc with the gRPC server.
+func Register(s grpc.ServiceRegistrar, svc s3.Service) {
+	RegisterStorageServer(s, NewServer(svc))
+}
+
+func (s *server) Get(req *GetRequest, stream grpc.ServerStreamingServer[Chunk]) error {
+	body, err := s.svc.WithContext(stream.Context()).GetReader(req.GetKey())
+	if err != nil {
+		return toStatus(err)
+	}
+	defer body.Close()
+
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := io.ReadFull(body, buf)
+		if n > 0 {
+			if err := stream.Send(&Chunk{Data: buf[:n]}); err != nil {
+				return err
+			}
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil
+		}
+		if err != nil {
+			return toStatus(err)
+		}
+	}
+}
+
+// Put streams the chunks received to the object as they arrive, so bodies
+// of any size up to the service's maximum put size are never held in
+// memory whole. The key must come in the first message and in no other.
+// Nothing is written if the stream fails or is cancelled.
+func (s *server) Put(stream grpc.ClientStreamingServer[PutRequest, PutResponse]) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	key, ok := req.GetPart().(*PutRequest_Key)
+	if !ok || key.Key == "" {
+		return status.Error(codes.InvalidArgument, "first message must carry the key")
+	}
+
+	w := s.svc.WithContext(stream.Context()).NewWriter(key.Key)
+	for {
+		req, err = stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if _, ok = req.GetPart().(*PutRequest_Key); ok {
+			err = status.Error(codes.InvalidArgument, "only the first message may carry the key")
+		}
+		if err == nil {
+			_, err = w.Write(req.GetData())
+		}
+		if err != nil {
+			abort(w, err)
+			return toStatus(err)
+		}
+	}
+
+	if err = w.Close(); err != nil {
+		return toStatus(err)
+	}
+	return stream.SendAndClose(&PutResponse{})
+}
+
+// abort closes the writer without writing the object, when it supports it.
+func abort(w io.WriteCloser, err error) {
+	if a, ok := w.(interface{ CloseWithError(error) error }); ok {
+		_ = a.CloseWithError(err)
+	}
+}
+
+func (s *server) Delete(ctx context.Context, req *DeleteRequest) (*DeleteResponse, error) {
+	if err := s.svc.WithContext(ctx).Delete(req.GetKey()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &DeleteResponse{}, nil
+}
+
+func (s *server) List(ctx context.Context, req *ListRequest) (*ListResponse, error) {
+	size := req.GetSize()
+	if size <= 0 || size > 1000 {
+		size = 1000
+	}
+	keys, err := s.svc.WithContext(ctx).Keys(req.GetPrefix(), req.GetAfter(), size)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &ListResponse{Keys: keys}, nil
+}
+
+func (s *server) Presign(ctx context.Context, req *PresignRequest) (*PresignResponse, error) {
+	exp := req.GetExpires()
+	if exp <= 0 {
+		exp = 15
+	}
+	url, err := s.svc.WithContext(ctx).URL(req.GetKey(), exp)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &PresignResponse{Url: url}, nil
+}
+
+func toStatus(err error) error {
+	switch {
+	case s3.IsNotFound(err):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, s3.ErrPutTooLarge):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	return status.Error(codes.Unknown, err.Error())
+}
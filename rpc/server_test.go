@@ -0,0 +1,150 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/nelsw/s3"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type testService struct {
+	s3.Service
+	objects map[string][]byte
+	maxSize int
+	ctx     context.Context
+}
+
+func (s *testService) WithContext(ctx context.Context) s3.Service {
+	s.ctx = ctx
+	return s
+}
+
+func (s *testService) NewWriter(k string) io.WriteCloser {
+	return &testWriter{s: s, k: k}
+}
+
+type testWriter struct {
+	s   *testService
+	k   string
+	buf bytes.Buffer
+}
+
+func (w *testWriter) Write(p []byte) (int, error) {
+	if w.s.maxSize > 0 && w.buf.Len()+len(p) > w.s.maxSize {
+		return 0, s3.ErrPutTooLarge
+	}
+	return w.buf.Write(p)
+}
+
+func (w *testWriter) Close() error {
+	w.s.objects[w.k] = w.buf.Bytes()
+	return nil
+}
+
+func (s *testService) GetReader(k string) (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(s.objects[k])), nil
+}
+
+func (s *testService) Delete(k string) error {
+	delete(s.objects, k)
+	return nil
+}
+
+func (s *testService) Keys(p, a string, n int32, _ ...s3.KeyFilter) ([]string, error) {
+	var keys []string
+	for k := range s.objects {
+		if strings.HasPrefix(k, p) && k > a {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+func TestServer(t *testing.T) {
+
+	svc := &testService{objects: map[string][]byte{}}
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	Register(srv, svc)
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-correlation-id", "abc")
+	c := NewStorageClient(conn)
+	body := bytes.Repeat([]byte("0123456789"), chunkSize/5)
+
+	put, err := c.Put(ctx)
+	assert.NoError(t, err)
+	assert.NoError(t, put.Send(&PutRequest{Part: &PutRequest_Key{Key: "users/1.json"}}))
+	assert.NoError(t, put.Send(&PutRequest{Part: &PutRequest_Data{Data: body[:chunkSize]}}))
+	assert.NoError(t, put.Send(&PutRequest{Part: &PutRequest_Data{Data: body[chunkSize:]}}))
+	_, err = put.CloseAndRecv()
+	assert.NoError(t, err)
+	assert.Equal(t, body, svc.objects["users/1.json"])
+	md, _ := metadata.FromIncomingContext(svc.ctx)
+	assert.Equal(t, []string{"abc"}, md.Get("x-correlation-id"))
+
+	// bodies over the maximum put size are refused as they stream in
+	svc.maxSize = chunkSize
+	put, err = c.Put(ctx)
+	assert.NoError(t, err)
+	assert.NoError(t, put.Send(&PutRequest{Part: &PutRequest_Key{Key: "users/2.json"}}))
+	_ = put.Send(&PutRequest{Part: &PutRequest_Data{Data: body}})
+	_, err = put.CloseAndRecv()
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.NotContains(t, svc.objects, "users/2.json")
+	svc.maxSize = 0
+
+	// the key must come first, and only first
+	put, err = c.Put(ctx)
+	assert.NoError(t, err)
+	assert.NoError(t, put.Send(&PutRequest{Part: &PutRequest_Data{Data: body}}))
+	_ = put.Send(&PutRequest{Part: &PutRequest_Key{Key: "users/3.json"}})
+	_, err = put.CloseAndRecv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	put, err = c.Put(ctx)
+	assert.NoError(t, err)
+	assert.NoError(t, put.Send(&PutRequest{Part: &PutRequest_Key{Key: "users/3.json"}}))
+	_ = put.Send(&PutRequest{Part: &PutRequest_Key{Key: "users/4.json"}})
+	_, err = put.CloseAndRecv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.NotContains(t, svc.objects, "users/3.json")
+
+	get, err := c.Get(ctx, &GetRequest{Key: "users/1.json"})
+	assert.NoError(t, err)
+	var got []byte
+	for {
+		chunk, err := get.Recv()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		got = append(got, chunk.GetData()...)
+	}
+	assert.Equal(t, body, got)
+
+	list, err := c.List(ctx, &ListRequest{Prefix: "users/"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"users/1.json"}, list.GetKeys())
+
+	_, err = c.Delete(ctx, &DeleteRequest{Key: "users/1.json"})
+	assert.NoError(t, err)
+	assert.Empty(t, svc.objects)
+}
@@ -4,22 +4,126 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"iter"
+	"mime"
+	"net/http"
 	"os"
+	"path"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/rs/zerolog/log"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/rs/zerolog"
 )
 
+// ErrObjectTooLarge is returned by Get and Find when an object exceeds
+// the size configured with WithMaxGetSize.
+var ErrObjectTooLarge = errors.New("s3: object too large to buffer")
+
+// ErrPutTooLarge is returned by writes larger than the size configured with
+// WithMaxPutSize.
+var ErrPutTooLarge = errors.New("s3: object exceeds maximum put size")
+
 type Service interface {
 	Delete(string) error
 	Get(string) ([]byte, error)
+	GetReader(string) (io.ReadCloser, error)
 	Put(string, any) error
-	Keys(string, string, int32) ([]string, error)
+	Copy(string, string) error
+	Keys(string, string, int32, ...KeyFilter) ([]string, error)
+	Stat(string) (ObjectInfo, error)
+	ListDir(string) ([]string, []ObjectInfo, error)
 	URL(string, int64) (string, error)
 	Find(string, any) error
+	CDNSignedURL(string, time.Duration) (string, error)
+	CDNSignedCookies(string, time.Duration) ([]*http.Cookie, error)
+	DeploySite(string, string, ...SiteOption) error
+	PutArchive(string, string, ArchiveFormat) error
+	StreamArchive(string, io.Writer, ArchiveFormat) error
+	Unpack(string, string) error
+	Export(string, string) error
+	Import(string, string) error
+	Diff(string, string) ([]string, []string, []string, error)
+	Sync(string, string) (int, error)
+	Stats(string) (int64, int64, error)
+	LargestObjects(string, int) ([]ObjectInfo, error)
+	EstimateCost(string, StorageClass) (Cost, error)
+	ReadInventory(string) iter.Seq2[InventoryRecord, error]
+	SubmitBatchJob(string, BatchOperation, string) (BatchJob, error)
+	BatchJobStatus(BatchJob) (BatchStatus, error)
+	WaitBatchJob(BatchJob, time.Duration) (BatchStatus, error)
+	TagPrefix(string, map[string]string, int) error
+	Scan(string, ...ScanOption) (ScanReport, error)
+	GC(string, func(string) bool, time.Duration, ...GCOption) ([]string, error)
+	PutWithTTL(string, any, time.Duration) error
+	Sweep(string) (int, error)
+	ConfigureReplication(string, string, ...ReplicationRule) error
+	ReplicationStatus(string) (string, error)
+	ReadAhead(string, int64, int) (io.ReadCloser, error)
+	NewWriter(string) io.WriteCloser
+	Pipe(string) (io.WriteCloser, <-chan error)
+	PutAtomic(string, any) error
+	RenamePrefix(string, string, int) error
+	Touch(string) error
+	GetOrCreate(string, any, func() (any, error)) error
+	Patch(string, any) error
+	ApplyPatch(string, []PatchOp) ([]byte, string, error)
+	History(string, int) ([]Revision, error)
+	At(string, time.Time, any) error
+	Snapshot(string, string) error
+	RestoreSnapshot(string) error
+	Index(string, func([]byte) []string)
+	QueryIndex(string, string) ([]string, error)
+	Reindex(string, string) error
+	Query(string) *Query
+	TrackLatest(string)
+	Latest(string, int) ([]string, error)
+	ListModifiedBetween(string, time.Time, time.Time) ([]ObjectInfo, error)
+	Count(string) (int64, error)
+	Page(string, Cursor, int32) ([]string, Cursor, error)
+	ListSharded([]string, int) ([]string, error)
+	ListShardedKeys(string, int, int) ([]string, error)
+	StreamKeys(context.Context, string) (<-chan ObjectInfo, <-chan error)
+	WalkParallel(string, int, func(context.Context, ObjectInfo) error) error
+	WithContext(context.Context) Service
+	ReadAccessLogs(string) iter.Seq2[AccessRecord, error]
+	RequiredPolicy(...Feature) string
+	DeleteVersion(string, string) error
+	SetVersioning(bool, bool) error
+	SetBucketEncryption(SSEConfig) error
+	GetBucketEncryption() (SSEConfig, error)
+	SetPublicAccessBlock(PublicAccessBlock) error
+	GetPublicAccessBlock() (PublicAccessBlock, error)
+	AppendLog(string) *AppendLog
+	Queue(string) *Queue
+	Txn() *Txn
+	PutIdempotent(string, any, string) (string, error)
+	PutWithResult(string, any) (PutResult, error)
+	PutWithContentType(string, []byte, string) error
+	PresignMultipartUpload(string, int64, time.Duration) (PresignedUpload, error)
+	CompletePresignedUpload(string, string, int64) error
+	AbortPresignedUpload(string, string) error
+	ResumableUploads(string) http.Handler
+	Migrate(string, string, func(string, []byte) (string, []byte, error), int) error
+	ReEncrypt(string, string, int) (ReEncryptReport, error)
+	MigrateCodec(string, Codec, Codec, func() any, int) error
+	Lease(string, time.Duration, time.Duration) (*Lease, error)
+	AddHooks(Hooks)
+	FlushMirrors() error
+	CloseMirrors() error
+	OpenObject(string) (io.ReadCloser, ObjectInfo, error)
+	Logger() *zerolog.Logger
+	PutBlob([]byte) (string, error)
+	GetBlob(string) ([]byte, error)
+	Notifications(string, ...NotificationOption) *Notifications
+	ConfigureNotifications(...NotificationTarget) error
+	Watch(string, time.Duration) (<-chan ChangeEvent, func())
 }
 
 type client struct {
@@ -27,6 +131,8 @@ type client struct {
 	*s3.Client
 	*s3.PresignClient
 	context.Context
+	*options
+	cfg aws.Config
 }
 
 // New returns a new S3 client with a Background context.
@@ -40,31 +146,82 @@ func New(optFns ...func(*config.LoadOptions) error) Service {
 // An optional variadic set of Config values can be provided as
 // input that will be prepended to the configs slice.
 func NewWithContext(ctx context.Context, optFns ...func(*config.LoadOptions) error) Service {
-	cfg, err := config.LoadDefaultConfig(ctx, optFns...)
+	return NewWithOptions(ctx, WithConfig(optFns...))
+}
+
+// NewWithOptions returns a new S3 client with the provided context and options.
+// The bucket defaults to the S3_BUCKET environment variable and may be a bucket
+// name, an access point ARN, or a Multi-Region Access Point ARN.
+func NewWithOptions(ctx context.Context, opts ...Option) Service {
+	o := &options{bucket: os.Getenv("S3_BUCKET")}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.bucket == "" {
+		panic("S3_BUCKET environment variable must be set")
+	}
+	if err := validateBucket(o.bucket); err != nil {
+		panic(err)
+	}
+	if o.directory {
+		if err := validateDirectoryBucket(o.bucket); err != nil {
+			panic(err)
+		}
+	}
+	if hc := o.buildHTTPClient(); hc != nil {
+		o.loadOptions = append(o.loadOptions, config.WithHTTPClient(hc))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, o.loadOptions...)
 	if err != nil {
 		panic(err)
 	}
-	b := os.Getenv("S3_BUCKET")
-	if b == "" {
-		panic("S3_BUCKET environment variable must be set")
+	if o.assumeRole != nil {
+		cfg.Credentials = o.assumeRole.credentials(cfg)
 	}
-	c := s3.NewFromConfig(cfg)
-	return &client{
+	b := o.bucket
+	c := s3.NewFromConfig(cfg, func(so *s3.Options) {
+		if arn.IsARN(b) {
+			// honor the region embedded in regional access point ARNs and
+			// keep SigV4A signing available for Multi-Region Access Points
+			so.UseARNRegion = !isMultiRegionAccessPoint(b)
+			so.DisableMultiRegionAccessPoints = false
+		}
+		if o.objectLambda != "" {
+			// Object Lambda access points may be in another region
+			so.UseARNRegion = !isMultiRegionAccessPoint(b)
+		}
+		if o.directory {
+			// directory buckets authenticate with CreateSession credentials
+			so.DisableS3ExpressSessionAuth = aws.Bool(false)
+		}
+		for _, fn := range o.s3Options {
+			fn(so)
+		}
+	})
+	if o.replica != nil {
+		o.replica.client = o.replica.newClient(cfg, o.s3Options)
+	}
+	cl := &client{
 		&b,
 		c,
-		s3.NewPresignClient(c),
+		s3.NewPresignClient(c, o.presignOptions),
 		ctx,
+		o,
+		cfg,
+	}
+	if o.dualWrite != nil && o.dualWrite.queue != nil {
+		go cl.mirrorLoop()
 	}
+	return cl
 }
 
 func (c *client) Delete(k string) error {
-	_, err := c.DeleteObject(c.Context, &s3.DeleteObjectInput{
+	_, err := c.deleteObject(&s3.DeleteObjectInput{
 		Bucket: c.Bucket,
 		Key:    &k,
 	})
 
-	log.Trace().
-		Err(err).
+	c.log("Delete", err).
 		Str("key", k).
 		Msg("Delete")
 
@@ -72,76 +229,341 @@ func (c *client) Delete(k string) error {
 }
 
 func (c *client) Get(k string) ([]byte, error) {
-	out, err := c.GetObject(c.Context, &s3.GetObjectInput{
-		Bucket: c.Bucket,
-		Key:    &k,
-	})
-
-	var body []byte
+	body, etag, fresh := c.cache.get(k)
+
+	var err error
+	if !fresh {
+		gen := c.cache.read(k)
+		defer c.cache.done(k)
+		in := &s3.GetObjectInput{Key: &k}
+		if etag != "" {
+			in.IfNoneMatch = &etag
+		}
+		var out *s3.GetObjectOutput
+		out, err = c.getObject(in)
+		switch {
+		case isNotModified(err):
+			err = nil
+			c.cache.validated(k, gen)
+		case err != nil:
+			body = nil
+		default:
+			defer out.Body.Close()
+			etag = aws.ToString(out.ETag)
+			if c.maxGetSize > 0 && aws.ToInt64(out.ContentLength) > c.maxGetSize {
+				body, err = nil, fmt.Errorf("%w: %s is %d bytes, use GetReader", ErrObjectTooLarge, k, *out.ContentLength)
+			} else if body, err = readAll(out.Body, aws.ToInt64(out.ContentLength)); err == nil {
+				c.cache.add(k, etag, body, gen)
+			}
+		}
+	}
 	if err == nil {
-		defer out.Body.Close()
-		body, err = io.ReadAll(out.Body)
+		err = c.afterGet(HookEvent{k, etag, body})
 	}
 
-	log.Trace().
-		Err(err).
+	c.log("Get", err).
 		Str("key", k).
+		Bool("cached", fresh).
 		Bytes("body", body).
 		Msg("Get")
 
 	return body, err
 }
 
-func (c *client) Put(k string, a any) (err error) {
+// GetReader returns the body of the object for streaming. The caller must close it.
+func (c *client) GetReader(k string) (io.ReadCloser, error) {
+	var body io.ReadCloser
+	var size int64
+	var err error
+	if c.diskCache != nil {
+		body, size, err = c.getCached(k)
+	} else {
+		var out *s3.GetObjectOutput
+		if out, err = c.getObject(&s3.GetObjectInput{Key: &k}); err == nil {
+			body = out.Body
+			size = aws.ToInt64(out.ContentLength)
+		}
+	}
 
-	var body []byte
-	switch b := a.(type) {
-	case []byte:
-		body = b
-	case string:
-		body = []byte(b)
-	default:
-		if body, err = json.Marshal(a); err != nil {
-			return
+	c.log("GetReader", err).
+		Str("key", k).
+		Int64("size", size).
+		Msg("GetReader")
+
+	return body, err
+}
+
+// OpenObject is GetReader also returning the info of the object, including
+// its Content-Type. The object is always read from S3, never the disk cache.
+func (c *client) OpenObject(k string) (io.ReadCloser, ObjectInfo, error) {
+	info := ObjectInfo{Key: k}
+	out, err := c.getObject(&s3.GetObjectInput{Key: &k})
+	if err == nil {
+		info.Size = aws.ToInt64(out.ContentLength)
+		info.ETag = aws.ToString(out.ETag)
+		info.LastModified = aws.ToTime(out.LastModified)
+		info.ContentType = aws.ToString(out.ContentType)
+	}
+
+	c.log("OpenObject", err).
+		Str("key", k).
+		Int64("size", info.Size).
+		Msg("OpenObject")
+
+	if err != nil {
+		return nil, info, err
+	}
+	return out.Body, info, nil
+}
+
+func (c *client) Put(k string, a any) error {
+	_, err := c.put("Put", k, a, &s3.PutObjectInput{})
+	return err
+}
+
+// PutResult identifies the object written by PutWithResult. VersionID is
+// empty unless the bucket is versioned.
+type PutResult struct {
+	ETag      string
+	VersionID string
+}
+
+// PutWithResult uploads the value as Put does and returns the ETag and
+// version of the object written, for conditional writes such as If-Match
+// updates and for recording exact versions.
+func (c *client) PutWithResult(k string, a any) (PutResult, error) {
+	return c.put("PutWithResult", k, a, &s3.PutObjectInput{})
+}
+
+// PutWithContentType uploads the body as Put does with the Content-Type given
+// rather than one detected from the key or body, e.g. one sent by a client.
+func (c *client) PutWithContentType(k string, body []byte, ct string) error {
+	_, err := c.put("PutWithContentType", k, body, &s3.PutObjectInput{ContentType: &ct})
+	return err
+}
+
+// put encodes the value as Put does and uploads it with the input, logging op.
+// The input's Content-Type, if set, is kept.
+func (c *client) put(op, k string, a any, in *s3.PutObjectInput) (res PutResult, err error) {
+
+	body, ct, release, err := encode(k, a)
+	if err != nil {
+		return
+	}
+	defer release()
+	if in.ContentType != nil {
+		ct = *in.ContentType
+	}
+
+	in.Bucket = c.Bucket
+	in.Key = &k
+	in.ContentType = &ct
+	out, err := c.putObject(in, body)
+	if err == nil {
+		res = PutResult{aws.ToString(out.ETag), aws.ToString(out.VersionId)}
+	}
+
+	c.log(op, err).
+		Str("key", k).
+		Str("type", ct).
+		Bytes("body", body).
+		Msg(op)
+
+	return
+}
+
+// Copy copies the object server-side. Objects larger than the 5 GiB a single
+// copy allows are copied with a multipart upload of ranged part copies. The
+// copy is encrypted as the source is. Copies to keys that are validated or
+// scanned read the source to inspect it first.
+func (c *client) Copy(src, dst string) error {
+	size, err := c.copy(src, dst, true)
+
+	c.log("Copy", err).
+		Str("src", src).
+		Str("dst", dst).
+		Int64("size", size).
+		Msg("Copy")
+
+	return err
+}
+
+// copy copies the object as Copy does and returns its size, inspecting it
+// for the destination if inspect is set.
+func (c *client) copy(src, dst string, inspect bool) (int64, error) {
+	head, err := c.HeadObject(c.Context, &s3.HeadObjectInput{
+		Bucket: c.Bucket,
+		Key:    &src,
+	})
+
+	var size int64
+	if err == nil && inspect {
+		err = c.inspectObject(src, head.ETag, dst)
+	}
+	if err == nil {
+		size = aws.ToInt64(head.ContentLength)
+		if size > maxCopySize {
+			err = c.multipartCopy(src, dst, head)
+		} else {
+			_, err = c.copyObject(&s3.CopyObjectInput{
+				Bucket:               c.Bucket,
+				Key:                  &dst,
+				CopySource:           aws.String(c.copySource(src)),
+				CopySourceIfMatch:    head.ETag,
+				ServerSideEncryption: head.ServerSideEncryption,
+				SSEKMSKeyId:          head.SSEKMSKeyId,
+				BucketKeyEnabled:     head.BucketKeyEnabled,
+			}, size)
 		}
 	}
+	return size, err
+}
+
+// copySized copies src, or its version if versionID is set, with in,
+// encrypted as the source is, or, when it is over the 5 GiB a single copy
+// allows, with a multipart copy. It returns the HEAD of the source copied
+// and, for single copies, the ETag of the copy.
+func (c *client) copySized(in *s3.CopyObjectInput, src, versionID string) (*s3.HeadObjectOutput, string, error) {
+	hin := &s3.HeadObjectInput{
+		Bucket:  c.Bucket,
+		Key:     &src,
+		IfMatch: in.CopySourceIfMatch,
+	}
+	if versionID != "" {
+		hin.VersionId = &versionID
+	}
+	head, err := c.HeadObject(c.Context, hin)
+	if err != nil {
+		return nil, "", err
+	}
+
+	size := aws.ToInt64(head.ContentLength)
+	if size > maxCopySize {
+		return head, "", c.multipartCopy(src, *in.Key, head)
+	}
+	in.ServerSideEncryption = head.ServerSideEncryption
+	in.SSEKMSKeyId = head.SSEKMSKeyId
+	in.BucketKeyEnabled = head.BucketKeyEnabled
+	out, err := c.copyObject(in, size)
+	if err != nil {
+		return nil, "", err
+	}
+	return head, aws.ToString(out.CopyObjectResult.ETag), nil
+}
 
-	_, err = c.PutObject(c.Context, &s3.PutObjectInput{
+// Touch copies the object over itself, keeping its headers and metadata, to
+// refresh its LastModified so lifecycle rules that expire objects by age
+// spare objects still in use. Tags and encryption are kept too.
+func (c *client) Touch(k string) error {
+	head, err := c.HeadObject(c.Context, &s3.HeadObjectInput{
 		Bucket: c.Bucket,
 		Key:    &k,
-		Body:   bytes.NewReader(body),
 	})
 
-	log.Trace().
-		Err(err).
+	if err == nil {
+		if aws.ToInt64(head.ContentLength) > maxCopySize {
+			err = c.multipartCopy(k, k, head)
+		} else {
+			_, err = c.copyObject(&s3.CopyObjectInput{
+				Bucket:               c.Bucket,
+				Key:                  &k,
+				CopySource:           aws.String(c.copySource(k)),
+				CopySourceIfMatch:    head.ETag,
+				MetadataDirective:    types.MetadataDirectiveReplace,
+				Metadata:             head.Metadata,
+				ContentType:          head.ContentType,
+				ContentEncoding:      head.ContentEncoding,
+				ContentDisposition:   head.ContentDisposition,
+				ContentLanguage:      head.ContentLanguage,
+				CacheControl:         head.CacheControl,
+				StorageClass:         head.StorageClass,
+				ServerSideEncryption: head.ServerSideEncryption,
+				SSEKMSKeyId:          head.SSEKMSKeyId,
+				BucketKeyEnabled:     head.BucketKeyEnabled,
+			}, aws.ToInt64(head.ContentLength))
+		}
+	}
+
+	c.log("Touch", err).
 		Str("key", k).
-		Bytes("body", body).
-		Msg("Put")
+		Msg("Touch")
+
+	return err
+}
 
+// encode returns the body and Content-Type Put stores for the value: bytes
+// and strings as-is, anything else as JSON. The body is only valid until
+// release is called.
+func encode(k string, a any) (body []byte, ct string, release func(), err error) {
+	release = func() {}
+	switch b := a.(type) {
+	case []byte:
+		body = b
+	case string:
+		body = []byte(b)
+	default:
+		var buf *bytes.Buffer
+		if buf, err = marshal(a); err != nil {
+			return
+		}
+		body, ct, release = buf.Bytes(), "application/json", func() { putBuffer(buf) }
+	}
+	if ct == "" {
+		ct = contentType(k, body)
+	}
 	return
 }
 
-func (c *client) Keys(p, a string, s int32) ([]string, error) {
+// contentType detects the Content-Type of an object from the key extension,
+// falling back to sniffing the body so browsers render presigned links.
+func contentType(k string, body []byte) string {
+	if ct := mime.TypeByExtension(path.Ext(k)); ct != "" {
+		return ct
+	}
+	return http.DetectContentType(body)
+}
 
-	out, err := c.ListObjectsV2(c.Context, &s3.ListObjectsV2Input{
+// Keys returns up to s keys under the prefix that sort after a and pass
+// every filter, in lexicographical order.
+func (c *client) Keys(p, a string, s int32, filters ...KeyFilter) ([]string, error) {
+
+	if c.directory {
+		return c.directoryKeys(p, a, s, filters)
+	}
+
+	in := &s3.ListObjectsV2Input{
 		Bucket:     c.Bucket,
 		Prefix:     &p,
 		MaxKeys:    &s,
 		StartAfter: &a,
-	})
+	}
+	if len(filters) > 0 {
+		// most listed keys may be filtered out, so list full pages
+		in.MaxKeys = aws.Int32(max(s, 1000))
+	}
+	paginator := s3.NewListObjectsV2Paginator(c.Client, in)
 
 	var keys []string
-	if err == nil {
-		for _, obj := range out.Contents {
-			keys = append(keys, *obj.Key)
+	var err error
+	for err == nil && int32(len(keys)) < s && paginator.HasMorePages() {
+		var out *s3.ListObjectsV2Output
+		if out, err = paginator.NextPage(c.Context); err == nil {
+			for _, obj := range out.Contents {
+				if int32(len(keys)) < s && matchKey(*obj.Key, filters) {
+					keys = append(keys, *obj.Key)
+				}
+			}
 		}
 	}
+	if err != nil {
+		keys = nil
+	}
 
-	log.Trace().
-		Err(err).
+	c.log("Keys", err).
 		Str("prefix", p).
 		Str("after", a).
 		Int32("size", s).
+		Int("filters", len(filters)).
 		Strs("keys", keys).
 		Msg("Keys")
 
@@ -160,8 +582,7 @@ func (c *client) URL(k string, i int64) (string, error) {
 		url = out.URL
 	}
 
-	log.Trace().
-		Err(err).
+	c.log("URL", err).
 		Str("key", k).
 		Int64("exp", i).
 		Str("url", url).
@@ -177,8 +598,7 @@ func (c *client) Find(k string, a any) error {
 		err = json.Unmarshal(b, a)
 	}
 
-	log.Trace().
-		Err(err).
+	c.log("FindOne", err).
 		Str("key", k).
 		Any("body", a).
 		Msg("FindOne")
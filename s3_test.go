@@ -1,11 +1,17 @@
 package s3
 
 import (
+	"context"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/oklog/ulid/v2"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -37,6 +43,22 @@ func testBody(id ...ulid.ULID) string {
 	return `{"id":"` + ID.String() + `"}`
 }
 
+// testServer returns a client for a bucket served by h, for exercising
+// client behavior without AWS.
+func testServer(t *testing.T, h http.HandlerFunc) *client {
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return NewWithOptions(context.Background(),
+		WithBucket("bytelyon-db"),
+		WithConfig(config.WithRegion("us-east-1")),
+		WithStaticCredentials("id", "secret", ""),
+		WithS3Options(func(o *s3.Options) {
+			o.BaseEndpoint = &srv.URL
+			o.UsePathStyle = true
+		}),
+	).(*client)
+}
+
 func InitTest(t *testing.T) {
 	t.Setenv("S3_BUCKET", "bytelyon-db")
 	service = New()
@@ -119,3 +141,112 @@ func TestClient_FindOne(t *testing.T) {
 	assert.NoError(t, service.Find(testKey(id), user))
 	assert.Equal(t, id, user.ID)
 }
+
+func TestContentType(t *testing.T) {
+	assert.Equal(t, "application/json", contentType(testKey(), []byte(testBody())))
+	assert.Equal(t, "image/png", contentType("users/avatar.png", nil))
+	assert.Equal(t, "text/html; charset=utf-8", contentType("pages/home", []byte("<!DOCTYPE html><html></html>")))
+}
+
+func TestClient_Get_MaxGetSize(t *testing.T) {
+
+	c := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(testBody()))
+	})
+
+	out, err := c.Get(testKey())
+	assert.NoError(t, err)
+	assert.Equal(t, testBody(), string(out))
+
+	WithMaxGetSize(8)(c.options)
+	_, err = c.Get(testKey())
+	assert.ErrorIs(t, err, ErrObjectTooLarge)
+	assert.ErrorIs(t, c.Find(testKey(), &struct{}{}), ErrObjectTooLarge)
+
+	body, err := c.GetReader(testKey())
+	assert.NoError(t, err)
+	defer body.Close()
+	out, err = io.ReadAll(body)
+	assert.NoError(t, err)
+	assert.Equal(t, testBody(), string(out))
+}
+
+func TestClient_OpenObject(t *testing.T) {
+
+	c, _ := newTestBucket(t)
+	assert.NoError(t, c.Put("users/1.json", map[string]string{"id": "1"}))
+
+	body, info, err := c.OpenObject("users/1.json")
+	assert.NoError(t, err)
+	defer body.Close()
+	out, err := io.ReadAll(body)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":"1"}`, string(out))
+	assert.Equal(t, "application/json", info.ContentType)
+	assert.Equal(t, int64(len(out)), info.Size)
+
+	_, _, err = c.OpenObject("users/2.json")
+	assert.True(t, IsNotFound(err))
+}
+
+func TestClient_Put_MaxPutSize(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	WithMaxPutSize(8)(c.options)
+
+	assert.NoError(t, c.Put("small", "12345678"))
+	assert.ErrorIs(t, c.Put("large", "123456789"), ErrPutTooLarge)
+
+	u := c.newUploader(&s3.PutObjectInput{Key: aws.String("streamed")})
+	_, err := u.Write([]byte("12345"))
+	assert.NoError(t, err)
+	_, err = u.Write([]byte("6789"))
+	assert.ErrorIs(t, err, ErrPutTooLarge)
+	assert.ErrorIs(t, u.Close(), ErrPutTooLarge)
+
+	assert.Equal(t, []string{"small"}, b.keys())
+}
+
+func TestClient_PutWithResult(t *testing.T) {
+
+	c := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"abc"`)
+		w.Header().Set("X-Amz-Version-Id", "v1")
+	})
+
+	res, err := c.PutWithResult("a.json", map[string]int{"a": 1})
+	assert.NoError(t, err)
+	assert.Equal(t, PutResult{ETag: `"abc"`, VersionID: "v1"}, res)
+
+	c, b := newTestBucket(t)
+	res, err = c.PutWithResult("a.json", map[string]int{"a": 1})
+	assert.NoError(t, err)
+	assert.Equal(t, b.object("a.json").etag, res.ETag)
+	assert.Empty(t, res.VersionID)
+}
+
+func TestClient_PutWithContentType(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	assert.NoError(t, c.PutWithContentType("a.json", []byte("a,b"), "text/csv"))
+	assert.Equal(t, "text/csv", b.object("a.json").header.Get("Content-Type"))
+	assert.Equal(t, "a,b", string(b.object("a.json").body))
+}
+
+func TestClient_Touch(t *testing.T) {
+
+	c, b := newTestBucket(t)
+
+	o := b.put("a.txt", []byte("a"), http.Header{"Content-Type": {"text/plain"}, "X-Amz-Meta-Owner": {"a"}, "X-Amz-Tagging": {"ttl-days=1"}})
+	o.modified = o.modified.Add(-time.Hour)
+
+	assert.NoError(t, c.Touch("a.txt"))
+	touched := b.object("a.txt")
+	assert.True(t, touched.modified.After(o.modified))
+	assert.Equal(t, o.etag, touched.etag)
+	assert.Equal(t, "text/plain", touched.header.Get("Content-Type"))
+	assert.Equal(t, "a", touched.header.Get("X-Amz-Meta-Owner"))
+	assert.Equal(t, "ttl-days=1", touched.header.Get("X-Amz-Tagging"))
+
+	assert.True(t, IsNotFound(c.Touch("missing")))
+}
@@ -0,0 +1,203 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ScanOption configures Scan.
+type ScanOption func(*scan)
+
+type scan struct {
+	sample  int64
+	workers int
+}
+
+// WithSample reads only the first and last n bytes of each object instead of
+// the whole body. Sampling detects truncated objects and JSON documents but
+// can't verify checksums.
+func WithSample(n int64) ScanOption {
+	return func(s *scan) {
+		s.sample = n
+	}
+}
+
+// WithScanWorkers sets how many objects are scanned concurrently.
+func WithScanWorkers(n int) ScanOption {
+	return func(s *scan) {
+		s.workers = n
+	}
+}
+
+// ScanReport is the outcome of a Scan.
+type ScanReport struct {
+	Scanned int
+	Bytes   int64
+	Issues  []ScanIssue
+}
+
+// ScanIssue describes an object that failed verification.
+type ScanIssue struct {
+	Key    string
+	Reason string
+}
+
+// Scan reads back every object under the prefix and reports the ones that are
+// truncated, don't match the MD5 of a single part ETag or the SHA-256 stored
+// in their metadata by Sync, or are JSON documents that don't parse. Objects
+// deleted or replaced while scanning are skipped. Issues are reported in no
+// particular order.
+func (c *client) Scan(p string, opts ...ScanOption) (ScanReport, error) {
+
+	s := &scan{workers: 8}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	var mu sync.Mutex
+	var report ScanReport
+	_, err := parallel(c.Context, s.workers, func(send func(types.Object) bool) error {
+		return c.walk(p, func(obj types.Object) error {
+			if !send(obj) {
+				return errWalkStopped
+			}
+			return nil
+		})
+	}, func(ctx context.Context, obj types.Object) error {
+		reason, n, err := c.withContext(ctx).verify(obj, s.sample)
+		if IsNotFound(err) || isPreconditionFailed(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		report.Scanned++
+		report.Bytes += n
+		if reason != "" {
+			report.Issues = append(report.Issues, ScanIssue{*obj.Key, reason})
+		}
+		return nil
+	})
+
+	c.log("Scan", err).
+		Str("prefix", p).
+		Int("scanned", report.Scanned).
+		Int("issues", len(report.Issues)).
+		Msg("Scan")
+
+	return report, err
+}
+
+// isJSON reports whether the object holds a JSON document.
+func isJSON(k, contentType string) bool {
+	return path.Ext(k) == ".json" || strings.HasPrefix(contentType, "application/json")
+}
+
+// verify reads the object, or samples of it, and returns why it is corrupt,
+// if it is, and the number of bytes read.
+func (c *client) verify(obj types.Object, sample int64) (string, int64, error) {
+	size := aws.ToInt64(obj.Size)
+	if sample > 0 && size > 2*sample {
+		return c.verifySample(obj, sample)
+	}
+
+	out, err := c.GetObject(c.Context, &s3.GetObjectInput{
+		Bucket:  c.Bucket,
+		Key:     obj.Key,
+		IfMatch: obj.ETag,
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	defer out.Body.Close()
+
+	sumMD5, sumSHA := md5.New(), sha256.New()
+	w := []io.Writer{sumMD5, sumSHA}
+	var doc bytes.Buffer
+	jsonDoc := isJSON(*obj.Key, aws.ToString(out.ContentType))
+	if jsonDoc {
+		w = append(w, &doc)
+	}
+	n, err := io.Copy(io.MultiWriter(w...), out.Body)
+	if err != nil {
+		return "", n, err
+	}
+
+	hexSum := func(h hash.Hash) string { return hex.EncodeToString(h.Sum(nil)) }
+	etag := strings.Trim(aws.ToString(out.ETag), `"`)
+	switch {
+	case n != size:
+		return fmt.Sprintf("truncated: read %d of %d bytes", n, size), n, nil
+	case etagIsMD5(etag, out.ServerSideEncryption, out.SSECustomerAlgorithm) && hexSum(sumMD5) != etag:
+		return "MD5 does not match ETag", n, nil
+	case out.Metadata[sha256Metadata] != "" && hexSum(sumSHA) != out.Metadata[sha256Metadata]:
+		return "SHA-256 does not match metadata", n, nil
+	case jsonDoc && !json.Valid(doc.Bytes()):
+		return "invalid JSON", n, nil
+	}
+	return "", n, nil
+}
+
+// etagIsMD5 reports whether the ETag of an object encrypted with sse, or with
+// SSE-C when customerAlgorithm is set, is the MD5 of its body, which it isn't
+// for multipart uploads or objects encrypted with SSE-KMS, DSSE-KMS or SSE-C.
+func etagIsMD5(etag string, sse types.ServerSideEncryption, customerAlgorithm *string) bool {
+	return !strings.Contains(etag, "-") &&
+		sse != types.ServerSideEncryptionAwsKms &&
+		sse != types.ServerSideEncryptionAwsKmsDsse &&
+		customerAlgorithm == nil
+}
+
+// verifySample reads the first and last n bytes of the object.
+func (c *client) verifySample(obj types.Object, n int64) (string, int64, error) {
+	size := aws.ToInt64(obj.Size)
+	var read int64
+	var parts [2][]byte
+	var contentType string
+	for i, rng := range []string{fmt.Sprintf("bytes=0-%d", n-1), fmt.Sprintf("bytes=-%d", n)} {
+		out, err := c.GetObject(c.Context, &s3.GetObjectInput{
+			Bucket:  c.Bucket,
+			Key:     obj.Key,
+			IfMatch: obj.ETag,
+			Range:   &rng,
+		})
+		if err != nil {
+			return "", read, err
+		}
+		parts[i], err = io.ReadAll(out.Body)
+		out.Body.Close()
+		read += int64(len(parts[i]))
+		if err != nil {
+			return "", read, err
+		}
+		contentType = aws.ToString(out.ContentType)
+		if int64(len(parts[i])) != n {
+			return fmt.Sprintf("truncated: read %d of %d sampled bytes of %d", len(parts[i]), n, size), read, nil
+		}
+	}
+
+	if isJSON(*obj.Key, contentType) {
+		head, tail := bytes.TrimSpace(parts[0]), bytes.TrimSpace(parts[1])
+		if len(head) == 0 || len(tail) == 0 ||
+			!(head[0] == '{' && tail[len(tail)-1] == '}' || head[0] == '[' && tail[len(tail)-1] == ']') {
+			return "invalid JSON", read, nil
+		}
+	}
+	return "", read, nil
+}
@@ -0,0 +1,52 @@
+package s3
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Scan(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	assert.NoError(t, c.Put("docs/ok.json", `{"ok":true}`))
+	assert.NoError(t, c.Put("docs/bad.json", `{"ok":tru`))
+	assert.NoError(t, c.Put("docs/flipped.txt", "hello"))
+	assert.NoError(t, c.Put("docs/synced.txt", "world"))
+	assert.NoError(t, c.Put("docs/customer.txt", "secret"))
+	assert.NoError(t, c.Put("other/bad.json", `{`))
+
+	b.object("docs/flipped.txt").body = []byte("hellO")
+	b.object("docs/synced.txt").header.Set("X-Amz-Meta-Sha256", "0000")
+	// the ETag of an object encrypted with SSE-C isn't its MD5
+	b.object("docs/customer.txt").body = []byte("ciphertext")
+	b.object("docs/customer.txt").header.Set("X-Amz-Server-Side-Encryption-Customer-Algorithm", "AES256")
+
+	report, err := c.Scan("docs/", WithScanWorkers(2))
+	assert.NoError(t, err)
+	assert.Equal(t, 5, report.Scanned)
+	assert.Equal(t, int64(len(`{"ok":true}`)+len(`{"ok":tru`)+20), report.Bytes)
+
+	sort.Slice(report.Issues, func(i, j int) bool { return report.Issues[i].Key < report.Issues[j].Key })
+	assert.Equal(t, []ScanIssue{
+		{"docs/bad.json", "invalid JSON"},
+		{"docs/flipped.txt", "MD5 does not match ETag"},
+		{"docs/synced.txt", "SHA-256 does not match metadata"},
+	}, report.Issues)
+}
+
+func TestClient_Scan_Sample(t *testing.T) {
+
+	c, _ := newTestBucket(t)
+	doc := `{"items":[` + strings.Repeat(`"item",`, 100) + `"last"]}`
+	assert.NoError(t, c.Put("docs/ok.json", doc))
+	assert.NoError(t, c.Put("docs/cut.json", doc[:len(doc)-20]))
+
+	report, err := c.Scan("docs/", WithSample(16))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, report.Scanned)
+	assert.Equal(t, int64(64), report.Bytes)
+	assert.Equal(t, []ScanIssue{{"docs/cut.json", "invalid JSON"}}, report.Issues)
+}
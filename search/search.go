@@ -0,0 +1,437 @@
+// Package search answers keyword queries over the documents stored under a
+// prefix of a bucket with an inverted index kept in the bucket itself.
+//
+// Writes are indexed in the background: the hooks an Index adds to the
+// service queue the keys written and deleted under its prefix, and their
+// terms are written to the bucket as segments, objects holding the terms of
+// every document written since the previous segment, every interval or on
+// Flush and Close. Search reads the segments it hasn't read yet, later
+// segments replacing the terms earlier ones hold for a document, and Compact
+// merges them into one. Build indexes documents written before the index was
+// registered or by other clients.
+//
+// Terms are the lowercased words of a JSON document's string values, or of
+// the whole body of a text document. Other content, and documents over
+// 1 MiB, aren't indexed. Queries rank documents by how many of the query's
+// terms they contain, then by how rare those terms are.
+package search
+
+import (
+	"encoding/json"
+	"io"
+	"maps"
+	"math"
+	"net/http"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/nelsw/s3"
+	"github.com/oklog/ulid/v2"
+)
+
+// segmentPrefix is where indexes keep their segments, as
+// .search/<name>/<id>, with ids sorting in the order they were written.
+const segmentPrefix = ".search/"
+
+// maxDocumentSize is the size of the largest document indexed.
+const maxDocumentSize = 1 << 20
+
+// segmentDocs is the most documents a segment written by Flush holds.
+const segmentDocs = 1000
+
+// Hit is a document matching a query. Its score is the number of query
+// terms it contains plus a fraction that grows with the rarity of those terms.
+type Hit struct {
+	Key   string
+	Score float64
+}
+
+// segment maps the documents written since the previous segment to their
+// terms, or to nil when they were deleted or aren't indexed.
+type segment map[string][]string
+
+// pendingDoc is a write queued to be indexed. Its body is nil for deletes
+// and for writes whose body wasn't given to hooks, which are read back.
+type pendingDoc struct {
+	body    []byte
+	deleted bool
+}
+
+// Index is a full-text index of the documents under a prefix.
+type Index struct {
+	svc    s3.Service
+	name   string
+	prefix string
+	fields []string
+
+	mu      sync.Mutex
+	pending map[string]pendingDoc
+	err     error
+
+	flushMu sync.Mutex
+
+	readMu   sync.Mutex
+	read     []string
+	docs     map[string][]string
+	postings map[string]map[string]struct{}
+
+	once sync.Once
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New adds a full-text index named name of the documents under the prefix to
+// the service, flushing the writes it queues every interval, or only on
+// Flush and Close when it is 0. When fields are given only those top level
+// fields of JSON documents are indexed.
+func New(svc s3.Service, name, prefix string, interval time.Duration, fields ...string) *Index {
+	ix := &Index{
+		svc:      svc,
+		name:     name,
+		prefix:   prefix,
+		fields:   fields,
+		pending:  map[string]pendingDoc{},
+		docs:     map[string][]string{},
+		postings: map[string]map[string]struct{}{},
+		done:     make(chan struct{}),
+	}
+	svc.AddHooks(s3.Hooks{
+		AfterPut: func(e s3.HookEvent) error {
+			if len(e.Body) > maxDocumentSize {
+				ix.queue(e.Key, pendingDoc{deleted: true})
+			} else {
+				// hooks' bodies are only valid until they return
+				ix.queue(e.Key, pendingDoc{body: slices.Clone(e.Body)})
+			}
+			return nil
+		},
+		AfterDelete: func(e s3.HookEvent) error {
+			ix.queue(e.Key, pendingDoc{deleted: true})
+			return nil
+		},
+	})
+	if interval > 0 {
+		ix.wg.Add(1)
+		go ix.flushLoop(interval)
+	}
+	return ix
+}
+
+// indexes reports whether the key is a document of the index.
+func (ix *Index) indexes(k string) bool {
+	return strings.HasPrefix(k, ix.prefix) && !strings.HasPrefix(k, segmentPrefix)
+}
+
+// queue queues a write of the key to be indexed, replacing any queued before.
+func (ix *Index) queue(k string, d pendingDoc) {
+	if !ix.indexes(k) {
+		return
+	}
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.pending[k] = d
+}
+
+func (ix *Index) flushLoop(interval time.Duration) {
+	defer ix.wg.Done()
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			err := ix.Flush()
+			ix.mu.Lock()
+			ix.err = err
+			ix.mu.Unlock()
+		case <-ix.done:
+			return
+		}
+	}
+}
+
+// Err returns the error of the last periodic flush, or nil if it succeeded.
+// Writes a flush fails to index stay queued for the next one.
+func (ix *Index) Err() error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return ix.err
+}
+
+// Flush writes the terms of the queued writes to the index as segments of
+// up to 1000 documents each, reading back the documents whose bodies the
+// hooks weren't given.
+func (ix *Index) Flush() error {
+	ix.flushMu.Lock()
+	defer ix.flushMu.Unlock()
+
+	ix.mu.Lock()
+	pending := ix.pending
+	ix.pending = map[string]pendingDoc{}
+	ix.mu.Unlock()
+
+	keys := slices.Sorted(maps.Keys(pending))
+	var err error
+	for len(keys) > 0 && err == nil {
+		n := min(len(keys), segmentDocs)
+		seg := segment{}
+		for i := 0; err == nil && i < n; i++ {
+			seg[keys[i]], err = ix.extract(keys[i], pending[keys[i]])
+		}
+		if err == nil {
+			err = ix.svc.Put(segmentPrefix+ix.name+"/"+ulid.Make().String(), seg)
+		}
+		if err == nil {
+			keys = keys[n:]
+		}
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	for _, k := range keys {
+		// keep writes queued while these were indexed
+		if _, ok := ix.pending[k]; !ok {
+			ix.pending[k] = pending[k]
+		}
+	}
+	return err
+}
+
+// Close stops the periodic flush and flushes the queued writes.
+func (ix *Index) Close() error {
+	ix.once.Do(func() {
+		close(ix.done)
+	})
+	ix.wg.Wait()
+	return ix.Flush()
+}
+
+// extract returns the terms of a queued write, nil when the document was
+// deleted or isn't indexed.
+func (ix *Index) extract(k string, d pendingDoc) ([]string, error) {
+	if d.deleted {
+		return nil, nil
+	}
+	body := d.body
+	if body == nil {
+		r, err := ix.svc.GetReader(k)
+		if s3.IsNotFound(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		if body, err = io.ReadAll(io.LimitReader(r, maxDocumentSize+1)); err != nil {
+			return nil, err
+		}
+		if len(body) > maxDocumentSize {
+			return nil, nil
+		}
+	}
+	return ix.terms(body), nil
+}
+
+// Build queues the documents under the index's prefix, for documents written
+// before it was added or by other clients, and flushes them a page at a time.
+func (ix *Index) Build() error {
+	var after string
+	for {
+		keys, err := ix.svc.Keys(ix.prefix, after, segmentDocs)
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			ix.queue(k, pendingDoc{})
+		}
+		if err = ix.Flush(); err != nil || len(keys) < segmentDocs {
+			return err
+		}
+		after = keys[len(keys)-1]
+	}
+}
+
+// segments returns the keys of the index's segments in the order they were
+// written.
+func (ix *Index) segments() ([]string, error) {
+	var segments []string
+	var after string
+	for {
+		keys, err := ix.svc.Keys(segmentPrefix+ix.name+"/", after, segmentDocs)
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, keys...)
+		if len(keys) < segmentDocs {
+			return segments, nil
+		}
+		after = keys[len(keys)-1]
+	}
+}
+
+// refresh merges the segments written since the index was last read, or
+// all of them again when they were compacted in the meantime.
+func (ix *Index) refresh() error {
+	segments, err := ix.segments()
+	if err != nil {
+		return err
+	}
+	if len(ix.read) > len(segments) || !slices.Equal(ix.read, segments[:len(ix.read)]) {
+		ix.read, ix.docs, ix.postings = nil, map[string][]string{}, map[string]map[string]struct{}{}
+	}
+	for _, k := range segments[len(ix.read):] {
+		var seg segment
+		if err := ix.svc.Find(k, &seg); err != nil {
+			return err
+		}
+		ix.apply(seg)
+		ix.read = append(ix.read, k)
+	}
+	return nil
+}
+
+// apply replaces the terms of the segment's documents.
+func (ix *Index) apply(seg segment) {
+	for k, terms := range seg {
+		for _, term := range ix.docs[k] {
+			delete(ix.postings[term], k)
+			if len(ix.postings[term]) == 0 {
+				delete(ix.postings, term)
+			}
+		}
+		if len(terms) == 0 {
+			delete(ix.docs, k)
+			continue
+		}
+		ix.docs[k] = terms
+		for _, term := range terms {
+			if ix.postings[term] == nil {
+				ix.postings[term] = map[string]struct{}{}
+			}
+			ix.postings[term][k] = struct{}{}
+		}
+	}
+}
+
+// Compact merges the index's segments into one holding the documents
+// indexed, so Search reads one object however many writes were indexed.
+// Segments written meanwhile sort after the merged one and are kept. It
+// must not run concurrently with another Compact of the index.
+func (ix *Index) Compact() error {
+	ix.readMu.Lock()
+	defer ix.readMu.Unlock()
+
+	if err := ix.refresh(); err != nil {
+		return err
+	}
+	if len(ix.read) < 2 {
+		return nil
+	}
+	// the merged segment sorts right after the last one merged
+	id, _, _ := strings.Cut(path.Base(ix.read[len(ix.read)-1]), "-")
+	merged := segmentPrefix + ix.name + "/" + id + "-" + ulid.Make().String()
+	if err := ix.svc.Put(merged, segment(ix.docs)); err != nil {
+		return err
+	}
+	for _, k := range ix.read {
+		if err := ix.svc.Delete(k); err != nil {
+			return err
+		}
+	}
+	ix.read = []string{merged}
+	return nil
+}
+
+// Search returns up to limit documents under the prefix containing any of
+// the query's terms, best matches first, or all of them when limit is 0.
+// Writes still queued to be indexed aren't found.
+func (ix *Index) Search(prefix, query string, limit int) ([]Hit, error) {
+	ix.readMu.Lock()
+	defer ix.readMu.Unlock()
+
+	if err := ix.refresh(); err != nil {
+		return nil, err
+	}
+
+	matched := map[string]int{}
+	rarity := map[string]float64{}
+	for _, term := range tokenize(query) {
+		keys := ix.postings[term]
+		for k := range keys {
+			if strings.HasPrefix(k, prefix) {
+				matched[k]++
+				rarity[k] += 1 / math.Log2(1+float64(len(keys)))
+			}
+		}
+	}
+
+	hits := make([]Hit, 0, len(matched))
+	for k, n := range matched {
+		hits = append(hits, Hit{k, float64(n) + rarity[k]/float64(n+1)})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Key < hits[j].Key
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+// terms returns the distinct terms of the document, or nil when it is
+// neither JSON nor text.
+func (ix *Index) terms(doc []byte) []string {
+	var v any
+	if json.Unmarshal(doc, &v) != nil {
+		if !utf8.Valid(doc) || !strings.HasPrefix(http.DetectContentType(doc), "text/") {
+			return nil
+		}
+		return tokenize(string(doc))
+	}
+	if m, ok := v.(map[string]any); ok && len(ix.fields) > 0 {
+		var selected []any
+		for _, f := range ix.fields {
+			selected = append(selected, m[f])
+		}
+		v = selected
+	}
+	var b strings.Builder
+	text(&b, v)
+	return tokenize(b.String())
+}
+
+// text writes the string values of the decoded JSON value.
+func text(b *strings.Builder, v any) {
+	switch v := v.(type) {
+	case string:
+		b.WriteString(v)
+		b.WriteByte(' ')
+	case []any:
+		for _, e := range v {
+			text(b, e)
+		}
+	case map[string]any:
+		for _, e := range v {
+			text(b, e)
+		}
+	}
+}
+
+// tokenize returns the distinct lowercased words of the text, ignoring
+// single characters.
+func tokenize(s string) []string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	words = slices.DeleteFunc(words, func(w string) bool { return len([]rune(w)) < 2 })
+	slices.Sort(words)
+	return slices.Compact(words)
+}
@@ -0,0 +1,177 @@
+package search
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/nelsw/s3"
+	"github.com/stretchr/testify/assert"
+)
+
+// testService keeps objects in memory, running hooks on Put and Delete as
+// the client does.
+type testService struct {
+	s3.Service
+	objects map[string][]byte
+	hooks   []s3.Hooks
+	fail    error
+}
+
+func newTestService() *testService {
+	return &testService{objects: map[string][]byte{}}
+}
+
+func (s *testService) AddHooks(h s3.Hooks) {
+	s.hooks = append(s.hooks, h)
+}
+
+func (s *testService) Put(k string, a any) error {
+	if s.fail != nil {
+		return s.fail
+	}
+	b, ok := a.([]byte)
+	if !ok {
+		var err error
+		if b, err = json.Marshal(a); err != nil {
+			return err
+		}
+	}
+	s.objects[k] = b
+	for _, h := range s.hooks {
+		if err := h.AfterPut(s3.HookEvent{Key: k, Body: b}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *testService) Delete(k string) error {
+	delete(s.objects, k)
+	for _, h := range s.hooks {
+		if err := h.AfterDelete(s3.HookEvent{Key: k}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *testService) GetReader(k string) (io.ReadCloser, error) {
+	b, ok := s.objects[k]
+	if !ok {
+		return nil, errors.New("NoSuchKey: not found")
+	}
+	return io.NopCloser(bytes.NewReader(b)), nil
+}
+
+func (s *testService) Find(k string, a any) error {
+	return json.Unmarshal(s.objects[k], a)
+}
+
+func (s *testService) Keys(p, after string, limit int32, _ ...s3.KeyFilter) ([]string, error) {
+	var keys []string
+	for _, k := range slices.Sorted(maps.Keys(s.objects)) {
+		if strings.HasPrefix(k, p) && k > after && len(keys) < int(limit) {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+func TestSearch(t *testing.T) {
+
+	svc := newTestService()
+	svc.objects["docs/old.json"] = []byte(`{"title": "Old News", "body": "nothing"}`)
+
+	ix := New(svc, "fulltext", "docs/", 0, "title", "body")
+	assert.NoError(t, svc.Put("docs/1.json", []byte(`{"title": "Go Concurrency", "body": "Channels and goroutines.", "id": "rust"}`)))
+	assert.NoError(t, svc.Put("docs/2.json", []byte(`{"title": "Rust ownership", "body": "Borrowing, not goroutines"}`)))
+	assert.NoError(t, svc.Put("docs/3.txt", []byte("goroutines in plain text")))
+	assert.NoError(t, svc.Put("docs/4.bin", []byte("\x00\x01goroutines\xff")))
+	assert.NoError(t, svc.Put("notes/5.txt", []byte("goroutines outside the prefix")))
+
+	// writes are indexed once flushed
+	hits, err := ix.Search("", "goroutines", 0)
+	assert.NoError(t, err)
+	assert.Empty(t, hits)
+	assert.NoError(t, ix.Flush())
+
+	hits, err = ix.Search("", "goroutines channels", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"docs/1.json", "docs/2.json", "docs/3.txt"}, keys(hits))
+
+	// unselected fields aren't indexed
+	hits, err = ix.Search("docs/", "RUST", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"docs/2.json"}, keys(hits))
+
+	hits, err = ix.Search("docs/", "goroutines", 1)
+	assert.NoError(t, err)
+	assert.Len(t, hits, 1)
+
+	assert.NoError(t, svc.Delete("docs/3.txt"))
+	assert.NoError(t, svc.Put("docs/2.json", []byte(`{"title": "Rust ownership"}`)))
+	assert.NoError(t, ix.Close())
+	hits, err = ix.Search("", "goroutines", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"docs/1.json"}, keys(hits))
+
+	// documents written before the index are found once built
+	hits, err = ix.Search("", "news", 0)
+	assert.NoError(t, err)
+	assert.Empty(t, hits)
+	assert.NoError(t, ix.Build())
+	hits, err = ix.Search("", "news", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"docs/old.json"}, keys(hits))
+
+	// compaction merges the segments, and other readers see the same index
+	segments, err := ix.segments()
+	assert.NoError(t, err)
+	assert.Len(t, segments, 3)
+	assert.NoError(t, ix.Compact())
+	segments, err = ix.segments()
+	assert.NoError(t, err)
+	assert.Len(t, segments, 1)
+
+	other := New(svc, "fulltext", "docs/", 0)
+	for _, ix := range []*Index{ix, other} {
+		hits, err = ix.Search("", "goroutines news rust", 0)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"docs/1.json", "docs/2.json", "docs/old.json"}, keys(hits))
+	}
+}
+
+func TestIndex_Flush(t *testing.T) {
+
+	svc := newTestService()
+	ix := New(svc, "fulltext", "", 0)
+	assert.NoError(t, svc.Put("a.txt", []byte("hello")))
+
+	// writes that fail to index stay queued
+	svc.fail = errors.New("boom")
+	assert.ErrorIs(t, ix.Flush(), svc.fail)
+	svc.fail = nil
+	assert.NoError(t, ix.Flush())
+
+	hits, err := ix.Search("", "hello", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a.txt"}, keys(hits))
+}
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(t, []string{"go", "hello", "über"}, tokenize("Hello, a Go über-HELLO!"))
+}
+
+func keys(hits []Hit) []string {
+	var keys []string
+	for _, h := range hits {
+		keys = append(keys, h.Key)
+	}
+	return keys
+}
@@ -0,0 +1,135 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// crockford is the base32 alphabet ULIDs are written in.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ListSharded lists every key under the prefixes with up to concurrency
+// prefixes listed at a time, returning them merged in lexicographical order.
+// S3 lists a single prefix one page after another, so splitting a large
+// prefix into shards, such as those returned by HexShards and ULIDShards,
+// and listing them in parallel enumerates it many times faster. Keys under
+// overlapping prefixes are returned once.
+func (c *client) ListSharded(prefixes []string, concurrency int) ([]string, error) {
+
+	results := make([][]string, len(prefixes))
+	shards := make([]int, len(prefixes))
+	for i := range shards {
+		shards[i] = i
+	}
+	_, err := parallel(c.Context, concurrency, sendEach(shards), func(ctx context.Context, i int) error {
+		return c.withContext(ctx).walk(prefixes[i], func(obj types.Object) error {
+			results[i] = append(results[i], *obj.Key)
+			return nil
+		})
+	})
+
+	var keys []string
+	if err == nil {
+		keys = slices.Concat(results...)
+		slices.Sort(keys)
+		keys = slices.Compact(keys)
+	}
+
+	c.log("ListSharded", err).
+		Int("prefixes", len(prefixes)).
+		Int("concurrency", concurrency).
+		Int("keys", len(keys)).
+		Msg("ListSharded")
+
+	return keys, err
+}
+
+// HexShards returns the prefix followed by every string of digits lowercase
+// hex digits, for keys that start with a hash or random hex ID.
+func HexShards(p string, digits int) []string {
+	shards := []string{p}
+	for range digits {
+		next := make([]string, 0, len(shards)*16)
+		for _, s := range shards {
+			for d := range 16 {
+				next = append(next, s+strconv.FormatInt(int64(d), 16))
+			}
+		}
+		shards = next
+	}
+	return shards
+}
+
+// ULIDShards returns the prefix followed by the first chars characters of
+// the ULIDs generated between from and to, for keys that start with a ULID,
+// where chars is at most 10.
+// ULIDs start with their timestamp, so each shard covers a span of time:
+// 4 characters span about 12 days, 5 about 9 hours and 6 about 17 minutes.
+func ULIDShards(p string, from, to time.Time, chars int) []string {
+	shift := 50 - 5*chars
+	first, last := from.UnixMilli()>>shift, to.UnixMilli()>>shift
+	var shards []string
+	for v := first; v <= last; v++ {
+		var b strings.Builder
+		b.WriteString(p)
+		for i := chars - 1; i >= 0; i-- {
+			b.WriteByte(crockford[v>>(5*i)&31])
+		}
+		shards = append(shards, b.String())
+	}
+	return shards
+}
+
+// ShardedKey spreads keys written at a high rate across n shards, each with
+// its own S3 request rate limit, by prefixing the key with a hex shard number
+// derived from its hash, e.g. "3/events/1.json". The same key always maps to
+// the same shard, so objects are read back from ShardedKey(k, n) and listed
+// with ListShardedKeys, while n stays the same.
+func ShardedKey(k string, n int) string {
+	if n < 1 {
+		panic(fmt.Sprintf("s3: %d key shards", n))
+	}
+	h := fnv.New32a()
+	h.Write([]byte(k))
+	return shardPrefix(int(h.Sum32()%uint32(n)), n) + k
+}
+
+// UnshardKey returns the key ShardedKey was given for a sharded key.
+func UnshardKey(k string) string {
+	_, k, _ = strings.Cut(k, "/")
+	return k
+}
+
+// ShardPrefixes returns the prefix in each of n shards.
+func ShardPrefixes(p string, n int) []string {
+	shards := make([]string, n)
+	for i := range shards {
+		shards[i] = shardPrefix(i, n) + p
+	}
+	return shards
+}
+
+// shardPrefix returns the prefix of shard i of n, zero padded so shards sort
+// in order.
+func shardPrefix(i, n int) string {
+	return fmt.Sprintf("%0*x/", len(strconv.FormatInt(int64(n-1), 16)), i)
+}
+
+// ListShardedKeys lists the keys written with ShardedKey(k, n) whose k is
+// under the prefix, listing up to concurrency shards at a time, and returns
+// them unsharded in lexicographical order.
+func (c *client) ListShardedKeys(p string, n, concurrency int) ([]string, error) {
+	keys, err := c.ListSharded(ShardPrefixes(p, n), concurrency)
+	for i, k := range keys {
+		keys[i] = UnshardKey(k)
+	}
+	slices.Sort(keys)
+	return keys, err
+}
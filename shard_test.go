@@ -0,0 +1,85 @@
+package s3
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_ListSharded(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	for _, k := range []string{"blobs/0a", "blobs/3f", "blobs/a1", "blobs/ff", "other/00"} {
+		b.put(k, nil, nil)
+	}
+
+	keys, err := c.ListSharded(HexShards("blobs/", 1), 4)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"blobs/0a", "blobs/3f", "blobs/a1", "blobs/ff"}, keys)
+
+	keys, err = c.ListSharded([]string{"blobs/", "blobs/a", "other/"}, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"blobs/0a", "blobs/3f", "blobs/a1", "blobs/ff", "other/00"}, keys)
+
+	keys, err = c.ListSharded(nil, 2)
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestHexShards(t *testing.T) {
+	assert.Equal(t, []string{"p/"}, HexShards("p/", 0))
+	shards := HexShards("p/", 2)
+	assert.Len(t, shards, 256)
+	assert.Equal(t, "p/00", shards[0])
+	assert.Equal(t, "p/0f", shards[15])
+	assert.Equal(t, "p/ff", shards[255])
+}
+
+func TestULIDShards(t *testing.T) {
+
+	from := time.Date(2025, 9, 4, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+	shards := ULIDShards("jobs/", from, to, 5)
+	assert.Len(t, shards, 3)
+
+	for _, at := range []time.Time{from, from.Add(13 * time.Hour), to} {
+		id := ulid.MustNew(ulid.Timestamp(at), ulid.DefaultEntropy()).String()
+		assert.Contains(t, shards, "jobs/"+id[:5])
+	}
+	assert.Empty(t, ULIDShards("jobs/", to, from, 5))
+}
+
+func TestShardedKey(t *testing.T) {
+
+	k := ShardedKey("events/1.json", 16)
+	assert.Regexp(t, `^[0-9a-f]/events/1\.json$`, k)
+	assert.Equal(t, k, ShardedKey("events/1.json", 16))
+	assert.Equal(t, "events/1.json", UnshardKey(k))
+	assert.Regexp(t, `^[0-9a-f]{2}/events/1\.json$`, ShardedKey("events/1.json", 256))
+	assert.Equal(t, "0/events/1.json", ShardedKey("events/1.json", 1))
+	assert.Panics(t, func() { ShardedKey("events/1.json", 0) })
+
+	assert.Equal(t, []string{"00/p/", "01/p/", "02/p/", "03/p/", "04/p/", "05/p/", "06/p/", "07/p/", "08/p/", "09/p/", "0a/p/", "0b/p/", "0c/p/", "0d/p/", "0e/p/", "0f/p/", "10/p/"}, ShardPrefixes("p/", 17))
+}
+
+func TestClient_ListShardedKeys(t *testing.T) {
+
+	c, _ := newTestBucket(t)
+	var want []string
+	for i := range 20 {
+		k := "events/" + string(rune('a'+i)) + ".json"
+		want = append(want, k)
+		assert.NoError(t, c.Put(ShardedKey(k, 8), []byte(k)))
+	}
+	assert.NoError(t, c.Put(ShardedKey("other/a.json", 8), []byte("{}")))
+
+	keys, err := c.ListShardedKeys("events/", 8, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, want, keys)
+
+	b, err := c.Get(ShardedKey(want[3], 8))
+	assert.NoError(t, err)
+	assert.Equal(t, want[3], string(b))
+}
@@ -0,0 +1,193 @@
+package s3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Precompression selects the Content-Encoding applied to compressible
+// assets uploaded by DeploySite.
+type Precompression string
+
+const (
+	NoPrecompression Precompression = ""
+	Gzip             Precompression = "gzip"
+	Brotli           Precompression = "br"
+)
+
+const (
+	hashedCacheControl  = "public, max-age=31536000, immutable"
+	htmlCacheControl    = "public, max-age=60, must-revalidate"
+	defaultCacheControl = "public, max-age=3600"
+)
+
+// hashedAsset matches file names carrying a content hash, e.g. app.3f9a2b1c.js.
+var hashedAsset = regexp.MustCompile(`[.-][0-9a-f]{8,}\.[^.]+$`)
+
+// SiteOption configures DeploySite.
+type SiteOption func(*site)
+
+type site struct {
+	precompression Precompression
+	keep           bool
+}
+
+// WithPrecompression compresses text assets before upload and sets their Content-Encoding.
+func WithPrecompression(p Precompression) SiteOption {
+	return func(s *site) {
+		s.precompression = p
+	}
+}
+
+// WithoutPrune keeps objects under the prefix that no longer exist locally.
+func WithoutPrune() SiteOption {
+	return func(s *site) {
+		s.keep = true
+	}
+}
+
+// cacheControl returns the Cache-Control header for a site asset.
+func cacheControl(name string) string {
+	switch {
+	case hashedAsset.MatchString(name):
+		return hashedCacheControl
+	case strings.HasSuffix(name, ".html") || strings.HasSuffix(name, ".htm"):
+		return htmlCacheControl
+	default:
+		return defaultCacheControl
+	}
+}
+
+// compressible reports whether assets of the content type benefit from precompression.
+func compressible(ct string) bool {
+	ct, _, _ = strings.Cut(ct, ";")
+	return strings.HasPrefix(ct, "text/") ||
+		strings.HasSuffix(ct, "javascript") ||
+		strings.HasSuffix(ct, "json") ||
+		strings.HasSuffix(ct, "xml") ||
+		ct == "application/wasm"
+}
+
+func compress(p Precompression, b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch p {
+	case Gzip:
+		w, _ = gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	case Brotli:
+		w = brotli.NewWriterLevel(&buf, brotli.BestCompression)
+	default:
+		return b, nil
+	}
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ErrSitePrefix is returned by DeploySite for an empty prefix, which would
+// prune the whole bucket.
+var ErrSitePrefix = errors.New("s3: site prefix is empty")
+
+// DeploySite uploads every file in dir under the prefix with a detected
+// Content-Type and an appropriate Cache-Control, then deletes objects under
+// the prefix that were removed locally. The prefix is a directory, so
+// deploying to "site" leaves "site2/" and "sitemap.xml" alone.
+func (c *client) DeploySite(dir, p string, opts ...SiteOption) error {
+
+	p = strings.TrimSuffix(p, "/")
+	if p == "" {
+		return ErrSitePrefix
+	}
+
+	s := &site{}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	uploaded := map[string]bool{}
+	err := filepath.WalkDir(dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, name)
+		if err != nil {
+			return err
+		}
+		k := path.Join(p, filepath.ToSlash(rel))
+		uploaded[k] = true
+		return c.deployFile(s, name, k)
+	})
+
+	var removed []string
+	if err == nil && !s.keep {
+		err = c.walk(p+"/", func(obj types.Object) error {
+			if !uploaded[*obj.Key] {
+				removed = append(removed, *obj.Key)
+			}
+			return nil
+		})
+		for i := 0; err == nil && i < len(removed); i++ {
+			err = c.Delete(removed[i])
+		}
+	}
+
+	c.log("DeploySite", err).
+		Str("dir", dir).
+		Str("prefix", p).
+		Int("uploaded", len(uploaded)).
+		Strs("removed", removed).
+		Msg("DeploySite")
+
+	return err
+}
+
+func (c *client) deployFile(s *site, name, k string) error {
+
+	body, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+
+	ct := contentType(k, body)
+
+	cc := cacheControl(k)
+	in := &s3.PutObjectInput{
+		Bucket:       c.Bucket,
+		Key:          &k,
+		ContentType:  &ct,
+		CacheControl: &cc,
+	}
+
+	if s.precompression != NoPrecompression && compressible(ct) {
+		if body, err = compress(s.precompression, body); err != nil {
+			return err
+		}
+		enc := string(s.precompression)
+		in.ContentEncoding = &enc
+	}
+	_, err = c.putObject(in, body)
+
+	c.log("DeploySite", err).
+		Str("key", k).
+		Str("type", ct).
+		Str("cache", cc).
+		Msg("DeploySite")
+
+	return err
+}
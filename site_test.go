@@ -0,0 +1,54 @@
+package s3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheControl(t *testing.T) {
+	assert.Equal(t, hashedCacheControl, cacheControl("site/assets/app.3f9a2b1c.js"))
+	assert.Equal(t, hashedCacheControl, cacheControl("site/assets/index-0badc0de11.css"))
+	assert.Equal(t, htmlCacheControl, cacheControl("site/index.html"))
+	assert.Equal(t, defaultCacheControl, cacheControl("site/favicon.ico"))
+	assert.Equal(t, defaultCacheControl, cacheControl("site/some-component.js"))
+}
+
+func TestCompress(t *testing.T) {
+
+	assert.True(t, compressible("text/html; charset=utf-8"))
+	assert.True(t, compressible("text/javascript; charset=utf-8"))
+	assert.False(t, compressible("image/png"))
+
+	b, err := compress(Gzip, []byte(testBody()))
+	assert.NoError(t, err)
+
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	assert.NoError(t, err)
+	b, err = io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, testBody(), string(b))
+}
+
+func TestClient_DeploySite(t *testing.T) {
+
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), []byte("<html></html>"), 0o644))
+
+	c, b := newTestBucket(t)
+	b.put("site/old.html", nil, nil)
+	b.put("site2/index.html", nil, nil)
+	b.put("sitemap.xml", nil, nil)
+
+	assert.NoError(t, c.DeploySite(dir, "site"))
+	assert.Equal(t, []string{"site/index.html", "site2/index.html", "sitemap.xml"}, b.keys())
+	assert.Equal(t, htmlCacheControl, b.object("site/index.html").header.Get("Cache-Control"))
+
+	assert.ErrorIs(t, c.DeploySite(dir, ""), ErrSitePrefix)
+	assert.ErrorIs(t, c.DeploySite(dir, "/"), ErrSitePrefix)
+}
@@ -0,0 +1,99 @@
+package s3
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// snapshotPrefix is where Snapshot writes its manifests.
+const snapshotPrefix = ".snapshots/"
+
+func snapshotKey(name string) string {
+	return snapshotPrefix + name + ".json"
+}
+
+// Snapshot records the current version of every object under the prefix in
+// a manifest named name under .snapshots/, so RestoreSnapshot can bring the
+// prefix back to this point in time. Nothing is copied, which makes snapshots
+// cheap, but they only last as long as the bucket keeps the versions, so the
+// bucket must be versioned and lifecycle rules must not expire noncurrent
+// versions the snapshot needs.
+func (c *client) Snapshot(p, name string) error {
+
+	m := Manifest{Source: p, Created: time.Now().UTC()}
+	paginator := s3.NewListObjectVersionsPaginator(c.Client, &s3.ListObjectVersionsInput{
+		Bucket: c.Bucket,
+		Prefix: &p,
+	})
+	var err error
+	for err == nil && paginator.HasMorePages() {
+		var page *s3.ListObjectVersionsOutput
+		if page, err = paginator.NextPage(c.Context); err != nil {
+			break
+		}
+		for _, v := range page.Versions {
+			if aws.ToBool(v.IsLatest) {
+				m.Objects = append(m.Objects, ManifestEntry{
+					Key:       strings.TrimPrefix(*v.Key, p),
+					Size:      aws.ToInt64(v.Size),
+					ETag:      aws.ToString(v.ETag),
+					VersionID: aws.ToString(v.VersionId),
+				})
+			}
+		}
+	}
+	if err == nil {
+		err = c.Put(snapshotKey(name), m)
+	}
+
+	c.log("Snapshot", err).
+		Str("prefix", p).
+		Str("name", name).
+		Int("objects", len(m.Objects)).
+		Msg("Snapshot")
+
+	return err
+}
+
+// RestoreSnapshot copies the versions recorded by the named snapshot back
+// over their keys, part by part when a version is beyond the single copy
+// limit. Objects written under the prefix since the snapshot was taken are
+// left in place.
+func (c *client) RestoreSnapshot(name string) error {
+
+	var m Manifest
+	err := c.readJSON(snapshotKey(name), &m)
+
+	var restored int
+	for i := 0; err == nil && i < len(m.Objects); i++ {
+		e := m.Objects[i]
+		k := m.Source + e.Key
+		src := c.copySource(k)
+		version := e.VersionID
+		if version == "null" {
+			version = ""
+		}
+		if version != "" {
+			src += "?versionId=" + url.QueryEscape(version)
+		}
+		if _, _, err = c.copySized(&s3.CopyObjectInput{
+			Bucket:     c.Bucket,
+			Key:        &k,
+			CopySource: &src,
+		}, k, version); err == nil {
+			restored++
+		}
+	}
+
+	c.log("RestoreSnapshot", err).
+		Str("name", name).
+		Str("prefix", m.Source).
+		Int("objects", restored).
+		Msg("RestoreSnapshot")
+
+	return err
+}
@@ -0,0 +1,61 @@
+package s3
+
+import (
+	"encoding/xml"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Snapshot(t *testing.T) {
+
+	var copied, heads []string
+	c, _ := newTestBucket(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.URL.Query().Has("versions"):
+				assert.Equal(t, "users/", r.URL.Query().Get("prefix"))
+				w.Header().Set("Content-Type", "application/xml")
+				_, _ = w.Write([]byte(`<ListVersionsResult>
+	<Version><Key>users/a.json</Key><VersionId>a2</VersionId><IsLatest>true</IsLatest><ETag>"a"</ETag><Size>1</Size></Version>
+	<Version><Key>users/a.json</Key><VersionId>a1</VersionId><IsLatest>false</IsLatest><ETag>"b"</ETag><Size>1</Size></Version>
+	<Version><Key>users/b.json</Key><VersionId>null</VersionId><IsLatest>true</IsLatest><ETag>"c"</ETag><Size>1</Size></Version>
+	<DeleteMarker><Key>users/c.json</Key><VersionId>c2</VersionId><IsLatest>true</IsLatest></DeleteMarker>
+	<Version><Key>users/c.json</Key><VersionId>c1</VersionId><IsLatest>false</IsLatest><ETag>"d"</ETag><Size>1</Size></Version>
+	</ListVersionsResult>`))
+			case r.Method == http.MethodHead:
+				// the restored versions are read to copy them as they're encrypted
+				heads = append(heads, r.URL.Path+"?"+r.URL.RawQuery)
+				w.Header().Set("Content-Length", "1")
+				w.Header().Set("ETag", `"a"`)
+			case r.Header.Get("X-Amz-Copy-Source") != "":
+				copied = append(copied, r.URL.Path+" < "+r.Header.Get("X-Amz-Copy-Source"))
+				writeXML(w, struct {
+					XMLName xml.Name `xml:"CopyObjectResult"`
+					ETag    string
+				}{ETag: `"a"`})
+			default:
+				next.ServeHTTP(w, r)
+			}
+		})
+	})
+
+	assert.NoError(t, c.Snapshot("users/", "nightly"))
+	var m Manifest
+	assert.NoError(t, c.Find(".snapshots/nightly.json", &m))
+	assert.Equal(t, "users/", m.Source)
+	assert.Equal(t, []ManifestEntry{
+		{Key: "a.json", Size: 1, ETag: `"a"`, VersionID: "a2"},
+		{Key: "b.json", Size: 1, ETag: `"c"`, VersionID: "null"},
+	}, m.Objects)
+
+	assert.NoError(t, c.RestoreSnapshot("nightly"))
+	assert.Equal(t, []string{
+		"/bytelyon-db/users/a.json < bytelyon-db/users%2Fa.json?versionId=a2",
+		"/bytelyon-db/users/b.json < bytelyon-db/users%2Fb.json",
+	}, copied)
+	assert.Equal(t, []string{"/bytelyon-db/users/a.json?versionId=a2", "/bytelyon-db/users/b.json?"}, heads)
+
+	assert.True(t, IsNotFound(c.RestoreSnapshot("missing")))
+}
@@ -0,0 +1,121 @@
+package s3
+
+import (
+	"container/heap"
+	"context"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// statsWorkers bounds the number of sub-prefixes listed concurrently by Stats.
+const statsWorkers = 8
+
+// Stats returns the number of objects under the prefix and their total size.
+// The prefix is split into its immediate sub-prefixes which are listed
+// concurrently.
+func (c *client) Stats(p string) (int64, int64, error) {
+
+	var count, size atomic.Int64
+	dirs, objects, err := c.ListDir(p)
+	for _, o := range objects {
+		count.Add(1)
+		size.Add(o.Size)
+	}
+
+	if err == nil {
+		_, err = parallel(c.Context, statsWorkers, sendEach(dirs), func(ctx context.Context, d string) error {
+			return c.withContext(ctx).walk(d, func(obj types.Object) error {
+				count.Add(1)
+				size.Add(aws.ToInt64(obj.Size))
+				return nil
+			})
+		})
+	}
+
+	c.log("Stats", err).
+		Str("prefix", p).
+		Int64("count", count.Load()).
+		Int64("bytes", size.Load()).
+		Msg("Stats")
+
+	return count.Load(), size.Load(), err
+}
+
+// Count returns the number of objects under the prefix, counting each page
+// of the listing as it arrives rather than collecting keys.
+func (c *client) Count(p string) (int64, error) {
+
+	paginator := s3.NewListObjectsV2Paginator(c.Client, &s3.ListObjectsV2Input{
+		Bucket: c.Bucket,
+		Prefix: &p,
+	})
+
+	var count int64
+	var err error
+	for err == nil && paginator.HasMorePages() {
+		var out *s3.ListObjectsV2Output
+		if out, err = paginator.NextPage(c.Context); err == nil {
+			count += int64(len(out.Contents))
+		}
+	}
+
+	c.log("Count", err).
+		Str("prefix", p).
+		Int64("count", count).
+		Msg("Count")
+
+	return count, err
+}
+
+// sizeHeap is a min-heap of objects by size.
+type sizeHeap []ObjectInfo
+
+func (h sizeHeap) Len() int           { return len(h) }
+func (h sizeHeap) Less(i, j int) bool { return h[i].Size < h[j].Size }
+func (h sizeHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *sizeHeap) Push(x any)        { *h = append(*h, x.(ObjectInfo)) }
+
+func (h *sizeHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// LargestObjects returns the n largest objects under the prefix, largest first.
+func (c *client) LargestObjects(p string, n int) ([]ObjectInfo, error) {
+
+	h := &sizeHeap{}
+	err := c.walk(p, func(obj types.Object) error {
+		if n <= 0 {
+			return nil
+		}
+		o := objectInfo(obj)
+		if h.Len() < n {
+			heap.Push(h, o)
+		} else if o.Size > (*h)[0].Size {
+			(*h)[0] = o
+			heap.Fix(h, 0)
+		}
+		return nil
+	})
+
+	var largest []ObjectInfo
+	if err == nil {
+		largest = make([]ObjectInfo, h.Len())
+		for i := len(largest) - 1; i >= 0; i-- {
+			largest[i] = heap.Pop(h).(ObjectInfo)
+		}
+	}
+
+	c.log("LargestObjects", err).
+		Str("prefix", p).
+		Int("n", n).
+		Int("objects", len(largest)).
+		Msg("LargestObjects")
+
+	return largest, err
+}
@@ -0,0 +1,56 @@
+package s3
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Stats(t *testing.T) {
+
+	c, _ := newTestBucket(t)
+	for i := range 12 {
+		assert.NoError(t, c.Put("users/"+strconv.Itoa(i%4)+"/"+strconv.Itoa(i)+".json", testBody()))
+	}
+	assert.NoError(t, c.Put("users/_.json", testBody()))
+	assert.NoError(t, c.Put("other/_.json", testBody()))
+
+	count, size, err := c.Stats("users/")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(13), count)
+	assert.Equal(t, int64(13*len(testBody())), size)
+}
+
+func TestClient_Count(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	for i := range 1500 {
+		b.put("users/"+strconv.Itoa(i)+".json", nil, nil)
+	}
+	b.put("other/_.json", nil, nil)
+
+	count, err := c.Count("users/")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1500), count)
+
+	count, err = c.Count("none/")
+	assert.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestClient_LargestObjects(t *testing.T) {
+
+	c, _ := newTestBucket(t)
+	for i := 1; i <= 10; i++ {
+		assert.NoError(t, c.Put("blobs/"+strconv.Itoa(i), make([]byte, i*100)))
+	}
+
+	largest, err := c.LargestObjects("blobs/", 3)
+	assert.NoError(t, err)
+	assert.Len(t, largest, 3)
+	assert.Equal(t, "blobs/10", largest[0].Key)
+	assert.Equal(t, int64(1000), largest[0].Size)
+	assert.Equal(t, "blobs/9", largest[1].Key)
+	assert.Equal(t, "blobs/8", largest[2].Key)
+}
@@ -0,0 +1,181 @@
+package s3
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// sha256Metadata is the user metadata key holding the hex encoded SHA-256
+// of the object body, as written by Sync.
+const sha256Metadata = "sha256"
+
+// Sync uploads the files in dir under the prefix, skipping files whose
+// remote object is unchanged, and returns the number of files uploaded.
+// Files are compared by size, then by ETag, computing the multipart ETag for
+// objects uploaded in parts, and finally by the SHA-256 stored in the object
+// metadata by previous syncs.
+func (c *client) Sync(dir, p string) (int, error) {
+
+	remote := map[string]types.Object{}
+	err := c.walk(p, func(obj types.Object) error {
+		remote[*obj.Key] = obj
+		return nil
+	})
+
+	var uploaded, skipped int
+	if err == nil {
+		err = filepath.WalkDir(dir, func(name string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(dir, name)
+			if err != nil {
+				return err
+			}
+			k := path.Join(p, filepath.ToSlash(rel))
+			if obj, ok := remote[k]; ok {
+				if same, err := c.unchanged(name, obj); err != nil || same {
+					skipped++
+					return err
+				}
+			}
+			uploaded++
+			return c.syncFile(name, k)
+		})
+	}
+
+	c.log("Sync", err).
+		Str("dir", dir).
+		Str("prefix", p).
+		Int("uploaded", uploaded).
+		Int("skipped", skipped).
+		Msg("Sync")
+
+	return uploaded, err
+}
+
+// unchanged reports whether the local file matches the remote object.
+func (c *client) unchanged(name string, obj types.Object) (bool, error) {
+	fi, err := os.Stat(name)
+	if err != nil || fi.Size() != aws.ToInt64(obj.Size) {
+		return false, err
+	}
+
+	etag := strings.Trim(aws.ToString(obj.ETag), `"`)
+	parts := 1
+	if i := strings.LastIndex(etag, "-"); i >= 0 {
+		if parts, err = strconv.Atoi(etag[i+1:]); err != nil {
+			parts = 0
+		}
+	}
+	if parts > 0 {
+		if local, err := fileETag(name, fi.Size(), parts); err != nil || local == etag {
+			return err == nil, err
+		}
+	}
+
+	sum, err := fileSHA256(name)
+	if err != nil {
+		return false, err
+	}
+	out, err := c.HeadObject(c.Context, &s3.HeadObjectInput{Bucket: c.Bucket, Key: obj.Key})
+	if err != nil {
+		return false, err
+	}
+	return out.Metadata[sha256Metadata] == sum, nil
+}
+
+// fileETag computes the ETag S3 assigns to the file when uploaded in the
+// given number of parts. The part size is assumed to be that of this client
+// when it fits, otherwise the smallest whole MiB that yields the part count.
+func fileETag(name string, size int64, parts int) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if parts == 1 {
+		h := md5.New()
+		if _, err = io.Copy(h, f); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	ps := int64(partSize)
+	if (size+ps-1)/ps != int64(parts) {
+		const mib = 1 << 20
+		ps = ((size+int64(parts)-1)/int64(parts) + mib - 1) / mib * mib
+	}
+
+	var sums []byte
+	for {
+		h := md5.New()
+		n, err := io.CopyN(h, f, ps)
+		if n > 0 {
+			sums = h.Sum(sums)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	sum := md5.Sum(sums)
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(sum[:]), len(sums)/md5.Size), nil
+}
+
+func fileSHA256(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// syncFile streams the file to the key, storing its SHA-256 in the metadata.
+func (c *client) syncFile(name, k string) error {
+	sum, err := fileSHA256(name)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	in := &s3.PutObjectInput{
+		Key:      &k,
+		Metadata: map[string]string{sha256Metadata: sum},
+	}
+	if ct := mime.TypeByExtension(path.Ext(k)); ct != "" {
+		in.ContentType = &ct
+	}
+	u := c.newUploader(in)
+	if _, err = io.Copy(u, f); err != nil {
+		return u.CloseWithError(err)
+	}
+	return u.Close()
+}
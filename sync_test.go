@@ -0,0 +1,36 @@
+package s3
+
+import (
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Sync(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	dir := testDir(t)
+
+	large := make([]byte, partSize+1024)
+	_, _ = rand.Read(large)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "large.bin"), large, 0o644))
+
+	n, err := c.Sync(dir, "site")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.True(t, strings.HasSuffix(b.object("site/large.bin").etag, `-2"`))
+	assert.Len(t, b.object("site/index.html").header.Get("X-Amz-Meta-Sha256"), 64)
+
+	n, err = c.Sync(dir, "site")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), []byte("<html>!</html>"), 0o644))
+	n, err = c.Sync(dir, "site")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
@@ -0,0 +1,73 @@
+package s3
+
+import (
+	"context"
+	"sort"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// TagPrefix merges the tags into the tag set of every object under the
+// prefix, overwriting tags with the same key and keeping the others. Objects
+// are tagged by up to concurrency workers as the prefix is listed, stopping
+// at the first error.
+func (c *client) TagPrefix(p string, tags map[string]string, concurrency int) error {
+
+	var tagged atomic.Int64
+	_, err := parallel(c.Context, concurrency, func(send func(string) bool) error {
+		return c.walk(p, func(obj types.Object) error {
+			if !send(*obj.Key) {
+				return errWalkStopped
+			}
+			return nil
+		})
+	}, func(ctx context.Context, k string) error {
+		if err := c.withContext(ctx).tag(k, tags); err != nil {
+			return err
+		}
+		tagged.Add(1)
+		return nil
+	})
+
+	c.log("TagPrefix", err).
+		Str("prefix", p).
+		Any("tags", tags).
+		Int64("tagged", tagged.Load()).
+		Msg("TagPrefix")
+
+	return err
+}
+
+// tag merges the tags into the tag set of the object.
+func (c *client) tag(k string, tags map[string]string) error {
+	out, err := c.GetObjectTagging(c.Context, &s3.GetObjectTaggingInput{
+		Bucket: c.Bucket,
+		Key:    &k,
+	})
+	if err != nil {
+		return err
+	}
+
+	merged := map[string]string{}
+	for _, t := range out.TagSet {
+		merged[*t.Key] = *t.Value
+	}
+	for name, v := range tags {
+		merged[name] = v
+	}
+
+	set := make([]types.Tag, 0, len(merged))
+	for name, v := range merged {
+		set = append(set, types.Tag{Key: &name, Value: &v})
+	}
+	sort.Slice(set, func(i, j int) bool { return *set[i].Key < *set[j].Key })
+
+	_, err = c.PutObjectTagging(c.Context, &s3.PutObjectTaggingInput{
+		Bucket:  c.Bucket,
+		Key:     &k,
+		Tagging: &types.Tagging{TagSet: set},
+	})
+	return err
+}
@@ -0,0 +1,41 @@
+package s3
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_TagPrefix(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	for _, k := range []string{"users/a.json", "users/b.json", "users/c.json", "orders/d.json"} {
+		assert.NoError(t, c.Put(k, "{}"))
+	}
+	b.object("users/a.json").header.Set("X-Amz-Tagging", "lifecycle=archive&team=web")
+
+	assert.NoError(t, c.TagPrefix("users/", map[string]string{"team": "data", "cost-center": "42"}, 2))
+
+	assert.Equal(t, "cost-center=42&lifecycle=archive&team=data", b.object("users/a.json").header.Get("X-Amz-Tagging"))
+	assert.Equal(t, "cost-center=42&team=data", b.object("users/c.json").header.Get("X-Amz-Tagging"))
+	assert.Empty(t, b.object("orders/d.json").header.Get("X-Amz-Tagging"))
+}
+
+func TestClient_TagPrefix_Error(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	for _, k := range []string{"users/a.json", "users/b.json", "users/c.json"} {
+		assert.NoError(t, c.Put(k, "{}"))
+	}
+	c = testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("tagging") {
+			writeError(w, http.StatusForbidden, "AccessDenied")
+			return
+		}
+		b.ServeHTTP(w, r)
+	})
+
+	err := c.TagPrefix("users/", map[string]string{"team": "data"}, 1)
+	assert.Equal(t, "AccessDenied", errorCode(err))
+}
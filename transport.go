@@ -0,0 +1,80 @@
+package s3
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+// WithHTTPClient sends requests with the HTTP client instead of the SDK default.
+// Proxy and connection pool options are applied to a copy of its
+// transport when it is an *http.Transport. The SDK refuses custom clients when
+// AWS_CA_BUNDLE is set.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = hc
+	}
+}
+
+// WithProxy sends requests through the HTTP proxy, overriding HTTPS_PROXY.
+func WithProxy(proxyURL string) Option {
+	return func(o *options) {
+		u, err := url.Parse(proxyURL)
+		if err != nil {
+			panic(err)
+		}
+		o.transport = append(o.transport, func(t *http.Transport) {
+			t.Proxy = http.ProxyURL(u)
+		})
+	}
+}
+
+// WithCABundle trusts the PEM encoded certificates in addition to the system
+// roots, for networks that intercept TLS with a private certificate authority.
+// It takes precedence over AWS_CA_BUNDLE and can't be combined with WithHTTPClient.
+func WithCABundle(pem []byte) Option {
+	return WithConfig(config.WithCustomCABundle(bytes.NewReader(pem)))
+}
+
+// WithMaxConnsPerHost limits the connections per host and keeps as many idle
+// connections alive, so highly concurrent callers reuse connections instead of
+// churning them.
+func WithMaxConnsPerHost(n int) Option {
+	return func(o *options) {
+		o.transport = append(o.transport, func(t *http.Transport) {
+			t.MaxConnsPerHost = n
+			t.MaxIdleConnsPerHost = n
+			t.MaxIdleConns = max(t.MaxIdleConns, n)
+		})
+	}
+}
+
+// buildHTTPClient returns the HTTP client configured by the options, or nil to
+// keep the SDK default.
+func (o *options) buildHTTPClient() aws.HTTPClient {
+	if o.httpClient == nil {
+		if len(o.transport) == 0 {
+			return nil
+		}
+		return awshttp.NewBuildableClient().WithTransportOptions(o.transport...)
+	}
+	hc := *o.httpClient
+	if t, ok := hc.Transport.(*http.Transport); ok {
+		hc.Transport = o.buildTransport(t)
+	} else if hc.Transport == nil && len(o.transport) > 0 {
+		hc.Transport = o.buildTransport(http.DefaultTransport.(*http.Transport))
+	}
+	return &hc
+}
+
+func (o *options) buildTransport(t *http.Transport) *http.Transport {
+	t = t.Clone()
+	for _, fn := range o.transport {
+		fn(t)
+	}
+	return t
+}
@@ -0,0 +1,101 @@
+package s3
+
+import (
+	"context"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/assert"
+)
+
+func transportClient(endpoint string, opts ...Option) Service {
+	return NewWithOptions(context.Background(), append([]Option{
+		WithBucket("bytelyon-db"),
+		WithConfig(config.WithRegion("us-east-1")),
+		WithStaticCredentials("id", "secret", ""),
+		WithS3Options(func(o *s3.Options) {
+			o.BaseEndpoint = &endpoint
+			o.UsePathStyle = true
+		}),
+	}, opts...)...)
+}
+
+func TestWithProxy(t *testing.T) {
+
+	t.Setenv("AWS_CA_BUNDLE", "")
+
+	var host string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host = r.Host
+		_, _ = io.WriteString(w, "proxied")
+	}))
+	t.Cleanup(proxy.Close)
+
+	b, err := transportClient("http://s3.corp.invalid", WithProxy(proxy.URL)).Get(testKey())
+	assert.NoError(t, err)
+	assert.Equal(t, "proxied", string(b))
+	assert.Equal(t, "s3.corp.invalid", host)
+}
+
+func TestWithCABundle(t *testing.T) {
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "trusted")
+	}))
+	t.Cleanup(srv.Close)
+
+	t.Setenv("AWS_CA_BUNDLE", "")
+
+	_, err := transportClient(srv.URL).Get(testKey())
+	assert.Error(t, err)
+
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	b, err := transportClient(srv.URL, WithCABundle(ca)).Get(testKey())
+	assert.NoError(t, err)
+	assert.Equal(t, "trusted", string(b))
+
+	assert.Panics(t, func() { transportClient(srv.URL, WithCABundle([]byte("not a certificate"))) })
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestWithHTTPClient(t *testing.T) {
+
+	t.Setenv("AWS_CA_BUNDLE", "")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "custom")
+	}))
+	t.Cleanup(srv.Close)
+
+	var calls int
+	hc := &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		calls++
+		return http.DefaultTransport.RoundTrip(r)
+	})}
+
+	b, err := transportClient(srv.URL, WithHTTPClient(hc)).Get(testKey())
+	assert.NoError(t, err)
+	assert.Equal(t, "custom", string(b))
+	assert.Equal(t, 1, calls)
+}
+
+func TestWithMaxConnsPerHost(t *testing.T) {
+
+	o := &options{}
+	WithMaxConnsPerHost(256)(o)
+	WithHTTPClient(&http.Client{Transport: &http.Transport{MaxIdleConns: 100}})(o)
+
+	tr := o.buildHTTPClient().(*http.Client).Transport.(*http.Transport)
+	assert.Equal(t, 256, tr.MaxConnsPerHost)
+	assert.Equal(t, 256, tr.MaxIdleConnsPerHost)
+	assert.Equal(t, 256, tr.MaxIdleConns)
+	assert.Nil(t, (&options{}).buildHTTPClient())
+}
@@ -0,0 +1,81 @@
+package s3
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+const (
+	// expiresMetadata is the user metadata key holding the RFC 3339 expiry
+	// of objects written by PutWithTTL.
+	expiresMetadata = "expires"
+	// ttlTag is the tag holding the TTL of objects written by PutWithTTL in
+	// whole days, rounded up.
+	ttlTag = "ttl-days"
+)
+
+// PutWithTTL puts the value as Put does and records when it expires. Expired
+// objects are removed by Sweep. The object is also tagged ttl-days=<n> with
+// the TTL rounded up to whole days, so a lifecycle rule expiring objects
+// tagged ttl-days=n after n days removes them without sweeping.
+func (c *client) PutWithTTL(k string, a any, ttl time.Duration) error {
+	expires := time.Now().Add(ttl).UTC()
+	days := int(math.Ceil(ttl.Hours() / 24))
+	_, err := c.put("PutWithTTL", k, a, &s3.PutObjectInput{
+		Metadata: map[string]string{expiresMetadata: expires.Format(time.RFC3339)},
+		Tagging:  aws.String(fmt.Sprintf("%s=%d", ttlTag, days)),
+	})
+	return err
+}
+
+// Sweep deletes the expired objects written by PutWithTTL under the prefix and
+// returns how many were deleted. Expiry is read from each object's metadata,
+// costing a HEAD request per object. Objects are deleted only if unchanged
+// since they were read, so those rewritten meanwhile are kept.
+func (c *client) Sweep(p string) (int, error) {
+
+	now := time.Now()
+	var deleted int
+	err := c.walk(p, func(obj types.Object) error {
+		out, err := c.HeadObject(c.Context, &s3.HeadObjectInput{
+			Bucket:  c.Bucket,
+			Key:     obj.Key,
+			IfMatch: obj.ETag,
+		})
+		if IsNotFound(err) || isPreconditionFailed(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		expires, err := time.Parse(time.RFC3339, out.Metadata[expiresMetadata])
+		if err != nil || expires.After(now) {
+			return nil
+		}
+		_, err = c.deleteObject(&s3.DeleteObjectInput{
+			Bucket:  c.Bucket,
+			Key:     obj.Key,
+			IfMatch: out.ETag,
+		})
+		if IsNotFound(err) || isPreconditionFailed(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		deleted++
+		return nil
+	})
+
+	c.log("Sweep", err).
+		Str("prefix", p).
+		Int("deleted", deleted).
+		Msg("Sweep")
+
+	return deleted, err
+}
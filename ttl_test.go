@@ -0,0 +1,59 @@
+package s3
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_PutWithTTL(t *testing.T) {
+
+	c, b := newTestBucket(t)
+
+	assert.NoError(t, c.PutWithTTL("cache/a.json", map[string]int{"a": 1}, 36*time.Hour))
+	o := b.object("cache/a.json")
+	assert.Equal(t, `{"a":1}`, string(o.body))
+	assert.Equal(t, "application/json", o.header.Get("Content-Type"))
+	assert.Equal(t, "ttl-days=2", o.header.Get("X-Amz-Tagging"))
+
+	expires, err := time.Parse(time.RFC3339, o.header.Get("X-Amz-Meta-Expires"))
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(36*time.Hour), expires, time.Minute)
+}
+
+func TestClient_Sweep(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	assert.NoError(t, c.PutWithTTL("cache/expired", "x", -time.Minute))
+	assert.NoError(t, c.PutWithTTL("cache/fresh", "x", time.Hour))
+	assert.NoError(t, c.Put("cache/forever", "x"))
+
+	n, err := c.Sweep("cache/")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{"cache/forever", "cache/fresh"}, b.keys())
+}
+
+func TestClient_Sweep_replaced(t *testing.T) {
+
+	var b *testBucket
+	c, b := newTestBucket(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r)
+			// the object is rewritten between its HEAD and its delete
+			if r.Method == http.MethodHead {
+				b.mu.Lock()
+				b.put("cache/expired", []byte(`"y"`), nil)
+				b.mu.Unlock()
+			}
+		})
+	})
+	assert.NoError(t, c.PutWithTTL("cache/expired", "x", -time.Minute))
+
+	n, err := c.Sweep("cache/")
+	assert.NoError(t, err)
+	assert.Zero(t, n)
+	assert.Equal(t, `"y"`, string(b.object("cache/expired").body))
+}
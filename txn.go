@@ -0,0 +1,136 @@
+package s3
+
+import (
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/oklog/ulid/v2"
+)
+
+// Txn is a set of writes to related objects that are applied together by
+// Commit with best-effort atomicity: every value is staged under .staging/
+// before any key is changed, and keys already changed are restored if
+// applying another write fails. Readers can observe a partially applied
+// transaction, and one interrupted by a crash is neither completed nor
+// rolled back.
+type Txn struct {
+	c   *client
+	ops []txnOp
+}
+
+type txnOp struct {
+	key    string
+	value  any
+	delete bool
+}
+
+// Txn starts a transaction.
+func (c *client) Txn() *Txn {
+	return &Txn{c: c}
+}
+
+// Put adds writing the value, encoded as Put does, to the transaction.
+func (t *Txn) Put(k string, a any) *Txn {
+	t.ops = append(t.ops, txnOp{key: k, value: a})
+	return t
+}
+
+// Delete adds deleting the object to the transaction.
+func (t *Txn) Delete(k string) *Txn {
+	t.ops = append(t.ops, txnOp{key: k, delete: true})
+	return t
+}
+
+// Commit stages the values put, backs up the objects the transaction
+// changes, then applies the writes in order. If staging fails nothing is
+// changed, and if applying a write fails the keys already written are
+// restored from their backups. Staged values and backups are deleted once
+// the transaction is committed or rolled back.
+func (t *Txn) Commit() error {
+
+	staging := stagingPrefix + ulid.Make().String() + "/"
+
+	staged := make([]string, len(t.ops))
+	var err error
+	for i := 0; err == nil && i < len(t.ops); i++ {
+		if op := t.ops[i]; !op.delete {
+			staged[i] = fmt.Sprintf("%s%d/%s", staging, i, path.Base(op.key))
+			var v any
+			if v, err = t.c.inspectStaged(op.key, op.value); err == nil {
+				_, err = t.c.put("Put", staged[i], v, &s3.PutObjectInput{})
+			}
+		}
+	}
+
+	// backups maps each key changed to its backup, or "" when it didn't exist
+	backups := map[string]string{}
+	for i := 0; err == nil && i < len(t.ops); i++ {
+		k := t.ops[i].key
+		if _, ok := backups[k]; ok {
+			continue
+		}
+		backup := fmt.Sprintf("%sbackup/%d/%s", staging, i, path.Base(k))
+		if _, err = t.c.copy(k, backup, false); IsNotFound(err) {
+			backup, err = "", nil
+		}
+		backups[k] = backup
+	}
+
+	var applied int
+	for ; err == nil && applied < len(t.ops); applied++ {
+		if op := t.ops[applied]; op.delete {
+			err = t.c.Delete(op.key)
+		} else {
+			// values were inspected before they were staged
+			_, err = t.c.copy(staged[applied], op.key, false)
+		}
+	}
+
+	var rollback error
+	if err != nil && applied > 0 {
+		// the failed write may have been applied, so it is restored too
+		rollback = t.rollback(backups, t.ops[:applied])
+	}
+	cleanup := t.c.walk(staging, func(obj types.Object) error {
+		_, err := t.c.deleteObject(&s3.DeleteObjectInput{Bucket: t.c.Bucket, Key: obj.Key})
+		return err
+	})
+	if err == nil {
+		err = cleanup
+	}
+	err = errors.Join(err, rollback)
+
+	t.c.log("Commit", err).
+		Str("staging", staging).
+		Int("ops", len(t.ops)).
+		Int("applied", applied).
+		Msg("Commit")
+
+	return err
+}
+
+// rollback restores the keys changed by the ops from their backups,
+// deleting those that didn't exist before the transaction.
+func (t *Txn) rollback(backups map[string]string, ops []txnOp) error {
+	var errs []error
+	restored := map[string]bool{}
+	for _, op := range ops {
+		if restored[op.key] {
+			continue
+		}
+		restored[op.key] = true
+		var err error
+		if backup := backups[op.key]; backup != "" {
+			_, err = t.c.copy(backup, op.key, false)
+		} else {
+			err = t.c.Delete(op.key)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("s3: rolling back %s: %w", op.key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,68 @@
+package s3
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTxn_Commit(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	assert.NoError(t, c.Put("a.json", map[string]int{"v": 0}))
+	assert.NoError(t, c.Put("b.json", map[string]int{"v": 0}))
+
+	err := c.Txn().
+		Put("a.json", map[string]int{"v": 1}).
+		Put("c.json", map[string]int{"v": 1}).
+		Delete("b.json").
+		Put("a.json", map[string]int{"v": 2}).
+		Commit()
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"a.json", "c.json"}, b.keys())
+	assert.Equal(t, `{"v":2}`, string(b.object("a.json").body))
+	assert.Equal(t, "application/json", b.object("c.json").header.Get("Content-Type"))
+}
+
+func TestTxn_Rollback(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	assert.NoError(t, c.Put("a.json", map[string]int{"v": 0}))
+	assert.NoError(t, c.Put("b.json", map[string]int{"v": 0}))
+
+	errRejected := errors.New("rejected")
+	c.hooks = append(c.hooks, Hooks{AfterPut: func(e HookEvent) error {
+		if e.Key == "c.json" {
+			return errRejected
+		}
+		return nil
+	}})
+
+	err := c.Txn().
+		Put("a.json", map[string]int{"v": 1}).
+		Delete("b.json").
+		Put("c.json", map[string]int{"v": 1}).
+		Put("d.json", map[string]int{"v": 1}).
+		Commit()
+	assert.ErrorIs(t, err, errRejected)
+
+	assert.Equal(t, []string{"a.json", "b.json"}, b.keys())
+	assert.Equal(t, `{"v":0}`, string(b.object("a.json").body))
+	assert.Equal(t, `{"v":0}`, string(b.object("b.json").body))
+}
+
+func TestTxn_StagingFailed(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	assert.NoError(t, c.Put("a.json", map[string]int{"v": 0}))
+	c.maxPutSize = 10
+
+	err := c.Txn().
+		Delete("a.json").
+		Put("b.json", map[string]string{"v": "too large to stage"}).
+		Commit()
+	assert.ErrorIs(t, err, ErrPutTooLarge)
+	assert.Equal(t, []string{"a.json"}, b.keys())
+}
@@ -0,0 +1,233 @@
+package s3
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// ErrInvalidDocument is returned by writes refused by a validator
+// registered with WithValidator.
+var ErrInvalidDocument = errors.New("s3: invalid document")
+
+// maxValidatedSize is the largest body streamed writes and copies hold in
+// memory for validators, which need documents whole.
+var maxValidatedSize = 64 << 20
+
+type validator struct {
+	pattern  string
+	validate func([]byte) error
+}
+
+// WithValidator validates the body of every object written to a key matching
+// the shell pattern, as path.Match matches it, with the function before it is
+// uploaded, e.g. against a JSON Schema, and refuses writes it returns an
+// error for with ErrInvalidDocument, so malformed documents never enter the
+// store. Streamed writes keep a copy of the body to validate while their
+// parts upload, and complete only if it is valid; copies validate their
+// source, and presigned and resumable uploads are staged under .staging/
+// and validated when complete. Bodies over 64 MiB can't be validated this
+// way and are refused. Txn validates values before staging them. It panics
+// if the pattern is malformed.
+func WithValidator(pattern string, validate func([]byte) error) Option {
+	if _, err := path.Match(pattern, ""); err != nil {
+		panic("s3: malformed glob " + pattern)
+	}
+	return func(o *options) {
+		o.validators = append(o.validators, validator{pattern, validate})
+	}
+}
+
+// validates reports whether writes to the key are validated.
+func (c *client) validates(k string) bool {
+	for _, v := range c.validators {
+		if ok, _ := path.Match(v.pattern, k); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// validate runs the validators matching the key over the body.
+func (c *client) validate(k string, body []byte) error {
+	for _, v := range c.validators {
+		if ok, _ := path.Match(v.pattern, k); !ok {
+			continue
+		}
+		if err := v.validate(body); err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrInvalidDocument, k, err)
+		}
+	}
+	return nil
+}
+
+// inspects reports whether bodies written to the key are validated or
+// scanned before they are uploaded.
+func (c *client) inspects(k string) bool {
+	return c.validates(k) || c.scans(k)
+}
+
+// inspect validates and scans a body written to the key.
+func (c *client) inspect(k string, body []byte) error {
+	if err := c.validate(k, body); err != nil {
+		return err
+	}
+	return c.scanContent(k, body)
+}
+
+// inspectStaged inspects a value, encoded as Put encodes it, or read in
+// full when it is an io.Reader, for the key it is staged elsewhere to be
+// copied to. It returns the value to stage, which is the body read when the
+// value was a reader.
+func (c *client) inspectStaged(k string, a any) (any, error) {
+	if !c.inspects(k) {
+		return a, nil
+	}
+	if r, ok := a.(io.Reader); ok {
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return b, c.inspect(k, b)
+	}
+	body, _, release, err := encode(k, a)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return a, c.inspect(k, body)
+}
+
+// inspector inspects a body as it is written, for writes streamed in parts
+// rather than held in memory. Scanners read the body through pipes as it is
+// written; validators are given a copy of it once it is complete.
+type inspector struct {
+	c     *client
+	k     string
+	body  *bytes.Buffer
+	err   error
+	scans []*pipedScan
+}
+
+// pipedScan is a scanner reading a body through a pipe.
+type pipedScan struct {
+	contentScanner
+	w      *io.PipeWriter
+	threat string
+	err    error
+	done   chan struct{}
+}
+
+// errInspectionAborted ends the scans of a body that won't be written.
+var errInspectionAborted = errors.New("s3: inspection aborted")
+
+// newInspector starts inspecting a body written to the key, or returns nil
+// when writes to the key aren't inspected.
+func (c *client) newInspector(k string) *inspector {
+	if !c.inspects(k) {
+		return nil
+	}
+	in := &inspector{c: c, k: k}
+	if c.validates(k) {
+		in.body = &bytes.Buffer{}
+	}
+	for _, s := range c.scanners {
+		if ok, _ := path.Match(s.pattern, k); !ok {
+			continue
+		}
+		pr, pw := io.Pipe()
+		ps := &pipedScan{contentScanner: s, w: pw, done: make(chan struct{})}
+		go func() {
+			defer close(ps.done)
+			ps.threat, ps.err = s.scanner.Scan(c.Context, k, pr)
+			// a scanner that stops reading early doesn't block the writes
+			pr.CloseWithError(io.ErrClosedPipe)
+		}()
+		in.scans = append(in.scans, ps)
+	}
+	return in
+}
+
+func (in *inspector) Write(p []byte) (int, error) {
+	if in.body != nil {
+		if in.body.Len()+len(p) > maxValidatedSize {
+			in.err = fmt.Errorf("%w: %s is over the %d bytes that can be validated", ErrInvalidDocument, in.k, maxValidatedSize)
+			in.body = nil
+		} else {
+			in.body.Write(p)
+		}
+	}
+	for _, ps := range in.scans {
+		// a scanner that stopped reading has its verdict
+		_, _ = ps.w.Write(p)
+	}
+	return len(p), nil
+}
+
+// finish waits for the scanners' verdicts and validates the body, returning
+// why the write is refused and, when a scanner found a threat, the prefix to
+// quarantine the body under and the threat.
+func (in *inspector) finish() (string, string, error) {
+	for _, ps := range in.scans {
+		ps.w.Close()
+	}
+	err := in.err
+	if err == nil && in.body != nil {
+		err = in.c.validate(in.k, in.body.Bytes())
+	}
+	var quarantine, threat string
+	for _, ps := range in.scans {
+		<-ps.done
+		in.c.log("ScanContent", ps.err).
+			Str("key", in.k).
+			Str("threat", ps.threat).
+			Msg("ScanContent")
+		switch {
+		case err != nil:
+		case ps.threat != "":
+			quarantine, threat = ps.quarantine, ps.threat
+			err = fmt.Errorf("%w: %s: %s", ErrInfected, in.k, ps.threat)
+		case ps.err != nil:
+			err = ps.err
+		}
+	}
+	return quarantine, threat, err
+}
+
+// abort stops inspecting a body that won't be written.
+func (in *inspector) abort() {
+	for _, ps := range in.scans {
+		ps.w.CloseWithError(errInspectionAborted)
+		<-ps.done
+	}
+}
+
+// inspectObject inspects the object at src, at the ETag when it isn't nil,
+// for a copy to the key, streaming it through the scanners. An object a
+// scanner finds a threat in is copied to its quarantine, if any.
+func (c *client) inspectObject(src string, etag *string, k string) error {
+	in := c.newInspector(k)
+	if in == nil {
+		return nil
+	}
+	out, err := c.getObject(&s3.GetObjectInput{Bucket: c.Bucket, Key: &src, IfMatch: etag})
+	if err != nil {
+		in.abort()
+		return err
+	}
+	_, err = io.Copy(in, out.Body)
+	out.Body.Close()
+	if err != nil {
+		in.abort()
+		return err
+	}
+	quarantine, threat, err := in.finish()
+	if quarantine != "" {
+		err = errors.Join(err, c.quarantineObject(src, out.ETag, quarantine+k, threat))
+	}
+	return err
+}
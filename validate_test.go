@@ -0,0 +1,79 @@
+package s3
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func validJSON(b []byte) error {
+	if !json.Valid(b) {
+		return errors.New("not JSON")
+	}
+	return nil
+}
+
+func TestWithValidator(t *testing.T) {
+
+	assert.Panics(t, func() { WithValidator("[", validJSON) })
+
+	c, b := newTestBucket(t)
+	WithValidator("users/*.json", validJSON)(c.options)
+
+	assert.NoError(t, c.Put("users/a.json", map[string]string{"name": "a"}))
+	err := c.Put("users/b.json", "{")
+	assert.ErrorIs(t, err, ErrInvalidDocument)
+	assert.ErrorContains(t, err, "users/b.json: not JSON")
+	assert.NoError(t, c.Put("notes/b.json", "{"))
+	assert.NoError(t, c.Put("users/b.txt", "{"))
+
+	err = c.Txn().Put("users/c.json", "{").Commit()
+	assert.ErrorIs(t, err, ErrInvalidDocument)
+
+	assert.ErrorIs(t, c.PutAtomic("users/c.json", "{"), ErrInvalidDocument)
+	assert.ErrorIs(t, c.PutAtomic("users/c.json", strings.NewReader("{")), ErrInvalidDocument)
+	assert.NoError(t, c.PutAtomic("users/e.json", strings.NewReader("{}")))
+	assert.Equal(t, "{}", string(b.object("users/e.json").body))
+
+	w := c.NewWriter("users/d.json")
+	_, err = w.Write([]byte("{"))
+	assert.NoError(t, err)
+	assert.ErrorIs(t, w.Close(), ErrInvalidDocument)
+
+	assert.Equal(t, []string{"notes/b.json", "users/a.json", "users/b.txt", "users/e.json"}, b.keys())
+}
+
+func TestWithValidator_Streamed(t *testing.T) {
+
+	c, b := newTestBucket(t)
+	WithValidator("users/*.json", validJSON)(c.options)
+
+	// parts upload as they are written and the object is completed once valid
+	doc := `"` + strings.Repeat("a", partSize) + `"`
+	w := c.NewWriter("users/a.json")
+	_, err := w.Write([]byte(doc))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, b.uploads)
+	assert.Nil(t, b.object("users/a.json"))
+	assert.NoError(t, w.Close())
+	assert.True(t, bytes.Equal([]byte(doc), b.object("users/a.json").body))
+
+	w = c.NewWriter("users/b.json")
+	_, err = w.Write([]byte(doc[1:]))
+	assert.NoError(t, err)
+	assert.ErrorIs(t, w.Close(), ErrInvalidDocument)
+	assert.Nil(t, b.object("users/b.json"))
+
+	defer func(n int) { maxValidatedSize = n }(maxValidatedSize)
+	maxValidatedSize = partSize
+	w = c.NewWriter("users/c.json")
+	_, err = w.Write([]byte(doc))
+	assert.NoError(t, err)
+	assert.ErrorIs(t, w.Close(), ErrInvalidDocument)
+	assert.Nil(t, b.object("users/c.json"))
+	assert.Empty(t, b.uploads)
+}
@@ -0,0 +1,123 @@
+package s3
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ChangeType is the kind of change reported by Watch.
+type ChangeType string
+
+const (
+	Created ChangeType = "created"
+	Updated ChangeType = "updated"
+	Deleted ChangeType = "deleted"
+)
+
+// ChangeEvent reports a key under a watched prefix that changed between polls.
+type ChangeEvent struct {
+	Type ChangeType
+	Key  string
+	ETag string
+	Size int64
+}
+
+// Watch polls the prefix every interval and emits an event for every key that
+// was created, updated (its ETag changed) or deleted since the previous poll.
+// The first listing establishes the baseline, retried every interval until
+// it succeeds. Call stop to end the watch, which closes the channel, as does
+// the client's context being done.
+func (c *client) Watch(p string, interval time.Duration) (<-chan ChangeEvent, func()) {
+
+	ch := make(chan ChangeEvent)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(ch)
+
+		prev, err := c.snapshot(p)
+		for err != nil {
+			c.log("Watch", err).Str("prefix", p).Msg("Watch")
+			select {
+			case <-done:
+				return
+			case <-c.Context.Done():
+				return
+			case <-time.After(interval):
+			}
+			prev, err = c.snapshot(p)
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-c.Context.Done():
+				return
+			case <-ticker.C:
+			}
+
+			next, err := c.snapshot(p)
+			if err != nil {
+				c.log("Watch", err).Str("prefix", p).Msg("Watch")
+				continue
+			}
+			for _, e := range diffSnapshots(prev, next) {
+				select {
+				case ch <- e:
+				case <-done:
+					return
+				case <-c.Context.Done():
+					return
+				}
+			}
+			prev = next
+		}
+	}()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			close(done)
+		})
+	}
+}
+
+// snapshot lists the prefix as a map of key to object.
+func (c *client) snapshot(p string) (map[string]types.Object, error) {
+	m := map[string]types.Object{}
+	err := c.walk(p, func(obj types.Object) error {
+		m[*obj.Key] = obj
+		return nil
+	})
+	return m, err
+}
+
+// diffSnapshots returns the changes between two listings ordered by key.
+func diffSnapshots(prev, next map[string]types.Object) []ChangeEvent {
+	var events []ChangeEvent
+	for k, obj := range next {
+		old, ok := prev[k]
+		switch {
+		case !ok:
+			events = append(events, ChangeEvent{Created, k, aws.ToString(obj.ETag), aws.ToInt64(obj.Size)})
+		case aws.ToString(old.ETag) != aws.ToString(obj.ETag):
+			events = append(events, ChangeEvent{Updated, k, aws.ToString(obj.ETag), aws.ToInt64(obj.Size)})
+		}
+	}
+	for k, obj := range prev {
+		if _, ok := next[k]; !ok {
+			events = append(events, ChangeEvent{Deleted, k, aws.ToString(obj.ETag), aws.ToInt64(obj.Size)})
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Key < events[j].Key
+	})
+	return events
+}
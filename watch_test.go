@@ -0,0 +1,49 @@
+package s3
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+
+	obj := func(etag string) types.Object {
+		return types.Object{ETag: aws.String(etag), Size: aws.Int64(1)}
+	}
+
+	prev := map[string]types.Object{"a": obj("1"), "b": obj("1"), "c": obj("1")}
+	next := map[string]types.Object{"a": obj("1"), "b": obj("2"), "d": obj("1")}
+
+	assert.Equal(t, []ChangeEvent{
+		{Updated, "b", "2", 1},
+		{Deleted, "c", "1", 1},
+		{Created, "d", "1", 1},
+	}, diffSnapshots(prev, next))
+	assert.Empty(t, diffSnapshots(next, next))
+}
+
+func TestClient_Watch_canceled(t *testing.T) {
+
+	c := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusForbidden, "AccessDenied")
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	c.Context = ctx
+
+	// the watch ends while the baseline listing is still failing
+	ch, stop := c.Watch("docs/", time.Hour)
+	defer stop()
+	cancel()
+	select {
+	case _, ok := <-ch:
+		assert.False(t, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch didn't end with the context")
+	}
+}